@@ -0,0 +1,195 @@
+// Package animation renders effects such as rainbows, fades, chases and fire
+// on LED strips and matrices.
+//
+// An Effect renders the frame at a given time into a slice of colors, one per
+// LED. A Scheduler plays a list of effects, with transitions between them,
+// and writes the frames to an Output, such as a ws2812.Device:
+//
+//	s := animation.NewScheduler(ws2812.New(pin), 30,
+//		animation.Step{Effect: &animation.Rainbow{Period: 5 * time.Second}, Duration: 20 * time.Second},
+//		animation.Step{Effect: &animation.Fire{}, Duration: 20 * time.Second, Transition: 2 * time.Second},
+//	)
+//	s.Run(20 * time.Millisecond)
+//
+// Effects only depend on the time they are given, so they can be tested
+// without hardware or a real clock, by calling Scheduler.Update.
+package animation // import "tinygo.org/x/drivers/animation"
+
+import (
+	"image/color"
+	"time"
+)
+
+// Effect is an animation of a strip or a matrix of LEDs.
+type Effect interface {
+	// Frame renders the frame at time t since the start of the effect into
+	// pixels. Times increase, not at a fixed rate, but start again from zero
+	// when the effect is restarted.
+	Frame(t time.Duration, pixels []color.RGBA)
+}
+
+// Output is a device that shows the colors of a strip of LEDs, such as
+// ws2812.Device.
+type Output interface {
+	WriteColors(pixels []color.RGBA) error
+}
+
+// OutputFunc adapts a function to the Output interface, to wrap devices with
+// a different API:
+//
+//	animation.OutputFunc(func(pixels []color.RGBA) error {
+//		_, err := apa102.WriteColors(pixels)
+//		return err
+//	})
+type OutputFunc func(pixels []color.RGBA) error
+
+// WriteColors calls f(pixels).
+func (f OutputFunc) WriteColors(pixels []color.RGBA) error {
+	return f(pixels)
+}
+
+// Step is an effect played by a Scheduler.
+type Step struct {
+	Effect Effect
+
+	// Duration is how long the effect plays before the next step starts.
+	Duration time.Duration
+
+	// Transition is the duration of the crossfade from the previous step,
+	// from the start of this step. The previous effect keeps running during
+	// the transition.
+	Transition time.Duration
+}
+
+// Scheduler plays steps in a loop on an output.
+type Scheduler struct {
+	Output Output
+	Steps  []Step
+
+	pixels  []color.RGBA
+	scratch []color.RGBA // for transitions
+}
+
+// NewScheduler returns a scheduler for n LEDs that plays the given steps in a
+// loop.
+func NewScheduler(out Output, n int, steps ...Step) *Scheduler {
+	return &Scheduler{
+		Output: out,
+		Steps:  steps,
+		pixels: make([]color.RGBA, n),
+	}
+}
+
+// Render renders the frame at time t since the start of the first step, and
+// returns the colors of the LEDs. The slice is reused by the next call.
+func (s *Scheduler) Render(t time.Duration) []color.RGBA {
+	var cycle time.Duration
+	for _, step := range s.Steps {
+		cycle += step.Duration
+	}
+	if cycle <= 0 {
+		fill(s.pixels, color.RGBA{})
+		return s.pixels
+	}
+	local := t % cycle
+	i, start := 0, time.Duration(0)
+	for local >= start+s.Steps[i].Duration {
+		start += s.Steps[i].Duration
+		i++
+	}
+	step := s.Steps[i]
+	local -= start
+	step.Effect.Frame(local, s.pixels)
+	if local >= step.Transition || t < cycle && i == 0 {
+		// no transition into the first step when starting
+		return s.pixels
+	}
+
+	prev := i - 1
+	if prev < 0 {
+		prev = len(s.Steps) - 1
+	}
+	if len(s.scratch) != len(s.pixels) {
+		s.scratch = make([]color.RGBA, len(s.pixels))
+	}
+	s.Steps[prev].Effect.Frame(local+s.Steps[prev].Duration, s.scratch)
+	Blend(s.pixels, s.scratch, s.pixels, fraction(local, step.Transition))
+	return s.pixels
+}
+
+// Update renders the frame at time t since the start of the first step and
+// writes it to the output.
+func (s *Scheduler) Update(t time.Duration) error {
+	return s.Output.WriteColors(s.Render(t))
+}
+
+// Run updates the output every interval, until writing to the output fails.
+func (s *Scheduler) Run(interval time.Duration) error {
+	start := time.Now()
+	next := start
+	for {
+		if err := s.Update(time.Since(start)); err != nil {
+			return err
+		}
+		next = next.Add(interval)
+		time.Sleep(time.Until(next))
+	}
+}
+
+// Crossfade is an effect that fades from an effect to another during
+// Duration, then keeps showing the second one. Both effects start with the
+// crossfade.
+type Crossfade struct {
+	From, To Effect
+	Duration time.Duration
+
+	scratch []color.RGBA
+}
+
+// Frame renders both effects, mixed according to t.
+func (c *Crossfade) Frame(t time.Duration, pixels []color.RGBA) {
+	c.To.Frame(t, pixels)
+	if t >= c.Duration {
+		return
+	}
+	if len(c.scratch) != len(pixels) {
+		c.scratch = make([]color.RGBA, len(pixels))
+	}
+	c.From.Frame(t, c.scratch)
+	Blend(pixels, c.scratch, pixels, fraction(t, c.Duration))
+}
+
+// fraction returns t/d scaled to 0-255.
+func fraction(t, d time.Duration) uint8 {
+	if t <= 0 {
+		return 0
+	}
+	if t >= d {
+		return 255
+	}
+	return uint8(int64(t) * 255 / int64(d))
+}
+
+// Blend sets dst to the mix of a and b, from only a with f = 0 to only b with
+// f = 255. dst may be a or b.
+func Blend(dst, a, b []color.RGBA, f uint8) {
+	for i := range dst {
+		dst[i] = Mix(a[i], b[i], f)
+	}
+}
+
+// Mix returns the mix of two colors, from only a with f = 0 to only b with
+// f = 255.
+func Mix(a, b color.RGBA, f uint8) color.RGBA {
+	mix := func(x, y uint8) uint8 {
+		return uint8((uint16(x)*uint16(255-f) + uint16(y)*uint16(f) + 127) / 255)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// fill sets all pixels to c.
+func fill(pixels []color.RGBA, c color.RGBA) {
+	for i := range pixels {
+		pixels[i] = c
+	}
+}
@@ -0,0 +1,125 @@
+package animation
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+var (
+	black = color.RGBA{A: 255}
+	red   = color.RGBA{R: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+)
+
+// recorder is an output that keeps the frames written to it.
+type recorder struct {
+	frames [][]color.RGBA
+}
+
+func (r *recorder) WriteColors(pixels []color.RGBA) error {
+	r.frames = append(r.frames, append([]color.RGBA(nil), pixels...))
+	return nil
+}
+
+func TestScheduler(t *testing.T) {
+	c := qt.New(t)
+	out := &recorder{}
+	s := NewScheduler(out, 2,
+		Step{Effect: &Solid{red}, Duration: time.Second},
+		Step{Effect: &Solid{blue}, Duration: time.Second, Transition: 200 * time.Millisecond},
+	)
+	// no transition into the first step when starting
+	c.Assert(s.Update(0), qt.IsNil)
+	c.Assert(out.frames[0], qt.DeepEquals, []color.RGBA{red, red})
+
+	c.Assert(s.Render(500 * time.Millisecond)[0], qt.Equals, red)
+	c.Assert(s.Render(1100 * time.Millisecond)[0], qt.Equals, color.RGBA{R: 128, B: 127, A: 255})
+	c.Assert(s.Render(1200 * time.Millisecond)[0], qt.Equals, blue)
+	// loops
+	c.Assert(s.Render(2500 * time.Millisecond)[0], qt.Equals, red)
+
+	s.Steps[0].Transition = 100 * time.Millisecond
+	c.Assert(s.Render(2050 * time.Millisecond)[0], qt.Equals, color.RGBA{R: 127, B: 128, A: 255})
+	c.Assert(s.Render(50 * time.Millisecond)[0], qt.Equals, red)
+
+	s.Steps = nil
+	c.Assert(s.Render(0), qt.DeepEquals, []color.RGBA{{}, {}})
+}
+
+func TestCrossfade(t *testing.T) {
+	c := qt.New(t)
+	pixels := make([]color.RGBA, 3)
+	f := &Crossfade{From: &Solid{red}, To: &Solid{blue}, Duration: time.Second}
+	f.Frame(0, pixels)
+	c.Assert(pixels[2], qt.Equals, red)
+	f.Frame(250*time.Millisecond, pixels)
+	c.Assert(pixels[2], qt.Equals, color.RGBA{R: 192, B: 63, A: 255})
+	f.Frame(time.Second, pixels)
+	c.Assert(pixels[2], qt.Equals, blue)
+}
+
+func TestMix(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(Mix(red, blue, 0), qt.Equals, red)
+	c.Assert(Mix(red, blue, 255), qt.Equals, blue)
+	c.Assert(Mix(black, color.RGBA{R: 100, A: 255}, 128), qt.Equals, color.RGBA{R: 50, A: 255})
+}
+
+func TestOutputFunc(t *testing.T) {
+	c := qt.New(t)
+	var got []color.RGBA
+	s := NewScheduler(OutputFunc(func(pixels []color.RGBA) error {
+		got = pixels
+		return nil
+	}), 1, Step{Effect: &Solid{blue}, Duration: time.Second})
+	c.Assert(s.Update(0), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []color.RGBA{blue})
+}
+
+func TestMatrix(t *testing.T) {
+	c := qt.New(t)
+	m := Matrix{Width: 3, Height: 2, Serpentine: true}
+	c.Assert(m.Index(0, 0), qt.Equals, 0)
+	c.Assert(m.Index(2, 0), qt.Equals, 2)
+	c.Assert(m.Index(2, 1), qt.Equals, 3)
+	c.Assert(m.Index(0, 1), qt.Equals, 5)
+	c.Assert(m.Index(3, 0), qt.Equals, -1)
+
+	// The rotated matrix maps pixels the same way as a rotated display.
+	for r := drivers.Rotation0; r <= drivers.Rotation270Mirror; r++ {
+		m := Matrix{Width: 4, Height: 3, Rotation: r}
+		d := tester.NewDisplay(4, 3)
+		d.SetRotation(r)
+		w, h := m.Size()
+		dw, dh := d.Size()
+		c.Assert([]int16{w, h}, qt.DeepEquals, []int16{dw, dh})
+		d.SetPixel(1, 0, red)
+		i := m.Index(1, 0)
+		c.Assert(d.Image.RGBAAt(i%4, i/4), qt.Equals, red, qt.Commentf("rotation %d", r))
+	}
+}
+
+func TestDisplayOutput(t *testing.T) {
+	c := qt.New(t)
+	m := Matrix{Width: 2, Height: 2, Serpentine: true}
+	d := tester.NewBufferedDisplay(2, 2)
+	s := NewScheduler(&DisplayOutput{Display: d, Matrix: m}, 4, Step{
+		Effect: &Func2D{Matrix: m, Func: func(t time.Duration, x, y int16) color.RGBA {
+			if x == 1 && y == 1 {
+				return red
+			}
+			return black
+		}},
+		Duration: time.Second,
+	})
+	c.Assert(s.Render(0), qt.DeepEquals, []color.RGBA{black, black, red, black})
+	c.Assert(s.Update(0), qt.IsNil)
+	c.Assert(d.GetPixel(1, 1), qt.Equals, red)
+	c.Assert(d.GetPixel(0, 1), qt.Equals, black)
+	c.Assert(d.Frames, qt.Equals, 1)
+}
@@ -0,0 +1,258 @@
+package animation
+
+import (
+	"image/color"
+	"time"
+)
+
+// Solid shows a single color.
+type Solid struct {
+	Color color.RGBA
+}
+
+// Frame sets all pixels to the color.
+func (s *Solid) Frame(t time.Duration, pixels []color.RGBA) {
+	fill(pixels, s.Color)
+}
+
+// Rainbow shows all hues, moving along the strip.
+type Rainbow struct {
+	// Period is the time it takes for a hue to go all around the color
+	// wheel. Zero means that the rainbow doesn't move.
+	Period time.Duration
+
+	// Length is the number of LEDs that show all hues. Zero means the whole
+	// strip.
+	Length int
+}
+
+// Frame renders the rainbow at time t.
+func (r *Rainbow) Frame(t time.Duration, pixels []color.RGBA) {
+	length := r.Length
+	if length <= 0 {
+		length = len(pixels)
+	}
+	var offset int
+	if r.Period > 0 {
+		offset = int(int64(t%r.Period) * 256 / int64(r.Period))
+	}
+	for i := range pixels {
+		pixels[i] = Hue(uint8(i*256/length + offset))
+	}
+}
+
+// Fade fades from a color to the next, in a loop.
+type Fade struct {
+	Colors []color.RGBA
+
+	// Period is the time it takes to fade from a color to the next.
+	Period time.Duration
+}
+
+// Frame renders the mix of colors at time t.
+func (f *Fade) Frame(t time.Duration, pixels []color.RGBA) {
+	if len(f.Colors) == 0 || f.Period <= 0 {
+		fill(pixels, color.RGBA{})
+		return
+	}
+	n := int64(t / f.Period)
+	from := f.Colors[n%int64(len(f.Colors))]
+	to := f.Colors[(n+1)%int64(len(f.Colors))]
+	fill(pixels, Mix(from, to, fraction(t%f.Period, f.Period)))
+}
+
+// Chase shows groups of lit LEDs moving along the strip.
+type Chase struct {
+	Color      color.RGBA
+	Background color.RGBA
+
+	// Length is the number of lit LEDs in a group, at least 1.
+	Length int
+
+	// Spacing is the distance between the start of two groups. Zero means
+	// that there is a single group on the strip.
+	Spacing int
+
+	// Step is the time it takes for the groups to move by one LED. Zero
+	// means that they don't move. It can be negative to move backwards.
+	Step time.Duration
+}
+
+// Frame renders the groups at their position at time t.
+func (c *Chase) Frame(t time.Duration, pixels []color.RGBA) {
+	spacing := c.Spacing
+	if spacing <= 0 {
+		spacing = len(pixels)
+	}
+	length := c.Length
+	if length < 1 {
+		length = 1
+	}
+	var pos int
+	if c.Step != 0 {
+		pos = int(int64(t/c.Step) % int64(spacing))
+	}
+	for i := range pixels {
+		d := (i - pos) % spacing
+		if d < 0 {
+			d += spacing
+		}
+		if d < length {
+			pixels[i] = c.Color
+		} else {
+			pixels[i] = c.Background
+		}
+	}
+}
+
+// Fire simulates flames rising along the strip, from its first LED. It is
+// deterministic: it always renders the same frames for the same seed and
+// times.
+type Fire struct {
+	// Cooling is how much the flames cool down as they rise, usually between
+	// 20 and 100. Zero means 55.
+	Cooling uint8
+
+	// Sparking is the chance, out of 255, that a new spark ignites at each
+	// step, usually between 50 and 200. Zero means 120.
+	Sparking uint8
+
+	// Interval is the time between steps of the simulation. Zero means 15ms.
+	Interval time.Duration
+
+	// Seed initializes the random number generator.
+	Seed uint32
+
+	heat  []uint8
+	steps int64 // steps of the simulation so far
+	rand  uint32
+}
+
+// maxFireSteps limits the number of steps simulated by a frame, when it is
+// rendered long after the previous one.
+const maxFireSteps = 100
+
+// Frame advances the simulation to time t and renders it.
+func (f *Fire) Frame(t time.Duration, pixels []color.RGBA) {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = 15 * time.Millisecond
+	}
+	steps := int64(t / interval)
+	if len(f.heat) != len(pixels) || steps < f.steps {
+		f.reset(len(pixels))
+	}
+	if steps-f.steps > maxFireSteps {
+		f.steps = steps - maxFireSteps
+	}
+	for ; f.steps < steps; f.steps++ {
+		f.step()
+	}
+	for i, h := range f.heat {
+		pixels[i] = HeatColor(h)
+	}
+}
+
+// reset restarts the simulation.
+func (f *Fire) reset(n int) {
+	if cap(f.heat) >= n {
+		f.heat = f.heat[:n]
+		for i := range f.heat {
+			f.heat[i] = 0
+		}
+	} else {
+		f.heat = make([]uint8, n)
+	}
+	f.steps = 0
+	f.rand = f.Seed
+	if f.rand == 0 {
+		f.rand = 1
+	}
+}
+
+// random returns a pseudo-random number between 0 and n-1, with xorshift32.
+func (f *Fire) random(n uint32) uint32 {
+	f.rand ^= f.rand << 13
+	f.rand ^= f.rand >> 17
+	f.rand ^= f.rand << 5
+	return f.rand % n
+}
+
+// step advances the simulation, the same way as the Fire2012 effect of
+// FastLED.
+func (f *Fire) step() {
+	n := len(f.heat)
+	if n == 0 {
+		return
+	}
+	cooling := uint32(f.Cooling)
+	if cooling == 0 {
+		cooling = 55
+	}
+	sparking := uint32(f.Sparking)
+	if sparking == 0 {
+		sparking = 120
+	}
+
+	// cool down every cell a little
+	maxCooling := cooling*10/uint32(n) + 2
+	for i, h := range f.heat {
+		c := f.random(maxCooling)
+		if uint32(h) > c {
+			f.heat[i] = h - uint8(c)
+		} else {
+			f.heat[i] = 0
+		}
+	}
+
+	// heat rises and diffuses
+	for i := n - 1; i >= 2; i-- {
+		f.heat[i] = uint8((uint16(f.heat[i-1]) + 2*uint16(f.heat[i-2])) / 3)
+	}
+
+	// ignite new sparks near the bottom
+	if f.random(255) < sparking {
+		i := int(f.random(7))
+		if i >= n {
+			i = n - 1
+		}
+		h := uint32(f.heat[i]) + 160 + f.random(96)
+		if h > 255 {
+			h = 255
+		}
+		f.heat[i] = uint8(h)
+	}
+}
+
+// HeatColor returns the color of a flame of the given temperature, from black
+// through red and yellow to white.
+func HeatColor(heat uint8) color.RGBA {
+	// scale to 0-191, in three ramps of 64
+	t := uint16(heat) * 191 / 255
+	ramp := uint8(t&0x3F) << 2
+	switch t >> 6 {
+	case 0:
+		return color.RGBA{R: ramp, A: 255}
+	case 1:
+		return color.RGBA{R: 255, G: ramp, A: 255}
+	default:
+		return color.RGBA{R: 255, G: 255, B: ramp, A: 255}
+	}
+}
+
+// Hue returns the fully saturated color of hue h, with red at 0, green at 85
+// and blue at 170.
+func Hue(h uint8) color.RGBA {
+	switch {
+	case h < 85:
+		v := h * 3
+		return color.RGBA{R: 255 - v, G: v, A: 255}
+	case h < 170:
+		v := (h - 85) * 3
+		return color.RGBA{G: 255 - v, B: v, A: 255}
+	default:
+		// up to 85 steps, back to red at 255
+		v := (h - 170) * 3
+		return color.RGBA{R: v, B: 255 - v, A: 255}
+	}
+}
@@ -0,0 +1,102 @@
+package animation
+
+import (
+	"image/color"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestHue(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(Hue(0), qt.Equals, red)
+	c.Assert(Hue(85), qt.Equals, color.RGBA{G: 255, A: 255})
+	c.Assert(Hue(170), qt.Equals, blue)
+	c.Assert(Hue(42), qt.Equals, color.RGBA{R: 129, G: 126, A: 255})
+
+	// The hues wrap around smoothly.
+	c.Assert(Hue(254), qt.Equals, color.RGBA{R: 252, B: 3, A: 255})
+	c.Assert(Hue(255), qt.Equals, red)
+	for h := 0; h < 256; h++ {
+		a, b := Hue(uint8(h)), Hue(uint8(h+1))
+		for _, d := range []int{int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B)} {
+			c.Assert(d >= -3 && d <= 3, qt.IsTrue, qt.Commentf("hue %d", h))
+		}
+	}
+}
+
+func TestRainbow(t *testing.T) {
+	c := qt.New(t)
+	pixels := make([]color.RGBA, 3)
+	r := &Rainbow{Period: time.Second}
+	r.Frame(0, pixels)
+	c.Assert(pixels, qt.DeepEquals, []color.RGBA{Hue(0), Hue(85), Hue(170)})
+	r.Frame(500*time.Millisecond, pixels)
+	c.Assert(pixels[0], qt.Equals, Hue(128))
+}
+
+func TestFade(t *testing.T) {
+	c := qt.New(t)
+	pixels := make([]color.RGBA, 1)
+	f := &Fade{Colors: []color.RGBA{red, blue, black}, Period: time.Second}
+	f.Frame(0, pixels)
+	c.Assert(pixels[0], qt.Equals, red)
+	f.Frame(1500*time.Millisecond, pixels)
+	c.Assert(pixels[0], qt.Equals, color.RGBA{B: 128, A: 255})
+	f.Frame(2*time.Second, pixels)
+	c.Assert(pixels[0], qt.Equals, black)
+	f.Frame(3*time.Second, pixels)
+	c.Assert(pixels[0], qt.Equals, red)
+}
+
+func TestChase(t *testing.T) {
+	c := qt.New(t)
+	pixels := make([]color.RGBA, 6)
+	ch := &Chase{Color: red, Background: black, Length: 2, Spacing: 3, Step: 100 * time.Millisecond}
+	ch.Frame(0, pixels)
+	c.Assert(pixels, qt.DeepEquals, []color.RGBA{red, red, black, red, red, black})
+	ch.Frame(100*time.Millisecond, pixels)
+	c.Assert(pixels, qt.DeepEquals, []color.RGBA{black, red, red, black, red, red})
+
+	ch.Step = -100 * time.Millisecond
+	ch.Frame(100*time.Millisecond, pixels)
+	c.Assert(pixels, qt.DeepEquals, []color.RGBA{red, black, red, red, black, red})
+}
+
+func TestFire(t *testing.T) {
+	c := qt.New(t)
+	render := func(f *Fire, times ...time.Duration) []color.RGBA {
+		pixels := make([]color.RGBA, 20)
+		for _, t := range times {
+			f.Frame(t, pixels)
+		}
+		return pixels
+	}
+	// The same seed and times give the same frames, whatever the frame rate.
+	a := render(&Fire{Seed: 42}, time.Second)
+	b := render(&Fire{Seed: 42}, 100*time.Millisecond, 500*time.Millisecond, time.Second)
+	c.Assert(a, qt.DeepEquals, b)
+	c.Assert(a, qt.Not(qt.DeepEquals), render(&Fire{Seed: 43}, time.Second))
+
+	// Restarting the effect restarts the simulation.
+	f := &Fire{Seed: 42}
+	render(f, 2*time.Second)
+	c.Assert(render(f, time.Second), qt.DeepEquals, a)
+
+	// The bottom of the flames is hot.
+	lit := 0
+	for _, p := range a[:5] {
+		if p.R > 0 {
+			lit++
+		}
+	}
+	c.Assert(lit > 0, qt.IsTrue)
+}
+
+func TestHeatColor(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(HeatColor(0), qt.Equals, black)
+	c.Assert(HeatColor(255), qt.Equals, color.RGBA{R: 255, G: 255, B: 252, A: 255})
+	c.Assert(HeatColor(128).R, qt.Equals, uint8(255))
+}
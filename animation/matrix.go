@@ -0,0 +1,95 @@
+package animation
+
+import (
+	"image/color"
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+// Matrix maps the positions of a matrix of LEDs to their index in a strip.
+// The LEDs are wired in rows, starting from the top left one.
+type Matrix struct {
+	Width, Height int16 // of the matrix, as wired
+
+	// Serpentine is true if every other row is wired from right to left.
+	Serpentine bool
+
+	// Rotation rotates the positions, clock-wise. Mirrored rotations are
+	// supported.
+	Rotation drivers.Rotation
+}
+
+// Size returns the size of the rotated matrix.
+func (m Matrix) Size() (w, h int16) {
+	if m.Rotation%2 == 1 {
+		return m.Height, m.Width
+	}
+	return m.Width, m.Height
+}
+
+// Index returns the index in the strip of the LED at x, y, or -1 if x, y is
+// outside of the matrix.
+func (m Matrix) Index(x, y int16) int {
+	w, h := m.Size()
+	if x < 0 || x >= w || y < 0 || y >= h {
+		return -1
+	}
+	if m.Rotation >= drivers.Rotation0Mirror {
+		x = w - 1 - x
+	}
+	switch m.Rotation % 4 {
+	case drivers.Rotation90:
+		x, y = m.Width-1-y, x
+	case drivers.Rotation180:
+		x, y = m.Width-1-x, m.Height-1-y
+	case drivers.Rotation270:
+		x, y = y, m.Height-1-x
+	}
+	if m.Serpentine && y%2 == 1 {
+		x = m.Width - 1 - x
+	}
+	return int(y)*int(m.Width) + int(x)
+}
+
+// Func2D is an effect that computes the color of each LED of a matrix from
+// its position.
+type Func2D struct {
+	Matrix Matrix
+	Func   func(t time.Duration, x, y int16) color.RGBA
+}
+
+// Frame calls Func for every LED of the matrix.
+func (f *Func2D) Frame(t time.Duration, pixels []color.RGBA) {
+	w, h := f.Matrix.Size()
+	for y := int16(0); y < h; y++ {
+		for x := int16(0); x < w; x++ {
+			if i := f.Matrix.Index(x, y); i < len(pixels) {
+				pixels[i] = f.Func(t, x, y)
+			}
+		}
+	}
+}
+
+// DisplayOutput is an output that shows the LEDs of a matrix on a display,
+// for LED matrices that implement drivers.Displayer.
+type DisplayOutput struct {
+	Display drivers.Displayer
+
+	// Matrix maps the pixels of the display to the colors written, usually
+	// the same as the one used by the effects.
+	Matrix Matrix
+}
+
+// WriteColors sets the pixels of the display and shows them.
+func (d *DisplayOutput) WriteColors(pixels []color.RGBA) error {
+	w, h := d.Matrix.Size()
+	for y := int16(0); y < h; y++ {
+		for x := int16(0); x < w; x++ {
+			if i := d.Matrix.Index(x, y); i < len(pixels) {
+				d.Display.SetPixel(x, y, pixels[i])
+			}
+		}
+	}
+	return d.Display.Display()
+}
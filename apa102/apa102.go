@@ -4,10 +4,12 @@
 package apa102 // import "tinygo.org/x/drivers/apa102"
 
 import (
+	"errors"
 	"image/color"
 	"machine"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/ledstrip"
 )
 
 const (
@@ -23,6 +25,8 @@ const (
 
 var startFrame = []byte{0x00, 0x00, 0x00, 0x00}
 
+var errWhiteChannel = errors.New("apa102: LEDs have no white channel")
+
 // Device wraps APA102 SPI LEDs.
 type Device struct {
 	bus   drivers.SPI
@@ -74,6 +78,23 @@ func (d *Device) WriteColors(cs []color.RGBA) (n int, err error) {
 	return len(cs), nil
 }
 
+// WriteBuffer writes the colors of the buffer out using the APA102 protocol,
+// encoded with the color order, brightness and gamma of the buffer. The
+// global brightness of the LEDs is set to the maximum, and Order is ignored.
+func (d *Device) WriteBuffer(b *ledstrip.Buffer) error {
+	if b.Order().Channels() != 3 {
+		return errWhiteChannel
+	}
+	d.startFrame()
+	for i := 0; i < b.Len(); i++ {
+		d.buf[0] = 0xff
+		b.Encode(d.buf[1:], i)
+		d.bus.Tx(d.buf[:], nil)
+	}
+	d.endFrame(b.Len())
+	return nil
+}
+
 // Write the raw bytes using the APA102 protocol.
 func (d *Device) Write(buf []byte) (n int, err error) {
 	d.startFrame()
@@ -170,3 +170,41 @@ func (d *Device) Write(data []byte) (n int, err error) {
 func (d *Device) Read(data []uint8) (n int, err error) {
 	return d.readAt(data, d.currentRAMAddress)
 }
+
+// Size returns the size of the usable memory in bytes.
+func (d *Device) Size() int64 {
+	return int64(d.endRAMAddress)
+}
+
+// WriteBlockSize returns the page size of the EEPROM. Writes that are
+// aligned to a page are the fastest.
+func (d *Device) WriteBlockSize() int64 {
+	return int64(d.pageSize)
+}
+
+// EraseBlockSize returns the page size of the EEPROM. An EEPROM doesn't need
+// to be erased before writing; erasing is only provided so the device can be
+// used as a block device, for example by the kvstore package.
+func (d *Device) EraseBlockSize() int64 {
+	return int64(d.pageSize)
+}
+
+// EraseBlocks fills len pages starting at page start with 0xFF, like an
+// erased flash memory.
+func (d *Device) EraseBlocks(start, len int64) error {
+	var page [32]byte
+	for i := range page {
+		page[i] = 0xFF
+	}
+	size := int64(d.pageSize)
+	for addr := start * size; addr < (start+len)*size; addr += int64(cap(page)) {
+		n := (start+len)*size - addr
+		if n > int64(cap(page)) {
+			n = int64(cap(page))
+		}
+		if _, err := d.WriteAt(page[:n], addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
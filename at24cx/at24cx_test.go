@@ -0,0 +1,69 @@
+package at24cx
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
+)
+
+func newTestDevice(c *qt.C) (*tester.EEPROM, *Device) {
+	bus := tester.NewI2CBus(c)
+	eeprom := tester.NewEEPROM(c, Address, 4096, 32)
+	bus.AddDevice(eeprom)
+	dev := New(bus)
+	dev.Configure(Config{})
+	return eeprom, &dev
+}
+
+func TestWriteAtReadAt(t *testing.T) {
+	c := qt.New(t)
+	eeprom, dev := newTestDevice(c)
+
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(65 + i%26)
+	}
+	n, err := dev.WriteAt(data, 20)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, len(data))
+	c.Assert(eeprom.Data[20:120], qt.DeepEquals, data)
+	c.Assert(eeprom.Data[19], qt.Equals, byte(0xFF))
+	c.Assert(eeprom.Data[120], qt.Equals, byte(0xFF))
+
+	buf := make([]byte, len(data))
+	_, err = dev.ReadAt(buf, 20)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+
+	c.Assert(dev.WriteByte(7, 0x42), qt.IsNil)
+	b, err := dev.ReadByte(7)
+	c.Assert(err, qt.IsNil)
+	c.Assert(b, qt.Equals, byte(0x42))
+}
+
+func TestEraseBlocks(t *testing.T) {
+	c := qt.New(t)
+	eeprom, dev := newTestDevice(c)
+	for i := range eeprom.Data {
+		eeprom.Data[i] = 0
+	}
+	c.Assert(dev.Size(), qt.Equals, int64(4096))
+	c.Assert(dev.EraseBlockSize(), qt.Equals, int64(32))
+
+	c.Assert(dev.EraseBlocks(2, 3), qt.IsNil)
+	c.Assert(eeprom.Data[64:160], qt.DeepEquals, bytes.Repeat([]byte{0xFF}, 96))
+	c.Assert(eeprom.Data[63], qt.Equals, byte(0))
+	c.Assert(eeprom.Data[160], qt.Equals, byte(0))
+}
+
+func TestPowerLoss(t *testing.T) {
+	c := qt.New(t)
+	eeprom, dev := newTestDevice(c)
+	eeprom.WriteLimit = 40
+	_, err := dev.WriteAt(bytes.Repeat([]byte{1}, 64), 0)
+	c.Assert(err, qt.Equals, tester.ErrPowerLoss)
+	c.Assert(eeprom.Data[39], qt.Equals, byte(1))
+	c.Assert(eeprom.Data[40], qt.Equals, byte(0xFF))
+}
@@ -0,0 +1,285 @@
+// Package charlcd provides a text UI layer for character LCDs such as the
+// HD44780, driven by the hd44780 (GPIO) and hd44780i2c (I2C) packages.
+//
+// A Terminal writes UTF-8 text to a display as an io.Writer, with line feeds
+// and scrolling, maps runes to the character ROM of the controller, and
+// manages the 8 custom characters of the controller to show glyphs defined by
+// the program, such as progress bars and big digits.
+package charlcd // import "tinygo.org/x/drivers/charlcd"
+
+import (
+	"unicode/utf8"
+)
+
+// Device is a character LCD, as implemented by the hd44780 and hd44780i2c
+// drivers.
+type Device interface {
+	// Size returns the size of the display, in characters.
+	Size() (w, h int16)
+
+	// ClearDisplay clears the display and moves the cursor to (0, 0).
+	ClearDisplay()
+
+	// Home moves the cursor to (0, 0).
+	Home()
+
+	// SetCursor moves the cursor to column x of line y.
+	SetCursor(x, y uint8)
+
+	// WriteChars writes character codes from the cursor, on the same line.
+	WriteChars(codes []byte)
+
+	// CreateCharacter stores the pattern of custom character code (0-7).
+	CreateCharacter(code uint8, pattern []byte)
+
+	DisplayOn(on bool)
+	CursorOn(on bool)
+	CursorBlink(on bool)
+}
+
+// Backlighter is implemented by devices with a backlight that can be turned
+// on and off, such as hd44780i2c.
+type Backlighter interface {
+	BacklightOn(on bool)
+}
+
+// Glyph is the pattern of a custom character: 8 rows of 5 pixels, from top
+// to bottom. Bit 4 of each row is the leftmost pixel.
+type Glyph [8]byte
+
+// Terminal writes text to a character LCD.
+//
+// Text wraps at the end of a line, and the display scrolls up when text goes
+// past the last line. '\n' moves to the start of the next line and '\r' to
+// the start of the current one.
+//
+// Runes are shown as custom glyphs if defined with DefineGlyph, as characters
+// of the ROM if the charset has them, and as '?' otherwise. The controller
+// only holds 8 custom characters at a time: a glyph takes the place of one
+// that is no longer visible, and is shown as '?' when all of them are.
+type Terminal struct {
+	dev     Device
+	charset Charset
+
+	width, height int
+	screen        []byte // character codes shown, line by line
+	x, y          int
+	synced        bool // whether the cursor of the device is at (x, y)
+
+	glyphs map[rune]Glyph
+	slots  [8]rune // rune of each custom character
+	loaded [8]bool // whether slots holds a rune
+
+	partial  [utf8.UTFMax]byte // incomplete rune at the end of a write
+	npartial int
+	codes    [4]byte // scratch space for encode
+	char     [1]byte // scratch space for put
+}
+
+// NewTerminal returns a terminal that writes to dev, mapping runes with
+// charset. A nil charset is ASCII. It clears the display.
+func NewTerminal(dev Device, charset Charset) *Terminal {
+	if charset == nil {
+		charset = ASCII
+	}
+	w, h := dev.Size()
+	t := &Terminal{
+		dev:     dev,
+		charset: charset,
+		width:   int(w),
+		height:  int(h),
+		screen:  make([]byte, int(w)*int(h)),
+		glyphs:  make(map[rune]Glyph),
+	}
+	t.Clear()
+	return t
+}
+
+// Clear clears the display and moves the cursor to (0, 0).
+func (t *Terminal) Clear() {
+	t.dev.ClearDisplay()
+	for i := range t.screen {
+		t.screen[i] = ' '
+	}
+	t.x, t.y = 0, 0
+	t.synced = true
+}
+
+// SetCursor moves the cursor to column x of line y.
+func (t *Terminal) SetCursor(x, y int) {
+	t.x = clamp(x, 0, t.width)
+	t.y = clamp(y, 0, t.height-1)
+	t.synced = false
+}
+
+// Cursor returns the position of the cursor.
+func (t *Terminal) Cursor() (x, y int) {
+	return t.x, t.y
+}
+
+// Write writes UTF-8 text at the cursor. A rune may be split across writes.
+// It always returns len(p), nil.
+func (t *Terminal) Write(p []byte) (n int, err error) {
+	i := 0
+	for t.npartial > 0 && i < len(p) {
+		t.partial[t.npartial] = p[i]
+		t.npartial++
+		i++
+		if utf8.FullRune(t.partial[:t.npartial]) {
+			r, size := utf8.DecodeRune(t.partial[:t.npartial])
+			if size < t.npartial {
+				// invalid sequence: write the last byte on its own
+				i--
+			}
+			t.npartial = 0
+			t.writeRune(r)
+		}
+	}
+	for i < len(p) {
+		if !utf8.FullRune(p[i:]) {
+			t.npartial = copy(t.partial[:], p[i:])
+			break
+		}
+		r, size := utf8.DecodeRune(p[i:])
+		i += size
+		t.writeRune(r)
+	}
+	return len(p), nil
+}
+
+// WriteString is like Write, for a string.
+func (t *Terminal) WriteString(s string) (n int, err error) {
+	return t.Write([]byte(s))
+}
+
+// DefineGlyph defines the glyph shown for r, replacing the character of the
+// ROM if any. Runes of the Unicode private use area, from U+E000, are free
+// for the program.
+func (t *Terminal) DefineGlyph(r rune, g Glyph) {
+	t.glyphs[r] = g
+	for i := range t.slots {
+		if t.loaded[i] && t.slots[i] == r {
+			t.dev.CreateCharacter(uint8(i), g[:])
+			t.synced = false
+		}
+	}
+}
+
+// define is like DefineGlyph, but does nothing if r already has glyph g.
+func (t *Terminal) define(r rune, g Glyph) {
+	if old, ok := t.glyphs[r]; !ok || old != g {
+		t.DefineGlyph(r, g)
+	}
+}
+
+func (t *Terminal) writeRune(r rune) {
+	switch r {
+	case '\n':
+		t.newLine()
+		return
+	case '\r':
+		t.x = 0
+		t.synced = false
+		return
+	}
+	for _, code := range t.encode(r) {
+		if t.x >= t.width {
+			t.newLine()
+		}
+		t.put(code)
+	}
+}
+
+// encode returns the character codes of r.
+func (t *Terminal) encode(r rune) []byte {
+	if g, ok := t.glyphs[r]; ok {
+		if slot := t.load(r, g); slot >= 0 {
+			t.codes[0] = byte(slot)
+			return t.codes[:1]
+		}
+	} else if codes := t.charset(t.codes[:0], r); len(codes) > 0 {
+		return codes
+	}
+	t.codes[0] = '?'
+	return t.codes[:1]
+}
+
+// load returns the custom character that shows r, storing g in a free one if
+// needed, or -1 if all of them are in use.
+func (t *Terminal) load(r rune, g Glyph) int {
+	slot := -1
+	for i := range t.slots {
+		if t.loaded[i] && t.slots[i] == r {
+			return i
+		}
+		if slot < 0 && (!t.loaded[i] || !t.visible(byte(i))) {
+			slot = i
+		}
+	}
+	if slot >= 0 {
+		t.slots[slot] = r
+		t.loaded[slot] = true
+		t.dev.CreateCharacter(uint8(slot), g[:])
+		t.synced = false
+	}
+	return slot
+}
+
+// visible returns whether code is shown on the display.
+func (t *Terminal) visible(code byte) bool {
+	for _, c := range t.screen {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// put writes code at the cursor, which must be on the display, and moves the
+// cursor right.
+func (t *Terminal) put(code byte) {
+	if !t.synced {
+		t.dev.SetCursor(uint8(t.x), uint8(t.y))
+		t.synced = true
+	}
+	t.char[0] = code
+	t.dev.WriteChars(t.char[:])
+	t.screen[t.y*t.width+t.x] = code
+	t.x++
+	if t.x == t.width {
+		// the address counter of the controller doesn't move to the
+		// next line
+		t.synced = false
+	}
+}
+
+// newLine moves the cursor to the start of the next line, scrolling the
+// display if needed.
+func (t *Terminal) newLine() {
+	t.x = 0
+	t.y++
+	t.synced = false
+	if t.y < t.height {
+		return
+	}
+	t.y = t.height - 1
+	copy(t.screen, t.screen[t.width:])
+	last := t.screen[t.y*t.width:]
+	for i := range last {
+		last[i] = ' '
+	}
+	for y := 0; y < t.height; y++ {
+		t.dev.SetCursor(0, uint8(y))
+		t.dev.WriteChars(t.screen[y*t.width : (y+1)*t.width])
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,162 @@
+package charlcd_test
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/charlcd"
+	"tinygo.org/x/drivers/hd44780i2c"
+	"tinygo.org/x/drivers/tester"
+)
+
+var _ charlcd.Device = (*hd44780i2c.Device)(nil)
+
+func newTerminal(c *qt.C, width, height uint8, charset charlcd.Charset) (*charlcd.Terminal, *tester.HD44780I2C) {
+	bus := tester.NewI2CBus(c)
+	lcd := tester.NewHD44780I2C(c, 0x27, int(width), int(height))
+	bus.AddDevice(lcd)
+	dev := hd44780i2c.New(bus, 0x27)
+	c.Assert(dev.Configure(hd44780i2c.Config{Width: width, Height: height}), qt.IsNil)
+	return charlcd.NewTerminal(&dev, charset), lcd
+}
+
+func TestTerminal(t *testing.T) {
+	c := qt.New(t)
+	term, lcd := newTerminal(c, 8, 2, nil)
+	c.Assert(lcd.DisplayOn, qt.IsTrue)
+	c.Assert(lcd.Backlight, qt.IsTrue)
+
+	term.Write([]byte("Hi\nthere"))
+	c.Assert(lcd.Lines(), qt.DeepEquals, []string{"Hi      ", "there   "})
+
+	term.Write([]byte("\rT"))
+	c.Assert(lcd.Lines()[1], qt.Equals, "There   ")
+
+	// wrap and scroll
+	term.SetCursor(5, 1)
+	term.Write([]byte("abcdefg"))
+	c.Assert(lcd.Lines(), qt.DeepEquals, []string{"Thereabc", "defg    "})
+	x, y := term.Cursor()
+	c.Assert([]int{x, y}, qt.DeepEquals, []int{4, 1})
+
+	term.Write([]byte("\n\n1é"))
+	c.Assert(lcd.Lines(), qt.DeepEquals, []string{"        ", "1?      "})
+
+	term.Clear()
+	c.Assert(lcd.Lines(), qt.DeepEquals, []string{"        ", "        "})
+}
+
+func TestTerminalCharset(t *testing.T) {
+	c := qt.New(t)
+	term, lcd := newTerminal(c, 16, 4, charlcd.A00)
+
+	// split in the middle of a rune
+	s := []byte("¥1 カ")
+	term.Write(s[:1])
+	term.Write(s[1:6])
+	term.Write(s[6:])
+	c.Assert(lcd.Lines()[0], qt.Equals, "\x5c1 \xb6            ")
+
+	term.SetCursor(0, 3)
+	term.Write([]byte("ガ°C"))
+	c.Assert(lcd.Lines()[3], qt.Equals, "\xb6\xde\xdfC            ")
+	c.Assert(lcd.Lines()[2], qt.Equals, "                ")
+}
+
+func TestCharsets(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range []struct {
+		charset charlcd.Charset
+		r       rune
+		want    string
+	}{
+		{charlcd.ASCII, 'a', "a"},
+		{charlcd.ASCII, '~', "~"},
+		{charlcd.ASCII, 'é', ""},
+		{charlcd.A00, '\\', ""},
+		{charlcd.A00, '~', ""},
+		{charlcd.A00, '→', "\x7e"},
+		{charlcd.A00, 'ｱ', "\xb1"},
+		{charlcd.A00, 'ア', "\xb1"},
+		{charlcd.A00, 'あ', "\xb1"},
+		{charlcd.A00, 'ッ', "\xaf"},
+		{charlcd.A00, 'パ', "\xca\xdf"},
+		{charlcd.A00, 'ぶ', "\xcc\xde"},
+		{charlcd.A00, 'ヴ', "\xb3\xde"},
+		{charlcd.A00, 'ン', "\xdd"},
+		{charlcd.A00, 'µ', "\xe4"},
+		{charlcd.A00, 'π', "\xf7"},
+		{charlcd.A02, 'é', "\xe9"},
+		{charlcd.A02, ' ', "\xa0"},
+		{charlcd.A02, 'π', ""},
+	} {
+		got := string(test.charset(nil, test.r))
+		c.Check(got, qt.Equals, test.want, qt.Commentf("%q", test.r))
+	}
+}
+
+func TestGlyphs(t *testing.T) {
+	c := qt.New(t)
+	term, lcd := newTerminal(c, 16, 2, nil)
+
+	heart := charlcd.Glyph{0x00, 0x0A, 0x1F, 0x1F, 0x0E, 0x04, 0x00, 0x00}
+	term.DefineGlyph(0xE100, heart)
+	term.Write([]byte("I \ue100 Go"))
+	c.Assert(lcd.Lines()[0], qt.Equals, "I \x00 Go          ")
+	c.Assert(lcd.Glyph(0), qt.Equals, [8]byte(heart))
+
+	// redefining a glyph updates the display
+	heart[0] = 0x1F
+	term.DefineGlyph(0xE100, heart)
+	c.Assert(lcd.Glyph(0), qt.Equals, [8]byte(heart))
+
+	// only 8 glyphs can be visible
+	term.SetCursor(0, 1)
+	for i := 1; i <= 8; i++ {
+		r := rune(0xE100 + i)
+		term.DefineGlyph(r, charlcd.Glyph{byte(i)})
+		term.Write([]byte(string(r)))
+	}
+	c.Assert(lcd.Lines()[1], qt.Equals, "\x01\x02\x03\x04\x05\x06\x07?        ")
+
+	// glyphs that are no longer visible free their character
+	term.SetCursor(2, 0)
+	term.Write([]byte("<3"))
+	term.SetCursor(7, 1)
+	term.Write([]byte(string(rune(0xE108))))
+	c.Assert(lcd.Lines()[1], qt.Equals, "\x01\x02\x03\x04\x05\x06\x07\x00        ")
+	c.Assert(lcd.Glyph(0), qt.Equals, [8]byte{8})
+	x, y := term.Cursor()
+	c.Assert([]int{x, y}, qt.DeepEquals, []int{8, 1})
+
+	// the cursor of the device follows the terminal after changing glyphs
+	term.Write([]byte("!"))
+	c.Assert(lcd.Lines()[1][8], qt.Equals, byte('!'))
+}
+
+func TestWidgets(t *testing.T) {
+	c := qt.New(t)
+	term, lcd := newTerminal(c, 16, 2, nil)
+
+	term.Write([]byte("ab"))
+	term.ProgressBar(0, 1, 4, 13, 20) // 13 of 20 pixels
+	c.Assert(lcd.Lines()[1], qt.Equals, "\x00\x00\x01             ")
+	c.Assert(lcd.Glyph(0), qt.Equals, [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F})
+	c.Assert(lcd.Glyph(1), qt.Equals, [8]byte{0x1C, 0x1C, 0x1C, 0x1C, 0x1C, 0x1C, 0x1C, 0x1C})
+	term.ProgressBar(0, 1, 4, 20, 20)
+	c.Assert(lcd.Lines()[1], qt.Equals, "\x00\x00\x00\x00            ")
+
+	// the text cursor doesn't move
+	term.Write([]byte("c"))
+	c.Assert(lcd.Lines()[0], qt.Equals, "abc             ")
+
+	term.Clear()
+	term.BigText(0, 0, "10")
+	lines := lcd.Lines()
+	full, upper, lower := lines[0][1], lines[0][0], lines[1][0]
+	c.Assert(lines[0][:7], qt.Equals, string([]byte{upper, full, ' ', ' ', full, upper, full}))
+	c.Assert(lines[1][:7], qt.Equals, string([]byte{lower, full, lower, ' ', full, lower, full}))
+	c.Assert(lcd.Glyph(full), qt.Equals, [8]byte{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F})
+	c.Assert(lcd.Glyph(upper), qt.Equals, [8]byte{0x1F, 0x1F, 0x1F, 0, 0, 0, 0, 0})
+	c.Assert(lcd.Glyph(lower), qt.Equals, [8]byte{0, 0, 0, 0, 0, 0x1F, 0x1F, 0x1F})
+}
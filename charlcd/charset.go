@@ -0,0 +1,78 @@
+package charlcd
+
+// Charset converts runes to the character codes of the ROM of a controller.
+// It appends the codes of r to dst, usually a single one, or returns dst
+// unchanged if the ROM doesn't have r.
+type Charset func(dst []byte, r rune) []byte
+
+// ASCII is the charset of the printable ASCII characters, which most ROMs
+// have at the same codes.
+func ASCII(dst []byte, r rune) []byte {
+	if r >= 0x20 && r <= 0x7E {
+		return append(dst, byte(r))
+	}
+	return dst
+}
+
+// A00 is the charset of the Japanese ROM of the HD44780 (ROM code A00), the
+// most common one. It has ASCII, except for '\\' and '~' where it has '¥' and
+// '→', katakana, and a few Greek letters and symbols.
+//
+// Hiragana and full-width katakana are shown as half-width katakana, with a
+// separate voiced sound mark when needed: ガ takes two characters.
+func A00(dst []byte, r rune) []byte {
+	switch {
+	case r >= 0x20 && r <= 0x7D && r != '\\':
+		return append(dst, byte(r))
+	case r >= 0xFF61 && r <= 0xFF9F: // half-width katakana
+		return append(dst, byte(r-0xFF61+0xA1))
+	case r >= 0x30A1 && r <= 0x30F6: // katakana
+		return appendKana(dst, int(r-0x30A1))
+	case r >= 0x3041 && r <= 0x3096: // hiragana
+		return appendKana(dst, int(r-0x3041))
+	}
+	for _, s := range a00Symbols {
+		if s.r == r {
+			return append(dst, s.code)
+		}
+	}
+	return dst
+}
+
+// A02 is the charset of the European ROM of the HD44780 (ROM code A02). It
+// has ASCII, and the Latin-1 characters from U+00A0 to U+00FF at the same
+// codes.
+func A02(dst []byte, r rune) []byte {
+	if r >= 0x20 && r <= 0x7E || r >= 0xA0 && r <= 0xFF {
+		return append(dst, byte(r))
+	}
+	return dst
+}
+
+func appendKana(dst []byte, i int) []byte {
+	dst = append(dst, kanaCodes[i])
+	if kanaMarks[i] != 0 {
+		dst = append(dst, kanaMarks[i])
+	}
+	return dst
+}
+
+// kanaCodes holds the codes of the katakana from U+30A1 to U+30F6, and
+// kanaMarks the voiced or semi-voiced sound mark that follows them, if any.
+const (
+	kanaCodes = "\xa7\xb1\xa8\xb2\xa9\xb3\xaa\xb4\xab\xb5\xb6\xb6\xb7\xb7\xb8\xb8\xb9\xb9\xba\xba\xbb\xbb\xbc\xbc\xbd\xbd\xbe\xbe\xbf\xbf\xc0\xc0\xc1\xc1\xaf\xc2\xc2\xc3\xc3\xc4\xc4\xc5\xc6\xc7\xc8\xc9\xca\xca\xca\xcb\xcb\xcb\xcc\xcc\xcc\xcd\xcd\xcd\xce\xce\xce\xcf\xd0\xd1\xd2\xd3\xac\xd4\xad\xd5\xae\xd6\xd7\xd8\xd9\xda\xdb\xdc\xdc\xb2\xb4\xa6\xdd\xb3\xb6\xb9"
+	kanaMarks = "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\xde\x00\x00\xde\x00\xde\x00\xde\x00\x00\x00\x00\x00\x00\xde\xdf\x00\xde\xdf\x00\xde\xdf\x00\xde\xdf\x00\xde\xdf\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xde\x00\x00"
+)
+
+var a00Symbols = []struct {
+	r    rune
+	code byte
+}{
+	{'¥', 0x5C}, {'→', 0x7E}, {'←', 0x7F},
+	{'。', 0xA1}, {'「', 0xA2}, {'」', 0xA3}, {'、', 0xA4}, {'・', 0xA5}, {'·', 0xA5},
+	{'ー', 0xB0}, {'゛', 0xDE}, {'゜', 0xDF}, {'°', 0xDF},
+	{'α', 0xE0}, {'ä', 0xE1}, {'β', 0xE2}, {'ε', 0xE3}, {'μ', 0xE4}, {'µ', 0xE4},
+	{'σ', 0xE5}, {'ρ', 0xE6}, {'√', 0xE8}, {'¢', 0xEC}, {'£', 0xED}, {'ñ', 0xEE},
+	{'ö', 0xEF}, {'θ', 0xF2}, {'∞', 0xF3}, {'Ω', 0xF4}, {'ü', 0xF5}, {'Σ', 0xF6},
+	{'π', 0xF7}, {'千', 0xFA}, {'万', 0xFB}, {'円', 0xFC}, {'÷', 0xFD}, {'█', 0xFF},
+}
@@ -0,0 +1,129 @@
+package charlcd
+
+// Runes of the glyphs of the widgets, in the Unicode private use area.
+const (
+	runeBar     rune = 0xE000 // + number of columns, 1 to 5
+	runeFull    rune = 0xE010
+	runeUpper   rune = 0xE011 // upper bar
+	runeLower   rune = 0xE012 // lower bar
+	runeUpperMd rune = 0xE013 // upper and middle bars
+	runeLowerMd rune = 0xE014 // middle and lower bars
+)
+
+var bigGlyphs = [...]struct {
+	r rune
+	g Glyph
+}{
+	{runeFull, Glyph{0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F, 0x1F}},
+	{runeUpper, Glyph{0x1F, 0x1F, 0x1F, 0, 0, 0, 0, 0}},
+	{runeLower, Glyph{0, 0, 0, 0, 0, 0x1F, 0x1F, 0x1F}},
+	{runeUpperMd, Glyph{0x1F, 0x1F, 0x1F, 0, 0, 0, 0x1F, 0x1F}},
+	{runeLowerMd, Glyph{0x1F, 0, 0, 0, 0, 0x1F, 0x1F, 0x1F}},
+}
+
+// bigChars holds the 3x2 cells of the big characters: the top line, then the
+// bottom line.
+var bigChars = map[byte][6]rune{
+	'0': {runeFull, runeUpper, runeFull, runeFull, runeLower, runeFull},
+	'1': {runeUpper, runeFull, ' ', runeLower, runeFull, runeLower},
+	'2': {runeUpperMd, runeUpperMd, runeFull, runeFull, runeLowerMd, runeLowerMd},
+	'3': {runeUpperMd, runeUpperMd, runeFull, runeLowerMd, runeLowerMd, runeFull},
+	'4': {runeFull, runeLower, runeFull, ' ', ' ', runeFull},
+	'5': {runeFull, runeUpperMd, runeUpperMd, runeLowerMd, runeLowerMd, runeFull},
+	'6': {runeFull, runeUpperMd, runeUpperMd, runeFull, runeLowerMd, runeFull},
+	'7': {runeUpper, runeUpper, runeFull, ' ', ' ', runeFull},
+	'8': {runeFull, runeUpperMd, runeFull, runeFull, runeLowerMd, runeFull},
+	'9': {runeFull, runeUpperMd, runeFull, runeLowerMd, runeLowerMd, runeFull},
+	'-': {runeLower, runeLower, runeLower, ' ', ' ', ' '},
+	' ': {' ', ' ', ' ', ' ', ' ', ' '},
+}
+
+// ProgressBar draws a horizontal bar of width characters at column x of line
+// y, filled in proportion to value / max with a resolution of one pixel
+// column. It uses up to 3 custom characters, and leaves the cursor where it
+// was.
+func (t *Terminal) ProgressBar(x, y, width, value, max int) {
+	if width <= 0 || max <= 0 {
+		return
+	}
+	value = clamp(value, 0, max)
+	filled := value * width * 5 / max
+	for i := 1; i <= 5; i++ {
+		var g Glyph
+		for row := range g {
+			g[row] = 0x1F &^ (0x1F >> uint(i))
+		}
+		t.define(runeBar+rune(i), g)
+	}
+	cells := make([]rune, width)
+	for i := range cells {
+		switch n := filled - 5*i; {
+		case n >= 5:
+			cells[i] = runeBar + 5
+		case n > 0:
+			cells[i] = runeBar + rune(n)
+		default:
+			cells[i] = ' '
+		}
+	}
+	t.draw(x, y, cells)
+}
+
+// BigText draws digits, '-' and ' ' at column x of line y, 3 characters wide
+// and 2 lines high each, with a blank column between them. Other characters
+// are drawn as blanks. It uses up to 5 custom characters, and leaves the
+// cursor where it was.
+func (t *Terminal) BigText(x, y int, s string) {
+	for _, b := range bigGlyphs {
+		t.define(b.r, b.g)
+	}
+	top := make([]rune, 0, 4*len(s))
+	bottom := make([]rune, 0, 4*len(s))
+	for i := 0; i < len(s); i++ {
+		c := bigChars[s[i]]
+		if i > 0 {
+			top = append(top, ' ')
+			bottom = append(bottom, ' ')
+		}
+		for j := 0; j < 3; j++ {
+			top = append(top, cellRune(c[j]))
+			bottom = append(bottom, cellRune(c[3+j]))
+		}
+	}
+	t.draw(x, y, top)
+	t.draw(x, y+1, bottom)
+}
+
+// cellRune returns the rune of a cell of a big character, which is 0 for
+// unknown characters.
+func cellRune(r rune) rune {
+	if r == 0 {
+		return ' '
+	}
+	return r
+}
+
+// draw writes runes at column x of line y, clipped to the line, and leaves the
+// cursor where it was.
+func (t *Terminal) draw(x, y int, runes []rune) {
+	if y < 0 || y >= t.height {
+		return
+	}
+	oldX, oldY := t.x, t.y
+	t.x, t.y = x, y
+	t.synced = false
+	for _, r := range runes {
+		for _, code := range t.encode(r) {
+			if t.x >= t.width {
+				break
+			}
+			if t.x >= 0 {
+				t.put(code)
+			} else {
+				t.x++
+			}
+		}
+	}
+	t.x, t.y = oldX, oldY
+	t.synced = false
+}
@@ -0,0 +1,119 @@
+// Command ninafw flashes nina-fw (or any other image) to the ESP32 of a
+// WiFiNINA module by talking to the ESP32 ROM bootloader directly, replacing
+// the need for esptool.
+//
+// The host microcontroller must be running a serial passthrough program,
+// such as SerialNINAPassthrough in ../../wifinina/updater, that forwards the
+// serial port and the DTR/RTS lines to the ESP32.
+//
+// Usage:
+//
+//	ninafw -port /dev/ttyACM0 NINA_W102-v1.4.8.bin
+//
+// See ../../wifinina/README.md for the full procedure.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	err := run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// modemLines controls the lines used to reset the ESP32 into its bootloader.
+type modemLines interface {
+	SetDTR(on bool) error
+	SetRTS(on bool) error
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet(args[0], flag.ExitOnError)
+	port := flags.String("port", "/dev/ttyACM0", "serial port of the passthrough program")
+	baud := flags.Int("baud", 115200, "baud rate")
+	offset := flags.Uint("offset", 0, "flash offset to write the image to")
+	flashSize := flags.Uint("flash-size", 2*1024*1024, "size of the ESP32 SPI flash in bytes")
+	reset := flags.Bool("reset", true, "reset the ESP32 into the bootloader using DTR/RTS")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [flags] FILE\n", args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args[1:])
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	image, err := ioutil.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p, err := openSerial(*port, *baud)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	if *reset {
+		if err := resetIntoBootloader(p); err != nil {
+			return err
+		}
+	}
+
+	l := NewLoader(p)
+	if err := l.Sync(); err != nil {
+		return err
+	}
+	fmt.Println("connected to ESP32 bootloader")
+
+	if err := l.SPIAttach(); err != nil {
+		return err
+	}
+	if err := l.SetFlashParams(uint32(*flashSize)); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = l.WriteFlash(uint32(*offset), image, func(written, total int) {
+		fmt.Printf("\rwriting at %#08x... (%d %%)", uint32(*offset)+uint32(written), written*100/total)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote and verified %d bytes in %.1fs\n", len(image), time.Since(start).Seconds())
+
+	return l.FlashEnd(true)
+}
+
+// resetIntoBootloader toggles EN and GPIO0 of the ESP32 through the RTS and
+// DTR lines so that it starts in serial download mode.
+func resetIntoBootloader(m modemLines) error {
+	steps := []struct {
+		dtr, rts bool
+		delay    time.Duration
+	}{
+		{false, true, 100 * time.Millisecond}, // EN low
+		{true, false, 50 * time.Millisecond},  // EN high, GPIO0 low
+		{false, false, 0},                     // release GPIO0
+	}
+	for _, s := range steps {
+		if err := m.SetDTR(s.dtr); err != nil {
+			return err
+		}
+		if err := m.SetRTS(s.rts); err != nil {
+			return err
+		}
+		time.Sleep(s.delay)
+	}
+	return nil
+}
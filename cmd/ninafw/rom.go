@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Commands understood by the ESP32 ROM bootloader.
+const (
+	cmdFlashBegin    = 0x02
+	cmdFlashData     = 0x03
+	cmdFlashEnd      = 0x04
+	cmdSync          = 0x08
+	cmdReadReg       = 0x0A
+	cmdSPISetParams  = 0x0B
+	cmdSPIAttach     = 0x0D
+	cmdSPIFlashMD5   = 0x13
+	directionRequest = 0x00
+	directionReply   = 0x01
+)
+
+const (
+	// flashBlockSize is the payload size of a single FLASH_DATA command
+	// accepted by the ESP32 ROM.
+	flashBlockSize = 0x400
+
+	// checksumSeed is the initial value of the FLASH_DATA payload checksum.
+	checksumSeed = 0xEF
+
+	// romStatusLen is the number of status bytes the ESP32 ROM appends to
+	// every response.
+	romStatusLen = 4
+
+	defaultTimeout = 3 * time.Second
+	syncTimeout    = 100 * time.Millisecond
+
+	// Erasing and hashing take time proportional to the region size.
+	eraseTimeoutPerMB = 30 * time.Second
+	md5TimeoutPerMB   = 8 * time.Second
+)
+
+var (
+	errInvalidEscape = errors.New("slip: invalid escape sequence")
+	errTimeout       = errors.New("timeout waiting for bootloader response")
+	errNoSync        = errors.New("could not sync with bootloader")
+	errMD5Mismatch   = errors.New("flash MD5 does not match image")
+)
+
+// romError is a failure status reported by the bootloader.
+type romError struct {
+	op   byte
+	code byte
+}
+
+func (e *romError) Error() string {
+	msg, ok := romErrorMessages[e.code]
+	if !ok {
+		msg = "unknown error"
+	}
+	return fmt.Sprintf("bootloader command %#02x failed: %s (%#02x)", e.op, msg, e.code)
+}
+
+var romErrorMessages = map[byte]string{
+	0x05: "received message is invalid",
+	0x06: "failed to act on received message",
+	0x07: "invalid CRC in message",
+	0x08: "flash write error",
+	0x09: "flash read error",
+	0x0A: "flash read length error",
+	0x0B: "deflate error",
+}
+
+// Loader talks to the ESP32 ROM bootloader over a serial connection.
+type Loader struct {
+	w       io.Writer
+	packets chan []byte
+	closed  chan struct{}
+	readErr error
+
+	// Timeout is used for commands that do not have a size dependent
+	// timeout.
+	Timeout time.Duration
+}
+
+// NewLoader returns a Loader using the given connection. Incoming frames are
+// read in a separate goroutine so that every command can time out, even if
+// the underlying connection has no support for deadlines.
+func NewLoader(rw io.ReadWriter) *Loader {
+	l := &Loader{
+		w:       rw,
+		packets: make(chan []byte, 16),
+		closed:  make(chan struct{}),
+		Timeout: defaultTimeout,
+	}
+	go l.readLoop(newSLIPReader(rw))
+	return l
+}
+
+func (l *Loader) readLoop(r *slipReader) {
+	for {
+		packet, err := r.ReadPacket()
+		if err == errInvalidEscape {
+			continue
+		}
+		if err != nil {
+			l.readErr = err
+			close(l.closed)
+			return
+		}
+		l.packets <- packet
+	}
+}
+
+// Sync synchronizes with the bootloader so that it can detect the baud rate.
+// It must be called after the chip was reset into the bootloader.
+func (l *Loader) Sync() error {
+	data := make([]byte, 36)
+	copy(data, []byte{0x07, 0x07, 0x12, 0x20})
+	for i := 4; i < len(data); i++ {
+		data[i] = 0x55
+	}
+	for attempt := 0; attempt < 10; attempt++ {
+		_, _, err := l.command(cmdSync, data, 0, syncTimeout)
+		if err == nil {
+			// The ROM answers each SYNC several times: drop the rest.
+			l.drain(syncTimeout)
+			return nil
+		}
+		if err != errTimeout {
+			return err
+		}
+	}
+	return errNoSync
+}
+
+// ReadReg reads a 32-bit register of the chip.
+func (l *Loader) ReadReg(addr uint32) (uint32, error) {
+	val, _, err := l.command(cmdReadReg, le32(addr), 0, l.Timeout)
+	return val, err
+}
+
+// SPIAttach attaches the SPI flash using the default pin configuration.
+func (l *Loader) SPIAttach() error {
+	_, _, err := l.command(cmdSPIAttach, make([]byte, 8), 0, l.Timeout)
+	return err
+}
+
+// SetFlashParams tells the ROM the geometry of the attached SPI flash.
+func (l *Loader) SetFlashParams(size uint32) error {
+	data := concat(le32(0), le32(size), le32(64*1024), le32(4*1024), le32(256), le32(0xFFFF))
+	_, _, err := l.command(cmdSPISetParams, data, 0, l.Timeout)
+	return err
+}
+
+// FlashBegin erases the flash region and prepares writing blocks blocks of
+// flashBlockSize bytes at offset.
+func (l *Loader) FlashBegin(size, blocks, offset uint32) error {
+	data := concat(le32(size), le32(blocks), le32(flashBlockSize), le32(offset))
+	_, _, err := l.command(cmdFlashBegin, data, 0, scaledTimeout(size, eraseTimeoutPerMB))
+	return err
+}
+
+// FlashData writes a single block. The block is padded with 0xFF up to
+// flashBlockSize.
+func (l *Loader) FlashData(seq uint32, block []byte) error {
+	payload := make([]byte, flashBlockSize)
+	n := copy(payload, block)
+	for i := n; i < len(payload); i++ {
+		payload[i] = 0xFF
+	}
+	data := concat(le32(uint32(len(payload))), le32(seq), le32(0), le32(0), payload)
+	_, _, err := l.command(cmdFlashData, data, checksum(payload), l.Timeout)
+	return err
+}
+
+// FlashEnd finishes a flash operation. When reboot is set the chip leaves
+// the bootloader and starts the new firmware.
+func (l *Loader) FlashEnd(reboot bool) error {
+	arg := uint32(1)
+	if reboot {
+		arg = 0
+	}
+	_, _, err := l.command(cmdFlashEnd, le32(arg), 0, l.Timeout)
+	return err
+}
+
+// FlashMD5 returns the MD5 sum of the given flash region as calculated by
+// the chip.
+func (l *Loader) FlashMD5(offset, size uint32) ([]byte, error) {
+	data := concat(le32(offset), le32(size), le32(0), le32(0))
+	_, body, err := l.command(cmdSPIFlashMD5, data, 0, scaledTimeout(size, md5TimeoutPerMB))
+	if err != nil {
+		return nil, err
+	}
+	body = body[:len(body)-romStatusLen]
+	switch len(body) {
+	case 32:
+		// The ROM returns the digest as hex characters.
+		return hex.DecodeString(string(body))
+	case 16:
+		return body, nil
+	}
+	return nil, fmt.Errorf("unexpected MD5 response length %d", len(body))
+}
+
+// WriteFlash writes image to flash at offset and verifies the written data.
+// The progress callback, if not nil, is called after each block.
+func (l *Loader) WriteFlash(offset uint32, image []byte, progress func(written, total int)) error {
+	size := uint32(len(image))
+	blocks := (size + flashBlockSize - 1) / flashBlockSize
+	if err := l.FlashBegin(size, blocks, offset); err != nil {
+		return err
+	}
+	for seq := uint32(0); seq < blocks; seq++ {
+		start := seq * flashBlockSize
+		end := start + flashBlockSize
+		if end > size {
+			end = size
+		}
+		if err := l.FlashData(seq, image[start:end]); err != nil {
+			return fmt.Errorf("block %d: %w", seq, err)
+		}
+		if progress != nil {
+			progress(int(end), int(size))
+		}
+	}
+	sum, err := l.FlashMD5(offset, size)
+	if err != nil {
+		return err
+	}
+	want := md5.Sum(image)
+	if !bytes.Equal(sum, want[:]) {
+		return errMD5Mismatch
+	}
+	return nil
+}
+
+// command sends a request and waits for the matching response. It returns
+// the value field and the data of the response, including status bytes.
+func (l *Loader) command(op byte, data []byte, chk uint32, timeout time.Duration) (uint32, []byte, error) {
+	packet := make([]byte, 8, 8+len(data))
+	packet[0] = directionRequest
+	packet[1] = op
+	binary.LittleEndian.PutUint16(packet[2:], uint16(len(data)))
+	binary.LittleEndian.PutUint32(packet[4:], chk)
+	packet = append(packet, data...)
+	if _, err := l.w.Write(slipEncode(packet)); err != nil {
+		return 0, nil, err
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case resp := <-l.packets:
+			if len(resp) < 8 || resp[0] != directionReply || resp[1] != op {
+				// Stale response of an earlier command, or noise.
+				continue
+			}
+			size := int(binary.LittleEndian.Uint16(resp[2:]))
+			val := binary.LittleEndian.Uint32(resp[4:])
+			body := resp[8:]
+			if size > len(body) || size < romStatusLen {
+				return 0, nil, fmt.Errorf("malformed response to command %#02x", op)
+			}
+			body = body[:size]
+			if status := body[size-romStatusLen]; status != 0 {
+				return 0, nil, &romError{op: op, code: body[size-romStatusLen+1]}
+			}
+			return val, body, nil
+		case <-l.closed:
+			return 0, nil, l.readErr
+		case <-deadline:
+			return 0, nil, errTimeout
+		}
+	}
+}
+
+// drain discards incoming packets until nothing arrives for the given
+// duration.
+func (l *Loader) drain(quiet time.Duration) {
+	for {
+		select {
+		case <-l.packets:
+		case <-time.After(quiet):
+			return
+		}
+	}
+}
+
+func checksum(data []byte) uint32 {
+	chk := byte(checksumSeed)
+	for _, b := range data {
+		chk ^= b
+	}
+	return uint32(chk)
+}
+
+func scaledTimeout(size uint32, perMB time.Duration) time.Duration {
+	t := time.Duration(float64(perMB) * float64(size) / (1024 * 1024))
+	if t < defaultTimeout {
+		return defaultTimeout
+	}
+	return t
+}
+
+func le32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func concat(parts ...[]byte) []byte {
+	var buf []byte
+	for _, p := range parts {
+		buf = append(buf, p...)
+	}
+	return buf
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// fakeROM emulates the parts of the ESP32 ROM bootloader used by Loader.
+type fakeROM struct {
+	r io.Reader
+	w io.Writer
+
+	flash      []byte
+	attached   bool
+	synced     bool
+	eraseStart uint32
+	nextSeq    uint32
+	ended      bool
+	rebooted   bool
+
+	// skipErase makes FLASH_BEGIN leave the flash contents untouched.
+	skipErase bool
+
+	// corruptBlock makes the fake reply with an invalid CRC error for the
+	// given FLASH_DATA sequence number.
+	corruptBlock int
+}
+
+// duplex joins a reader and a writer.
+type duplex struct {
+	io.Reader
+	io.Writer
+}
+
+// newFakeROM returns a fake bootloader and a connection to it.
+func newFakeROM(flashSize int) (*fakeROM, io.ReadWriter) {
+	hostR, romW := io.Pipe()
+	romR, hostW := io.Pipe()
+	rom := &fakeROM{
+		r:            romR,
+		w:            romW,
+		flash:        bytes.Repeat([]byte{0xA5}, flashSize),
+		corruptBlock: -1,
+	}
+	// Emulate the boot message printed by the ROM before any framing.
+	go func() {
+		romW.Write([]byte("ets Jun  8 2016 00:22:57\r\nwaiting for download\r\n"))
+		rom.serve()
+	}()
+	return rom, duplex{hostR, hostW}
+}
+
+func (f *fakeROM) serve() {
+	r := newSLIPReader(f.r)
+	for {
+		packet, err := r.ReadPacket()
+		if err != nil {
+			return
+		}
+		op := packet[1]
+		size := binary.LittleEndian.Uint16(packet[2:])
+		chk := binary.LittleEndian.Uint32(packet[4:])
+		data := packet[8 : 8+int(size)]
+
+		var body []byte
+		status := byte(0)
+		code := byte(0)
+		switch op {
+		case cmdSync:
+			f.synced = true
+			// The real ROM replies several times to each SYNC.
+			for i := 0; i < 7; i++ {
+				f.reply(op, 0, nil, 0, 0)
+			}
+		case cmdSPIAttach:
+			f.attached = true
+		case cmdSPISetParams:
+		case cmdFlashBegin:
+			size := binary.LittleEndian.Uint32(data[0:])
+			f.eraseStart = binary.LittleEndian.Uint32(data[12:])
+			for i := f.eraseStart; i < f.eraseStart+size && !f.skipErase; i++ {
+				f.flash[i] = 0xFF
+			}
+			f.nextSeq = 0
+		case cmdFlashData:
+			n := binary.LittleEndian.Uint32(data[0:])
+			seq := binary.LittleEndian.Uint32(data[4:])
+			payload := data[16 : 16+n]
+			if checksum(payload) != chk || int(seq) == f.corruptBlock {
+				status, code = 1, 0x07
+				break
+			}
+			if seq != f.nextSeq {
+				status, code = 1, 0x05
+				break
+			}
+			start := f.eraseStart + seq*flashBlockSize
+			for i, b := range payload {
+				if int(start)+i < len(f.flash) {
+					f.flash[int(start)+i] &= b
+				}
+			}
+			f.nextSeq++
+		case cmdFlashEnd:
+			f.ended = true
+			f.rebooted = binary.LittleEndian.Uint32(data) == 0
+		case cmdSPIFlashMD5:
+			addr := binary.LittleEndian.Uint32(data[0:])
+			size := binary.LittleEndian.Uint32(data[4:])
+			sum := md5.Sum(f.flash[addr : addr+size])
+			body = []byte(hex.EncodeToString(sum[:]))
+		default:
+			status, code = 1, 0x05
+		}
+		f.reply(op, 0, body, status, code)
+	}
+}
+
+func (f *fakeROM) reply(op byte, val uint32, body []byte, status, code byte) {
+	packet := make([]byte, 8)
+	packet[0] = directionReply
+	packet[1] = op
+	binary.LittleEndian.PutUint16(packet[2:], uint16(len(body)+romStatusLen))
+	binary.LittleEndian.PutUint32(packet[4:], val)
+	packet = append(packet, body...)
+	packet = append(packet, status, code, 0, 0)
+	f.w.Write(slipEncode(packet))
+}
+
+func TestSLIPRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	packet := []byte{0x01, slipEnd, 0x02, slipEsc, slipEnd, slipEsc, 0x03}
+	frame := slipEncode(packet)
+	c.Assert(bytes.Count(frame, []byte{slipEnd}), qt.Equals, 2)
+
+	// Garbage before the frame must be ignored.
+	r := newSLIPReader(bytes.NewReader(append([]byte("boot\r\n"), frame...)))
+	got, err := r.ReadPacket()
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, packet)
+}
+
+func TestChecksum(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(checksum(nil), qt.Equals, uint32(0xEF))
+	c.Assert(checksum([]byte{0xEF}), qt.Equals, uint32(0))
+	c.Assert(checksum([]byte{0x01, 0x02}), qt.Equals, uint32(0xEF^0x01^0x02))
+}
+
+func TestSync(t *testing.T) {
+	c := qt.New(t)
+	rom, conn := newFakeROM(64 * 1024)
+	l := NewLoader(conn)
+	c.Assert(l.Sync(), qt.IsNil)
+	c.Assert(rom.synced, qt.IsTrue)
+
+	// Extra SYNC replies must not be taken as the answer to later commands.
+	c.Assert(l.SPIAttach(), qt.IsNil)
+	c.Assert(rom.attached, qt.IsTrue)
+}
+
+func TestWriteFlash(t *testing.T) {
+	c := qt.New(t)
+	rom, conn := newFakeROM(64 * 1024)
+	l := NewLoader(conn)
+	c.Assert(l.Sync(), qt.IsNil)
+	c.Assert(l.SPIAttach(), qt.IsNil)
+	c.Assert(l.SetFlashParams(64*1024), qt.IsNil)
+
+	// An image that is not a multiple of the block size and contains
+	// bytes that need SLIP escaping.
+	image := make([]byte, 3*flashBlockSize+100)
+	for i := range image {
+		image[i] = byte(i * 7)
+	}
+	image[10] = slipEnd
+	image[11] = slipEsc
+
+	var calls, last int
+	err := l.WriteFlash(0x1000, image, func(written, total int) {
+		calls++
+		last = written
+		c.Check(total, qt.Equals, len(image))
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(calls, qt.Equals, 4)
+	c.Assert(last, qt.Equals, len(image))
+	c.Assert(rom.flash[0x1000:0x1000+len(image)], qt.DeepEquals, image)
+	c.Assert(rom.flash[0xFFF], qt.Equals, byte(0xA5))
+
+	c.Assert(l.FlashEnd(true), qt.IsNil)
+	c.Assert(rom.ended, qt.IsTrue)
+	c.Assert(rom.rebooted, qt.IsTrue)
+}
+
+func TestWriteFlashError(t *testing.T) {
+	c := qt.New(t)
+	rom, conn := newFakeROM(64 * 1024)
+	rom.corruptBlock = 1
+	l := NewLoader(conn)
+	c.Assert(l.Sync(), qt.IsNil)
+
+	err := l.WriteFlash(0, make([]byte, 4*flashBlockSize), nil)
+	c.Assert(err, qt.ErrorMatches, `block 1: bootloader command 0x03 failed: invalid CRC in message \(0x07\)`)
+}
+
+func TestWriteFlashVerify(t *testing.T) {
+	c := qt.New(t)
+	rom, conn := newFakeROM(64 * 1024)
+	rom.skipErase = true
+	l := NewLoader(conn)
+	c.Assert(l.Sync(), qt.IsNil)
+
+	// Without an erase, programming can only clear bits and the written
+	// data no longer matches the image.
+	image := bytes.Repeat([]byte{0xFF}, 100)
+	c.Assert(l.WriteFlash(0, image, nil), qt.Equals, errMD5Mismatch)
+}
+
+func TestResetSequence(t *testing.T) {
+	c := qt.New(t)
+	var lines recordLines
+	c.Assert(resetIntoBootloader(&lines), qt.IsNil)
+	c.Assert(lines.states, qt.DeepEquals, []string{"DTR=0", "RTS=1", "DTR=1", "RTS=0", "DTR=0", "RTS=0"})
+}
+
+type recordLines struct {
+	states []string
+}
+
+func (r *recordLines) SetDTR(on bool) error {
+	r.states = append(r.states, "DTR="+boolString(on))
+	return nil
+}
+
+func (r *recordLines) SetRTS(on bool) error {
+	r.states = append(r.states, "RTS="+boolString(on))
+	return nil
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
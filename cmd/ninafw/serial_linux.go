@@ -0,0 +1,91 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// cbaud masks the baud rate bits of Termios.Cflag.
+const cbaud = 0x100F
+
+var baudRates = map[int]uint32{
+	9600:    syscall.B9600,
+	19200:   syscall.B19200,
+	38400:   syscall.B38400,
+	57600:   syscall.B57600,
+	115200:  syscall.B115200,
+	230400:  syscall.B230400,
+	460800:  syscall.B460800,
+	921600:  syscall.B921600,
+	1500000: syscall.B1500000,
+}
+
+// serialPort is a raw mode serial device.
+type serialPort struct {
+	*os.File
+}
+
+// openSerial opens the serial device and puts it in raw 8N1 mode with the
+// given baud rate.
+func openSerial(name string, baud int) (*serialPort, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", baud)
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	var t syscall.Termios
+	if err := ioctl(f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		f.Close()
+		return nil, err
+	}
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB | cbaud
+	t.Cflag |= syscall.CS8 | syscall.CREAD | syscall.CLOCAL | speed
+	t.Ispeed = speed
+	t.Ospeed = speed
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err := ioctl(f.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &serialPort{f}, nil
+}
+
+// SetDTR sets the state of the DTR modem line.
+func (p *serialPort) SetDTR(on bool) error {
+	return p.setModemBit(syscall.TIOCM_DTR, on)
+}
+
+// SetRTS sets the state of the RTS modem line.
+func (p *serialPort) SetRTS(on bool) error {
+	return p.setModemBit(syscall.TIOCM_RTS, on)
+}
+
+func (p *serialPort) setModemBit(bit int, on bool) error {
+	req := uintptr(syscall.TIOCMBIC)
+	if on {
+		req = syscall.TIOCMBIS
+	}
+	bits := bit
+	return ioctl(p.Fd(), req, uintptr(unsafe.Pointer(&bits)))
+}
+
+func ioctl(fd, req, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
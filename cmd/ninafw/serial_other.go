@@ -0,0 +1,28 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// serialPort is a raw mode serial device.
+type serialPort struct {
+	*os.File
+}
+
+func openSerial(name string, baud int) (*serialPort, error) {
+	return nil, errors.New("serial ports are only supported on Linux")
+}
+
+// SetDTR sets the state of the DTR modem line.
+func (p *serialPort) SetDTR(on bool) error {
+	return nil
+}
+
+// SetRTS sets the state of the RTS modem line.
+func (p *serialPort) SetRTS(on bool) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+// SLIP framing as used by the ESP32 ROM bootloader. Every packet is
+// surrounded by slipEnd bytes, and occurrences of slipEnd and slipEsc inside
+// the packet are escaped.
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+// slipEncode returns the SLIP encoded frame for the given packet.
+func slipEncode(packet []byte) []byte {
+	frame := make([]byte, 0, len(packet)+len(packet)/16+2)
+	frame = append(frame, slipEnd)
+	for _, b := range packet {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, slipEnd)
+}
+
+// slipReader reads SLIP frames from an underlying reader.
+type slipReader struct {
+	r *bufio.Reader
+}
+
+func newSLIPReader(r io.Reader) *slipReader {
+	return &slipReader{r: bufio.NewReader(r)}
+}
+
+// ReadPacket returns the next non-empty packet. Any bytes received outside
+// of a frame (for example boot messages printed by the ROM) are discarded.
+func (s *slipReader) ReadPacket() ([]byte, error) {
+	var packet []byte
+	inFrame := false
+	escaped := false
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if !inFrame {
+			if b == slipEnd {
+				inFrame = true
+			}
+			continue
+		}
+		if escaped {
+			escaped = false
+			switch b {
+			case slipEscEnd:
+				packet = append(packet, slipEnd)
+			case slipEscEsc:
+				packet = append(packet, slipEsc)
+			default:
+				return nil, errInvalidEscape
+			}
+			continue
+		}
+		switch b {
+		case slipEnd:
+			if len(packet) == 0 {
+				// Two consecutive END bytes: treat the second one as the
+				// start of a new frame.
+				continue
+			}
+			return packet, nil
+		case slipEsc:
+			escaped = true
+		default:
+			packet = append(packet, b)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package drivers
 
-import "image/color"
+import (
+	"errors"
+	"image/color"
+)
 
 type Displayer interface {
 	// Size returns the current size of the display.
@@ -12,3 +15,106 @@ type Displayer interface {
 	// Display sends the buffer (if any) to the screen.
 	Display() error
 }
+
+// The interfaces below are implemented by displays that support faster ways
+// of drawing than SetPixel or other features. Graphics libraries can check
+// for them with a type assertion and fall back to SetPixel otherwise.
+
+// RectFiller is a display that can fill a rectangle with a single color
+// faster than by setting each pixel.
+type RectFiller interface {
+	Displayer
+
+	// FillRectangle fills the rectangle at x, y with the given width and
+	// height. It returns an error if the rectangle is not within the
+	// display.
+	FillRectangle(x, y, width, height int16, c color.RGBA) error
+}
+
+// BitmapDrawer is a display that can draw a bitmap of RGB565 pixels.
+type BitmapDrawer interface {
+	Displayer
+
+	// DrawRGBBitmap draws the w×h bitmap in data, in rows from the top left
+	// corner, at x, y. It returns an error if the bitmap is not within the
+	// display or data is too short.
+	DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error
+}
+
+// Rotation is the rotation of a display, clockwise.
+type Rotation uint8
+
+const (
+	Rotation0 Rotation = iota
+	Rotation90
+	Rotation180
+	Rotation270
+
+	// Mirrored rotations, supported by some displays.
+	Rotation0Mirror
+	Rotation90Mirror
+	Rotation180Mirror
+	Rotation270Mirror
+)
+
+// ErrInvalidRotation is returned by SetRotation for rotations that are not
+// supported by the display.
+var ErrInvalidRotation = errors.New("drivers: invalid display rotation")
+
+// Rotater is a display that can be rotated. Size returns the size of the
+// rotated display.
+type Rotater interface {
+	Displayer
+
+	// Rotation returns the current rotation of the display.
+	Rotation() Rotation
+
+	// SetRotation sets the rotation of the display.
+	SetRotation(rotation Rotation) error
+}
+
+// Scroller is a display that supports hardware vertical scrolling.
+type Scroller interface {
+	Displayer
+
+	// SetScrollArea sets the area that scrolls, between the fixed areas at
+	// the top and the bottom of the display.
+	SetScrollArea(topFixedArea, bottomFixedArea int16)
+
+	// SetScroll sets the line of the scroll area that is shown at its top.
+	SetScroll(line int16)
+
+	// StopScroll returns the display to its normal state.
+	StopScroll()
+}
+
+// PartialDisplayer is a buffered display that can send part of its buffer to
+// the screen, which is faster than Display, especially for e-paper displays.
+type PartialDisplayer interface {
+	Displayer
+
+	// DisplayRect sends the given rectangle of the buffer to the screen.
+	DisplayRect(x, y, width, height int16) error
+}
+
+// AsyncBitmapDrawer is a display that can send a bitmap in the background,
+// so that the next part of the image can be computed in the meantime. The
+// transfer is only asynchronous if the bus implements AsyncSPI.
+//
+// To draw a large area using two buffers, fill the first buffer, start
+// drawing it, fill the second buffer while the first one is being sent, start
+// drawing the second buffer, which waits for the first one to be sent, and so
+// on.
+type AsyncBitmapDrawer interface {
+	Displayer
+
+	// StartDrawRGBBitmap8 starts drawing the w×h bitmap of big-endian RGB565
+	// pixels in data at x, y. It first waits for the previous transfer, if
+	// any. data must not be modified until WaitDraw or the next call to
+	// StartDrawRGBBitmap8 returns.
+	StartDrawRGBBitmap8(x, y int16, data []byte, w, h int16) error
+
+	// WaitDraw blocks until the bitmap passed to StartDrawRGBBitmap8 has been
+	// sent.
+	WaitDraw() error
+}
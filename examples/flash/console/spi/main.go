@@ -8,13 +8,15 @@ import (
 )
 
 func main() {
-	console_example.RunFor(
-		flash.NewSPI(
-			&machine.SPI1,
-			machine.SPI1_SDO_PIN,
-			machine.SPI1_SDI_PIN,
-			machine.SPI1_SCK_PIN,
-			machine.SPI1_CS_PIN,
-		),
-	)
+	machine.SPI1.Configure(machine.SPIConfig{
+		Frequency: 5000000,
+		SDI:       machine.SPI1_SDI_PIN,
+		SDO:       machine.SPI1_SDO_PIN,
+		SCK:       machine.SPI1_SCK_PIN,
+		Mode:      0,
+	})
+	cs := machine.SPI1_CS_PIN
+	cs.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	console_example.RunFor(flash.NewSPI(&machine.SPI1, cs))
 }
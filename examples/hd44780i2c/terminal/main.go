@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"machine"
+	"strconv"
+	"time"
+
+	"tinygo.org/x/drivers/charlcd"
+	"tinygo.org/x/drivers/hd44780i2c"
+)
+
+func main() {
+	machine.I2C0.Configure(machine.I2CConfig{
+		Frequency: machine.TWI_FREQ_400KHZ,
+	})
+
+	lcd := hd44780i2c.New(machine.I2C0, 0x27)
+	lcd.Configure(hd44780i2c.Config{
+		Width:  16,
+		Height: 2,
+	})
+
+	// Most modules have the Japanese ROM (A00); use charlcd.A02 for the
+	// European one.
+	term := charlcd.NewTerminal(&lcd, charlcd.A00)
+	term.DefineGlyph(0xE000, charlcd.Glyph{0x00, 0x0A, 0x1F, 0x1F, 0x0E, 0x04, 0x00, 0x00})
+
+	fmt.Fprint(term, "TinyGo  LCD\nｺﾝﾆﾁﾊ 20°C")
+	time.Sleep(3 * time.Second)
+
+	for i := 0; i <= 100; i++ {
+		term.Clear()
+		fmt.Fprintf(term, "Loading %3d%%", i)
+		term.ProgressBar(0, 1, 16, i, 100)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	for i := 0; ; i++ {
+		term.BigText(0, 0, strconv.Itoa(i%10000))
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,43 @@
+// This example scrolls text across four cascaded 8x8 LED matrix modules, such
+// as a FC-16 module.
+package main
+
+import (
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/max72xx"
+	"tinygo.org/x/tinyfont"
+)
+
+func main() {
+	// Pins for Arduino Nano 33 IOT
+	err := machine.SPI0.Configure(machine.SPIConfig{
+		SDO:       machine.D11,
+		SCK:       machine.D13,
+		Frequency: 10000000,
+	})
+	if err != nil {
+		println(err.Error())
+	}
+
+	display := max72xx.NewMatrix(machine.SPI0, machine.D6)
+	err = display.Configure(max72xx.MatrixConfig{
+		Modules:   4,
+		Reverse:   true,
+		Intensity: 2,
+	})
+	if err != nil {
+		println(err.Error())
+	}
+
+	scroller := &max72xx.Scroller{
+		Matrix: display,
+		Font:   &tinyfont.TomThumb,
+		Text:   "Hello TinyGo!",
+		Y:      6,
+	}
+	for {
+		scroller.Scroll(50 * time.Millisecond)
+	}
+}
@@ -245,17 +245,17 @@ func lsblk(argv []string) {
 			"-Always1                %d\r\n"+
 			"-CRC                    %02X\r\n"+
 			"-------------------------------------\r\n\r\n",
-		"attrs.JedecID",         // attrs.JedecID,
-		cid.ProductSerialNumber, // serialNumber1,
-		0,                       // status1,
-		0,                       // status2,
-		csd.TRAN_SPEED,          // attrs.MaxClockSpeedMHz,
-		false,                   // attrs.HasSectorProtection,
-		false,                   // attrs.SupportsFastRead,
-		false,                   // attrs.SupportsQSPI,
-		false,                   // attrs.SupportsQSPIWrites,
-		false,                   // attrs.WriteStatusSplit,
-		false,                   // attrs.SingleStatusByte,
+		"attrs.JedecID",           // attrs.JedecID,
+		cid.ProductSerialNumber,   // serialNumber1,
+		0,                         // status1,
+		0,                         // status2,
+		csd.MaxTransferRate()/1e6, // attrs.MaxClockSpeedMHz,
+		false,                     // attrs.HasSectorProtection,
+		false,                     // attrs.SupportsFastRead,
+		false,                     // attrs.SupportsQSPI,
+		false,                     // attrs.SupportsQSPIWrites,
+		false,                     // attrs.WriteStatusSplit,
+		false,                     // attrs.SingleStatusByte,
 		sectors,
 		csd.Size(),
 		cid.ManufacturerID,
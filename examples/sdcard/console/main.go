@@ -23,7 +23,17 @@ func main() {
 	led := ledPin
 	led.Configure(machine.PinConfig{Mode: machine.PinOutput})
 
-	sd := sdcard.New(spi, sckPin, sdoPin, sdiPin, csPin)
+	// Cards must be initialized at 400 kHz or less.
+	spiConfig := machine.SPIConfig{
+		SCK:       sckPin,
+		SDO:       sdoPin,
+		SDI:       sdiPin,
+		Frequency: 250000,
+	}
+	spi.Configure(spiConfig)
+	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	sd := sdcard.New(spi, csPin)
 	err := sd.Configure()
 	if err != nil {
 		fmt.Printf("%s\r\n", err.Error())
@@ -32,6 +42,9 @@ func main() {
 		}
 	}
 
+	spiConfig.Frequency = 4000000
+	spi.Configure(spiConfig)
+
 	go RunFor(&sd)
 
 	for {
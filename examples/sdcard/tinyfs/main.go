@@ -20,7 +20,17 @@ var (
 )
 
 func main() {
-	sd := sdcard.New(spi, sckPin, sdoPin, sdiPin, csPin)
+	// Cards must be initialized at 400 kHz or less.
+	spiConfig := machine.SPIConfig{
+		SCK:       sckPin,
+		SDO:       sdoPin,
+		SDI:       sdiPin,
+		Frequency: 250000,
+	}
+	spi.Configure(spiConfig)
+	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+
+	sd := sdcard.New(spi, csPin)
 	err := sd.Configure()
 	if err != nil {
 		fmt.Printf("%s\r\n", err.Error())
@@ -29,6 +39,9 @@ func main() {
 		}
 	}
 
+	spiConfig.Frequency = 4000000
+	spi.Configure(spiConfig)
+
 	filesystem := fatfs.New(&sd)
 
 	// Configure FATFS with sector size (must match value in ff.h - use 512)
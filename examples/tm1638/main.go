@@ -0,0 +1,31 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/segment"
+	"tinygo.org/x/drivers/tm1638"
+)
+
+func main() {
+	tm := tm1638.New(machine.D2, machine.D3, machine.D4, 2) // stb, clk, dio, brightness
+	tm.Configure()
+
+	marquee := &segment.Marquee{Text: "Hello TinyGo"}
+	for !marquee.Step(tm.Digits[:]) {
+		tm.Display()
+		time.Sleep(time.Millisecond * 200)
+	}
+
+	tm.DisplayFloat(3.14159, 4)
+	time.Sleep(time.Millisecond * 1000)
+
+	// light the LED above each button that is pressed
+	for {
+		buttons := tm.Buttons()
+		tm.LEDs = buttons
+		tm.DisplayNumber(int(buttons))
+		time.Sleep(time.Millisecond * 50)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"machine"
+
+	"image/color"
+
+	"tinygo.org/x/drivers/image/dither"
+	"tinygo.org/x/drivers/waveshare-epd/epd5in65f"
+)
+
+var display epd5in65f.Device
+
+func main() {
+	machine.SPI0.Configure(machine.SPIConfig{
+		Frequency: 8000000,
+		Mode:      0,
+	})
+
+	display = epd5in65f.New(machine.SPI0, machine.D5, machine.D6, machine.D9, machine.D10)
+	display.Configure(epd5in65f.Config{})
+
+	println("Clear the display")
+	display.ClearDisplay()
+
+	// Show a hue gradient that fades to white at the bottom, dithered to the
+	// 7 colors of the display
+	w, h := display.Size()
+	fs := dither.NewFloydSteinberg(epd5in65f.Palette, int(w))
+	row := make([]color.RGBA, w)
+	for y := int16(0); y < h; y++ {
+		for x := range row {
+			row[x] = fade(hue(int32(x)*1530/int32(w)), int32(y)*255/int32(h))
+		}
+		fs.DrawRow(&display, 0, y, row)
+	}
+	println("Show the gradient")
+	display.Display()
+	display.DeepSleep()
+	println("You could remove power now")
+}
+
+// hue returns the fully saturated color of hue h, from 0 to 1529.
+func hue(h int32) color.RGBA {
+	v := uint8(h % 255)
+	switch h / 255 {
+	case 0:
+		return color.RGBA{255, v, 0, 255}
+	case 1:
+		return color.RGBA{255 - v, 255, 0, 255}
+	case 2:
+		return color.RGBA{0, 255, v, 255}
+	case 3:
+		return color.RGBA{0, 255 - v, 255, 255}
+	case 4:
+		return color.RGBA{v, 0, 255, 255}
+	default:
+		return color.RGBA{255, 0, 255 - v, 255}
+	}
+}
+
+// fade mixes c with white, from 0 (only c) to 255 (only white).
+func fade(c color.RGBA, f int32) color.RGBA {
+	mix := func(v uint8) uint8 {
+		return uint8((int32(v)*(255-f) + 255*f) / 255)
+	}
+	return color.RGBA{mix(c.R), mix(c.G), mix(c.B), 255}
+}
@@ -0,0 +1,42 @@
+// Connects to a WS2812 RGB LED strip with 10 LEDS, using the SDO pin of a
+// SPI bus.
+package main
+
+import (
+	"image/color"
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers/ws2812spi"
+)
+
+var leds [10]color.RGBA
+
+func main() {
+	machine.SPI0.Configure(machine.SPIConfig{
+		Frequency: ws2812spi.Frequency3Bits,
+		Mode:      0,
+	})
+
+	ws, err := ws2812spi.New(machine.SPI0, 3)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	rg := false
+
+	for {
+		rg = !rg
+		for i := range leds {
+			rg = !rg
+			if rg {
+				leds[i] = color.RGBA{R: 0xff, G: 0x00, B: 0x00}
+			} else {
+				leds[i] = color.RGBA{R: 0x00, G: 0xff, B: 0x00}
+			}
+		}
+
+		ws.WriteColors(leds[:])
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,133 @@
+package fat
+
+// BlockDevice is the storage a FAT filesystem lives on. It is implemented by
+// sdcard.Device and flash.Device.
+type BlockDevice interface {
+	// ReadAt reads len(buf) bytes starting at the given byte offset.
+	ReadAt(buf []byte, off int64) (int, error)
+
+	// WriteAt writes len(buf) bytes starting at the given byte offset.
+	WriteAt(buf []byte, off int64) (int, error)
+
+	// Size returns the size of the device in bytes.
+	Size() int64
+
+	// EraseBlockSize returns the smallest erasable area in bytes.
+	EraseBlockSize() int64
+
+	// EraseBlocks erases the given number of blocks, in units of
+	// EraseBlockSize.
+	EraseBlocks(start, len int64) error
+}
+
+// sectorSize is the logical block size used to address partitions and the
+// smallest unit the cache works with.
+const sectorSize = 512
+
+// blockCache is a single block write-back cache in front of a BlockDevice.
+//
+// Devices with an erase block size larger than a sector (NOR flash) can not
+// overwrite data in place, so for them the cache holds a whole erase block
+// and writes it back with an erase followed by a write. Devices that erase
+// per sector, such as SD cards, are written directly.
+type blockCache struct {
+	dev       BlockDevice
+	blockSize int64
+	erase     bool
+	buf       []byte
+	block     int64
+	dirty     bool
+}
+
+func newBlockCache(dev BlockDevice) *blockCache {
+	c := &blockCache{
+		dev:       dev,
+		blockSize: sectorSize,
+		block:     -1,
+	}
+	if ebs := dev.EraseBlockSize(); ebs > sectorSize {
+		c.blockSize = ebs
+		c.erase = true
+	}
+	c.buf = make([]byte, c.blockSize)
+	return c
+}
+
+// readAt fills p with data starting at byte offset off.
+func (c *blockCache) readAt(p []byte, off int64) error {
+	for len(p) > 0 {
+		block := off / c.blockSize
+		start := off % c.blockSize
+		n := int64(len(p))
+		if n > c.blockSize-start {
+			n = c.blockSize - start
+		}
+		if block == c.block {
+			copy(p[:n], c.buf[start:])
+		} else if _, err := c.dev.ReadAt(p[:n], off); err != nil {
+			// Reads that miss the cache go to the device directly so large
+			// sequential reads are not slowed down by the cache.
+			return err
+		}
+		p = p[n:]
+		off += n
+	}
+	return nil
+}
+
+// writeAt writes p starting at byte offset off. Data is kept in the cache
+// until another block is written or flush is called.
+func (c *blockCache) writeAt(p []byte, off int64) error {
+	for len(p) > 0 {
+		block := off / c.blockSize
+		start := off % c.blockSize
+		n := int64(len(p))
+		if n > c.blockSize-start {
+			n = c.blockSize - start
+		}
+		if err := c.load(block, n == c.blockSize); err != nil {
+			return err
+		}
+		copy(c.buf[start:], p[:n])
+		c.dirty = true
+		p = p[n:]
+		off += n
+	}
+	return nil
+}
+
+// load makes block the cached block. If overwrite is set the current
+// contents are not read because the caller replaces all of them.
+func (c *blockCache) load(block int64, overwrite bool) error {
+	if block == c.block {
+		return nil
+	}
+	if err := c.flush(); err != nil {
+		return err
+	}
+	c.block = -1
+	if !overwrite {
+		if _, err := c.dev.ReadAt(c.buf, block*c.blockSize); err != nil {
+			return err
+		}
+	}
+	c.block = block
+	return nil
+}
+
+// flush writes the cached block back to the device if it was modified.
+func (c *blockCache) flush() error {
+	if !c.dirty {
+		return nil
+	}
+	if c.erase {
+		if err := c.dev.EraseBlocks(c.block, 1); err != nil {
+			return err
+		}
+	}
+	if _, err := c.dev.WriteAt(c.buf, c.block*c.blockSize); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,388 @@
+package fat
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Directory entry attributes.
+const (
+	attrReadOnly  = 0x01
+	attrHidden    = 0x02
+	attrSystem    = 0x04
+	attrVolumeID  = 0x08
+	attrDirectory = 0x10
+	attrArchive   = 0x20
+	attrLongName  = attrReadOnly | attrHidden | attrSystem | attrVolumeID
+
+	entrySize    = 32
+	entryDeleted = 0xE5
+	lfnLast      = 0x40
+	lfnChars     = 13
+
+	// Flags in the reserved byte used by Windows NT to store the case of
+	// short names.
+	caseLowerBase = 0x08
+	caseLowerExt  = 0x10
+)
+
+// dirent is a decoded directory entry together with its location.
+type dirent struct {
+	name    string
+	short   [11]byte
+	ntCase  byte
+	attr    byte
+	cluster uint32
+	size    uint32
+	mtime   time.Time
+
+	// dir is the first cluster of the directory containing the entry.
+	// offset is the byte offset of the short entry, first is the offset of
+	// the first (long name) slot belonging to the entry.
+	dir    uint32
+	offset int64
+	first  int64
+	slots  int
+}
+
+func (e *dirent) isDir() bool {
+	return e.attr&attrDirectory != 0
+}
+
+// dirWalker iterates over the 32-byte slots of a directory.
+type dirWalker struct {
+	fs      *FS
+	cluster uint32 // current cluster, 0 for the fixed FAT12/16 root
+	index   uint32 // slot index within the cluster or fixed root
+}
+
+func (fs *FS) walkDir(cluster uint32) dirWalker {
+	if cluster == 0 && fs.typ == FAT32 {
+		cluster = fs.rootCluster
+	}
+	return dirWalker{fs: fs, cluster: cluster}
+}
+
+// next returns the offset of the next slot. It returns ok == false at the
+// end of the directory.
+func (w *dirWalker) next() (off int64, ok bool, err error) {
+	fs := w.fs
+	if w.cluster == 0 {
+		if w.index >= fs.rootEntries {
+			return 0, false, nil
+		}
+		off = fs.rootStart + int64(w.index)*entrySize
+		w.index++
+		return off, true, nil
+	}
+	if w.cluster == clusterEOC {
+		return 0, false, nil
+	}
+	if w.index == fs.clusterSize/entrySize {
+		next, err := fs.fatEntry(w.cluster)
+		if err != nil {
+			return 0, false, err
+		}
+		w.index = 0
+		w.cluster = next
+		if next == clusterEOC {
+			return 0, false, nil
+		}
+	}
+	off = fs.clusterOffset(w.cluster) + int64(w.index)*entrySize
+	w.index++
+	return off, true, nil
+}
+
+// readDir calls fn for every entry in the directory starting at cluster,
+// skipping volume labels and deleted entries. Iteration stops when fn
+// returns false.
+func (fs *FS) readDir(cluster uint32, fn func(e *dirent) bool) error {
+	w := fs.walkDir(cluster)
+	var raw [entrySize]byte
+	var lfn []uint16
+	var lfnSum byte
+	var lfnNext int
+	var first int64
+	slots := 0
+	for {
+		off, ok, err := w.next()
+		if err != nil || !ok {
+			return err
+		}
+		if err := fs.cache.readAt(raw[:], off); err != nil {
+			return err
+		}
+		if raw[0] == 0 {
+			// End of directory marker.
+			return nil
+		}
+		if raw[0] == entryDeleted {
+			lfn = lfn[:0]
+			continue
+		}
+		if raw[11]&0x3F == attrLongName {
+			ord := int(raw[0] & 0x3F)
+			if raw[0]&lfnLast != 0 {
+				lfn = make([]uint16, ord*lfnChars)
+				lfnSum = raw[13]
+				lfnNext = ord
+				first = off
+				slots = 0
+			}
+			if ord != lfnNext || ord == 0 || raw[13] != lfnSum || len(lfn) < ord*lfnChars {
+				lfn = lfn[:0]
+				continue
+			}
+			getLFNChars(raw[:], lfn[(ord-1)*lfnChars:])
+			lfnNext--
+			slots++
+			continue
+		}
+		if raw[11]&attrVolumeID != 0 {
+			lfn = lfn[:0]
+			continue
+		}
+
+		e := dirent{offset: off, dir: cluster}
+		e.decode(raw[:])
+		if len(lfn) > 0 && lfnNext == 0 && shortNameChecksum(e.short[:]) == lfnSum {
+			e.name = decodeLFN(lfn)
+			e.first = first
+			e.slots = slots + 1
+		} else {
+			e.name = e.shortName()
+			e.first = off
+			e.slots = 1
+		}
+		lfn = lfn[:0]
+		if !fn(&e) {
+			return nil
+		}
+	}
+}
+
+// decode parses a 32-byte short directory entry.
+func (e *dirent) decode(raw []byte) {
+	copy(e.short[:], raw[0:11])
+	if e.short[0] == 0x05 {
+		e.short[0] = entryDeleted
+	}
+	e.attr = raw[11]
+	e.ntCase = raw[12]
+	e.cluster = uint32(binary.LittleEndian.Uint16(raw[26:])) |
+		uint32(binary.LittleEndian.Uint16(raw[20:]))<<16
+	e.size = binary.LittleEndian.Uint32(raw[28:])
+	e.mtime = decodeTime(binary.LittleEndian.Uint16(raw[24:]), binary.LittleEndian.Uint16(raw[22:]))
+}
+
+// encode writes the short directory entry. The creation and access times
+// are set to the modification time.
+func (e *dirent) encode(raw []byte) {
+	copy(raw[0:11], e.short[:])
+	if raw[0] == entryDeleted {
+		raw[0] = 0x05
+	}
+	raw[11] = e.attr
+	raw[12] = e.ntCase
+	date, tm := encodeTime(e.mtime)
+	raw[13] = 0
+	binary.LittleEndian.PutUint16(raw[14:], tm)
+	binary.LittleEndian.PutUint16(raw[16:], date)
+	binary.LittleEndian.PutUint16(raw[18:], date)
+	binary.LittleEndian.PutUint16(raw[20:], uint16(e.cluster>>16))
+	binary.LittleEndian.PutUint16(raw[22:], tm)
+	binary.LittleEndian.PutUint16(raw[24:], date)
+	binary.LittleEndian.PutUint16(raw[26:], uint16(e.cluster))
+	binary.LittleEndian.PutUint32(raw[28:], e.size)
+}
+
+// shortName returns the 8.3 name in its display form.
+func (e *dirent) shortName() string {
+	base := strings.TrimRight(string(e.short[0:8]), " ")
+	ext := strings.TrimRight(string(e.short[8:11]), " ")
+	if e.ntCase&caseLowerBase != 0 {
+		base = strings.ToLower(base)
+	}
+	if e.ntCase&caseLowerExt != 0 {
+		ext = strings.ToLower(ext)
+	}
+	if ext == "" {
+		return base
+	}
+	return base + "." + ext
+}
+
+// lfnOffsets lists the byte offsets of the characters in a long name slot.
+var lfnOffsets = [lfnChars]int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+
+func getLFNChars(raw []byte, dst []uint16) {
+	for i, o := range lfnOffsets {
+		dst[i] = binary.LittleEndian.Uint16(raw[o:])
+	}
+}
+
+func decodeLFN(u []uint16) string {
+	for i, c := range u {
+		if c == 0 {
+			u = u[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// encodeLFN encodes slot ord (1-based) of the long name into raw.
+func encodeLFN(raw []byte, name []uint16, ord, count int, sum byte) {
+	raw[0] = byte(ord)
+	if ord == count {
+		raw[0] |= lfnLast
+	}
+	raw[11] = attrLongName
+	raw[12] = 0
+	raw[13] = sum
+	raw[26] = 0
+	raw[27] = 0
+	for i, o := range lfnOffsets {
+		n := (ord-1)*lfnChars + i
+		c := uint16(0xFFFF)
+		if n < len(name) {
+			c = name[n]
+		} else if n == len(name) {
+			c = 0
+		}
+		binary.LittleEndian.PutUint16(raw[o:], c)
+	}
+}
+
+func shortNameChecksum(short []byte) byte {
+	var sum byte
+	for _, c := range short[:11] {
+		sum = (sum&1)<<7 + sum>>1 + c
+	}
+	return sum
+}
+
+// validName reports whether name can be used as a long file name.
+func validName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > 255 {
+		return false
+	}
+	if strings.TrimRight(name, ". ") != name {
+		return false
+	}
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`"*/:<>?\|`, r) {
+			return false
+		}
+	}
+	return len(utf16.Encode([]rune(name))) <= 255
+}
+
+// isShortChar reports whether c may appear in a short name.
+func isShortChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c >= 0x80 || strings.IndexByte("$%'-_@~`!(){}^#&", c) >= 0
+}
+
+// makeShortName converts name to an 8.3 entry. It reports whether the name
+// is represented exactly, possibly using the NT case flags, so that no long
+// name entries are needed.
+func makeShortName(name string) (short [11]byte, ntCase byte, exact bool) {
+	for i := range short {
+		short[i] = ' '
+	}
+	base, ext := name, ""
+	if i := strings.LastIndexByte(name, '.'); i > 0 {
+		base, ext = name[:i], name[i+1:]
+	}
+	exact = len(base) <= 8 && len(ext) <= 3 && strings.IndexByte(base, '.') < 0
+	lowerBase, upperBase := caseOf(base)
+	lowerExt, upperExt := caseOf(ext)
+	if lowerBase && upperBase || lowerExt && upperExt {
+		exact = false
+	}
+	if lowerBase {
+		ntCase |= caseLowerBase
+	}
+	if lowerExt {
+		ntCase |= caseLowerExt
+	}
+
+	fill := func(dst []byte, s string) {
+		n := 0
+		for i := 0; i < len(s) && n < len(dst); i++ {
+			c := s[i]
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if c == ' ' || c == '.' {
+				exact = false
+				continue
+			}
+			if !isShortChar(c) || c >= 0x80 {
+				exact = false
+				c = '_'
+			}
+			dst[n] = c
+			n++
+		}
+	}
+	fill(short[0:8], base)
+	fill(short[8:11], ext)
+	if short[0] == ' ' {
+		short[0] = '_'
+		exact = false
+	}
+	if !exact {
+		ntCase = 0
+	}
+	return short, ntCase, exact
+}
+
+// caseOf reports whether s contains lower and upper case ASCII letters.
+func caseOf(s string) (lower, upper bool) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		lower = lower || c >= 'a' && c <= 'z'
+		upper = upper || c >= 'A' && c <= 'Z'
+	}
+	return lower, upper
+}
+
+// numericTail replaces the end of the base name with ~n.
+func numericTail(short [11]byte, n int) [11]byte {
+	tail := "~" + strconv.Itoa(n)
+	end := 8
+	for end > 0 && short[end-1] == ' ' {
+		end--
+	}
+	if end > 8-len(tail) {
+		end = 8 - len(tail)
+	}
+	copy(short[end:], tail)
+	return short
+}
+
+// decodeTime converts a FAT date and time.
+func decodeTime(date, tm uint16) time.Time {
+	if date == 0 {
+		return time.Time{}
+	}
+	return time.Date(1980+int(date>>9), time.Month(date>>5&0xF), int(date&0x1F),
+		int(tm>>11), int(tm>>5&0x3F), int(tm&0x1F)*2, 0, time.Local)
+}
+
+// encodeTime converts t to a FAT date and time. Times before 1980 are
+// clamped.
+func encodeTime(t time.Time) (date, tm uint16) {
+	if t.Year() < 1980 {
+		return 0x21, 0 // 1980-01-01
+	}
+	date = uint16(t.Year()-1980)<<9 | uint16(t.Month())<<5 | uint16(t.Day())
+	tm = uint16(t.Hour())<<11 | uint16(t.Minute())<<5 | uint16(t.Second()/2)
+	return date, tm
+}
@@ -0,0 +1,260 @@
+// Package fat implements the FAT12, FAT16 and FAT32 filesystems with long
+// file name support on top of block devices such as sdcard.Device and
+// flash.Device.
+//
+// Unlike tinygo.org/x/tinyfs/fatfs it is written in pure Go, so it does not
+// need CGo and can be tested on a regular computer with disk images.
+//
+//	sd := sdcard.New(spi, csPin)
+//	sd.Configure()
+//
+//	filesystem := fat.New(&sd)
+//	filesystem.Configure(&fat.Config{})
+//	if err := filesystem.Mount(); err != nil {
+//		println(err.Error())
+//	}
+//	f, err := filesystem.OpenFile("logs/today.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+//
+// With Go 1.16 or newer an FS also implements io/fs.FS, fs.ReadDirFS and
+// fs.StatFS.
+package fat // import "tinygo.org/x/drivers/fat"
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFAT       = errors.New("fat: not a FAT filesystem")
+	ErrNotMounted   = errors.New("fat: filesystem not mounted")
+	ErrNoSpace      = errors.New("fat: no space left on device")
+	ErrInvalidName  = errors.New("fat: invalid file name")
+	ErrDirNotEmpty  = errors.New("fat: directory not empty")
+	ErrNoPartition  = errors.New("fat: partition not found")
+	ErrCorruptChain = errors.New("fat: corrupt cluster chain")
+)
+
+// Type is the FAT variant of a volume.
+type Type uint8
+
+const (
+	FAT12 Type = 12
+	FAT16 Type = 16
+	FAT32 Type = 32
+)
+
+// Config contains the mount options of a filesystem.
+type Config struct {
+	// Partition is the index of the partition, as returned by
+	// ReadPartitions, to mount. It is ignored for devices without a
+	// partition table.
+	Partition int
+
+	// Now returns the time used for file timestamps. It defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// FS is a FAT filesystem on a block device.
+type FS struct {
+	dev    BlockDevice
+	cache  *blockCache
+	config Config
+
+	mounted bool
+	typ     Type
+
+	// All offsets are in bytes from the start of the device.
+	volStart    int64
+	sectorSize  uint32
+	clusterSize uint32
+	numFATs     uint32
+	fatStart    int64
+	fatSize     int64
+	rootStart   int64 // FAT12/16 only
+	rootEntries uint32
+	rootCluster uint32 // FAT32 only
+	dataStart   int64
+	clusters    uint32 // number of data clusters
+
+	fsInfo    int64 // FAT32 only, 0 if there is none
+	freeCount uint32
+	nextFree  uint32
+
+	buf [32]byte
+}
+
+// New returns a new filesystem for the given device. Call Configure and
+// Mount before using it.
+func New(dev BlockDevice) *FS {
+	return &FS{
+		dev: dev,
+	}
+}
+
+// Configure sets the mount options.
+func (fs *FS) Configure(config *Config) {
+	fs.config = *config
+	if fs.config.Now == nil {
+		fs.config.Now = time.Now
+	}
+}
+
+// Type returns the FAT type of the mounted volume.
+func (fs *FS) Type() Type {
+	return fs.typ
+}
+
+// Mount reads the partition table, if any, and the boot sector of the
+// volume.
+func (fs *FS) Mount() error {
+	if fs.config.Now == nil {
+		fs.Configure(&fs.config)
+	}
+	fs.cache = newBlockCache(fs.dev)
+	fs.volStart = 0
+	parts, err := ReadPartitions(fs.dev)
+	switch {
+	case err == ErrNoPartitionTable:
+	case err != nil:
+		return err
+	case fs.config.Partition >= len(parts):
+		return ErrNoPartition
+	default:
+		fs.volStart = parts[fs.config.Partition].Start
+	}
+
+	var boot [sectorSize]byte
+	if err := fs.cache.readAt(boot[:], fs.volStart); err != nil {
+		return err
+	}
+	if err := fs.parseBootSector(boot[:]); err != nil {
+		return err
+	}
+	fs.mounted = true
+	return nil
+}
+
+// Unmount writes all pending changes to the device.
+func (fs *FS) Unmount() error {
+	if err := fs.Sync(); err != nil {
+		return err
+	}
+	fs.mounted = false
+	return nil
+}
+
+// Sync writes all cached data and the FAT32 free cluster information to the
+// device.
+func (fs *FS) Sync() error {
+	if !fs.mounted {
+		return ErrNotMounted
+	}
+	if fs.fsInfo != 0 {
+		binary.LittleEndian.PutUint32(fs.buf[0:], fs.freeCount)
+		binary.LittleEndian.PutUint32(fs.buf[4:], fs.nextFree)
+		if err := fs.cache.writeAt(fs.buf[:8], fs.fsInfo+488); err != nil {
+			return err
+		}
+	}
+	return fs.cache.flush()
+}
+
+// isBootSector reports whether the sector looks like a FAT boot sector.
+func isBootSector(b []byte) bool {
+	if b[0] != 0xEB && b[0] != 0xE9 {
+		return false
+	}
+	bps := binary.LittleEndian.Uint16(b[11:])
+	spc := b[13]
+	return (bps == 512 || bps == 1024 || bps == 2048 || bps == 4096) &&
+		spc != 0 && spc&(spc-1) == 0 &&
+		binary.LittleEndian.Uint16(b[14:]) != 0 && b[16] != 0
+}
+
+func (fs *FS) parseBootSector(b []byte) error {
+	if !isBootSector(b) || b[510] != 0x55 || b[511] != 0xAA {
+		return ErrNotFAT
+	}
+	bps := uint32(binary.LittleEndian.Uint16(b[11:]))
+	spc := uint32(b[13])
+	reserved := uint32(binary.LittleEndian.Uint16(b[14:]))
+	fs.numFATs = uint32(b[16])
+	fs.rootEntries = uint32(binary.LittleEndian.Uint16(b[17:]))
+	total := uint32(binary.LittleEndian.Uint16(b[19:]))
+	if total == 0 {
+		total = binary.LittleEndian.Uint32(b[32:])
+	}
+	fatSize16 := uint32(binary.LittleEndian.Uint16(b[22:]))
+	fatSize := fatSize16
+	if fatSize == 0 {
+		fatSize = binary.LittleEndian.Uint32(b[36:])
+	}
+	rootSectors := (fs.rootEntries*32 + bps - 1) / bps
+	meta := reserved + fs.numFATs*fatSize + rootSectors
+	if fatSize == 0 || total <= meta {
+		return ErrNotFAT
+	}
+
+	fs.sectorSize = bps
+	fs.clusterSize = bps * spc
+	fs.fatStart = fs.volStart + int64(reserved*bps)
+	fs.fatSize = int64(fatSize * bps)
+	fs.rootStart = fs.fatStart + int64(fs.numFATs)*fs.fatSize
+	fs.dataStart = fs.rootStart + int64(rootSectors*bps)
+	fs.clusters = (total - meta) / spc
+
+	// The FAT type is determined by the cluster count, except that only
+	// FAT32 has no FAT size nor root directory in the common part of the
+	// BPB, which some tools use for small FAT32 volumes.
+	switch {
+	case fatSize16 == 0 || fs.rootEntries == 0:
+		fs.typ = FAT32
+	case fs.clusters < 4085:
+		fs.typ = FAT12
+	case fs.clusters < 65525:
+		fs.typ = FAT16
+	default:
+		fs.typ = FAT32
+	}
+
+	fs.fsInfo = 0
+	fs.freeCount = 0xFFFFFFFF
+	fs.nextFree = 2
+	if fs.typ == FAT32 {
+		if fs.rootEntries != 0 {
+			return ErrNotFAT
+		}
+		fs.rootCluster = binary.LittleEndian.Uint32(b[44:])
+		if sec := binary.LittleEndian.Uint16(b[48:]); sec != 0 && sec != 0xFFFF {
+			fs.readFSInfo(fs.volStart + int64(uint32(sec)*bps))
+		}
+	}
+	return nil
+}
+
+// readFSInfo loads the free cluster hints of a FAT32 volume. Invalid hints
+// are ignored.
+func (fs *FS) readFSInfo(off int64) {
+	var info [sectorSize]byte
+	if err := fs.cache.readAt(info[:], off); err != nil {
+		return
+	}
+	if binary.LittleEndian.Uint32(info[0:]) != 0x41615252 ||
+		binary.LittleEndian.Uint32(info[484:]) != 0x61417272 {
+		return
+	}
+	fs.fsInfo = off
+	if n := binary.LittleEndian.Uint32(info[488:]); n <= fs.clusters {
+		fs.freeCount = n
+	}
+	if n := binary.LittleEndian.Uint32(info[492:]); n >= 2 && n < fs.clusters+2 {
+		fs.nextFree = n
+	}
+}
+
+// clusterOffset returns the byte offset of the given data cluster.
+func (fs *FS) clusterOffset(cluster uint32) int64 {
+	return fs.dataStart + int64(cluster-2)*int64(fs.clusterSize)
+}
@@ -0,0 +1,632 @@
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// memDevice is a block device backed by memory. If eraseSize is larger than
+// a sector it behaves like NOR flash: writes can only clear bits.
+type memDevice struct {
+	data      []byte
+	eraseSize int64
+	erases    int
+}
+
+func newMemDevice(size int) *memDevice {
+	return &memDevice{data: make([]byte, size), eraseSize: sectorSize}
+}
+
+func newNORDevice(size int) *memDevice {
+	d := &memDevice{data: bytes.Repeat([]byte{0xFF}, size), eraseSize: 4096}
+	return d
+}
+
+func (d *memDevice) ReadAt(buf []byte, off int64) (int, error) {
+	if off+int64(len(buf)) > int64(len(d.data)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return copy(buf, d.data[off:]), nil
+}
+
+func (d *memDevice) WriteAt(buf []byte, off int64) (int, error) {
+	if off+int64(len(buf)) > int64(len(d.data)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	for i, b := range buf {
+		if d.eraseSize > sectorSize {
+			d.data[off+int64(i)] &= b
+		} else {
+			d.data[off+int64(i)] = b
+		}
+	}
+	return len(buf), nil
+}
+
+func (d *memDevice) Size() int64 {
+	return int64(len(d.data))
+}
+
+func (d *memDevice) EraseBlockSize() int64 {
+	return d.eraseSize
+}
+
+func (d *memDevice) EraseBlocks(start, n int64) error {
+	d.erases++
+	for i := start * d.eraseSize; i < (start+n)*d.eraseSize; i++ {
+		d.data[i] = 0xFF
+	}
+	return nil
+}
+
+var testTime = time.Date(2021, 11, 5, 13, 37, 42, 0, time.Local)
+
+func newTestFS(c *qt.C, dev BlockDevice, config *FormatConfig) *FS {
+	fs := New(dev)
+	fs.Configure(&Config{Now: func() time.Time { return testTime }})
+	c.Assert(fs.Format(config), qt.IsNil)
+	c.Assert(fs.Mount(), qt.IsNil)
+	return fs
+}
+
+func remount(c *qt.C, fs *FS) *FS {
+	c.Assert(fs.Unmount(), qt.IsNil)
+	fs2 := New(fs.dev)
+	fs2.Configure(&fs.config)
+	c.Assert(fs2.Mount(), qt.IsNil)
+	return fs2
+}
+
+func writeFile(c *qt.C, fs *FS, name string, data []byte) {
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	c.Assert(err, qt.IsNil)
+	n, err := f.Write(data)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, len(data))
+	c.Assert(f.Close(), qt.IsNil)
+}
+
+func readFile(c *qt.C, fs *FS, name string) []byte {
+	f, err := fs.OpenFile(name, os.O_RDONLY)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	c.Assert(err, qt.IsNil)
+	return data
+}
+
+func readDirNames(c *qt.C, fs *FS, name string) []string {
+	f, err := fs.OpenFile(name, os.O_RDONLY)
+	c.Assert(err, qt.IsNil)
+	defer f.Close()
+	infos, err := f.Readdir(-1)
+	c.Assert(err, qt.IsNil)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func pattern(n int, seed byte) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i*31) ^ seed
+	}
+	return b
+}
+
+var formatTests = []struct {
+	name string
+	size int
+	typ  Type
+}{
+	{"FAT12", 1 << 20, FAT12},
+	{"FAT16", 16 << 20, FAT16},
+	{"FAT32", 40 << 20, FAT32},
+}
+
+func TestFormatAndMount(t *testing.T) {
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			fs := newTestFS(c, newMemDevice(tt.size), &FormatConfig{Type: tt.typ, SectorsPerCluster: 1, Label: "tinygo"})
+			c.Assert(fs.Type(), qt.Equals, tt.typ)
+			c.Assert(readDirNames(c, fs, "/"), qt.HasLen, 0)
+		})
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			fs := newTestFS(c, newMemDevice(tt.size), &FormatConfig{Type: tt.typ, SectorsPerCluster: 1})
+
+			small := []byte("hello, world\n")
+			large := pattern(70000, 3)
+			writeFile(c, fs, "hello.txt", small)
+			writeFile(c, fs, "A Long File Name With Spaces.data", large)
+			c.Assert(fs.Mkdir("sub"), qt.IsNil)
+			c.Assert(fs.Mkdir("sub/deeper"), qt.IsNil)
+			writeFile(c, fs, "sub/deeper/ünïcödé.txt", small)
+
+			fs = remount(c, fs)
+			c.Assert(readFile(c, fs, "hello.txt"), qt.DeepEquals, small)
+			c.Assert(readFile(c, fs, "/HELLO.TXT"), qt.DeepEquals, small)
+			c.Assert(readFile(c, fs, "a long file name with spaces.data"), qt.DeepEquals, large)
+			c.Assert(readFile(c, fs, "sub/deeper/ünïcödé.txt"), qt.DeepEquals, small)
+			c.Assert(readDirNames(c, fs, "/"), qt.DeepEquals, []string{"hello.txt", "A Long File Name With Spaces.data", "sub"})
+
+			info, err := fs.Stat("sub/deeper/ünïcödé.txt")
+			c.Assert(err, qt.IsNil)
+			c.Assert(info.Size(), qt.Equals, int64(len(small)))
+			c.Assert(info.IsDir(), qt.IsFalse)
+			c.Assert(info.ModTime().Equal(testTime), qt.IsTrue)
+
+			info, err = fs.Stat("sub")
+			c.Assert(err, qt.IsNil)
+			c.Assert(info.IsDir(), qt.IsTrue)
+
+			_, err = fs.Stat("missing")
+			c.Assert(os.IsNotExist(err), qt.IsTrue)
+		})
+	}
+}
+
+func TestShortNameAlias(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(1<<20), &FormatConfig{})
+	writeFile(c, fs, "Long Name One.txt", []byte("1"))
+	writeFile(c, fs, "Long Name Two.txt", []byte("2"))
+	c.Assert(readFile(c, fs, "LONGNA~1.TXT"), qt.DeepEquals, []byte("1"))
+	c.Assert(readFile(c, fs, "longna~2.txt"), qt.DeepEquals, []byte("2"))
+}
+
+func TestManyFilesGrowDirectory(t *testing.T) {
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			fs := newTestFS(c, newMemDevice(tt.size), &FormatConfig{Type: tt.typ, SectorsPerCluster: 1})
+			c.Assert(fs.Mkdir("many"), qt.IsNil)
+			// Each file needs three slots, so this spans several clusters.
+			var want []string
+			for i := 0; i < 50; i++ {
+				name := "file number " + string(rune('A'+i%26)) + string(rune('a'+i/26)) + ".log"
+				writeFile(c, fs, "many/"+name, []byte(name))
+				want = append(want, name)
+			}
+			fs = remount(c, fs)
+			c.Assert(readDirNames(c, fs, "many"), qt.DeepEquals, want)
+			for _, name := range want {
+				c.Assert(string(readFile(c, fs, "many/"+name)), qt.Equals, name)
+			}
+		})
+	}
+}
+
+func TestRootDirectoryFull(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(1<<20), &FormatConfig{Type: FAT12})
+	var err error
+	for i := 0; i < 600 && err == nil; i++ {
+		var f *File
+		f, err = fs.OpenFile(string(rune('A'+i%26))+string(rune('A'+i/26%26))+".TXT", os.O_CREATE|os.O_WRONLY)
+		if err == nil {
+			f.Close()
+		}
+	}
+	c.Assert(err, qt.ErrorMatches, ".*no space left on device")
+}
+
+func TestSeekAppendTruncate(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(1<<20), &FormatConfig{SectorsPerCluster: 1})
+	writeFile(c, fs, "f", []byte("0123456789"))
+
+	f, err := fs.OpenFile("f", os.O_RDWR)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Seek(4, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Write([]byte("xy"))
+	c.Assert(err, qt.IsNil)
+	// Writing past the end fills the gap with zeros.
+	_, err = f.Seek(1000, io.SeekStart)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Write([]byte("end"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.Close(), qt.IsNil)
+
+	data := readFile(c, fs, "f")
+	c.Assert(data, qt.HasLen, 1003)
+	c.Assert(string(data[:10]), qt.Equals, "0123xy6789")
+	c.Assert(data[10:1000], qt.DeepEquals, make([]byte, 990))
+	c.Assert(string(data[1000:]), qt.Equals, "end")
+
+	f, err = fs.OpenFile("f", os.O_WRONLY|os.O_APPEND)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Write([]byte("!"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(f.Truncate(600), qt.IsNil)
+	c.Assert(f.Close(), qt.IsNil)
+	data = readFile(c, fs, "f")
+	c.Assert(data, qt.HasLen, 600)
+
+	// The clusters freed by truncating are reused.
+	free := fs.nextFree
+	writeFile(c, fs, "f", nil)
+	c.Assert(fs.nextFree <= free, qt.IsTrue)
+	c.Assert(readFile(c, fs, "f"), qt.HasLen, 0)
+}
+
+func TestOpenFlags(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(1<<20), &FormatConfig{})
+	_, err := fs.OpenFile("nope", os.O_RDONLY)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	writeFile(c, fs, "a", []byte("a"))
+	_, err = fs.OpenFile("a", os.O_CREATE|os.O_EXCL|os.O_WRONLY)
+	c.Assert(os.IsExist(err), qt.IsTrue)
+
+	f, err := fs.OpenFile("a", os.O_RDONLY)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Write([]byte("b"))
+	c.Assert(err, qt.Equals, os.ErrPermission)
+	c.Assert(f.Close(), qt.IsNil)
+	c.Assert(f.Close(), qt.Equals, os.ErrClosed)
+
+	_, err = fs.OpenFile("missing/a", os.O_CREATE|os.O_WRONLY)
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+	_, err = fs.OpenFile("bad:name", os.O_CREATE|os.O_WRONLY)
+	c.Assert(err, qt.ErrorMatches, ".*invalid file name")
+}
+
+func TestRemove(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(1<<20), &FormatConfig{SectorsPerCluster: 1})
+	c.Assert(fs.Mkdir("dir"), qt.IsNil)
+	writeFile(c, fs, "dir/Some Long Name", pattern(5000, 1))
+	free := fs.freeCount
+
+	c.Assert(fs.Remove("dir"), qt.ErrorMatches, ".*directory not empty")
+	c.Assert(fs.Remove("dir/some long name"), qt.IsNil)
+	c.Assert(fs.Remove("dir"), qt.IsNil)
+	c.Assert(readDirNames(c, fs, "/"), qt.HasLen, 0)
+	_, err := fs.Stat("dir")
+	c.Assert(os.IsNotExist(err), qt.IsTrue)
+
+	// Allocating again must reuse the freed clusters.
+	writeFile(c, fs, "again", pattern(5000, 2))
+	c.Assert(fs.freeCount, qt.Equals, free)
+}
+
+func TestRename(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(16<<20), &FormatConfig{})
+	c.Assert(fs.Mkdir("a"), qt.IsNil)
+	c.Assert(fs.Mkdir("b"), qt.IsNil)
+	writeFile(c, fs, "a/file.txt", []byte("data"))
+	writeFile(c, fs, "other", []byte("other"))
+
+	c.Assert(fs.Rename("a/file.txt", "b/Renamed File.txt"), qt.IsNil)
+	c.Assert(readFile(c, fs, "b/Renamed File.txt"), qt.DeepEquals, []byte("data"))
+	c.Assert(readDirNames(c, fs, "a"), qt.HasLen, 0)
+
+	// Only changing the case is allowed even though the target "exists".
+	c.Assert(fs.Rename("b/renamed file.txt", "b/RENAMED FILE.TXT"), qt.IsNil)
+	c.Assert(readDirNames(c, fs, "b"), qt.DeepEquals, []string{"RENAMED FILE.TXT"})
+
+	c.Assert(fs.Rename("other", "b/renamed file.txt"), qt.ErrorMatches, ".*file already exists")
+
+	// Moving a directory updates its ".." entry.
+	c.Assert(fs.Rename("b", "a/b"), qt.IsNil)
+	c.Assert(readFile(c, fs, "a/b/RENAMED FILE.TXT"), qt.DeepEquals, []byte("data"))
+	e, err := fs.resolve("a")
+	c.Assert(err, qt.IsNil)
+	dotdot, err := fs.resolve("a/b/..")
+	c.Assert(err, qt.IsNil)
+	c.Assert(dotdot.cluster, qt.Equals, e.cluster)
+
+	c.Assert(fs.Rename("a", "a/b/c"), qt.ErrorMatches, ".*invalid file name")
+}
+
+func TestNORFlash(t *testing.T) {
+	c := qt.New(t)
+	dev := newNORDevice(2 << 20)
+	fs := newTestFS(c, dev, &FormatConfig{})
+	data := pattern(20000, 9)
+	writeFile(c, fs, "log.bin", data)
+	writeFile(c, fs, "log.bin", data[:100])
+	c.Assert(fs.Mkdir("config"), qt.IsNil)
+	writeFile(c, fs, "config/wifi.txt", []byte("ssid=tinygo"))
+	c.Assert(dev.erases > 0, qt.IsTrue)
+
+	fs = remount(c, fs)
+	c.Assert(readFile(c, fs, "log.bin"), qt.DeepEquals, data[:100])
+	c.Assert(readFile(c, fs, "config/wifi.txt"), qt.DeepEquals, []byte("ssid=tinygo"))
+}
+
+func TestFAT32FSInfo(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(40<<20), &FormatConfig{Type: FAT32, SectorsPerCluster: 1})
+	free := fs.freeCount
+	c.Assert(free, qt.Equals, fs.clusters-1)
+	writeFile(c, fs, "x", pattern(3*512, 0))
+	fs = remount(c, fs)
+	c.Assert(fs.freeCount, qt.Equals, free-3)
+}
+
+func TestNoSpace(t *testing.T) {
+	c := qt.New(t)
+	fs := newTestFS(c, newMemDevice(256<<10), &FormatConfig{})
+	f, err := fs.OpenFile("big", os.O_CREATE|os.O_WRONLY)
+	c.Assert(err, qt.IsNil)
+	_, err = f.Write(make([]byte, 512<<10))
+	c.Assert(err, qt.Equals, ErrNoSpace)
+	c.Assert(f.Close(), qt.IsNil)
+}
+
+func TestMakeShortName(t *testing.T) {
+	c := qt.New(t)
+	tests := []struct {
+		name   string
+		short  string
+		ntCase byte
+		exact  bool
+	}{
+		{"README.TXT", "README  TXT", 0, true},
+		{"readme.txt", "README  TXT", caseLowerBase | caseLowerExt, true},
+		{"Makefile", "MAKEFILE   ", 0, false},
+		{"a.tar.gz", "ATAR    GZ ", 0, false},
+		{".bashrc", "BASHRC     ", 0, false},
+		{"long file name.html", "LONGFILEHTM", 0, false},
+		{"über.txt", "__BER   TXT", 0, false},
+	}
+	for _, tt := range tests {
+		short, ntCase, exact := makeShortName(tt.name)
+		c.Check(string(short[:]), qt.Equals, tt.short, qt.Commentf(tt.name))
+		c.Check(ntCase, qt.Equals, tt.ntCase, qt.Commentf(tt.name))
+		c.Check(exact, qt.Equals, tt.exact, qt.Commentf(tt.name))
+	}
+
+	var short [11]byte
+	copy(short[:], "LONGFILEHTM")
+	short = numericTail(short, 1)
+	c.Assert(string(short[:]), qt.Equals, "LONGFI~1HTM")
+	copy(short[:], "AB      TXT")
+	short = numericTail(short, 12)
+	c.Assert(string(short[:]), qt.Equals, "AB~12   TXT")
+}
+
+func TestPartitions(t *testing.T) {
+	c := qt.New(t)
+	dev := newMemDevice(8 << 20)
+	mbr := dev.data[:512]
+	putMBREntry(mbr, 0, TypeFAT12, 2048, 2048)
+	putMBREntry(mbr, 1, TypeFAT16B, 4096, 12288)
+	mbr[510], mbr[511] = 0x55, 0xAA
+
+	parts, err := ReadPartitions(dev)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parts, qt.DeepEquals, []Partition{
+		{Start: 2048 * 512, Size: 2048 * 512, Type: TypeFAT12},
+		{Start: 4096 * 512, Size: 12288 * 512, Type: TypeFAT16B},
+	})
+	c.Assert(parts[1].IsFAT(), qt.IsTrue)
+
+	fs := New(dev)
+	fs.Configure(&Config{Partition: 1})
+	c.Assert(fs.Format(&FormatConfig{}), qt.IsNil)
+	c.Assert(fs.Mount(), qt.IsNil)
+	writeFile(c, fs, "second.txt", []byte("2"))
+	c.Assert(fs.Unmount(), qt.IsNil)
+
+	// The first partition was not touched.
+	fs = New(dev)
+	fs.Configure(&Config{Partition: 0})
+	c.Assert(fs.Mount(), qt.Equals, ErrNotFAT)
+	fs.Configure(&Config{Partition: 2})
+	c.Assert(fs.Mount(), qt.Equals, ErrNoPartition)
+	fs.Configure(&Config{Partition: 1})
+	c.Assert(fs.Mount(), qt.IsNil)
+	c.Assert(readFile(c, fs, "second.txt"), qt.DeepEquals, []byte("2"))
+
+	// A formatted volume without partition table.
+	dev = newMemDevice(1 << 20)
+	newTestFS(c, dev, &FormatConfig{})
+	_, err = ReadPartitions(dev)
+	c.Assert(err, qt.Equals, ErrNoPartitionTable)
+}
+
+func TestGPT(t *testing.T) {
+	c := qt.New(t)
+	dev := newMemDevice(8 << 20)
+	mbr := dev.data[:512]
+	putMBREntry(mbr, 0, TypeGPT, 1, 8<<20/512-1)
+	mbr[510], mbr[511] = 0x55, 0xAA
+
+	hdr := dev.data[512:1024]
+	copy(hdr, "EFI PART")
+	binary.LittleEndian.PutUint64(hdr[72:], 2)
+	binary.LittleEndian.PutUint32(hdr[80:], 128)
+	binary.LittleEndian.PutUint32(hdr[84:], 128)
+	entry := dev.data[1024+128 : 1024+256] // second entry, the first is unused
+	copy(entry[0:16], basicDataGUID[:])
+	binary.LittleEndian.PutUint64(entry[32:], 2048)
+	binary.LittleEndian.PutUint64(entry[40:], 2048+8191)
+	for i, r := range "DATA" {
+		binary.LittleEndian.PutUint16(entry[56+2*i:], uint16(r))
+	}
+
+	parts, err := ReadPartitions(dev)
+	c.Assert(err, qt.IsNil)
+	c.Assert(parts, qt.HasLen, 1)
+	c.Assert(parts[0].Start, qt.Equals, int64(2048*512))
+	c.Assert(parts[0].Size, qt.Equals, int64(8192*512))
+	c.Assert(parts[0].Name, qt.Equals, "DATA")
+	c.Assert(parts[0].IsFAT(), qt.IsTrue)
+
+	fs := newTestFS(c, dev, &FormatConfig{})
+	c.Assert(fs.Type(), qt.Equals, FAT12)
+	writeFile(c, fs, "gpt", []byte("ok"))
+	fs = remount(c, fs)
+	c.Assert(readFile(c, fs, "gpt"), qt.DeepEquals, []byte("ok"))
+}
+
+func putMBREntry(mbr []byte, i int, typ byte, start, size uint32) {
+	e := mbr[446+16*i:]
+	e[4] = typ
+	binary.LittleEndian.PutUint32(e[8:], start)
+	binary.LittleEndian.PutUint32(e[12:], size)
+}
+
+// TestHandcraftedImage reads a small FAT12 image built by hand following the
+// specification, independently of Format and the write path.
+func TestHandcraftedImage(t *testing.T) {
+	c := qt.New(t)
+	dev := newMemDevice(64 * 512)
+	img := dev.data
+
+	// Boot sector: 512 bytes per sector, 1 sector per cluster, 1 reserved
+	// sector, 2 FATs of 1 sector, 16 root entries and 64 sectors.
+	copy(img[0:], []byte{0xEB, 0x3C, 0x90})
+	copy(img[3:], "MSWIN4.1")
+	copy(img[11:], []byte{0x00, 0x02, 0x01, 0x01, 0x00, 0x02, 0x10, 0x00, 0x40, 0x00, 0xF8, 0x01, 0x00})
+	img[510], img[511] = 0x55, 0xAA
+
+	// Both FATs: clusters 2->3, 4, 5 and 6 are single-cluster chains.
+	fat := []byte{0xF8, 0xFF, 0xFF, 0x03, 0xF0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F}
+	copy(img[1*512:], fat)
+	copy(img[2*512:], fat)
+
+	// Root directory at sector 3.
+	root := img[3*512:]
+	putLFN(root[0:], 0x42, "xt", 0x1B)
+	putLFN(root[32:], 0x01, "Hello World.t", 0x1B)
+	putShort(root[64:], "HELLOW~1TXT", 0x20, 0, 2, 600)
+	putShort(root[96:], "README  TXT", 0x20, 0x18, 4, 5)
+	putShort(root[128:], "DELETED TXT", 0x20, 0, 0, 0)
+	root[128] = 0xE5
+	putShort(root[160:], "DOCS       ", 0x10, 0, 5, 0)
+	putShort(root[192:], "TINYGO     ", 0x08, 0, 0, 0)
+
+	// Data starts at sector 4 with cluster 2.
+	copy(img[4*512:], bytes.Repeat([]byte("A"), 512))
+	copy(img[5*512:], bytes.Repeat([]byte("B"), 88))
+	copy(img[6*512:], "hi!\r\n")
+	docs := img[7*512:]
+	putShort(docs[0:], ".          ", 0x10, 0, 5, 0)
+	putShort(docs[32:], "..         ", 0x10, 0, 0, 0)
+	putShort(docs[64:], "A       TXT", 0x20, 0, 0, 0)
+
+	fs := New(dev)
+	fs.Configure(&Config{})
+	c.Assert(fs.Mount(), qt.IsNil)
+	c.Assert(fs.Type(), qt.Equals, FAT12)
+	c.Assert(readDirNames(c, fs, "/"), qt.DeepEquals, []string{"Hello World.txt", "readme.txt", "DOCS"})
+	c.Assert(readDirNames(c, fs, "docs"), qt.DeepEquals, []string{"A.TXT"})
+
+	data := readFile(c, fs, "hello world.txt")
+	c.Assert(data, qt.HasLen, 600)
+	c.Assert(string(data[511:513]), qt.Equals, "AB")
+	c.Assert(string(readFile(c, fs, "README.TXT")), qt.Equals, "hi!\r\n")
+	c.Assert(readFile(c, fs, "DOCS/A.TXT"), qt.HasLen, 0)
+
+	info, err := fs.Stat("readme.txt")
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.ModTime(), qt.Equals, time.Date(2021, 11, 5, 13, 37, 42, 0, time.Local))
+}
+
+// TestSmallFAT32 mounts a FAT32 volume with fewer clusters than the FAT32
+// minimum, as some tools make, which is recognized by its BPB.
+func TestSmallFAT32(t *testing.T) {
+	c := qt.New(t)
+	dev := newMemDevice(1024 * 512)
+	img := dev.data
+
+	// Boot sector: 512 bytes per sector, 1 sector per cluster, 32 reserved
+	// sectors, 2 FATs of 8 sectors, no root entries, 1024 sectors, root
+	// directory in cluster 2 and FSInfo in sector 1: 976 clusters.
+	copy(img[0:], []byte{0xEB, 0x58, 0x90})
+	copy(img[3:], "MSWIN4.1")
+	copy(img[11:], []byte{0x00, 0x02, 0x01, 0x20, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0xF8, 0x00, 0x00})
+	binary.LittleEndian.PutUint32(img[32:], 1024)
+	binary.LittleEndian.PutUint32(img[36:], 8)
+	binary.LittleEndian.PutUint32(img[44:], 2)
+	binary.LittleEndian.PutUint16(img[48:], 1)
+	img[510], img[511] = 0x55, 0xAA
+
+	// FSInfo: clusters 2 and 3 are used.
+	info := img[1*512:]
+	binary.LittleEndian.PutUint32(info[0:], 0x41615252)
+	binary.LittleEndian.PutUint32(info[484:], 0x61417272)
+	binary.LittleEndian.PutUint32(info[488:], 974)
+	binary.LittleEndian.PutUint32(info[492:], 4)
+	info[510], info[511] = 0x55, 0xAA
+
+	// Both FATs: the root directory and the file are single-cluster chains.
+	for _, start := range []int{32, 40} {
+		fat := img[start*512:]
+		for i, v := range []uint32{0x0FFFFFF8, 0x0FFFFFFF, 0x0FFFFFFF, 0x0FFFFFFF} {
+			binary.LittleEndian.PutUint32(fat[i*4:], v)
+		}
+	}
+
+	// Data starts at sector 48 with cluster 2, the root directory.
+	putShort(img[48*512:], "README  TXT", 0x20, 0x18, 3, 5)
+	copy(img[49*512:], "hi!\r\n")
+
+	fs := New(dev)
+	fs.Configure(&Config{Now: func() time.Time { return testTime }})
+	c.Assert(fs.Mount(), qt.IsNil)
+	c.Assert(fs.Type(), qt.Equals, FAT32)
+	c.Assert(fs.clusters, qt.Equals, uint32(976))
+	c.Assert(fs.freeCount, qt.Equals, uint32(974))
+	c.Assert(string(readFile(c, fs, "README.TXT")), qt.Equals, "hi!\r\n")
+
+	// Writing uses 32-bit FAT entries.
+	data := pattern(3*512, 7)
+	writeFile(c, fs, "big.bin", data)
+	fs = remount(c, fs)
+	c.Assert(fs.freeCount, qt.Equals, uint32(971))
+	c.Assert(readFile(c, fs, "big.bin"), qt.DeepEquals, data)
+	c.Assert(binary.LittleEndian.Uint32(img[32*512+4*4:]), qt.Equals, uint32(5))
+}
+
+func putLFN(b []byte, ord byte, chars string, sum byte) {
+	b[0] = ord
+	b[11] = 0x0F
+	b[13] = sum
+	units := []uint16{}
+	for _, r := range chars {
+		units = append(units, uint16(r))
+	}
+	if len(units) < 13 {
+		units = append(units, 0)
+	}
+	for len(units) < 13 {
+		units = append(units, 0xFFFF)
+	}
+	offs := []int{1, 3, 5, 7, 9, 14, 16, 18, 20, 22, 24, 28, 30}
+	for i, o := range offs {
+		binary.LittleEndian.PutUint16(b[o:], units[i])
+	}
+}
+
+func putShort(b []byte, name string, attr, ntCase byte, cluster, size uint32) {
+	copy(b[0:11], name)
+	b[11] = attr
+	b[12] = ntCase
+	binary.LittleEndian.PutUint16(b[22:], 13<<11|37<<5|21) // 13:37:42
+	binary.LittleEndian.PutUint16(b[24:], 41<<9|11<<5|5)   // 2021-11-05
+	binary.LittleEndian.PutUint16(b[26:], uint16(cluster))
+	binary.LittleEndian.PutUint32(b[28:], size)
+}
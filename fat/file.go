@@ -0,0 +1,405 @@
+package fat
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+var errIsDir = errors.New("is a directory")
+
+// File is an open file or directory.
+type File struct {
+	fs     *FS
+	name   string
+	e      dirent
+	flag   int
+	pos    int64
+	dirty  bool
+	closed bool
+
+	// cluster caches the cluster with index chainIdx in the file's chain, so
+	// sequential access does not walk the FAT from the start.
+	cluster  uint32
+	chainIdx uint32
+
+	// dirRead is the number of entries already returned by Readdir.
+	dirRead int
+}
+
+// OpenFile opens the named file with the given os.O_* flags. Directories
+// can only be opened read-only.
+func (fs *FS) OpenFile(name string, flag int) (*File, error) {
+	f, err := fs.openFile(name, flag)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return f, nil
+}
+
+func (fs *FS) openFile(name string, flag int) (*File, error) {
+	e, err := fs.resolve(name)
+	switch {
+	case err == os.ErrNotExist && flag&os.O_CREATE != 0:
+		parent, base, err := fs.resolveParent(name)
+		if err != nil {
+			return nil, err
+		}
+		e = &dirent{name: base, attr: attrArchive, mtime: fs.config.Now()}
+		if err := fs.createEntry(parent.cluster, e); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, os.ErrExist
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if e.isDir() && writable {
+		return nil, errIsDir
+	}
+	if writable && e.attr&attrReadOnly != 0 {
+		return nil, os.ErrPermission
+	}
+	f := &File{
+		fs:   fs,
+		name: name,
+		e:    *e,
+		flag: flag,
+	}
+	if writable && flag&os.O_TRUNC != 0 && e.size != 0 {
+		if err := f.Truncate(0); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Name returns the name of the file as passed to OpenFile.
+func (f *File) Name() string {
+	return f.name
+}
+
+// Stat returns information about the file.
+func (f *File) Stat() (os.FileInfo, error) {
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	return newFileInfo(&f.e), nil
+}
+
+// Read reads up to len(p) bytes from the file.
+func (f *File) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.e.isDir() {
+		return 0, errIsDir
+	}
+	if f.flag&os.O_WRONLY != 0 {
+		return 0, os.ErrPermission
+	}
+	if f.pos >= int64(f.e.size) {
+		return 0, io.EOF
+	}
+	if remain := int64(f.e.size) - f.pos; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n := 0
+	for n < len(p) {
+		cluster, err := f.clusterAt(f.pos, false)
+		if err != nil {
+			return n, err
+		}
+		off := f.pos % int64(f.fs.clusterSize)
+		chunk := p[n:]
+		if int64(len(chunk)) > int64(f.fs.clusterSize)-off {
+			chunk = chunk[:int64(f.fs.clusterSize)-off]
+		}
+		if err := f.fs.cache.readAt(chunk, f.fs.clusterOffset(cluster)+off); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		f.pos += int64(len(chunk))
+	}
+	return n, nil
+}
+
+// Write writes p to the file, growing it as needed.
+func (f *File) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, os.ErrPermission
+	}
+	if f.flag&os.O_APPEND != 0 {
+		f.pos = int64(f.e.size)
+	}
+	if f.pos > int64(f.e.size) {
+		// Fill the gap left by seeking past the end with zeros.
+		pos := f.pos
+		f.pos = int64(f.e.size)
+		if err := f.writeZeros(pos - f.pos); err != nil {
+			return 0, err
+		}
+	}
+	return f.write(p)
+}
+
+func (f *File) write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if f.pos >= 0xFFFFFFFF {
+			return n, ErrNoSpace
+		}
+		cluster, err := f.clusterAt(f.pos, true)
+		if err != nil {
+			return n, err
+		}
+		off := f.pos % int64(f.fs.clusterSize)
+		chunk := p[n:]
+		if int64(len(chunk)) > int64(f.fs.clusterSize)-off {
+			chunk = chunk[:int64(f.fs.clusterSize)-off]
+		}
+		if err := f.fs.cache.writeAt(chunk, f.fs.clusterOffset(cluster)+off); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		f.pos += int64(len(chunk))
+		if f.pos > int64(f.e.size) {
+			f.e.size = uint32(f.pos)
+		}
+		f.dirty = true
+	}
+	return n, nil
+}
+
+func (f *File) writeZeros(n int64) error {
+	var zero [sectorSize]byte
+	for n > 0 {
+		chunk := zero[:]
+		if n < int64(len(chunk)) {
+			chunk = chunk[:n]
+		}
+		if _, err := f.write(chunk); err != nil {
+			return err
+		}
+		n -= int64(len(chunk))
+	}
+	return nil
+}
+
+// clusterAt returns the cluster holding the byte at pos. If alloc is set
+// the chain is extended as needed.
+func (f *File) clusterAt(pos int64, alloc bool) (uint32, error) {
+	fs := f.fs
+	idx := uint32(pos / int64(fs.clusterSize))
+	if f.e.cluster == 0 {
+		if !alloc {
+			return 0, io.EOF
+		}
+		cluster, err := fs.allocCluster(0)
+		if err != nil {
+			return 0, err
+		}
+		f.e.cluster = cluster
+		f.dirty = true
+	}
+	if f.cluster == 0 || idx < f.chainIdx {
+		f.cluster = f.e.cluster
+		f.chainIdx = 0
+	}
+	for f.chainIdx < idx {
+		next, err := fs.fatEntry(f.cluster)
+		if err != nil {
+			return 0, err
+		}
+		if next == clusterEOC {
+			if !alloc {
+				return 0, ErrCorruptChain
+			}
+			next, err = fs.allocCluster(f.cluster)
+			if err != nil {
+				return 0, err
+			}
+		}
+		f.cluster = next
+		f.chainIdx++
+	}
+	return f.cluster, nil
+}
+
+// Seek sets the offset for the next Read or Write.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += int64(f.e.size)
+	default:
+		return 0, os.ErrInvalid
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+// Truncate changes the size of the file.
+func (f *File) Truncate(size int64) error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return os.ErrPermission
+	}
+	if size < 0 || size > 0xFFFFFFFF {
+		return os.ErrInvalid
+	}
+	if size > int64(f.e.size) {
+		pos := f.pos
+		f.pos = int64(f.e.size)
+		err := f.writeZeros(size - f.pos)
+		f.pos = pos
+		return err
+	}
+	fs := f.fs
+	f.cluster = 0
+	if size == 0 {
+		if err := fs.freeChain(f.e.cluster); err != nil {
+			return err
+		}
+		f.e.cluster = 0
+	} else {
+		last, err := f.clusterAt(size-1, false)
+		if err != nil {
+			return err
+		}
+		next, err := fs.fatEntry(last)
+		if err != nil {
+			return err
+		}
+		if next != clusterEOC {
+			if err := fs.setFATEntry(last, clusterEOC); err != nil {
+				return err
+			}
+			if err := fs.freeChain(next); err != nil {
+				return err
+			}
+		}
+	}
+	f.e.size = uint32(size)
+	f.dirty = true
+	return nil
+}
+
+// Sync updates the directory entry of the file and writes all cached data
+// to the device.
+func (f *File) Sync() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	if f.dirty {
+		f.e.mtime = f.fs.config.Now()
+		f.e.attr |= attrArchive
+		if err := f.fs.writeEntry(&f.e); err != nil {
+			return err
+		}
+		f.dirty = false
+	}
+	return f.fs.Sync()
+}
+
+// Close closes the file, writing all pending changes.
+func (f *File) Close() error {
+	if f.closed {
+		return os.ErrClosed
+	}
+	err := f.Sync()
+	f.closed = true
+	return err
+}
+
+// Readdir reads the contents of the directory and returns up to n entries
+// in directory order. If n <= 0 all remaining entries are returned.
+func (f *File) Readdir(n int) ([]os.FileInfo, error) {
+	entries, err := f.readdir(n)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, len(entries))
+	for i := range entries {
+		infos[i] = entries[i]
+	}
+	if n > 0 && len(infos) == 0 {
+		return nil, io.EOF
+	}
+	return infos, nil
+}
+
+func (f *File) readdir(n int) ([]*fileInfo, error) {
+	if f.closed {
+		return nil, os.ErrClosed
+	}
+	if !f.e.isDir() {
+		return nil, errors.New("not a directory")
+	}
+	var entries []*fileInfo
+	skip := f.dirRead
+	err := f.fs.readDir(f.e.cluster, func(e *dirent) bool {
+		if e.name == "." || e.name == ".." {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		entries = append(entries, newFileInfo(e))
+		return n <= 0 || len(entries) < n
+	})
+	f.dirRead += len(entries)
+	return entries, err
+}
+
+// fileInfo implements os.FileInfo.
+type fileInfo struct {
+	name  string
+	size  int64
+	attr  byte
+	mtime time.Time
+}
+
+func newFileInfo(e *dirent) *fileInfo {
+	return &fileInfo{name: e.name, size: int64(e.size), attr: e.attr, mtime: e.mtime}
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *fileInfo) IsDir() bool        { return fi.attr&attrDirectory != 0 }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
+func (fi *fileInfo) Mode() os.FileMode {
+	mode := os.FileMode(0666)
+	if fi.attr&attrReadOnly != 0 {
+		mode = 0444
+	}
+	if fi.IsDir() {
+		mode |= os.ModeDir | 0111
+	}
+	return mode
+}
+
+// sortInfos sorts file information by name.
+func sortInfos(infos []*fileInfo) {
+	sort.Slice(infos, func(i, j int) bool { return infos[i].name < infos[j].name })
+}
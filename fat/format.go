@@ -0,0 +1,260 @@
+package fat
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// FormatConfig contains the options for Format.
+type FormatConfig struct {
+	// Type selects the FAT type. If zero it is chosen based on the volume
+	// size.
+	Type Type
+
+	// SectorsPerCluster sets the cluster size in 512 byte sectors. It must
+	// be a power of two. If zero a default for the volume size is used.
+	SectorsPerCluster uint32
+
+	// Label is the volume label, up to 11 characters.
+	Label string
+}
+
+// Format creates an empty filesystem on the volume that Mount would use:
+// the partition selected by Config.Partition, or the whole device if it has
+// no partition table. Any mounted filesystem is unmounted first.
+func (fs *FS) Format(config *FormatConfig) error {
+	fs.mounted = false
+	fs.cache = newBlockCache(fs.dev)
+	start, size := int64(0), fs.dev.Size()
+	parts, err := ReadPartitions(fs.dev)
+	switch {
+	case err == ErrNoPartitionTable:
+	case err != nil:
+		return err
+	case fs.config.Partition >= len(parts):
+		return ErrNoPartition
+	default:
+		start = parts[fs.config.Partition].Start
+		size = parts[fs.config.Partition].Size
+	}
+
+	l, err := newLayout(uint32(size/sectorSize), config)
+	if err != nil {
+		return err
+	}
+
+	// Clear the reserved sectors, the FATs and the root directory.
+	var sector [sectorSize]byte
+	for i := uint32(0); i < l.dataStart(); i++ {
+		if err := fs.cache.writeAt(sector[:], start+int64(i)*sectorSize); err != nil {
+			return err
+		}
+	}
+	if l.typ == FAT32 {
+		for i := uint32(0); i < l.spc; i++ {
+			off := start + int64(l.dataStart()+i)*sectorSize
+			if err := fs.cache.writeAt(sector[:], off); err != nil {
+				return err
+			}
+		}
+	}
+
+	l.bootSector(sector[:], uint32(start/sectorSize), config.Label)
+	if err := fs.cache.writeAt(sector[:], start); err != nil {
+		return err
+	}
+	if l.typ == FAT32 {
+		if err := fs.cache.writeAt(sector[:], start+6*sectorSize); err != nil {
+			return err
+		}
+		info := make([]byte, sectorSize)
+		binary.LittleEndian.PutUint32(info[0:], 0x41615252)
+		binary.LittleEndian.PutUint32(info[484:], 0x61417272)
+		binary.LittleEndian.PutUint32(info[488:], l.clusters-1)
+		binary.LittleEndian.PutUint32(info[492:], 3)
+		binary.LittleEndian.PutUint32(info[508:], 0xAA550000)
+		for _, s := range []int64{1, 7} {
+			if err := fs.cache.writeAt(info, start+s*sectorSize); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The first two FAT entries hold the media descriptor and an end of
+	// chain marker. On FAT32 the third one is the root directory.
+	var head []byte
+	switch l.typ {
+	case FAT12:
+		head = []byte{0xF8, 0xFF, 0xFF}
+	case FAT16:
+		head = []byte{0xF8, 0xFF, 0xFF, 0xFF}
+	default:
+		head = []byte{0xF8, 0xFF, 0xFF, 0x0F, 0xFF, 0xFF, 0xFF, 0x0F, 0xFF, 0xFF, 0xFF, 0x0F}
+	}
+	for i := uint32(0); i < l.numFATs; i++ {
+		off := start + int64(l.reserved+i*l.fatSize)*sectorSize
+		if err := fs.cache.writeAt(head, off); err != nil {
+			return err
+		}
+	}
+
+	if label := formatLabel(config.Label); label != "" {
+		var raw [entrySize]byte
+		copy(raw[:11], label)
+		raw[11] = attrVolumeID
+		off := start + int64(l.reserved+l.numFATs*l.fatSize)*sectorSize
+		if err := fs.cache.writeAt(raw[:], off); err != nil {
+			return err
+		}
+	}
+	return fs.cache.flush()
+}
+
+// layout is the geometry of a volume to be formatted. All sizes are in
+// sectors.
+type layout struct {
+	typ         Type
+	total       uint32
+	spc         uint32
+	reserved    uint32
+	numFATs     uint32
+	fatSize     uint32
+	rootEntries uint32
+	clusters    uint32
+}
+
+func newLayout(total uint32, config *FormatConfig) (*layout, error) {
+	typ := config.Type
+	if typ == 0 {
+		switch {
+		case total <= 8*1024*1024/sectorSize:
+			typ = FAT12
+		case total <= 512*1024*1024/sectorSize:
+			typ = FAT16
+		default:
+			typ = FAT32
+		}
+	}
+	candidates := []uint32{config.SectorsPerCluster}
+	if config.SectorsPerCluster == 0 {
+		candidates = []uint32{1, 2, 4, 8, 16, 32, 64, 128}
+		if typ == FAT32 {
+			// Prefer 4 KiB clusters, like most formatting tools do.
+			candidates = []uint32{8, 16, 32, 64, 4, 2, 1}
+		}
+	}
+	for _, spc := range candidates {
+		l := &layout{typ: typ, total: total, spc: spc, reserved: 1, numFATs: 2, rootEntries: 512}
+		if typ == FAT32 {
+			l.reserved = 32
+			l.rootEntries = 0
+		}
+		if l.compute() {
+			return l, nil
+		}
+	}
+	return nil, ErrNoSpace
+}
+
+// compute calculates the FAT size and cluster count. It reports whether the
+// cluster count is valid for the FAT type.
+func (l *layout) compute() bool {
+	if l.spc == 0 || l.spc&(l.spc-1) != 0 || l.spc > 128 {
+		return false
+	}
+	bits := uint32(l.typ)
+	rootSectors := l.rootEntries * entrySize / sectorSize
+	l.fatSize = 1
+	for {
+		meta := l.reserved + rootSectors + l.numFATs*l.fatSize
+		if meta >= l.total {
+			return false
+		}
+		l.clusters = (l.total - meta) / l.spc
+		need := uint32(((uint64(l.clusters+2)*uint64(bits)+7)/8 + sectorSize - 1) / sectorSize)
+		if need <= l.fatSize {
+			break
+		}
+		l.fatSize = need
+	}
+	switch l.typ {
+	case FAT12:
+		return l.clusters >= 1 && l.clusters < 4085
+	case FAT16:
+		return l.clusters >= 4085 && l.clusters < 65525
+	default:
+		return l.clusters >= 65525 && l.clusters < 0x0FFFFFF5
+	}
+}
+
+func (l *layout) dataStart() uint32 {
+	return l.reserved + l.numFATs*l.fatSize + l.rootEntries*entrySize/sectorSize
+}
+
+// bootSector fills b with the boot sector of the volume.
+func (l *layout) bootSector(b []byte, hidden uint32, label string) {
+	for i := range b {
+		b[i] = 0
+	}
+	if l.typ == FAT32 {
+		copy(b[0:], []byte{0xEB, 0x58, 0x90})
+	} else {
+		copy(b[0:], []byte{0xEB, 0x3C, 0x90})
+	}
+	copy(b[3:11], "TINYGO  ")
+	binary.LittleEndian.PutUint16(b[11:], sectorSize)
+	b[13] = byte(l.spc)
+	binary.LittleEndian.PutUint16(b[14:], uint16(l.reserved))
+	b[16] = byte(l.numFATs)
+	binary.LittleEndian.PutUint16(b[17:], uint16(l.rootEntries))
+	if l.total < 0x10000 && l.typ != FAT32 {
+		binary.LittleEndian.PutUint16(b[19:], uint16(l.total))
+	} else {
+		binary.LittleEndian.PutUint32(b[32:], l.total)
+	}
+	b[21] = 0xF8 // fixed disk
+	binary.LittleEndian.PutUint16(b[24:], 63)
+	binary.LittleEndian.PutUint16(b[26:], 255)
+	binary.LittleEndian.PutUint32(b[28:], hidden)
+
+	ext := b[36:]
+	if l.typ == FAT32 {
+		binary.LittleEndian.PutUint32(b[36:], l.fatSize)
+		binary.LittleEndian.PutUint32(b[44:], 2) // root directory cluster
+		binary.LittleEndian.PutUint16(b[48:], 1) // FSInfo sector
+		binary.LittleEndian.PutUint16(b[50:], 6) // backup boot sector
+		ext = b[64:]
+	} else {
+		binary.LittleEndian.PutUint16(b[22:], uint16(l.fatSize))
+	}
+	ext[0] = 0x80 // drive number
+	ext[2] = 0x29 // extended boot signature
+	binary.LittleEndian.PutUint32(ext[3:], 0x1F6E7967^l.total)
+	if label = formatLabel(label); label == "" {
+		label = "NO NAME    "
+	}
+	copy(ext[7:18], label)
+	switch l.typ {
+	case FAT12:
+		copy(ext[18:26], "FAT12   ")
+	case FAT16:
+		copy(ext[18:26], "FAT16   ")
+	default:
+		copy(ext[18:26], "FAT32   ")
+	}
+	b[510] = 0x55
+	b[511] = 0xAA
+}
+
+// formatLabel returns the label padded to 11 characters, or an empty string
+// if there is no label.
+func formatLabel(label string) string {
+	label = strings.ToUpper(label)
+	if label == "" {
+		return ""
+	}
+	if len(label) > 11 {
+		label = label[:11]
+	}
+	return label + strings.Repeat(" ", 11-len(label))
+}
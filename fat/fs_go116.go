@@ -0,0 +1,70 @@
+//go:build go1.16
+// +build go1.16
+
+package fat
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Open opens the named file for reading. It implements io/fs.FS.
+func (fsys *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := fsys.OpenFile(name, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadDir returns the entries of the named directory sorted by name. It
+// implements io/fs.ReadDirFS.
+func (fsys *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := fsys.OpenFile(name, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	infos, err := f.readdir(-1)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	sortInfos(infos)
+	return dirEntries(infos), nil
+}
+
+// ReadDir reads the contents of the directory and returns up to n entries
+// in directory order. It implements io/fs.ReadDirFile.
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	infos, err := f.readdir(n)
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 && len(infos) == 0 {
+		return nil, io.EOF
+	}
+	return dirEntries(infos), nil
+}
+
+func dirEntries(infos []*fileInfo) []fs.DirEntry {
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = dirEntry{info}
+	}
+	return entries
+}
+
+// dirEntry implements fs.DirEntry.
+type dirEntry struct {
+	*fileInfo
+}
+
+func (d dirEntry) Type() fs.FileMode          { return d.Mode().Type() }
+func (d dirEntry) Info() (fs.FileInfo, error) { return d.fileInfo, nil }
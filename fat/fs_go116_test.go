@@ -0,0 +1,40 @@
+//go:build go1.16
+// +build go1.16
+
+package fat
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFSInterface(t *testing.T) {
+	for _, tt := range formatTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := qt.New(t)
+			fsys := newTestFS(c, newMemDevice(tt.size), &FormatConfig{Type: tt.typ, SectorsPerCluster: 1})
+			c.Assert(fsys.Mkdir("dir"), qt.IsNil)
+			c.Assert(fsys.Mkdir("dir/empty"), qt.IsNil)
+			writeFile(c, fsys, "dir/Long Name.txt", pattern(3000, 1))
+			writeFile(c, fsys, "top.bin", pattern(100, 2))
+
+			c.Assert(fstest.TestFS(fsys, "dir/Long Name.txt", "top.bin", "dir/empty"), qt.IsNil)
+
+			data, err := fs.ReadFile(fsys, "dir/Long Name.txt")
+			c.Assert(err, qt.IsNil)
+			c.Assert(data, qt.DeepEquals, pattern(3000, 1))
+
+			entries, err := fs.ReadDir(fsys, "dir")
+			c.Assert(err, qt.IsNil)
+			c.Assert(entries, qt.HasLen, 2)
+			c.Assert(entries[0].Name(), qt.Equals, "Long Name.txt")
+			c.Assert(entries[1].IsDir(), qt.IsTrue)
+
+			_, err = fsys.Open("/top.bin")
+			c.Assert(err, qt.ErrorMatches, ".*invalid argument")
+		})
+	}
+}
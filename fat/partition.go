@@ -0,0 +1,150 @@
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"unicode/utf16"
+)
+
+var (
+	// ErrNoPartitionTable is returned by ReadPartitions when the first
+	// sector of the device holds neither an MBR nor a protective MBR.
+	ErrNoPartitionTable = errors.New("fat: no partition table")
+
+	errInvalidGPT = errors.New("fat: invalid GPT header")
+)
+
+// Partition describes a primary MBR partition or a GPT partition.
+type Partition struct {
+	// Start is the offset of the partition in bytes.
+	Start int64
+
+	// Size is the length of the partition in bytes.
+	Size int64
+
+	// Type is the MBR partition type. It is 0xEE for GPT partitions.
+	Type byte
+
+	// TypeGUID is the partition type GUID in on-disk byte order. It is only
+	// set for GPT partitions.
+	TypeGUID [16]byte
+
+	// Name is the GPT partition name.
+	Name string
+}
+
+// MBR partition types of FAT volumes.
+const (
+	TypeFAT12    = 0x01
+	TypeFAT16    = 0x04
+	TypeFAT16B   = 0x06
+	TypeFAT32CHS = 0x0B
+	TypeFAT32LBA = 0x0C
+	TypeFAT16LBA = 0x0E
+	TypeGPT      = 0xEE
+)
+
+// basicDataGUID is the GPT "Microsoft basic data" partition type
+// (EBD0A0A2-B9E5-4433-87C0-68B6B72699C7) in on-disk byte order.
+var basicDataGUID = [16]byte{
+	0xA2, 0xA0, 0xD0, 0xEB, 0xE5, 0xB9, 0x33, 0x44,
+	0x87, 0xC0, 0x68, 0xB6, 0xB7, 0x26, 0x99, 0xC7,
+}
+
+// IsFAT reports whether the partition type indicates a FAT volume.
+func (p Partition) IsFAT() bool {
+	switch p.Type {
+	case TypeFAT12, TypeFAT16, TypeFAT16B, TypeFAT32CHS, TypeFAT32LBA, TypeFAT16LBA:
+		return true
+	case TypeGPT:
+		return p.TypeGUID == basicDataGUID
+	}
+	return false
+}
+
+// ReadPartitions returns the partitions listed in the MBR or, when the MBR
+// is a protective MBR, in the GUID partition table. Extended MBR partitions
+// are not followed. If the first sector is a FAT boot sector (a disk without
+// partition table) ErrNoPartitionTable is returned.
+func ReadPartitions(dev BlockDevice) ([]Partition, error) {
+	var sector [sectorSize]byte
+	if _, err := dev.ReadAt(sector[:], 0); err != nil {
+		return nil, err
+	}
+	if sector[510] != 0x55 || sector[511] != 0xAA || isBootSector(sector[:]) {
+		return nil, ErrNoPartitionTable
+	}
+
+	var parts []Partition
+	for i := 0; i < 4; i++ {
+		e := sector[446+16*i : 446+16*(i+1)]
+		typ := e[4]
+		if typ == 0 {
+			continue
+		}
+		if typ == TypeGPT {
+			return readGPT(dev)
+		}
+		if e[0]&0x7F != 0 {
+			// The boot indicator must be 0x00 or 0x80.
+			return nil, ErrNoPartitionTable
+		}
+		parts = append(parts, Partition{
+			Start: int64(binary.LittleEndian.Uint32(e[8:])) * sectorSize,
+			Size:  int64(binary.LittleEndian.Uint32(e[12:])) * sectorSize,
+			Type:  typ,
+		})
+	}
+	return parts, nil
+}
+
+func readGPT(dev BlockDevice) ([]Partition, error) {
+	var hdr [sectorSize]byte
+	if _, err := dev.ReadAt(hdr[:], sectorSize); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(hdr[0:8], []byte("EFI PART")) {
+		return nil, errInvalidGPT
+	}
+	entriesLBA := int64(binary.LittleEndian.Uint64(hdr[72:]))
+	count := int(binary.LittleEndian.Uint32(hdr[80:]))
+	entrySize := int(binary.LittleEndian.Uint32(hdr[84:]))
+	if entrySize < 128 || count > 1024 {
+		return nil, errInvalidGPT
+	}
+
+	var parts []Partition
+	entry := make([]byte, entrySize)
+	for i := 0; i < count; i++ {
+		if _, err := dev.ReadAt(entry, entriesLBA*sectorSize+int64(i*entrySize)); err != nil {
+			return nil, err
+		}
+		var p Partition
+		copy(p.TypeGUID[:], entry[0:16])
+		if p.TypeGUID == [16]byte{} {
+			continue
+		}
+		first := int64(binary.LittleEndian.Uint64(entry[32:]))
+		last := int64(binary.LittleEndian.Uint64(entry[40:]))
+		p.Start = first * sectorSize
+		p.Size = (last - first + 1) * sectorSize
+		p.Type = TypeGPT
+		p.Name = decodeUTF16(entry[56:128])
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+// decodeUTF16 decodes a zero terminated little endian UTF-16 string.
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,367 @@
+package fat
+
+import (
+	"os"
+	"path"
+	"strings"
+	"unicode/utf16"
+)
+
+// rootDirent returns a pseudo entry for the root directory.
+func (fs *FS) rootDirent() dirent {
+	return dirent{name: ".", attr: attrDirectory}
+}
+
+// splitPath returns the components of a slash separated path. Leading
+// slashes are ignored, so "/a/b" and "a/b" are the same file.
+func splitPath(name string) []string {
+	name = path.Clean("/" + name)
+	if name == "/" {
+		return nil
+	}
+	return strings.Split(name[1:], "/")
+}
+
+// lookup finds the entry with the given name in a directory. Names are
+// compared case insensitively, against both the long and the short name.
+func (fs *FS) lookup(dir uint32, name string) (*dirent, error) {
+	var found *dirent
+	err := fs.readDir(dir, func(e *dirent) bool {
+		if strings.EqualFold(e.name, name) || strings.EqualFold(e.shortName(), name) {
+			found = e
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, os.ErrNotExist
+	}
+	return found, nil
+}
+
+// resolve returns the entry of the given path.
+func (fs *FS) resolve(name string) (*dirent, error) {
+	if !fs.mounted {
+		return nil, ErrNotMounted
+	}
+	parts := splitPath(name)
+	e := fs.rootDirent()
+	cur := &e
+	for _, p := range parts {
+		if !cur.isDir() {
+			return nil, os.ErrNotExist
+		}
+		next, err := fs.lookup(cur.cluster, p)
+		if err != nil {
+			return nil, err
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// resolveParent returns the directory entry that contains name, and the
+// last path element.
+func (fs *FS) resolveParent(name string) (*dirent, string, error) {
+	if !fs.mounted {
+		return nil, "", ErrNotMounted
+	}
+	parts := splitPath(name)
+	if len(parts) == 0 {
+		return nil, "", ErrInvalidName
+	}
+	dir, err := fs.resolve(strings.Join(parts[:len(parts)-1], "/"))
+	if err != nil {
+		return nil, "", err
+	}
+	if !dir.isDir() {
+		return nil, "", os.ErrNotExist
+	}
+	return dir, parts[len(parts)-1], nil
+}
+
+// createEntry adds a new entry to the directory starting at cluster dir.
+// The caller must make sure that no entry with the same name exists.
+func (fs *FS) createEntry(dir uint32, e *dirent) error {
+	if !validName(e.name) {
+		return ErrInvalidName
+	}
+	short, ntCase, exact := makeShortName(e.name)
+
+	// Collect the short names in use to pick a unique one.
+	used := map[[11]byte]bool{}
+	err := fs.readDir(dir, func(d *dirent) bool {
+		used[d.short] = true
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if exact && used[short] {
+		exact = false
+	}
+	var lfn []uint16
+	if !exact {
+		ntCase = 0
+		lfn = utf16.Encode([]rune(e.name))
+		// Lossy conversions always get a numeric tail, like on Windows.
+		base := short
+		short = numericTail(base, 1)
+		for n := 2; used[short]; n++ {
+			if n > 999999 {
+				return ErrNoSpace
+			}
+			short = numericTail(base, n)
+		}
+	}
+	e.short = short
+	e.ntCase = ntCase
+	lfnSlots := (len(lfn) + lfnChars - 1) / lfnChars
+	e.slots = lfnSlots + 1
+
+	slots, err := fs.findFreeSlots(dir, e.slots)
+	if err != nil {
+		return err
+	}
+	var raw [entrySize]byte
+	sum := shortNameChecksum(short[:])
+	for i := 0; i < lfnSlots; i++ {
+		encodeLFN(raw[:], lfn, lfnSlots-i, lfnSlots, sum)
+		if err := fs.cache.writeAt(raw[:], slots[i]); err != nil {
+			return err
+		}
+	}
+	e.dir = dir
+	e.first = slots[0]
+	e.offset = slots[len(slots)-1]
+	return fs.writeEntry(e)
+}
+
+// findFreeSlots returns the offsets of n consecutive free slots in a
+// directory, extending the directory if necessary.
+func (fs *FS) findFreeSlots(dir uint32, n int) ([]int64, error) {
+	w := fs.walkDir(dir)
+	run := make([]int64, 0, n)
+	var raw [1]byte
+	last := w.cluster
+	for len(run) < n {
+		off, ok, err := w.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		last = w.cluster
+		if err := fs.cache.readAt(raw[:], off); err != nil {
+			return nil, err
+		}
+		if raw[0] == 0 || raw[0] == entryDeleted {
+			run = append(run, off)
+		} else {
+			run = run[:0]
+		}
+	}
+	for len(run) < n {
+		if last == 0 {
+			// The FAT12/16 root directory has a fixed size.
+			return nil, ErrNoSpace
+		}
+		cluster, err := fs.allocCluster(last)
+		if err != nil {
+			return nil, err
+		}
+		if err := fs.zeroCluster(cluster); err != nil {
+			return nil, err
+		}
+		off := fs.clusterOffset(cluster)
+		for i := int64(0); i < int64(fs.clusterSize) && len(run) < n; i += entrySize {
+			run = append(run, off+i)
+		}
+		last = cluster
+	}
+	return run, nil
+}
+
+// writeEntry writes the short entry of e to disk.
+func (fs *FS) writeEntry(e *dirent) error {
+	var raw [entrySize]byte
+	e.encode(raw[:])
+	return fs.cache.writeAt(raw[:], e.offset)
+}
+
+// removeEntry marks all slots of e as deleted.
+func (fs *FS) removeEntry(e *dirent) error {
+	w := fs.walkDir(e.dir)
+	marking := false
+	del := []byte{entryDeleted}
+	for {
+		off, ok, err := w.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrCorruptChain
+		}
+		if off == e.first {
+			marking = true
+		}
+		if marking {
+			if err := fs.cache.writeAt(del, off); err != nil {
+				return err
+			}
+		}
+		if off == e.offset {
+			return nil
+		}
+	}
+}
+
+// Stat returns information about the named file or directory.
+func (fs *FS) Stat(name string) (os.FileInfo, error) {
+	e, err := fs.resolve(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return newFileInfo(e), nil
+}
+
+// Mkdir creates a new directory.
+func (fs *FS) Mkdir(name string) error {
+	if err := fs.mkdir(name); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (fs *FS) mkdir(name string) error {
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.lookup(parent.cluster, base); err == nil {
+		return os.ErrExist
+	} else if err != os.ErrNotExist {
+		return err
+	}
+	cluster, err := fs.allocCluster(0)
+	if err != nil {
+		return err
+	}
+	if err := fs.zeroCluster(cluster); err != nil {
+		return err
+	}
+	now := fs.config.Now()
+	dot := dirent{attr: attrDirectory, cluster: cluster, mtime: now, offset: fs.clusterOffset(cluster)}
+	copy(dot.short[:], ".          ")
+	dotdot := dirent{attr: attrDirectory, cluster: parent.cluster, mtime: now, offset: dot.offset + entrySize}
+	copy(dotdot.short[:], "..         ")
+	if err := fs.writeEntry(&dot); err != nil {
+		return err
+	}
+	if err := fs.writeEntry(&dotdot); err != nil {
+		return err
+	}
+	e := dirent{name: base, attr: attrDirectory, cluster: cluster, mtime: now}
+	return fs.createEntry(parent.cluster, &e)
+}
+
+// Remove removes the named file or empty directory.
+func (fs *FS) Remove(name string) error {
+	if err := fs.remove(name); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (fs *FS) remove(name string) error {
+	parent, base, err := fs.resolveParent(name)
+	if err != nil {
+		return err
+	}
+	e, err := fs.lookup(parent.cluster, base)
+	if err != nil {
+		return err
+	}
+	if e.isDir() {
+		empty := true
+		err := fs.readDir(e.cluster, func(d *dirent) bool {
+			empty = d.name == "." || d.name == ".."
+			return empty
+		})
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return ErrDirNotEmpty
+		}
+	}
+	if err := fs.removeEntry(e); err != nil {
+		return err
+	}
+	return fs.freeChain(e.cluster)
+}
+
+// Rename moves oldname to newname. The target must not exist, except when
+// only the case of the name changes.
+func (fs *FS) Rename(oldname, newname string) error {
+	if err := fs.rename(oldname, newname); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+func (fs *FS) rename(oldname, newname string) error {
+	oldParent, oldBase, err := fs.resolveParent(oldname)
+	if err != nil {
+		return err
+	}
+	e, err := fs.lookup(oldParent.cluster, oldBase)
+	if err != nil {
+		return err
+	}
+	newParent, newBase, err := fs.resolveParent(newname)
+	if err != nil {
+		return err
+	}
+	if existing, err := fs.lookup(newParent.cluster, newBase); err == nil {
+		if existing.offset != e.offset {
+			return os.ErrExist
+		}
+	} else if err != os.ErrNotExist {
+		return err
+	}
+	if e.isDir() {
+		// A directory can not be moved into itself.
+		oldPath := path.Clean("/" + oldname)
+		newPath := path.Clean("/" + newname)
+		if strings.HasPrefix(strings.ToLower(newPath), strings.ToLower(oldPath)+"/") {
+			return ErrInvalidName
+		}
+	}
+
+	// Create the new entry before removing the old one, so the file is not
+	// lost if the target directory is full.
+	moved := *e
+	moved.name = newBase
+	if err := fs.createEntry(newParent.cluster, &moved); err != nil {
+		return err
+	}
+	if err := fs.removeEntry(e); err != nil {
+		return err
+	}
+	if e.isDir() && newParent.cluster != oldParent.cluster {
+		dotdot := dirent{offset: fs.clusterOffset(e.cluster) + entrySize}
+		var raw [entrySize]byte
+		if err := fs.cache.readAt(raw[:], dotdot.offset); err != nil {
+			return err
+		}
+		dotdot.decode(raw[:])
+		dotdot.cluster = newParent.cluster
+		return fs.writeEntry(&dotdot)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package fat
+
+import "encoding/binary"
+
+const (
+	clusterFree = 0
+	clusterEOC  = 0x0FFFFFFF // end of chain marker, as returned by fatEntry
+	clusterBad  = 0x0FFFFFF7
+)
+
+// fatEntry returns the FAT entry of the given cluster. End of chain markers
+// are normalized to clusterEOC and bad cluster markers to clusterBad.
+func (fs *FS) fatEntry(cluster uint32) (uint32, error) {
+	if cluster < 2 || cluster >= fs.clusters+2 {
+		return 0, ErrCorruptChain
+	}
+	var v uint32
+	switch fs.typ {
+	case FAT12:
+		off := cluster + cluster/2
+		if err := fs.cache.readAt(fs.buf[:2], fs.fatStart+int64(off)); err != nil {
+			return 0, err
+		}
+		v = uint32(binary.LittleEndian.Uint16(fs.buf[:]))
+		if cluster&1 != 0 {
+			v >>= 4
+		} else {
+			v &= 0xFFF
+		}
+		if v >= 0xFF7 {
+			v |= 0x0FFFF000
+		}
+	case FAT16:
+		if err := fs.cache.readAt(fs.buf[:2], fs.fatStart+int64(cluster)*2); err != nil {
+			return 0, err
+		}
+		v = uint32(binary.LittleEndian.Uint16(fs.buf[:]))
+		if v >= 0xFFF7 {
+			v |= 0x0FFF0000
+		}
+	default:
+		if err := fs.cache.readAt(fs.buf[:4], fs.fatStart+int64(cluster)*4); err != nil {
+			return 0, err
+		}
+		v = binary.LittleEndian.Uint32(fs.buf[:]) & 0x0FFFFFFF
+	}
+	if v >= 0x0FFFFFF8 {
+		v = clusterEOC
+	}
+	return v, nil
+}
+
+// setFATEntry updates the entry of the given cluster in all FAT copies.
+func (fs *FS) setFATEntry(cluster, value uint32) error {
+	for i := int64(0); i < int64(fs.numFATs); i++ {
+		start := fs.fatStart + i*fs.fatSize
+		var err error
+		switch fs.typ {
+		case FAT12:
+			off := start + int64(cluster+cluster/2)
+			if err = fs.cache.readAt(fs.buf[:2], off); err != nil {
+				return err
+			}
+			old := binary.LittleEndian.Uint16(fs.buf[:])
+			v := uint16(value & 0xFFF)
+			if cluster&1 != 0 {
+				v = old&0x000F | v<<4
+			} else {
+				v = old&0xF000 | v
+			}
+			binary.LittleEndian.PutUint16(fs.buf[:], v)
+			err = fs.cache.writeAt(fs.buf[:2], off)
+		case FAT16:
+			binary.LittleEndian.PutUint16(fs.buf[:], uint16(value))
+			err = fs.cache.writeAt(fs.buf[:2], start+int64(cluster)*2)
+		default:
+			off := start + int64(cluster)*4
+			if err = fs.cache.readAt(fs.buf[:4], off); err != nil {
+				return err
+			}
+			// The upper four bits are reserved and must be preserved.
+			old := binary.LittleEndian.Uint32(fs.buf[:])
+			binary.LittleEndian.PutUint32(fs.buf[:], old&0xF0000000|value&0x0FFFFFFF)
+			err = fs.cache.writeAt(fs.buf[:4], off)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocCluster allocates a free cluster and appends it to the chain ending
+// in prev. If prev is 0 a new chain is started.
+func (fs *FS) allocCluster(prev uint32) (uint32, error) {
+	cluster := fs.nextFree
+	for n := uint32(0); n < fs.clusters; n++ {
+		if cluster < 2 || cluster >= fs.clusters+2 {
+			cluster = 2
+		}
+		v, err := fs.fatEntry(cluster)
+		if err != nil {
+			return 0, err
+		}
+		if v == clusterFree {
+			if err := fs.setFATEntry(cluster, clusterEOC); err != nil {
+				return 0, err
+			}
+			if prev != 0 {
+				if err := fs.setFATEntry(prev, cluster); err != nil {
+					return 0, err
+				}
+			}
+			fs.nextFree = cluster + 1
+			if fs.freeCount != 0xFFFFFFFF && fs.freeCount > 0 {
+				fs.freeCount--
+			}
+			return cluster, nil
+		}
+		cluster++
+	}
+	return 0, ErrNoSpace
+}
+
+// freeChain releases all clusters of the chain starting at cluster.
+func (fs *FS) freeChain(cluster uint32) error {
+	for n := uint32(0); cluster >= 2 && cluster != clusterEOC; n++ {
+		if n > fs.clusters {
+			return ErrCorruptChain
+		}
+		next, err := fs.fatEntry(cluster)
+		if err != nil {
+			return err
+		}
+		if err := fs.setFATEntry(cluster, clusterFree); err != nil {
+			return err
+		}
+		if fs.freeCount != 0xFFFFFFFF {
+			fs.freeCount++
+		}
+		if cluster < fs.nextFree {
+			fs.nextFree = cluster
+		}
+		cluster = next
+	}
+	return nil
+}
+
+// zeroCluster fills the given cluster with zeros.
+func (fs *FS) zeroCluster(cluster uint32) error {
+	var zero [sectorSize]byte
+	off := fs.clusterOffset(cluster)
+	for i := int64(0); i < int64(fs.clusterSize); i += sectorSize {
+		if err := fs.cache.writeAt(zero[:], off+i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -28,6 +28,8 @@ func (fn DeviceIdentifierFunc) Identify(id JedecID) Attrs {
 // ahead of time you are only dealing with a limited set of memory devices, it
 // might be worthwhile to use your own implementation of a DeviceIdentifier
 // that only references those devices, so that more methods are marked unused.
+// Chips that are not recognized get their attributes from their SFDP tables
+// during Configure, see SFDPAttrs.
 var DefaultDeviceIdentifier = DeviceIdentifierFunc(func(id JedecID) Attrs {
 	switch id.Uint32() {
 	case 0x010617:
@@ -15,6 +15,22 @@ const (
 	PageSize = 256
 )
 
+type transport interface {
+	configure(config *DeviceConfig)
+	supportQuadMode() bool
+	setClockSpeed(hz uint32) (err error)
+	setAddressBytes(n int) (err error)
+	runCommand(cmd byte) (err error)
+	readCommand(cmd byte, rsp []byte) (err error)
+	writeCommand(cmd byte, data []byte) (err error)
+	eraseCommand(cmd byte, address uint32) (err error)
+	readMemory(addr uint32, rsp []byte) (err error)
+	writeMemory(addr uint32, data []byte) (err error)
+	readSFDP(addr uint32, rsp []byte) (err error)
+	readData(cmd byte, addr uint32, rsp []byte) (err error)
+	programCommand(cmd byte, addr uint32, data []byte) (err error)
+}
+
 // Device represents a NOR flash memory device accessible using SPI
 type Device struct {
 	trans transport
@@ -63,6 +79,8 @@ type Attrs struct {
 	// highest byte in the status register.
 	QuadEnableBitMask uint8
 
+	// The chip powers up with its sectors write protected. Configure removes
+	// the protection.
 	HasSectorProtection bool
 
 	// Supports the 0x0b fast read command with 8 dummy cycles.
@@ -83,6 +101,12 @@ type Attrs struct {
 	// Enable bit is in the first byte and the Read Status Register 2 command
 	// (0x35) is unsupported.
 	SingleStatusByte bool
+
+	// Number of address bytes, 4 for chips larger than 16MiB. Zero means 3.
+	AddressBytes uint8
+
+	// Erase commands supported by the chip, as found in the SFDP tables.
+	EraseTypes [4]EraseType
 }
 
 // Configure sets up the device and the underlying transport mechanism.  The
@@ -106,6 +130,13 @@ func (dev *Device) Configure(config *DeviceConfig) (err error) {
 		dev.attrs = Attrs{JedecID: id}
 	}
 
+	// fall back to the SFDP tables of the chip if its size is still unknown
+	if dev.attrs.TotalSize == 0 {
+		if attrs, err := SFDPAttrs(id, sfdpReader{dev}); err == nil {
+			dev.attrs = attrs
+		}
+	}
+
 	// We don't know what state the flash is in so wait for any remaining
 	// writes and then reset.
 
@@ -132,6 +163,22 @@ func (dev *Device) Configure(config *DeviceConfig) (err error) {
 	// Wait for the reset - 30us by default
 	time.Sleep(30 * time.Microsecond)
 
+	// Chips larger than 16MiB need 4-byte addresses to reach all of memory
+	if dev.attrs.AddressBytes == 4 {
+		if err := dev.trans.setAddressBytes(4); err != nil {
+			// the transport can only address the lower 16MiB
+			dev.attrs.AddressBytes = 3
+			dev.attrs.TotalSize = 1 << 24
+		} else {
+			if err := dev.WriteEnable(); err != nil {
+				return err
+			}
+			if err := dev.trans.runCommand(cmdEnter4ByteAddress); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Speed up to max device frequency
 	// I propose a check here for max frequency, but not put that functionality directly into the driver.
 	// Either that or we have to change the signature of the SPI interface in the machine package itself.
@@ -285,24 +332,70 @@ func (dev *Device) EraseBlockSize() int64 {
 	return SectorSize
 }
 
-// EraseBlocks erases the given number of blocks. An implementation may
-// transparently coalesce ranges of blocks into larger bundles if the chip
-// supports this. The start and len parameters are in block numbers, use
-// EraseBlockSize to map addresses to blocks.
+// EraseBlocks erases the given number of blocks. The start and len
+// parameters are in block numbers, use EraseBlockSize to map addresses to
+// blocks. Each part of the range is erased with the largest aligned erase
+// command the chip supports, up to a chip erase if the range covers the whole
+// chip. It returns once the erase has finished.
 func (dev *Device) EraseBlocks(start, len int64) error {
-	for i := start; i < start+len; i++ {
-		if err := dev.EraseBlock(uint32(i)); err != nil {
+	addr := start * SectorSize
+	end := (start + len) * SectorSize
+	if start < 0 || len < 0 || (dev.attrs.TotalSize > 0 && end > dev.Size()) {
+		return ErrInvalidAddrRange
+	}
+	if len > 0 && addr == 0 && end == int64(dev.attrs.TotalSize) {
+		if err := dev.EraseAll(); err != nil {
 			return err
 		}
+		return dev.waitUntilReady(chipEraseTimeout)
 	}
-	return nil
+	for addr < end {
+		et := dev.eraseType(addr, end-addr)
+		if err := dev.waitUntilReady(blockEraseTimeout); err != nil {
+			return err
+		}
+		if err := dev.WriteEnable(); err != nil {
+			return err
+		}
+		if err := dev.trans.eraseCommand(et.Opcode, uint32(addr)); err != nil {
+			return err
+		}
+		addr += int64(et.Size)
+	}
+	return dev.waitUntilReady(blockEraseTimeout)
+}
+
+// defaultEraseTypes are the erase commands used when the chip's SFDP tables
+// haven't been read.
+var defaultEraseTypes = [4]EraseType{
+	{Size: SectorSize, Opcode: cmdEraseSector},
+	{Size: 32 * 1024, Opcode: cmdErase32K},
+	{Size: BlockSize, Opcode: cmdEraseBlock},
+}
+
+// eraseType returns the largest erase command that erases memory starting at
+// addr, which is sector aligned, without erasing more than n bytes.
+func (dev *Device) eraseType(addr, n int64) EraseType {
+	types := dev.attrs.EraseTypes
+	if types == [4]EraseType{} {
+		types = defaultEraseTypes
+	}
+	best := EraseType{Size: SectorSize, Opcode: cmdEraseSector}
+	for _, et := range types {
+		size := int64(et.Size)
+		if size > int64(best.Size) && size <= n && addr%size == 0 {
+			best = et
+		}
+	}
+	return best
 }
 
 func (dev *Device) WriteEnable() error {
 	return dev.trans.runCommand(cmdWriteEnable)
 }
 
-// EraseBlock erases a block of memory at the specified index
+// EraseBlock erases the 64KiB block of memory at the specified index. Unlike
+// EraseBlocks, the index is in units of BlockSize.
 func (dev *Device) EraseBlock(blockNumber uint32) error {
 	if err := dev.WaitUntilReady(); err != nil {
 		return err
@@ -335,6 +428,25 @@ func (dev *Device) EraseAll() error {
 	return dev.trans.runCommand(cmdEraseChip)
 }
 
+// PowerDown puts the chip in deep power-down mode, in which it draws very
+// little current and ignores all commands until Resume is called.
+func (dev *Device) PowerDown() error {
+	if err := dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	return dev.trans.runCommand(cmdPowerDown)
+}
+
+// Resume wakes the chip up from deep power-down mode.
+func (dev *Device) Resume() error {
+	if err := dev.trans.runCommand(cmdReleasePowerDown); err != nil {
+		return err
+	}
+	// Wait for the chip to wake up - 30us is enough for all known chips
+	time.Sleep(30 * time.Microsecond)
+	return nil
+}
+
 // ReadStatus reads the value from status register 1 of the device
 func (dev *Device) ReadStatus() (status byte, err error) {
 	buf := make([]byte, 1)
@@ -352,7 +464,11 @@ func (dev *Device) ReadStatus2() (status byte, err error) {
 // WaitUntilReady queries the status register until the device is ready for the
 // next operation.
 func (dev *Device) WaitUntilReady() error {
-	expire := time.Now().UnixNano() + int64(1*time.Second)
+	return dev.waitUntilReady(time.Second)
+}
+
+func (dev *Device) waitUntilReady(timeout time.Duration) error {
+	expire := time.Now().UnixNano() + int64(timeout)
 	for s, err := dev.ReadStatus(); (s & 0x03) > 0; s, err = dev.ReadStatus() {
 		if err != nil {
 			return err
@@ -365,22 +481,36 @@ func (dev *Device) WaitUntilReady() error {
 }
 
 const (
-	cmdRead            = 0x03 // read memory using single-bit transfer
-	cmdQuadRead        = 0x6B // read with 1 line address, 4 line data
-	cmdReadJedecID     = 0x9F // read the JEDEC ID from the device
-	cmdPageProgram     = 0x02 // write a page of memory using single-bit transfer
-	cmdQuadPageProgram = 0x32 // write with 1 line address, 4 line data
-	cmdReadStatus      = 0x05 // read status register 1
-	cmdReadStatus2     = 0x35 // read status register 2
-	cmdWriteStatus     = 0x01 // write status register 1
-	cmdWriteStatus2    = 0x31 // write status register 2
-	cmdEnableReset     = 0x66 // enable reset
-	cmdReset           = 0x99 // perform reset
-	cmdWriteEnable     = 0x06 // write-enable memory
-	cmdWriteDisable    = 0x04 // write-protect memory
-	cmdEraseSector     = 0x20 // erase a sector of memory
-	cmdEraseBlock      = 0xD8 // erase a block of memory
-	cmdEraseChip       = 0xC7 // erase the entire chip
+	cmdRead              = 0x03 // read memory using single-bit transfer
+	cmdQuadRead          = 0x6B // read with 1 line address, 4 line data
+	cmdReadJedecID       = 0x9F // read the JEDEC ID from the device
+	cmdPageProgram       = 0x02 // write a page of memory using single-bit transfer
+	cmdQuadPageProgram   = 0x32 // write with 1 line address, 4 line data
+	cmdReadStatus        = 0x05 // read status register 1
+	cmdReadStatus2       = 0x35 // read status register 2
+	cmdWriteStatus       = 0x01 // write status register 1
+	cmdWriteStatus2      = 0x31 // write status register 2
+	cmdEnableReset       = 0x66 // enable reset
+	cmdReset             = 0x99 // perform reset
+	cmdWriteEnable       = 0x06 // write-enable memory
+	cmdWriteDisable      = 0x04 // write-protect memory
+	cmdEraseSector       = 0x20 // erase a sector of memory
+	cmdErase32K          = 0x52 // erase a 32KiB block of memory
+	cmdEraseBlock        = 0xD8 // erase a block of memory
+	cmdEraseChip         = 0xC7 // erase the entire chip
+	cmdReadSFDP          = 0x5A // read serial flash discoverable parameters
+	cmdEnter4ByteAddress = 0xB7 // switch to 4-byte addresses
+	cmdProgramSecurity   = 0x42 // program a security register
+	cmdEraseSecurity     = 0x44 // erase a security register
+	cmdReadSecurity      = 0x48 // read a security register
+	cmdPowerDown         = 0xB9 // enter deep power-down mode
+	cmdReleasePowerDown  = 0xAB // leave deep power-down mode
+)
+
+const (
+	// Maximum erase times of the slowest known chips
+	blockEraseTimeout = 4 * time.Second
+	chipEraseTimeout  = 400 * time.Second
 )
 
 type Error uint8
@@ -390,6 +520,10 @@ const (
 	ErrInvalidClockSpeed Error = iota
 	ErrInvalidAddrRange
 	ErrWaitExpired
+	ErrNoSFDP
+	ErrInvalidSFDP
+	ErrNotSupported
+	ErrLocked
 )
 
 func (err Error) Error() string {
@@ -400,6 +534,14 @@ func (err Error) Error() string {
 		return "flash: invalid address range"
 	case ErrWaitExpired:
 		return "flash: wait until ready expired"
+	case ErrNoSFDP:
+		return "flash: no SFDP tables"
+	case ErrInvalidSFDP:
+		return "flash: invalid SFDP tables"
+	case ErrNotSupported:
+		return "flash: not supported by the chip"
+	case ErrLocked:
+		return "flash: security register is locked"
 	default:
 		return "flash: unspecified error"
 	}
@@ -0,0 +1,229 @@
+package flash
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
+)
+
+func newTestDevice(c *qt.C, chip *tester.NORFlash) *Device {
+	dev := NewSPI(chip, chip.CS())
+	c.Assert(dev.Configure(&DeviceConfig{Identifier: DefaultDeviceIdentifier}), qt.IsNil)
+	return dev
+}
+
+func TestConfigure(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	chip.JEDECID = [3]byte{0xEF, 0x40, 0x15}
+	dev := newTestDevice(c, chip)
+
+	c.Assert(dev.Attrs(), qt.DeepEquals, W25Q16JVIQ())
+	c.Assert(dev.Size(), qt.Equals, int64(1<<21))
+	c.Assert(chip.Commands[cmdReset], qt.Equals, 1)
+	// The quad enable bit is only set with a quad capable transport.
+	c.Assert(chip.Status[1], qt.Equals, byte(0))
+	// The bus is raised to the maximum speed of the chip, up to 24MHz.
+	c.Assert(chip.BaudRate, qt.Equals, uint32(24e6))
+
+	chip = tester.NewNORFlash(c, 1<<21)
+	chip.JEDECID = [3]byte{0x01, 0x02, 0x03}
+	newTestDevice(c, chip)
+	c.Assert(chip.BaudRate, qt.Equals, uint32(5e6))
+}
+
+func TestConfigureSFDP(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<25)
+	chip.JEDECID = [3]byte{0xC2, 0x20, 0x19}
+	chip.SFDP, _ = ioutil.ReadAll(sfdpDump(c, "MX25L25645G"))
+	dev := newTestDevice(c, chip)
+
+	attrs := dev.Attrs()
+	c.Assert(attrs.TotalSize, qt.Equals, uint32(1<<25))
+	c.Assert(attrs.AddressBytes, qt.Equals, uint8(4))
+	c.Assert(chip.Commands[cmdEnter4ByteAddress], qt.Equals, 1)
+
+	// Memory above 16MiB is reachable with 4-byte addresses.
+	data := []byte("above 16MiB")
+	_, err := dev.WriteAt(data, 1<<24+100)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[1<<24+100:1<<24+100+len(data)], qt.DeepEquals, data)
+	c.Assert(chip.Data[100], qt.Equals, byte(0xFF))
+	buf := make([]byte, len(data))
+	_, err = dev.ReadAt(buf, 1<<24+100)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+
+	// So are the commands with dummy cycles after the address, such as fast
+	// read and security register reads.
+	for i := range buf {
+		buf[i] = 0
+	}
+	c.Assert(dev.trans.readData(0x0B, 1<<24+100, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+	c.Assert(dev.WriteSecurityRegister(1, 20, data), qt.IsNil)
+	c.Assert(dev.ReadSecurityRegister(1, 20, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+}
+
+func TestReadWrite(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	dev := newTestDevice(c, chip)
+
+	// A write across several pages.
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 3)
+	}
+	n, err := dev.WriteAt(data, 200)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, len(data))
+	c.Assert(chip.Data[200:1200], qt.DeepEquals, data)
+	c.Assert(chip.Commands[cmdPageProgram], qt.Equals, 5)
+
+	buf := make([]byte, len(data))
+	_, err = dev.ReadAt(buf, 200)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+
+	// Writing without erasing first only clears bits.
+	_, err = dev.WriteAt([]byte{0xF0}, 201)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[201], qt.Equals, data[1]&0xF0)
+
+	c.Assert(dev.EraseSector(0), qt.IsNil)
+	c.Assert(chip.Data[:SectorSize], qt.DeepEquals, bytes.Repeat([]byte{0xFF}, SectorSize))
+	c.Assert(chip.Erases[0], qt.Equals, 1)
+
+	c.Assert(dev.EraseAll(), qt.IsNil)
+	c.Assert(chip.Erases[1], qt.Equals, 1)
+}
+
+func TestPowerLoss(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	dev := newTestDevice(c, chip)
+
+	chip.WriteLimit = 300
+	_, err := dev.WriteAt(bytes.Repeat([]byte{0}, 1024), 0)
+	c.Assert(err, qt.Equals, tester.ErrPowerLoss)
+	c.Assert(chip.Data[299], qt.Equals, byte(0))
+	c.Assert(chip.Data[300], qt.Equals, byte(0xFF))
+
+	// After a power cycle the chip can be used again.
+	chip.PowerCycle()
+	dev = newTestDevice(c, chip)
+	_, err = dev.WriteAt([]byte{0x12}, 300)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[300], qt.Equals, byte(0x12))
+}
+
+func TestEraseBlocks(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	dev := newTestDevice(c, chip)
+	for i := range chip.Data {
+		chip.Data[i] = 0
+	}
+
+	// Sectors 7 to 56: a sector, a 32KiB block, two 64KiB blocks, a 32KiB
+	// block and a sector.
+	c.Assert(dev.EraseBlocks(7, 50), qt.IsNil)
+	c.Assert(chip.Data[7*SectorSize-1], qt.Equals, byte(0))
+	c.Assert(chip.Data[7*SectorSize:57*SectorSize], qt.DeepEquals, bytes.Repeat([]byte{0xFF}, 50*SectorSize))
+	c.Assert(chip.Data[57*SectorSize], qt.Equals, byte(0))
+	c.Assert(chip.Commands[cmdEraseSector], qt.Equals, 2)
+	c.Assert(chip.Commands[cmdErase32K], qt.Equals, 2)
+	c.Assert(chip.Commands[cmdEraseBlock], qt.Equals, 2)
+	for i, n := range chip.Erases {
+		want := 0
+		if i >= 7 && i < 57 {
+			want = 1
+		}
+		c.Assert(n, qt.Equals, want, qt.Commentf("sector %d", i))
+	}
+
+	// The whole chip.
+	c.Assert(dev.EraseBlocks(0, 1<<21/SectorSize), qt.IsNil)
+	c.Assert(chip.Commands[cmdEraseChip], qt.Equals, 1)
+	c.Assert(chip.Data[0], qt.Equals, byte(0xFF))
+
+	c.Assert(dev.EraseBlocks(1<<21/SectorSize, 1), qt.Equals, ErrInvalidAddrRange)
+}
+
+func TestBlockProtection(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	chip.JEDECID = [3]byte{0xEF, 0x40, 0x15}
+	chip.Status[1] = 0x02 // quad enable, which must be preserved
+	dev := newTestDevice(c, chip)
+
+	c.Assert(dev.SetBlockProtection(0x01), qt.IsNil)
+	bits, err := dev.BlockProtection()
+	c.Assert(err, qt.IsNil)
+	c.Assert(bits, qt.Equals, uint8(0x01))
+	c.Assert(chip.Status[1], qt.Equals, byte(0x02))
+
+	// The upper 1/64 of the chip is protected.
+	top := int64(1<<21 - 1<<15)
+	_, err = dev.WriteAt([]byte{0}, top)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[top], qt.Equals, byte(0xFF))
+	_, err = dev.WriteAt([]byte{0}, top-1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[top-1], qt.Equals, byte(0))
+
+	c.Assert(dev.SetBlockProtection(0), qt.IsNil)
+	_, err = dev.WriteAt([]byte{0}, top)
+	c.Assert(err, qt.IsNil)
+	c.Assert(chip.Data[top], qt.Equals, byte(0))
+}
+
+func TestSecurityRegisters(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	dev := newTestDevice(c, chip)
+
+	data := []byte("calibration")
+	c.Assert(dev.WriteSecurityRegister(2, 10, data), qt.IsNil)
+	buf := make([]byte, len(data))
+	c.Assert(dev.ReadSecurityRegister(2, 10, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+	c.Assert(chip.Security[1][10:10+len(data)], qt.DeepEquals, data)
+
+	c.Assert(dev.EraseSecurityRegister(2), qt.IsNil)
+	c.Assert(dev.ReadSecurityRegister(2, 10, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, bytes.Repeat([]byte{0xFF}, len(data)))
+
+	c.Assert(dev.WriteSecurityRegister(3, 0, data), qt.IsNil)
+	c.Assert(dev.LockSecurityRegister(3), qt.IsNil)
+	locked, err := dev.SecurityRegisterLocked(3)
+	c.Assert(err, qt.IsNil)
+	c.Assert(locked, qt.IsTrue)
+	locked, err = dev.SecurityRegisterLocked(1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(locked, qt.IsFalse)
+	c.Assert(dev.EraseSecurityRegister(3), qt.Equals, ErrLocked)
+
+	c.Assert(dev.ReadSecurityRegister(0, 0, buf), qt.Equals, ErrInvalidAddrRange)
+	c.Assert(dev.ReadSecurityRegister(1, 250, buf), qt.Equals, ErrInvalidAddrRange)
+}
+
+func TestPowerDown(t *testing.T) {
+	c := qt.New(t)
+	chip := tester.NewNORFlash(c, 1<<21)
+	dev := newTestDevice(c, chip)
+
+	c.Assert(dev.PowerDown(), qt.IsNil)
+	c.Assert(chip.PoweredDown(), qt.IsTrue)
+	c.Assert(dev.Resume(), qt.IsNil)
+	c.Assert(chip.PoweredDown(), qt.IsFalse)
+	id, err := dev.ReadJEDEC()
+	c.Assert(err, qt.IsNil)
+	c.Assert(id, qt.Equals, JedecID{0xEF, 0x40, 0x15})
+}
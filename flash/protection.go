@@ -0,0 +1,180 @@
+package flash
+
+// Block protection and security registers. The layout used here is the one of
+// Winbond chips, which is shared by GigaDevice, ISSI and several others: the
+// block protect bits are bits 2 to 6 of status register 1, and the lock bits
+// of the security registers are bits 3 to 5 of status register 2.
+
+const (
+	// SecurityRegisterSize is the size of each security register in bytes.
+	SecurityRegisterSize = 256
+
+	// SecurityRegisters is the number of security registers, numbered from 1.
+	SecurityRegisters = 3
+
+	statusBlockProtect = 0x7C // BP0-BP2, TB and SEC in status register 1
+)
+
+// BlockProtection returns the block protect bits of status register 1,
+// shifted so that BP0 is bit 0. On most chips these are BP0-BP2 followed by
+// TB (protect from the bottom) and SEC (protect sectors instead of blocks);
+// see the datasheet of the chip for the areas they protect.
+func (dev *Device) BlockProtection() (uint8, error) {
+	status, err := dev.ReadStatus()
+	return (status & statusBlockProtect) >> 2, err
+}
+
+// SetBlockProtection sets the block protect bits of status register 1, as
+// returned by BlockProtection. Program and erase commands that touch a
+// protected area are ignored by the chip. Zero removes all protection.
+//
+// Configure removes all protection of chips that have HasSectorProtection
+// set, so it must be set again after each Configure on those chips.
+func (dev *Device) SetBlockProtection(bits uint8) error {
+	status, err := dev.ReadStatus()
+	if err != nil {
+		return err
+	}
+	status = status&^statusBlockProtect | bits<<2&statusBlockProtect
+	return dev.writeStatus(status, 0, false)
+}
+
+// writeStatus writes status register 1 and, if setStatus2 is true, status
+// register 2. Status register 2 is preserved otherwise, because some chips
+// clear it when only one byte is written with the 0x01 command.
+func (dev *Device) writeStatus(status1, status2 byte, setStatus2 bool) (err error) {
+	if setStatus2 && dev.attrs.SingleStatusByte {
+		return ErrNotSupported
+	}
+	if !setStatus2 && !dev.attrs.SingleStatusByte && !dev.attrs.WriteStatusSplit {
+		if status2, err = dev.ReadStatus2(); err != nil {
+			return err
+		}
+		setStatus2 = true
+	}
+	if err = dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	if err = dev.WriteEnable(); err != nil {
+		return err
+	}
+	switch {
+	case setStatus2 && dev.attrs.WriteStatusSplit:
+		err = dev.trans.writeCommand(cmdWriteStatus2, []byte{status2})
+	case setStatus2:
+		err = dev.trans.writeCommand(cmdWriteStatus, []byte{status1, status2})
+	default:
+		err = dev.trans.writeCommand(cmdWriteStatus, []byte{status1})
+	}
+	if err != nil {
+		return err
+	}
+	return dev.WaitUntilReady()
+}
+
+// securityAddress returns the address of offset in security register reg,
+// which is numbered from 1.
+func securityAddress(reg int, offset, n int) (uint32, error) {
+	if reg < 1 || reg > SecurityRegisters || offset < 0 || offset+n > SecurityRegisterSize {
+		return 0, ErrInvalidAddrRange
+	}
+	return uint32(reg)<<12 | uint32(offset), nil
+}
+
+// ReadSecurityRegister reads len(buf) bytes from security register reg,
+// numbered from 1, starting at offset. Security registers are small one time
+// programmable areas separate from the main memory, for example for
+// calibration data or keys.
+func (dev *Device) ReadSecurityRegister(reg int, offset int, buf []byte) error {
+	addr, err := securityAddress(reg, offset, len(buf))
+	if err != nil {
+		return err
+	}
+	if err := dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	return dev.trans.readData(cmdReadSecurity, addr, buf)
+}
+
+// WriteSecurityRegister programs data into security register reg, numbered
+// from 1, starting at offset. Like the main memory, the register must be
+// erased first.
+func (dev *Device) WriteSecurityRegister(reg int, offset int, data []byte) error {
+	addr, err := securityAddress(reg, offset, len(data))
+	if err != nil {
+		return err
+	}
+	if err := dev.checkSecurityLock(reg); err != nil {
+		return err
+	}
+	if err := dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	if err := dev.WriteEnable(); err != nil {
+		return err
+	}
+	return dev.trans.programCommand(cmdProgramSecurity, addr, data)
+}
+
+// EraseSecurityRegister erases security register reg, numbered from 1.
+func (dev *Device) EraseSecurityRegister(reg int) error {
+	addr, err := securityAddress(reg, 0, 0)
+	if err != nil {
+		return err
+	}
+	if err := dev.checkSecurityLock(reg); err != nil {
+		return err
+	}
+	if err := dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	if err := dev.WriteEnable(); err != nil {
+		return err
+	}
+	return dev.trans.eraseCommand(cmdEraseSecurity, addr)
+}
+
+// SecurityRegisterLocked returns whether security register reg, numbered
+// from 1, has been locked with LockSecurityRegister.
+func (dev *Device) SecurityRegisterLocked(reg int) (bool, error) {
+	if _, err := securityAddress(reg, 0, 0); err != nil {
+		return false, err
+	}
+	if dev.attrs.SingleStatusByte {
+		return false, ErrNotSupported
+	}
+	status2, err := dev.ReadStatus2()
+	return status2&(0x04<<uint(reg)) != 0, err
+}
+
+// LockSecurityRegister permanently protects security register reg, numbered
+// from 1, against program and erase commands. This can't be undone.
+func (dev *Device) LockSecurityRegister(reg int) error {
+	if _, err := securityAddress(reg, 0, 0); err != nil {
+		return err
+	}
+	if dev.attrs.SingleStatusByte {
+		return ErrNotSupported
+	}
+	status1, err := dev.ReadStatus()
+	if err != nil {
+		return err
+	}
+	status2, err := dev.ReadStatus2()
+	if err != nil {
+		return err
+	}
+	return dev.writeStatus(status1, status2|0x04<<uint(reg), true)
+}
+
+func (dev *Device) checkSecurityLock(reg int) error {
+	if dev.attrs.SingleStatusByte {
+		// the chip has no lock bits to check
+		return nil
+	}
+	locked, err := dev.SecurityRegisterLocked(reg)
+	if err == nil && locked {
+		err = ErrLocked
+	}
+	return err
+}
@@ -0,0 +1,175 @@
+package flash
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// EraseType describes one of the erase commands supported by a flash chip.
+type EraseType struct {
+	// Size is the number of bytes erased by the command, zero if unused.
+	Size uint32
+
+	// Opcode is the instruction used to erase Size bytes.
+	Opcode byte
+}
+
+const (
+	sfdpSignature  = 0x50444653 // "SFDP", little endian
+	sfdpHeaderSize = 8
+	sfdpMaxHeaders = 16
+	sfdpBFPTDwords = 16 // dwords of the basic flash parameter table in use
+)
+
+// SFDPAttrs derives the attributes of a flash chip from its Serial Flash
+// Discoverable Parameters (JESD216), read through sfdp. It can be used for
+// chips that are not known to DefaultDeviceIdentifier, and is used by
+// Configure when the identifier does not know the size of the chip.
+//
+// The SFDP tables do not describe the maximum clock speed, the start up time
+// or quad page program support; these are left at their zero value.
+func SFDPAttrs(id JedecID, sfdp io.ReaderAt) (Attrs, error) {
+	var hdr [sfdpHeaderSize]byte
+	if _, err := sfdp.ReadAt(hdr[:], 0); err != nil {
+		return Attrs{}, err
+	}
+	if binary.LittleEndian.Uint32(hdr[0:]) != sfdpSignature {
+		return Attrs{}, ErrNoSFDP
+	}
+
+	// Find the basic flash parameter table, which is always the first
+	// parameter header. Later headers may describe a newer revision of it.
+	var bfptAddr, bfptLen uint32
+	nph := int(hdr[6]) + 1
+	if nph > sfdpMaxHeaders {
+		nph = sfdpMaxHeaders
+	}
+	for i := 0; i < nph; i++ {
+		if _, err := sfdp.ReadAt(hdr[:], int64(sfdpHeaderSize*(i+1))); err != nil {
+			return Attrs{}, err
+		}
+		if hdr[0] != 0x00 || hdr[7] != 0xFF || hdr[2] != 0x01 {
+			// not a JEDEC basic flash parameter table of major revision 1
+			continue
+		}
+		if i > 0 && uint32(hdr[3]) < bfptLen {
+			continue
+		}
+		bfptLen = uint32(hdr[3])
+		bfptAddr = uint32(hdr[4]) | uint32(hdr[5])<<8 | uint32(hdr[6])<<16
+	}
+	if bfptLen < 9 {
+		return Attrs{}, ErrInvalidSFDP
+	}
+	if bfptLen > sfdpBFPTDwords {
+		bfptLen = sfdpBFPTDwords
+	}
+
+	var buf [sfdpBFPTDwords * 4]byte
+	if _, err := sfdp.ReadAt(buf[:bfptLen*4], int64(bfptAddr)); err != nil {
+		return Attrs{}, err
+	}
+	var dw [sfdpBFPTDwords + 1]uint32 // 1-based, as in the standard
+	for i := uint32(0); i < bfptLen; i++ {
+		dw[i+1] = binary.LittleEndian.Uint32(buf[i*4:])
+	}
+	return parseBFPT(id, &dw, bfptLen)
+}
+
+// parseBFPT converts a basic flash parameter table to device attributes.
+func parseBFPT(id JedecID, dw *[sfdpBFPTDwords + 1]uint32, n uint32) (Attrs, error) {
+	attrs := Attrs{
+		JedecID:          id,
+		SupportsFastRead: true,
+	}
+
+	// 2nd DWORD: memory density in bits
+	var bits uint64
+	if density := dw[2]; density&0x80000000 == 0 {
+		bits = uint64(density) + 1
+	} else {
+		// The size in bytes must fit in a uint32.
+		shift := density & 0x7FFFFFFF
+		if shift < 3 || shift > 34 {
+			return Attrs{}, ErrInvalidSFDP
+		}
+		bits = 1 << shift
+	}
+	attrs.TotalSize = uint32(bits / 8)
+
+	// 1st DWORD: address bytes and fast read modes
+	switch dw[1] >> 17 & 0x03 {
+	case 0x01, 0x02:
+		if attrs.TotalSize > 1<<24 {
+			attrs.AddressBytes = 4
+		}
+	default:
+		if attrs.TotalSize > 1<<24 {
+			// only the lower 16MiB can be addressed
+			attrs.TotalSize = 1 << 24
+		}
+	}
+	if dw[1]&(1<<22) != 0 {
+		// 3rd DWORD: the 1-1-4 read must match the 0x6B command with 8 dummy
+		// cycles that the quad transport uses.
+		fastRead := dw[3] >> 16
+		dummy := fastRead&0x1F + fastRead>>5&0x07
+		attrs.SupportsQSPI = byte(fastRead>>8) == cmdQuadRead && dummy == 8
+	}
+
+	// 8th and 9th DWORD: erase types
+	for i := range attrs.EraseTypes {
+		et := dw[8+i/2] >> (16 * uint(i%2))
+		if size := et & 0xFF; size > 0 && size < 32 {
+			attrs.EraseTypes[i] = EraseType{Size: 1 << size, Opcode: byte(et >> 8)}
+		}
+	}
+	if attrs.EraseTypes == [4]EraseType{} && dw[1]&0x03 == 0x01 {
+		// No erase types, but the 1st DWORD has a 4KiB erase.
+		attrs.EraseTypes[0] = EraseType{Size: SectorSize, Opcode: byte(dw[1] >> 8)}
+	}
+
+	// 15th DWORD (JESD216A and later): quad enable requirements
+	if n >= 15 && attrs.SupportsQSPI {
+		switch dw[15] >> 20 & 0x07 {
+		case 0x00:
+			// no quad enable bit
+		case 0x01, 0x04, 0x05:
+			// bit 1 of status register 2, written with 0x01
+			attrs.QuadEnableBitMask = 0x02
+		case 0x02:
+			// bit 6 of status register 1
+			attrs.QuadEnableBitMask = 0x40
+			attrs.SingleStatusByte = true
+		case 0x06:
+			// bit 1 of status register 2, written with 0x31
+			attrs.QuadEnableBitMask = 0x02
+			attrs.WriteStatusSplit = true
+		default:
+			// quad enable bit location not supported by this driver
+			attrs.SupportsQSPI = false
+		}
+	}
+	return attrs, nil
+}
+
+// ReadSFDP reads the Serial Flash Discoverable Parameters of the chip
+// starting at the given address, using the 0x5A command.
+func (dev *Device) ReadSFDP(addr uint32, buf []byte) error {
+	if err := dev.WaitUntilReady(); err != nil {
+		return err
+	}
+	return dev.trans.readSFDP(addr, buf)
+}
+
+// sfdpReader reads the SFDP tables of a device as an io.ReaderAt.
+type sfdpReader struct {
+	dev *Device
+}
+
+func (r sfdpReader) ReadAt(buf []byte, off int64) (int, error) {
+	if err := r.dev.ReadSFDP(uint32(off), buf); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
@@ -0,0 +1,134 @@
+package flash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// SFDP tables of a few chips, as listed in their datasheets. Only the
+// header, the parameter headers and the basic flash parameter table are
+// included.
+var sfdpDumps = map[string]string{
+	// Winbond W25Q128JV (JESD216B)
+	"W25Q128JV": `
+		53464450 060101ff 00060110 800000ff 84000102 d00000ff
+		` + strings.Repeat("ff", 0x80-24) + `
+		e520f9ff ffffff07 44eb086b 083b42bb feffffff ffff0000 ffff40eb 0c200f52
+		10d80000 3602a600 82ea14c9 e9637633 7a757a75 f7a2d55c 19f74dff e930f880`,
+
+	// Macronix MX25L25645G, 32MiB (JESD216B)
+	"MX25L25645G": `
+		53464450 060102ff 00060110 300000ff c2000104 100100ff 84000102 c00000ff
+		` + strings.Repeat("ff", 0x30-32) + `
+		e520fbff ffffff0f 44eb086b 083b04bb feffffff ffff00ff ffff44eb 0c200f52
+		10d800ff 234ac900 82d804c2 ecd68433 7a757a75 04c4d55c 00062c00 4200f0ff`,
+
+	// GigaDevice GD25Q16C (JESD216, nine DWORD table)
+	"GD25Q16C": `
+		53464450 000100ff 00000109 300000ff
+		` + strings.Repeat("ff", 0x30-16) + `
+		e520f1ff ffffff00 44eb086b 083b42bb eeffffff ffff00ff ffff00ff 0c200f52
+		10d800ff`,
+}
+
+func sfdpDump(c *qt.C, name string) *bytes.Reader {
+	s := strings.Join(strings.Fields(sfdpDumps[name]), "")
+	b, err := hex.DecodeString(s)
+	c.Assert(err, qt.IsNil)
+	return bytes.NewReader(b)
+}
+
+func TestSFDPAttrs(t *testing.T) {
+	tests := []struct {
+		name string
+		id   JedecID
+		want Attrs
+	}{
+		{"W25Q128JV", JedecID{0xEF, 0x40, 0x18}, Attrs{
+			TotalSize:         1 << 24,
+			JedecID:           JedecID{0xEF, 0x40, 0x18},
+			QuadEnableBitMask: 0x02,
+			SupportsFastRead:  true,
+			SupportsQSPI:      true,
+			EraseTypes: [4]EraseType{
+				{Size: 4 << 10, Opcode: 0x20},
+				{Size: 32 << 10, Opcode: 0x52},
+				{Size: 64 << 10, Opcode: 0xD8},
+			},
+		}},
+		{"MX25L25645G", JedecID{0xC2, 0x20, 0x19}, Attrs{
+			TotalSize:         1 << 25,
+			JedecID:           JedecID{0xC2, 0x20, 0x19},
+			QuadEnableBitMask: 0x40,
+			SupportsFastRead:  true,
+			SupportsQSPI:      true,
+			SingleStatusByte:  true,
+			AddressBytes:      4,
+			EraseTypes: [4]EraseType{
+				{Size: 4 << 10, Opcode: 0x20},
+				{Size: 32 << 10, Opcode: 0x52},
+				{Size: 64 << 10, Opcode: 0xD8},
+			},
+		}},
+		{"GD25Q16C", JedecID{0xC8, 0x40, 0x15}, Attrs{
+			TotalSize:        1 << 21,
+			JedecID:          JedecID{0xC8, 0x40, 0x15},
+			SupportsFastRead: true,
+			SupportsQSPI:     true,
+			EraseTypes: [4]EraseType{
+				{Size: 4 << 10, Opcode: 0x20},
+				{Size: 32 << 10, Opcode: 0x52},
+				{Size: 64 << 10, Opcode: 0xD8},
+			},
+		}},
+	}
+	for _, tt := range tests {
+		c := qt.New(t)
+		attrs, err := SFDPAttrs(tt.id, sfdpDump(c, tt.name))
+		c.Assert(err, qt.IsNil, qt.Commentf(tt.name))
+		c.Assert(attrs, qt.DeepEquals, tt.want, qt.Commentf(tt.name))
+	}
+}
+
+func TestSFDPDensity(t *testing.T) {
+	c := qt.New(t)
+	var dw [sfdpBFPTDwords + 1]uint32
+	dw[2] = 0x80000021 // 2^33 bits
+	attrs, err := parseBFPT(JedecID{}, &dw, 9)
+	c.Assert(err, qt.IsNil)
+	// no 4-byte address support: only the lower 16MiB can be used
+	c.Assert(attrs.TotalSize, qt.Equals, uint32(1<<24))
+
+	dw[1] = 1 << 18 // 4-byte addresses only
+	attrs, err = parseBFPT(JedecID{}, &dw, 9)
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.TotalSize, qt.Equals, uint32(1<<30))
+	c.Assert(attrs.AddressBytes, qt.Equals, uint8(4))
+
+	dw[2] = 0x80000022 // 2^34 bits
+	attrs, err = parseBFPT(JedecID{}, &dw, 9)
+	c.Assert(err, qt.IsNil)
+	c.Assert(attrs.TotalSize, qt.Equals, uint32(1<<31))
+
+	for _, density := range []uint32{0x80000023, 0x80000040} {
+		dw[2] = density
+		_, err = parseBFPT(JedecID{}, &dw, 9)
+		c.Assert(err, qt.Equals, ErrInvalidSFDP, qt.Commentf("density %#x", density))
+	}
+}
+
+func TestSFDPMissing(t *testing.T) {
+	c := qt.New(t)
+	_, err := SFDPAttrs(JedecID{}, bytes.NewReader(bytes.Repeat([]byte{0xFF}, 64)))
+	c.Assert(err, qt.Equals, ErrNoSFDP)
+
+	// header without a basic flash parameter table
+	dump, err := hex.DecodeString("53464450000100ff" + "c2000104100000ff")
+	c.Assert(err, qt.IsNil)
+	_, err = SFDPAttrs(JedecID{}, bytes.NewReader(dump))
+	c.Assert(err, qt.Equals, ErrInvalidSFDP)
+}
@@ -58,6 +58,17 @@ const (
 		(8 << sam.QSPI_INSTRFRAME_DUMMYLEN_Pos) |
 		(sam.QSPI_INSTRFRAME_TFRTYPE_READMEMORY << sam.QSPI_INSTRFRAME_TFRTYPE_Pos)
 
+	// Instruction frame for reading the SFDP tables or other data with an
+	// address and 8 dummy cycles
+	iframeReadData = 0x0 |
+		sam.QSPI_INSTRFRAME_WIDTH_SINGLE_BIT_SPI |
+		sam.QSPI_INSTRFRAME_ADDRLEN_24BITS |
+		sam.QSPI_INSTRFRAME_INSTREN |
+		sam.QSPI_INSTRFRAME_ADDREN |
+		sam.QSPI_INSTRFRAME_DATAEN |
+		(8 << sam.QSPI_INSTRFRAME_DUMMYLEN_Pos) |
+		(sam.QSPI_INSTRFRAME_TFRTYPE_READ << sam.QSPI_INSTRFRAME_TFRTYPE_Pos)
+
 	// Instruction frame for running a command that requires parameter data
 	iframeWriteCommand = 0x0 |
 		sam.QSPI_INSTRFRAME_WIDTH_SINGLE_BIT_SPI |
@@ -74,6 +85,15 @@ const (
 		sam.QSPI_INSTRFRAME_DATAEN |
 		(sam.QSPI_INSTRFRAME_TFRTYPE_WRITEMEMORY << sam.QSPI_INSTRFRAME_TFRTYPE_Pos)
 
+	// Instruction frame for writing data to an address with a single data line
+	iframeProgramCommand = 0x0 |
+		sam.QSPI_INSTRFRAME_WIDTH_SINGLE_BIT_SPI |
+		sam.QSPI_INSTRFRAME_ADDRLEN_24BITS |
+		sam.QSPI_INSTRFRAME_INSTREN |
+		sam.QSPI_INSTRFRAME_ADDREN |
+		sam.QSPI_INSTRFRAME_DATAEN |
+		(sam.QSPI_INSTRFRAME_TFRTYPE_WRITE << sam.QSPI_INSTRFRAME_TFRTYPE_Pos)
+
 	// Instruction frame for running an erase command that requires and address
 	iframeEraseCommand = 0x0 |
 		sam.QSPI_INSTRFRAME_WIDTH_SINGLE_BIT_SPI |
@@ -139,6 +159,14 @@ func (q qspiTransport) setClockSpeed(hz uint32) error {
 	return ErrInvalidClockSpeed
 }
 
+func (q qspiTransport) setAddressBytes(n int) error {
+	// The memory mapped QSPI address space is only 16MiB.
+	if n != 3 {
+		return ErrInvalidAddrRange
+	}
+	return nil
+}
+
 func (q qspiTransport) runCommand(cmd byte) (err error) {
 	q.runInstruction(cmd, iframeRunCommand)
 	q.endTransfer()
@@ -166,6 +194,30 @@ func (q qspiTransport) readMemory(addr uint32, buf []byte) (err error) {
 	return
 }
 
+func (q qspiTransport) readSFDP(addr uint32, buf []byte) (err error) {
+	return q.readData(cmdReadSFDP, addr, buf)
+}
+
+func (q qspiTransport) readData(cmd byte, addr uint32, buf []byte) (err error) {
+	q.disableAndClearCache()
+	sam.QSPI.INSTRADDR.Set(addr)
+	q.runInstruction(cmd, iframeReadData)
+	q.readInto(buf, 0)
+	q.endTransfer()
+	q.enableCache()
+	return
+}
+
+func (q qspiTransport) programCommand(cmd byte, addr uint32, data []byte) (err error) {
+	q.disableAndClearCache()
+	sam.QSPI.INSTRADDR.Set(addr)
+	q.runInstruction(cmd, iframeProgramCommand)
+	q.writeFrom(data, 0)
+	q.endTransfer()
+	q.enableCache()
+	return
+}
+
 func (q qspiTransport) writeCommand(cmd byte, data []byte) (err error) {
 	var dataen uint32
 	if len(data) > 0 {
@@ -1,66 +1,65 @@
 package flash
 
 import (
-	"machine"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
-type transport interface {
-	configure(config *DeviceConfig)
-	supportQuadMode() bool
-	setClockSpeed(hz uint32) (err error)
-	runCommand(cmd byte) (err error)
-	readCommand(cmd byte, rsp []byte) (err error)
-	writeCommand(cmd byte, data []byte) (err error)
-	eraseCommand(cmd byte, address uint32) (err error)
-	readMemory(addr uint32, rsp []byte) (err error)
-	writeMemory(addr uint32, data []byte) (err error)
-}
-
-// NewSPI returns a pointer to a flash device that uses a SPI peripheral to
-// communicate with a serial memory chip.
-func NewSPI(spi *machine.SPI, sdo, sdi, sck, cs machine.Pin) *Device {
+// NewSPI returns a pointer to a flash device that uses a SPI bus to
+// communicate with a serial memory chip. The bus must be configured in SPI
+// mode 0 before calling Configure. The chip select pin is configured as an
+// output if it is a machine.Pin, other pins must already be configured.
+//
+// If the bus has a SetBaudRate method, as machine.SPI on some chips, Configure
+// sets it to 5MHz and then raises it to Attrs().MaxClockSpeedMHz, up to 24MHz,
+// once the chip is known. Otherwise the frequency of the bus is kept.
+func NewSPI(spi drivers.SPI, cs drivers.PinOutput) *Device {
 	return &Device{
 		trans: &spiTransport{
 			spi: spi,
-			sdo: sdo,
-			sdi: sdi,
-			sck: sck,
 			ss:  cs,
+
+			addrBytes: 3,
 		},
 	}
 }
 
 type spiTransport struct {
-	spi *machine.SPI
-	sdo machine.Pin
-	sdi machine.Pin
-	sck machine.Pin
-	ss  machine.Pin
+	spi drivers.SPI
+	ss  drivers.PinOutput
+
+	addrBytes int
+	buf       [6]byte
+}
+
+// baudRateSetter is implemented by buses whose frequency can be changed.
+type baudRateSetter interface {
+	SetBaudRate(br uint32) error
 }
 
 func (tr *spiTransport) configure(config *DeviceConfig) {
-	// Configure spi bus
 	tr.setClockSpeed(5000000)
 
-	// Configure chip select pin
-	tr.ss.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	legacy.ConfigurePinOut(tr.ss)
 	tr.ss.High()
 }
 
 func (tr *spiTransport) setClockSpeed(hz uint32) error {
+	bus, ok := tr.spi.(baudRateSetter)
+	if !ok {
+		// The bus is configured by the caller.
+		return nil
+	}
 	// TODO: un-hardcode this max speed; it is probably a sensible
 	//       default maximum for atsamd and nrf at least
 	if hz > 24*1e6 {
 		hz = 24 * 1e6
 	}
-	tr.spi.Configure(machine.SPIConfig{
-		Frequency: hz,
-		SDI:       tr.sdi,
-		SDO:       tr.sdo,
-		SCK:       tr.sck,
-		LSBFirst:  false,
-		Mode:      0,
-	})
+	return bus.SetBaudRate(hz)
+}
+
+func (tr *spiTransport) setAddressBytes(n int) error {
+	tr.addrBytes = n
 	return nil
 }
 
@@ -77,25 +76,16 @@ func (tr *spiTransport) runCommand(cmd byte) (err error) {
 
 func (tr *spiTransport) readCommand(cmd byte, rsp []byte) (err error) {
 	tr.ss.Low()
-	if _, err := tr.spi.Transfer(byte(cmd)); err == nil {
+	if _, err = tr.spi.Transfer(byte(cmd)); err == nil {
 		err = tr.readInto(rsp)
 	}
 	tr.ss.High()
 	return
 }
 
-func (tr *spiTransport) readCommandByte(cmd byte) (rsp byte, err error) {
-	tr.ss.Low()
-	if _, err := tr.spi.Transfer(byte(cmd)); err == nil {
-		rsp, err = tr.spi.Transfer(0xFF)
-	}
-	tr.ss.High()
-	return
-}
-
 func (tr *spiTransport) writeCommand(cmd byte, data []byte) (err error) {
 	tr.ss.Low()
-	if _, err := tr.spi.Transfer(byte(cmd)); err == nil {
+	if _, err = tr.spi.Transfer(byte(cmd)); err == nil {
 		err = tr.writeFrom(data)
 	}
 	tr.ss.High()
@@ -104,14 +94,14 @@ func (tr *spiTransport) writeCommand(cmd byte, data []byte) (err error) {
 
 func (tr *spiTransport) eraseCommand(cmd byte, address uint32) (err error) {
 	tr.ss.Low()
-	err = tr.sendAddress(cmd, address)
+	err = tr.sendAddress(cmd, address, tr.addrBytes)
 	tr.ss.High()
 	return
 }
 
 func (tr *spiTransport) readMemory(addr uint32, rsp []byte) (err error) {
 	tr.ss.Low()
-	if err = tr.sendAddress(cmdRead, addr); err == nil {
+	if err = tr.sendAddress(cmdRead, addr, tr.addrBytes); err == nil {
 		err = tr.readInto(rsp)
 	}
 	tr.ss.High()
@@ -120,37 +110,59 @@ func (tr *spiTransport) readMemory(addr uint32, rsp []byte) (err error) {
 
 func (tr *spiTransport) writeMemory(addr uint32, data []byte) (err error) {
 	tr.ss.Low()
-	if err = tr.sendAddress(cmdPageProgram, addr); err == nil {
+	if err = tr.sendAddress(cmdPageProgram, addr, tr.addrBytes); err == nil {
 		err = tr.writeFrom(data)
 	}
 	tr.ss.High()
 	return
 }
 
-func (tr *spiTransport) sendAddress(cmd byte, addr uint32) error {
-	_, err := tr.spi.Transfer(byte(cmd))
-	if err == nil {
-		_, err = tr.spi.Transfer(byte((addr >> 16) & 0xFF))
-	}
-	if err == nil {
-		_, err = tr.spi.Transfer(byte((addr >> 8) & 0xFF))
-	}
-	if err == nil {
-		_, err = tr.spi.Transfer(byte(addr & 0xFF))
+func (tr *spiTransport) readData(cmd byte, addr uint32, rsp []byte) (err error) {
+	tr.ss.Low()
+	// the address is followed by 8 dummy cycles
+	if err = tr.sendAddress(cmd, addr, tr.addrBytes); err == nil {
+		if _, err = tr.spi.Transfer(0); err == nil {
+			err = tr.readInto(rsp)
+		}
 	}
-	return err
+	tr.ss.High()
+	return
 }
 
-func (tr *spiTransport) readInto(rsp []byte) (err error) {
-	for i, c := 0, len(rsp); i < c && err == nil; i++ {
-		rsp[i], err = tr.spi.Transfer(0xFF)
+func (tr *spiTransport) programCommand(cmd byte, addr uint32, data []byte) (err error) {
+	tr.ss.Low()
+	if err = tr.sendAddress(cmd, addr, tr.addrBytes); err == nil {
+		err = tr.writeFrom(data)
 	}
+	tr.ss.High()
 	return
 }
 
-func (tr *spiTransport) writeFrom(data []byte) (err error) {
-	for i, c := 0, len(data); i < c && err == nil; i++ {
-		_, err = tr.spi.Transfer(data[i])
+func (tr *spiTransport) readSFDP(addr uint32, rsp []byte) (err error) {
+	tr.ss.Low()
+	// SFDP is always read with a 3-byte address and 8 dummy cycles
+	if err = tr.sendAddress(cmdReadSFDP, addr<<8, 4); err == nil {
+		err = tr.readInto(rsp)
 	}
+	tr.ss.High()
 	return
 }
+
+// sendAddress sends a command followed by the n lowest bytes of addr.
+func (tr *spiTransport) sendAddress(cmd byte, addr uint32, n int) error {
+	buf := tr.buf[:n+1]
+	buf[0] = cmd
+	for i := n; i > 0; i-- {
+		buf[i] = byte(addr)
+		addr >>= 8
+	}
+	return tr.spi.Tx(buf, nil)
+}
+
+func (tr *spiTransport) readInto(rsp []byte) error {
+	return tr.spi.Tx(nil, rsp)
+}
+
+func (tr *spiTransport) writeFrom(data []byte) error {
+	return tr.spi.Tx(data, nil)
+}
@@ -42,6 +42,7 @@ type Device struct {
 
 	cursor     cursor
 	busyStatus []byte
+	control    uint8 // display on/off control instruction
 
 	clearHomeTime time.Duration // time clear/home instructions might take
 	instrExecTime time.Duration // time all other instructions might take
@@ -134,7 +135,8 @@ func (d *Device) Configure(cfg Config) error {
 	d.SendCommand(DISPLAY_OFF)
 	d.SendCommand(DISPLAY_CLEAR)
 	d.SendCommand(ENTRY_MODE | CURSOR_INCREASE | DISPLAY_NO_SHIFT)
-	d.SendCommand(DISPLAY_ON | uint8(cursor) | uint8(cursorBlink))
+	d.control = DISPLAY_ON | uint8(cursor) | uint8(cursorBlink)
+	d.SendCommand(d.control)
 	return nil
 }
 
@@ -185,7 +187,47 @@ func (d *Device) Display() error {
 func (d *Device) SetCursor(x, y uint8) {
 	d.cursor.x = x
 	d.cursor.y = y
-	d.SendCommand(DDRAM_SET | (x + (d.rowOffset[y] * y)))
+	d.SendCommand(DDRAM_SET | (x + d.rowOffset[y]))
+}
+
+// WriteChars writes character codes directly to the display from the cursor
+// position, on the same line, bypassing the buffer.
+func (d *Device) WriteChars(codes []byte) {
+	for _, c := range codes {
+		d.sendData(c)
+		d.cursor.x++
+	}
+}
+
+// Home sets the cursor back to position (0, 0), and undoes display shifts.
+func (d *Device) Home() {
+	d.SendCommand(CURSOR_HOME)
+	d.cursor.x = 0
+	d.cursor.y = 0
+}
+
+// DisplayOn turns on/off the display.
+func (d *Device) DisplayOn(option bool) {
+	d.setControl(DISPLAY_ON, option)
+}
+
+// CursorOn display/hides the cursor.
+func (d *Device) CursorOn(option bool) {
+	d.setControl(CURSOR_ON, option)
+}
+
+// CursorBlink turns on/off the blinking cursor mode.
+func (d *Device) CursorBlink(option bool) {
+	d.setControl(CURSOR_BLINK_ON, option)
+}
+
+func (d *Device) setControl(flag uint8, option bool) {
+	if option {
+		d.control |= flag
+	} else {
+		d.control &^= flag &^ DISPLAY_ON_OFF
+	}
+	d.SendCommand(d.control)
 }
 
 // SetRowOffsets sets initial memory addresses coresponding to the display rows
@@ -195,7 +237,7 @@ func (d *Device) SetCursor(x, y uint8) {
 func (d *Device) setRowOffsets() {
 	switch d.height {
 	case 1:
-		d.rowOffset = []uint8{}
+		d.rowOffset = []uint8{0x0}
 	case 2:
 		d.rowOffset = []uint8{0x0, 0x40, 0x0, 0x40}
 	case 4:
@@ -224,12 +266,22 @@ func (d *Device) sendData(data byte) {
 	}
 }
 
-// CreateCharacter crates characters using data and stores it under cgram Addr in CGRAM
-func (d *Device) CreateCharacter(cgramAddr uint8, data []byte) {
-	d.SendCommand(CGRAM_SET | cgramAddr)
+// CreateCharacter creates a custom character from the rows of its pattern
+// (data), and stores it as character code 0-7.
+//
+// For compatibility, multiples of 8 are taken as CGRAM addresses, as in
+// earlier versions: 0x08 is character 1, 0x10 character 2 and so on.
+func (d *Device) CreateCharacter(code uint8, data []byte) {
+	if code >= 8 && code%8 == 0 {
+		code /= 8
+	}
+	d.SendCommand(CGRAM_SET | (code&0x7)<<3)
 	for _, dd := range data {
 		d.sendData(dd)
 	}
+	if d.cursor.y < d.height {
+		d.SetCursor(d.cursor.x, d.cursor.y)
+	}
 }
 
 // busy returns true when hd447890 is busy
@@ -273,6 +325,8 @@ func (d *Device) Size() (w, h int16) {
 // ClearDisplay clears displayed content and buffer
 func (d *Device) ClearDisplay() {
 	d.SendCommand(DISPLAY_CLEAR)
+	d.cursor.x = 0
+	d.cursor.y = 0
 	d.ClearBuffer()
 }
 
@@ -122,7 +122,7 @@ func (d *Device) Home() {
 // For example, on 16x2 LCDs the range of x (column) is 0~15 and y (row) is 0~1.
 // if y is larger than actual rows, it would be set to 0 (restart from first row).
 func (d *Device) SetCursor(x, y uint8) {
-	rowOffset := []uint8{0x0, 0x40, 0x14, 0x54}
+	rowOffset := []uint8{0x0, 0x40, d.width, 0x40 + d.width}
 	if y > (d.height - 1) {
 		y = 0
 	}
@@ -149,6 +149,15 @@ func (d *Device) Print(data []byte) {
 	}
 }
 
+// WriteChars writes character codes from the current cursor position, on
+// the same line. Unlike Print, it doesn't handle '\n' or long lines.
+func (d *Device) WriteChars(codes []byte) {
+	for _, c := range codes {
+		d.sendData(c)
+		d.cursor.x++
+	}
+}
+
 // CreateCharacter crates custom characters (using data parameter)
 // and stores it under CGRAM address (using cgramAddr, 0x0-0x7).
 func (d *Device) CreateCharacter(cgramAddr uint8, data []byte) {
@@ -160,6 +169,11 @@ func (d *Device) CreateCharacter(cgramAddr uint8, data []byte) {
 	d.SetCursor(d.cursor.x, d.cursor.y)
 }
 
+// Size returns the size of the display, in characters.
+func (d *Device) Size() (w, h int16) {
+	return int16(d.width), int16(d.height)
+}
+
 // DisplayOn turns on/off the display.
 func (d *Device) DisplayOn(option bool) {
 	if option {
@@ -1,58 +1,56 @@
+//go:build tinygo
+// +build tinygo
+
 // Package hub75 implements a driver for the HUB75 LED matrix.
 //
 // Guide: https://cdn-learn.adafruit.com/downloads/pdf/32x16-32x32-rgb-led-matrix.pdf
 // This driver was inspired by https://github.com/2dom/PxMatrix
 //
+// With binary-coded modulation (Config.BCM), Display shows the display one
+// row and one bit plane at a time and must be called at a steady rate, for
+// example from a ticker:
+//
+//	go func() {
+//		for range time.Tick(100 * time.Microsecond) {
+//			display.Display()
+//		}
+//	}()
+//
 package hub75 // import "tinygo.org/x/drivers/hub75"
 
 import (
 	"image/color"
 	"machine"
+	"runtime"
+	"sync/atomic"
 	"time"
 
 	"tinygo.org/x/drivers"
 )
 
-type Config struct {
-	Width      int16
-	Height     int16
-	ColorDepth uint16
-	RowPattern int16
-	Brightness uint8
-	FastUpdate bool
-}
-
 type Device struct {
-	bus               drivers.SPI
-	a                 machine.Pin
-	b                 machine.Pin
-	c                 machine.Pin
-	d                 machine.Pin
-	oe                machine.Pin
-	lat               machine.Pin
-	width             int16
-	height            int16
-	brightness        uint8
-	fastUpdate        bool
-	colorDepth        uint16
-	colorStep         uint16
-	colorHalfStep     uint16
-	colorThirdStep    uint16
-	colorTwoThirdStep uint16
-	rowPattern        int16
-	rowsPerBuffer     int16
-	panelWidth        int16
-	panelWidthBytes   int16
-	pixelCounter      uint32
-	lineCounter       uint32
-	patternColorBytes uint8
-	rowSetsPerBuffer  uint8
-	sendBufferSize    uint16
-	rowOffset         []uint32
-	buffer            [][]uint8 // [ColorDepth][(width * height * 3(rgb)) / 8]uint8
-	displayColor      uint16
+	matrix
+
+	bus          drivers.SPI
+	a            machine.Pin
+	b            machine.Pin
+	c            machine.Pin
+	d            machine.Pin
+	oe           machine.Pin
+	lat          machine.Pin
+	fastUpdate   bool
+	pixelCounter uint32
+	lineCounter  uint32
+	shown        [][]uint8 // same as buffer, unless double buffering
+	displayColor uint16
+	bcmRow       uint16
+	bcmTicks     uint16 // calls to Display left for the current bit plane
+	doubleBuf    bool
+	swapPending  uint32 // set by SwapBuffers, cleared by Display
 }
 
+var _ drivers.Displayer = (*Device)(nil)
+
 // New returns a new HUB75 driver. Pass in a fully configured SPI bus.
 func New(b drivers.SPI, latPin, oePin, aPin, bPin, cPin, dPin machine.Pin) Device {
 	aPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
@@ -75,116 +73,47 @@ func New(b drivers.SPI, latPin, oePin, aPin, bPin, cPin, dPin machine.Pin) Devic
 
 // Configure sets up the device.
 func (d *Device) Configure(cfg Config) {
-	if cfg.Width != 0 {
-		d.width = cfg.Width
-	} else {
-		d.width = 64
-	}
-	if cfg.Height != 0 {
-		d.height = cfg.Height
-	} else {
-		d.height = 32
-	}
-	if cfg.ColorDepth != 0 {
-		d.colorDepth = cfg.ColorDepth
-	} else {
-		d.colorDepth = 8
-	}
-	if cfg.RowPattern != 0 {
-		d.rowPattern = cfg.RowPattern
-	} else {
-		d.rowPattern = 16
-	}
-	if cfg.Brightness != 0 {
-		d.brightness = cfg.Brightness
-	} else {
-		d.brightness = 255
-	}
-
+	d.matrix.configure(cfg)
 	d.fastUpdate = cfg.FastUpdate
-	d.rowsPerBuffer = d.height / 2
-	d.panelWidth = 1
-	d.panelWidthBytes = (d.width / d.panelWidth) / 8
-	d.rowOffset = make([]uint32, d.height)
-	d.patternColorBytes = uint8((d.height / d.rowPattern) * (d.width / 8))
-	d.rowSetsPerBuffer = uint8(d.rowsPerBuffer / d.rowPattern)
-	d.sendBufferSize = uint16(d.patternColorBytes) * 3
-	d.colorStep = 256 / d.colorDepth
-	d.colorHalfStep = d.colorStep / 2
-	d.colorThirdStep = d.colorStep / 3
-	d.colorTwoThirdStep = 2 * d.colorThirdStep
-	d.buffer = make([][]uint8, d.colorDepth)
-	for i := range d.buffer {
-		d.buffer[i] = make([]uint8, (d.width*d.height*3)/8)
+	d.shown = d.buffer
+	d.doubleBuf = cfg.DoubleBuffer
+	if d.doubleBuf {
+		d.shown = d.newBuffer()
 	}
-
-	d.colorHalfStep = d.colorStep / 2
-	d.colorThirdStep = d.colorStep / 3
-	d.colorTwoThirdStep = 2 * d.colorThirdStep
+	d.displayColor = 0
+	d.bcmRow = 0
+	d.bcmTicks = 0
+	d.swapPending = 0
 
 	d.a.Low()
 	d.b.Low()
 	d.c.Low()
 	d.d.Low()
 	d.oe.High()
-
-	var i uint32
-	for i = 0; i < uint32(d.height); i++ {
-		d.rowOffset[i] = (i%uint32(d.rowPattern))*uint32(d.sendBufferSize) + uint32(d.sendBufferSize) - 1
-	}
 }
 
 // SetPixel modifies the internal buffer in a single pixel.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	d.fillMatrixBuffer(x, y, c.R, c.G, c.B)
-}
-
-// fillMatrixBuffer modifies a pixel in the internal buffer given position and RGB values
-func (d *Device) fillMatrixBuffer(x int16, y int16, r uint8, g uint8, b uint8) {
-	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.logicalHeight {
 		return
 	}
-	x = d.width - 1 - x
-
-	var offsetR uint32
-	var offsetG uint32
-	var offsetB uint32
-
-	vertIndexInBuffer := uint8((int32(y) % int32(d.rowsPerBuffer)) / int32(d.rowPattern))
-	whichBuffer := uint8(y / d.rowsPerBuffer)
-	xByte := x / 8
-	whichPanel := uint8(xByte / d.panelWidthBytes)
-	inRowByteOffset := uint8(xByte % d.panelWidthBytes)
-
-	offsetR = d.rowOffset[y] - uint32(inRowByteOffset) - uint32(d.panelWidthBytes)*
-		(uint32(d.rowSetsPerBuffer)*(uint32(d.panelWidth)*uint32(whichBuffer)+uint32(whichPanel))+uint32(vertIndexInBuffer))
-	offsetG = offsetR - uint32(d.patternColorBytes)
-	offsetB = offsetG - uint32(d.patternColorBytes)
-
-	bitSelect := uint8(x % 8)
-
-	for c := uint16(0); c < d.colorDepth; c++ {
-		colorTresh := uint8(c*d.colorStep + d.colorHalfStep)
-		if r > colorTresh {
-			d.buffer[c][offsetR] |= 1 << bitSelect
-		} else {
-			d.buffer[c][offsetR] = d.buffer[c][offsetR] &^ 1 << bitSelect
-		}
-		if g > colorTresh {
-			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] |= 1 << bitSelect
-		} else {
-			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] &^= 1 << bitSelect
-		}
-		if b > colorTresh {
-			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] |= 1 << bitSelect
-		} else {
-			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] &^= 1 << bitSelect
-		}
-	}
+	x, y = d.chainXY(x, y)
+	d.fillMatrixBuffer(x, y, d.lut[c.R], d.lut[c.G], d.lut[c.B])
 }
 
 // Display sends the buffer (if any) to the screen.
+//
+// Without BCM, each call shows one of the ColorDepth bit planes of all rows.
+// With BCM, each call shows one bit plane of one row, or keeps the current
+// one on, see Config.BCM.
 func (d *Device) Display() error {
+	if d.bcm {
+		d.displayBCM()
+		return nil
+	}
+	if d.displayColor == 0 {
+		d.swap()
+	}
 	rp := uint16(d.rowPattern)
 	for i := uint16(0); i < rp; i++ {
 		// FAST UPDATES (only if brightness = 255)
@@ -194,13 +123,13 @@ func (d *Device) Display() error {
 			d.oe.Low()
 			d.lat.Low()
 			time.Sleep(1 * time.Microsecond)
-			d.bus.Tx(d.buffer[d.displayColor][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
+			d.bus.Tx(d.shown[d.displayColor][uint32(i)*d.sendBufferSize:uint32(i+1)*d.sendBufferSize], nil)
 			time.Sleep(10 * time.Microsecond)
 			d.oe.High()
 
 		} else { // NO FAST UPDATES
 			d.setMux(i)
-			d.bus.Tx(d.buffer[d.displayColor][i*d.sendBufferSize:(i+1)*d.sendBufferSize], nil)
+			d.bus.Tx(d.shown[d.displayColor][uint32(i)*d.sendBufferSize:uint32(i+1)*d.sendBufferSize], nil)
 			d.latch((255 * uint16(d.brightness)) / 255)
 		}
 	}
@@ -211,6 +140,60 @@ func (d *Device) Display() error {
 	return nil
 }
 
+// displayBCM shows the next bit plane of the current row, once the current
+// bit plane has been shown for long enough.
+func (d *Device) displayBCM() {
+	if d.bcmTicks > 0 {
+		d.bcmTicks--
+		return
+	}
+	if d.displayColor == 0 && d.bcmRow == 0 {
+		d.swap()
+	}
+	size := d.sendBufferSize
+	row := uint32(d.bcmRow)
+	// shift the data in while the previous bit plane is still shown
+	d.bus.Tx(d.shown[d.displayColor][row*size:(row+1)*size], nil)
+	d.oe.High()
+	d.setMux(d.bcmRow)
+	d.lat.High()
+	d.lat.Low()
+	d.oe.Low()
+	d.bcmTicks = 1<<d.displayColor - 1
+	d.displayColor++
+	if d.displayColor >= d.colorDepth {
+		d.displayColor = 0
+		d.bcmRow++
+		if d.bcmRow >= uint16(d.rowPattern) {
+			d.bcmRow = 0
+		}
+	}
+}
+
+// swap exchanges the shown buffer and the buffer that is drawn to, if
+// SwapBuffers asked for it. It is called at the start of a frame.
+func (d *Device) swap() {
+	if atomic.LoadUint32(&d.swapPending) != 0 {
+		d.buffer, d.shown = d.shown, d.buffer
+		atomic.StoreUint32(&d.swapPending, 0)
+	}
+}
+
+// SwapBuffers shows what was drawn since the previous call, from the start of
+// the next frame, and waits until then. Drawing then continues in the buffer
+// that was shown, so it must be cleared or redrawn entirely. Display must be
+// called concurrently, from a ticker or another goroutine, while it waits.
+// It does nothing without Config.DoubleBuffer.
+func (d *Device) SwapBuffers() {
+	if !d.doubleBuf {
+		return
+	}
+	atomic.StoreUint32(&d.swapPending, 1)
+	for atomic.LoadUint32(&d.swapPending) != 0 {
+		runtime.Gosched()
+	}
+}
+
 func (d *Device) latch(showTime uint16) {
 	d.lat.High()
 	d.lat.Low()
@@ -244,22 +227,25 @@ func (d *Device) setMux(value uint16) {
 
 // FlushDisplay flushes the display
 func (d *Device) FlushDisplay() {
-	var i uint16
+	var i uint32
 	for i = 0; i < d.sendBufferSize; i++ {
 		d.bus.Tx([]byte{0x00}, nil)
 	}
 }
 
-// SetBrightness changes the brightness of the display
+// SetBrightness changes the brightness of the display. With BCM, it only
+// applies to the pixels set afterwards.
 func (d *Device) SetBrightness(brightness uint8) {
 	d.brightness = brightness
+	if d.bcm {
+		d.buildLUT()
+	}
 }
 
 // ClearDisplay erases the internal buffer
 func (d *Device) ClearDisplay() {
-	bufferSize := (d.width * d.height * 3) / 8
-	for c := uint16(0); c < d.colorDepth; c++ {
-		for j := int16(0); j < bufferSize; j++ {
+	for c := range d.buffer {
+		for j := range d.buffer[c] {
 			d.buffer[c][j] = 0
 		}
 	}
@@ -267,5 +253,5 @@ func (d *Device) ClearDisplay() {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (w, h int16) {
-	return d.width, d.height
+	return d.logicalWidth, d.logicalHeight
 }
@@ -0,0 +1,255 @@
+package hub75
+
+import "math"
+
+type Config struct {
+	Width      int16 // Width is the size of the whole display, in pixels
+	Height     int16
+	ColorDepth uint16
+	RowPattern int16
+	Brightness uint8
+	FastUpdate bool
+
+	// PanelWidth and PanelHeight are the size of each of the chained panels
+	// that make up the display. Zero means that the display is a single
+	// panel, or a single row of panels as high as the display.
+	PanelWidth  int16
+	PanelHeight int16
+	Layout      Layout // Layout is the arrangement of the chained panels
+
+	// Gamma is the gamma correction applied to the colors, usually 2.2.
+	// Zero disables gamma correction.
+	Gamma float32
+
+	// BCM enables binary-coded modulation: ColorDepth is then the number of
+	// bits per color channel, at most 8, and each call to Display shows one
+	// bit plane of one row. A bit plane of weight 2^n stays on for 2^n calls,
+	// so a frame takes RowPattern*(2^ColorDepth-1) calls. Brightness is
+	// applied to the colors, through the gamma table.
+	BCM bool
+
+	// DoubleBuffer enables drawing to a buffer while the other one is shown.
+	// Call SwapBuffers to show what was drawn.
+	DoubleBuffer bool
+}
+
+// Layout is the arrangement of chained panels. The first panel of the chain
+// is always at the top left of the display, and the chain goes right along
+// the first row of panels.
+type Layout uint8
+
+const (
+	// Grid is the layout where every row of panels is chained from left to
+	// right, all panels being the same way up.
+	Grid Layout = iota
+
+	// Serpentine is the layout where the rows of panels are alternately
+	// chained from left to right and from right to left, with the panels of
+	// the right to left rows upside down, which keeps cables short.
+	Serpentine
+)
+
+// matrix is the frame buffer of a display and the position of the pixels in
+// it. Unlike the rest of Device, it doesn't depend on the machine package.
+type matrix struct {
+	width             int16
+	height            int16
+	brightness        uint8
+	colorDepth        uint16
+	colorStep         uint16
+	colorHalfStep     uint16
+	colorThirdStep    uint16
+	colorTwoThirdStep uint16
+	rowPattern        int16
+	rowsPerBuffer     int16
+	panelWidth        int16
+	panelWidthBytes   int16
+	patternColorBytes uint16
+	rowSetsPerBuffer  uint8
+	sendBufferSize    uint32 // bytes sent for each row of the pattern
+	rowOffset         []uint32
+	buffer            [][]uint8 // [ColorDepth][(width * height * 3(rgb)) / 8]uint8, drawn to
+
+	// The physical display is a single row of chained panels, width×height
+	// pixels. logicalWidth×logicalHeight is the size of the arranged panels.
+	logicalWidth  int16
+	logicalHeight int16
+	panelCols     int16 // number of columns of panels
+	panelRows     int16 // number of rows of panels
+	layout        Layout
+
+	gamma float32
+	lut   [256]uint8 // gamma and, with BCM, brightness
+	bcm   bool
+}
+
+// configure computes the layout of the buffer and allocates it.
+func (d *matrix) configure(cfg Config) {
+	if cfg.Width != 0 {
+		d.logicalWidth = cfg.Width
+	} else {
+		d.logicalWidth = 64
+	}
+	if cfg.Height != 0 {
+		d.logicalHeight = cfg.Height
+	} else {
+		d.logicalHeight = 32
+	}
+	if cfg.PanelWidth != 0 {
+		d.panelCols = d.logicalWidth / cfg.PanelWidth
+	} else {
+		d.panelCols = 1
+	}
+	if cfg.PanelHeight != 0 {
+		d.panelRows = d.logicalHeight / cfg.PanelHeight
+	} else {
+		d.panelRows = 1
+	}
+	d.layout = cfg.Layout
+	d.width = d.logicalWidth * d.panelRows
+	d.height = d.logicalHeight / d.panelRows
+	if cfg.ColorDepth != 0 {
+		d.colorDepth = cfg.ColorDepth
+	} else {
+		d.colorDepth = 8
+	}
+	d.bcm = cfg.BCM
+	if d.bcm && d.colorDepth > 8 {
+		d.colorDepth = 8
+	}
+	if cfg.RowPattern != 0 {
+		d.rowPattern = cfg.RowPattern
+	} else {
+		d.rowPattern = 16
+	}
+	if cfg.Brightness != 0 {
+		d.brightness = cfg.Brightness
+	} else {
+		d.brightness = 255
+	}
+
+	d.gamma = cfg.Gamma
+	d.buildLUT()
+	d.rowsPerBuffer = d.height / 2
+	d.panelWidth = 1
+	d.panelWidthBytes = (d.width / d.panelWidth) / 8
+	d.rowOffset = make([]uint32, d.height)
+	// a long chain of panels has more than 255 bytes per color and row
+	d.patternColorBytes = uint16(d.height/d.rowPattern) * uint16(d.width/8)
+	d.rowSetsPerBuffer = uint8(d.rowsPerBuffer / d.rowPattern)
+	d.sendBufferSize = uint32(d.patternColorBytes) * 3
+	d.colorStep = 256 / d.colorDepth
+	d.colorHalfStep = d.colorStep / 2
+	d.colorThirdStep = d.colorStep / 3
+	d.colorTwoThirdStep = 2 * d.colorThirdStep
+	d.buffer = d.newBuffer()
+
+	var i uint32
+	for i = 0; i < uint32(d.height); i++ {
+		d.rowOffset[i] = (i%uint32(d.rowPattern))*d.sendBufferSize + d.sendBufferSize - 1
+	}
+}
+
+// newBuffer allocates the bit planes of a buffer.
+func (d *matrix) newBuffer() [][]uint8 {
+	buffer := make([][]uint8, d.colorDepth)
+	for i := range buffer {
+		buffer[i] = make([]uint8, (int32(d.width)*int32(d.height)*3)/8)
+	}
+	return buffer
+}
+
+// buildLUT computes the table of gamma corrected color values.
+func (d *matrix) buildLUT() {
+	for i := range d.lut {
+		v := float64(i) / 255
+		if d.gamma > 0 {
+			v = math.Pow(v, float64(d.gamma))
+		}
+		if d.bcm {
+			v = v * float64(d.brightness) / 255
+		}
+		d.lut[i] = uint8(v*255 + 0.5)
+	}
+}
+
+// chainXY returns the position in the chain of panels of a pixel of the
+// display.
+func (d *matrix) chainXY(x, y int16) (int16, int16) {
+	if d.panelRows == 1 {
+		return x, y
+	}
+	panelWidth := d.logicalWidth / d.panelCols
+	col, row := x/panelWidth, y/d.height
+	x, y = x%panelWidth, y%d.height
+	if d.layout == Serpentine && row%2 == 1 {
+		// right to left, upside down
+		col = d.panelCols - 1 - col
+		x, y = panelWidth-1-x, d.height-1-y
+	}
+	return (row*d.panelCols+col)*panelWidth + x, y
+}
+
+// fillMatrixBuffer modifies a pixel in the internal buffer given position and RGB values
+func (d *matrix) fillMatrixBuffer(x int16, y int16, r uint8, g uint8, b uint8) {
+	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+		return
+	}
+	x = d.width - 1 - x
+
+	var offsetR uint32
+	var offsetG uint32
+	var offsetB uint32
+
+	vertIndexInBuffer := uint8((int32(y) % int32(d.rowsPerBuffer)) / int32(d.rowPattern))
+	whichBuffer := uint8(y / d.rowsPerBuffer)
+	xByte := x / 8
+	whichPanel := uint8(xByte / d.panelWidthBytes)
+	inRowByteOffset := uint32(xByte % d.panelWidthBytes)
+
+	offsetR = d.rowOffset[y] - inRowByteOffset - uint32(d.panelWidthBytes)*
+		(uint32(d.rowSetsPerBuffer)*(uint32(d.panelWidth)*uint32(whichBuffer)+uint32(whichPanel))+uint32(vertIndexInBuffer))
+	offsetG = offsetR - uint32(d.patternColorBytes)
+	offsetB = offsetG - uint32(d.patternColorBytes)
+
+	bitSelect := uint8(x % 8)
+
+	if d.bcm {
+		// plane c holds bit c of the most significant bits of the colors
+		shift := 8 - d.colorDepth
+		for c := uint16(0); c < d.colorDepth; c++ {
+			setBit(d.buffer[c], offsetR, bitSelect, r>>(shift+c)&1 != 0)
+			setBit(d.buffer[c], offsetG, bitSelect, g>>(shift+c)&1 != 0)
+			setBit(d.buffer[c], offsetB, bitSelect, b>>(shift+c)&1 != 0)
+		}
+		return
+	}
+
+	for c := uint16(0); c < d.colorDepth; c++ {
+		colorTresh := uint8(c*d.colorStep + d.colorHalfStep)
+		if r > colorTresh {
+			d.buffer[c][offsetR] |= 1 << bitSelect
+		} else {
+			d.buffer[c][offsetR] &^= 1 << bitSelect
+		}
+		if g > colorTresh {
+			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] |= 1 << bitSelect
+		} else {
+			d.buffer[(c+d.colorThirdStep)%d.colorDepth][offsetG] &^= 1 << bitSelect
+		}
+		if b > colorTresh {
+			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] |= 1 << bitSelect
+		} else {
+			d.buffer[(c+d.colorTwoThirdStep)%d.colorDepth][offsetB] &^= 1 << bitSelect
+		}
+	}
+}
+
+// setBit sets or clears a bit of buf[offset].
+func setBit(buf []uint8, offset uint32, bit uint8, on bool) {
+	if on {
+		buf[offset] |= 1 << bit
+	} else {
+		buf[offset] &^= 1 << bit
+	}
+}
@@ -0,0 +1,128 @@
+package hub75
+
+import (
+	"math/bits"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func newTestMatrix(cfg Config) *matrix {
+	d := &matrix{}
+	d.configure(cfg)
+	return d
+}
+
+// ones returns the number of bits set in buf.
+func ones(buf []uint8) int {
+	n := 0
+	for _, b := range buf {
+		n += bits.OnesCount8(b)
+	}
+	return n
+}
+
+// setBits returns the indexes of the bits set in buf.
+func setBits(buf []uint8) []int {
+	var idx []int
+	for i, b := range buf {
+		for j := 0; j < 8; j++ {
+			if b&(1<<j) != 0 {
+				idx = append(idx, i*8+j)
+			}
+		}
+	}
+	return idx
+}
+
+func TestChainXY(t *testing.T) {
+	c := qt.New(t)
+	// 2x2 panels of 32x16
+	cfg := Config{Width: 64, Height: 32, PanelWidth: 32, PanelHeight: 16, RowPattern: 8}
+	d := newTestMatrix(cfg)
+	c.Assert([]int16{d.width, d.height}, qt.DeepEquals, []int16{128, 16})
+
+	xy := func(x, y int16) []int16 {
+		x, y = d.chainXY(x, y)
+		return []int16{x, y}
+	}
+	c.Assert(xy(0, 0), qt.DeepEquals, []int16{0, 0})
+	c.Assert(xy(40, 5), qt.DeepEquals, []int16{40, 5})
+	c.Assert(xy(10, 20), qt.DeepEquals, []int16{74, 4})
+	c.Assert(xy(63, 31), qt.DeepEquals, []int16{127, 15})
+
+	cfg.Layout = Serpentine
+	d = newTestMatrix(cfg)
+	c.Assert(xy(40, 5), qt.DeepEquals, []int16{40, 5})
+	c.Assert(xy(10, 20), qt.DeepEquals, []int16{117, 11})
+	c.Assert(xy(63, 31), qt.DeepEquals, []int16{64, 0})
+
+	// A single row of panels is a single long panel.
+	d = newTestMatrix(Config{Width: 128, Height: 32, PanelWidth: 64})
+	c.Assert(xy(100, 20), qt.DeepEquals, []int16{100, 20})
+}
+
+func TestBufferLayout(t *testing.T) {
+	c := qt.New(t)
+	for _, cfg := range []Config{
+		{Width: 64, Height: 32, RowPattern: 16},
+		{Width: 64, Height: 32, PanelWidth: 32, PanelHeight: 16, RowPattern: 8, Layout: Serpentine},
+		// 4x2 panels of 64x64 at 1/16 scan: 256 bytes per color and row
+		{Width: 256, Height: 128, PanelWidth: 64, PanelHeight: 64, RowPattern: 16},
+	} {
+		d := newTestMatrix(cfg)
+		c.Assert(int(d.sendBufferSize)*int(d.rowPattern), qt.Equals, len(d.buffer[0]))
+
+		// Each row of the pattern is sent in its own part of the buffer, and
+		// all the pixels of a row have their own bits.
+		for y := int16(0); y < cfg.Height; y++ {
+			for _, plane := range d.buffer {
+				for i := range plane {
+					plane[i] = 0
+				}
+			}
+			_, row := d.chainXY(0, y)
+			for x := int16(0); x < cfg.Width; x++ {
+				x, y := d.chainXY(x, y)
+				d.fillMatrixBuffer(x, y, 255, 255, 255)
+			}
+			size := int(d.sendBufferSize)
+			part := int(row%d.rowPattern) * size
+			c.Assert(ones(d.buffer[0][part:part+size]), qt.Equals, 3*int(cfg.Width), qt.Commentf("%+v y=%d", cfg, y))
+			c.Assert(ones(d.buffer[0]), qt.Equals, 3*int(cfg.Width))
+		}
+	}
+}
+
+func TestBCMPlanes(t *testing.T) {
+	c := qt.New(t)
+	d := newTestMatrix(Config{Width: 32, Height: 16, RowPattern: 8, BCM: true, ColorDepth: 4})
+	c.Assert(d.buffer, qt.HasLen, 4)
+
+	// find the bits of each color of the pixel
+	pos := func(r, g, b uint8) int {
+		d.fillMatrixBuffer(3, 2, r, g, b)
+		for _, plane := range d.buffer {
+			c.Assert(setBits(plane), qt.HasLen, 1)
+		}
+		p := setBits(d.buffer[0])[0]
+		d.fillMatrixBuffer(3, 2, 0, 0, 0)
+		return p
+	}
+	r, g, b := pos(255, 0, 0), pos(0, 255, 0), pos(0, 0, 255)
+
+	// plane n holds bit 4+n of the colors
+	d.fillMatrixBuffer(3, 2, 0xA0, 0x50, 0xF0)
+	c.Assert(setBits(d.buffer[0]), qt.ContentEquals, []int{g, b})
+	c.Assert(setBits(d.buffer[1]), qt.ContentEquals, []int{r, b})
+	c.Assert(setBits(d.buffer[2]), qt.ContentEquals, []int{g, b})
+	c.Assert(setBits(d.buffer[3]), qt.ContentEquals, []int{r, b})
+
+	// The brightness is applied through the lookup table.
+	d = newTestMatrix(Config{BCM: true, Brightness: 128})
+	c.Assert(d.lut[255], qt.Equals, uint8(128))
+	c.Assert(d.lut[0], qt.Equals, uint8(0))
+	d = newTestMatrix(Config{Brightness: 128, Gamma: 2})
+	c.Assert(d.lut[255], qt.Equals, uint8(255))
+	c.Assert(d.lut[128], qt.Equals, uint8(64))
+}
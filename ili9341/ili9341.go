@@ -3,8 +3,10 @@ package ili9341
 import (
 	"errors"
 	"image/color"
-	"machine"
 	"time"
+
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 type Config struct {
@@ -19,14 +21,25 @@ type Device struct {
 	height   int16
 	rotation Rotation
 	driver   driver
+	drawing  bool // a transfer started by StartDrawRGBBitmap8 is pending
 
 	x0, x1 int16 // cached address window; prevents useless/expensive
 	y0, y1 int16 // syscalls to PASET and CASET
 
-	dc  machine.Pin
-	cs  machine.Pin
-	rst machine.Pin
-	rd  machine.Pin
+	// optional pins are nil
+	dc  drivers.PinOutput
+	cs  drivers.PinOutput
+	rst drivers.PinOutput
+	rd  drivers.PinOutput
+}
+
+// optionalPin returns nil if p is missing, which may also be passed as
+// machine.NoPin.
+func optionalPin(p drivers.PinOutput) drivers.PinOutput {
+	if legacy.IsNoPin(p) {
+		return nil
+	}
+	return p
 }
 
 var cmdBuf [6]byte
@@ -73,29 +86,27 @@ func (d *Device) Configure(config Config) {
 	d.x0, d.x1 = -(d.width + 1), d.x0
 	d.y0, d.y1 = -(d.height + 1), d.y0
 
-	output := machine.PinConfig{machine.PinOutput}
-
 	// configure chip select if there is one
-	if d.cs != machine.NoPin {
-		d.cs.Configure(output)
+	if d.cs != nil {
+		legacy.ConfigurePinOut(d.cs)
 		d.cs.High() // deselect
 	}
 
-	d.dc.Configure(output)
+	legacy.ConfigurePinOut(d.dc)
 	d.dc.High() // data mode
 
 	// driver-specific configuration
 	d.driver.configure(&config)
 
-	if d.rd != machine.NoPin {
-		d.rd.Configure(output)
+	if d.rd != nil {
+		legacy.ConfigurePinOut(d.rd)
 		d.rd.High()
 	}
 
 	// reset the display
-	if d.rst != machine.NoPin {
+	if d.rst != nil {
 		// configure hardware reset if there is one
-		d.rst.Configure(output)
+		legacy.ConfigurePinOut(d.rst)
 		d.rst.High()
 		delay(100)
 		d.rst.Low()
@@ -136,7 +147,7 @@ func (d *Device) Configure(config Config) {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (x, y int16) {
-	if d.rotation == 1 || d.rotation == 3 {
+	if d.rotation%2 == 1 { // 90 or 270 degrees, mirrored or not
 		return d.height, d.width
 	}
 	return d.width, d.height
@@ -163,9 +174,12 @@ func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
 		x >= k || (x+w) > k || y >= i || (y+h) > i {
 		return errors.New("rectangle coordinates outside display area")
 	}
+	if int32(len(data)) < int32(w)*int32(h) {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
 	d.setWindow(x, y, w, h)
 	d.startWrite()
-	d.driver.write16sl(data)
+	d.driver.write16sl(data[:int32(w)*int32(h)])
 	d.endWrite()
 	return nil
 }
@@ -184,6 +198,47 @@ func (d *Device) DrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
 	return nil
 }
 
+// StartDrawRGBBitmap8 starts copying an RGB bitmap to the display at the given
+// coordinates. If the bus implements drivers.AsyncSPI, it returns as soon as
+// the transfer has started, so that the next bitmap can be prepared in the
+// meantime; data must not be modified until WaitDraw or the next call to
+// StartDrawRGBBitmap8 returns. Otherwise the bitmap is sent before it
+// returns.
+func (d *Device) StartDrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	n := int32(w) * int32(h) * 2
+	if int32(len(data)) < n {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+	if err := d.WaitDraw(); err != nil {
+		return err
+	}
+	d.setWindow(x, y, w, h)
+	d.startWrite()
+	if err := d.driver.start8sl(data[:n]); err != nil {
+		d.endWrite()
+		return err
+	}
+	d.drawing = true
+	return nil
+}
+
+// WaitDraw waits until the bitmap passed to StartDrawRGBBitmap8 has been sent
+// to the display.
+func (d *Device) WaitDraw() error {
+	if !d.drawing {
+		return nil
+	}
+	d.drawing = false
+	err := d.driver.wait()
+	d.endWrite()
+	return err
+}
+
 // FillRectangle fills a rectangle at given coordinates with a color
 func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 	k, i := d.Size()
@@ -234,22 +289,29 @@ func (d *Device) DrawFastHLine(x0, x1, y int16, c color.RGBA) error {
 
 // FillScreen fills the screen with a given color
 func (d *Device) FillScreen(c color.RGBA) {
-	if d.rotation == Rotation0 || d.rotation == Rotation180 {
-		d.FillRectangle(0, 0, d.width, d.height, c)
-	} else {
-		d.FillRectangle(0, 0, d.height, d.width, c)
-	}
+	w, h := d.Size()
+	d.FillRectangle(0, 0, w, h, c)
+}
+
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
 }
 
-// GetRotation returns the current rotation of the device
+// GetRotation returns the current rotation of the device.
+//
+// Deprecated: use Rotation.
 func (d *Device) GetRotation() Rotation {
 	return d.rotation
 }
 
 // SetRotation changes the rotation of the device (clock-wise)
-func (d *Device) SetRotation(rotation Rotation) {
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > Rotation270Mirror {
+		return drivers.ErrInvalidRotation
+	}
 	madctl := uint8(0)
-	switch rotation % 8 {
+	switch rotation {
 	case Rotation0:
 		madctl = MADCTL_MX | MADCTL_BGR
 	case Rotation90:
@@ -270,6 +332,7 @@ func (d *Device) SetRotation(rotation Rotation) {
 	cmdBuf[0] = madctl
 	d.sendCommand(MADCTL, cmdBuf[:1])
 	d.rotation = rotation
+	return nil
 }
 
 // SetScrollArea sets an area to scroll with fixed top/bottom or left/right parts of the display
@@ -323,19 +386,20 @@ func (d *Device) setWindow(x, y, w, h int16) {
 
 //go:inline
 func (d *Device) startWrite() {
-	if d.cs != machine.NoPin {
+	if d.cs != nil {
 		d.cs.Low()
 	}
 }
 
 //go:inline
 func (d *Device) endWrite() {
-	if d.cs != machine.NoPin {
+	if d.cs != nil {
 		d.cs.High()
 	}
 }
 
 func (d *Device) sendCommand(cmd byte, data []byte) {
+	d.WaitDraw()
 	d.startWrite()
 	d.dc.Low()
 	d.driver.write8(cmd)
@@ -354,6 +418,11 @@ type driver interface {
 	write16(data uint16)
 	write16n(data uint16, n int)
 	write16sl(data []uint16)
+
+	// start8sl starts sending b, in the background if possible, and wait
+	// waits until it has been sent.
+	start8sl(b []byte) error
+	wait() error
 }
 
 func delay(m int) {
@@ -0,0 +1,44 @@
+package ili9341
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+var _ interface {
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+	drivers.Scroller
+	drivers.AsyncBitmapDrawer
+} = (*Device)(nil)
+
+func TestStartDrawRGBBitmap8(t *testing.T) {
+	c := qt.New(t)
+	dc, cs := tester.NewPin(c), tester.NewPin(c)
+	cs.Level = true
+	bus := tester.NewAsyncSPIBus(dc)
+	d := NewSPI(bus, dc, cs, nil)
+	d.width, d.height = 240, 320
+
+	strip := []byte{1, 2, 3, 4}
+	c.Assert(d.StartDrawRGBBitmap8(0, 1, strip, 2, 1), qt.IsNil)
+	c.Assert(bus.Pending, qt.DeepEquals, strip)
+	c.Assert(cs.Level, qt.IsFalse)
+
+	// The next command waits for the transfer.
+	d.StopScroll()
+	c.Assert(bus.Pending, qt.IsNil)
+	c.Assert(cs.Level, qt.IsTrue)
+	c.Assert(bus.Data, qt.DeepEquals, []byte{
+		0, 0, 0, 1, // columns
+		0, 1, 0, 1, // rows
+		1, 2, 3, 4,
+	})
+
+	c.Assert(d.StartDrawRGBBitmap8(239, 0, strip, 2, 1), qt.Not(qt.IsNil))
+	c.Assert(d.WaitDraw(), qt.IsNil)
+}
@@ -7,6 +7,8 @@ import (
 	"machine"
 	"runtime/volatile"
 	"unsafe"
+
+	"tinygo.org/x/drivers"
 )
 
 type parallelDriver struct {
@@ -25,12 +27,12 @@ type parallelDriver struct {
 	wrMaskClr uint32
 }
 
-func NewParallel(d0, wr, dc, cs, rst, rd machine.Pin) *Device {
+func NewParallel(d0, wr machine.Pin, dc, cs, rst, rd drivers.PinOutput) *Device {
 	return &Device{
 		dc:  dc,
-		cs:  cs,
-		rd:  rd,
-		rst: rst,
+		cs:  optionalPin(cs),
+		rd:  optionalPin(rd),
+		rst: optionalPin(rst),
 		driver: &parallelDriver{
 			d0: d0,
 			wr: wr,
@@ -108,3 +110,14 @@ func (pd *parallelDriver) write16sl(data []uint16) {
 		pd.write8(byte(data[i]))
 	}
 }
+
+// start8sl writes b synchronously, as this driver has no asynchronous
+// transfers.
+func (pd *parallelDriver) start8sl(b []byte) error {
+	pd.write8sl(b)
+	return nil
+}
+
+func (pd *parallelDriver) wait() error {
+	return nil
+}
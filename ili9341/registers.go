@@ -1,6 +1,9 @@
 package ili9341
 
-type Rotation uint8
+import "tinygo.org/x/drivers"
+
+// Rotation is the rotation of the display, see drivers.Rotation.
+type Rotation = drivers.Rotation
 
 const (
 
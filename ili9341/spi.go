@@ -4,26 +4,30 @@
 package ili9341
 
 import (
-	"machine"
-
 	"tinygo.org/x/drivers"
 )
 
 var buf [64]byte
 
 type spiDriver struct {
-	bus drivers.SPI
+	bus   drivers.SPI
+	async drivers.AsyncSPI // nil if the bus doesn't support it
 }
 
-func NewSPI(bus drivers.SPI, dc, cs, rst machine.Pin) *Device {
+func NewSPI(bus drivers.SPI, dc, cs, rst drivers.PinOutput) *Device {
 	return &Device{
-		dc:  dc,
-		cs:  cs,
-		rst: rst,
-		rd:  machine.NoPin,
-		driver: &spiDriver{
-			bus: bus,
-		},
+		dc:     dc,
+		cs:     optionalPin(cs),
+		rst:    optionalPin(rst),
+		driver: newSPIDriver(bus),
+	}
+}
+
+func newSPIDriver(bus drivers.SPI) *spiDriver {
+	async, _ := bus.(drivers.AsyncSPI)
+	return &spiDriver{
+		bus:   bus,
+		async: async,
 	}
 }
 
@@ -72,3 +76,19 @@ func (pd *spiDriver) write16sl(data []uint16) {
 		pd.bus.Tx(buf[:2], nil)
 	}
 }
+
+// start8sl starts sending b in the background if the bus supports it, or
+// sends it synchronously otherwise.
+func (pd *spiDriver) start8sl(b []byte) error {
+	if pd.async == nil {
+		return pd.bus.Tx(b, nil)
+	}
+	return pd.async.StartTx(b)
+}
+
+func (pd *spiDriver) wait() error {
+	if pd.async == nil {
+		return nil
+	}
+	return pd.async.Wait()
+}
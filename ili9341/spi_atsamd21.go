@@ -6,18 +6,19 @@ package ili9341
 import (
 	"device/sam"
 	"machine"
+
+	"tinygo.org/x/drivers"
 )
 
 type spiDriver struct {
 	bus machine.SPI
 }
 
-func NewSPI(bus machine.SPI, dc, cs, rst machine.Pin) *Device {
+func NewSPI(bus machine.SPI, dc, cs, rst drivers.PinOutput) *Device {
 	return &Device{
 		dc:  dc,
-		cs:  cs,
-		rst: rst,
-		rd:  machine.NoPin,
+		cs:  optionalPin(cs),
+		rst: optionalPin(rst),
 		driver: &spiDriver{
 			bus: bus,
 		},
@@ -115,3 +116,14 @@ func (pd *spiDriver) write16sl(data []uint16) {
 	for pd.bus.Bus.SYNCBUSY.HasBits(sam.SERCOM_SPI_SYNCBUSY_CTRLB) {
 	}
 }
+
+// start8sl writes b synchronously, as this driver has no asynchronous
+// transfers.
+func (pd *spiDriver) start8sl(b []byte) error {
+	pd.write8sl(b)
+	return nil
+}
+
+func (pd *spiDriver) wait() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package bmp
+
+import (
+	"image"
+	"io"
+)
+
+// A portion of the image data consisting of data, x, y, w, and h is passed to
+// Callback. The size of the whole image is passed as width and height.
+type Callback func(data []uint16, x, y, w, h, width, height int16)
+
+// Decoder decodes BMP images and passes the pixels to its callback. Unlike
+// the package-level functions, each Decoder has its own buffer and callback,
+// so that several images can be decoded at the same time, for example to
+// different displays.
+type Decoder struct {
+	buf      []uint16
+	callback Callback
+}
+
+// NewDecoder returns a decoder that passes the pixels to fn, in buf. The
+// buffer must hold at least 1 pixel, and is best at least as long as a row of the image.
+func NewDecoder(buf []uint16, fn Callback) *Decoder {
+	return &Decoder{buf: buf, callback: fn}
+}
+
+// DecodeConfig returns the color model and dimensions of a BMP image without
+// decoding the entire image.
+func (dec *Decoder) DecodeConfig(r io.Reader) (image.Config, error) {
+	return DecodeConfig(r)
+}
+
+// defaultDecoder is used by the package-level functions.
+var defaultDecoder = Decoder{
+	callback: func(data []uint16, x, y, w, h, width, height int16) {},
+}
+
+// SetCallback registers the buffer and fn required for Callback. Callback can
+// be called multiple times by calling Decode().
+//
+// The buffer and callback are shared by the package-level functions: use a
+// Decoder to decode several images at the same time.
+func SetCallback(buf []uint16, fn Callback) {
+	defaultDecoder = Decoder{buf: buf, callback: fn}
+}
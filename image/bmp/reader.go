@@ -0,0 +1,484 @@
+// Package bmp implements a BMP image decoder.
+//
+// Like the png and jpeg packages, it doesn't return an image.Image: the image
+// is drawn row by row through the callback set by SetCallback, in RGB565, so
+// that decoding only needs memory for a row of pixels.
+//
+// It supports 1, 4 and 8-bit images with a palette, compressed with RLE or
+// not, and 16, 24 and 32-bit images, with bit fields or not. The alpha
+// channel, if any, is ignored.
+//
+// The BMP specification is at
+// https://learn.microsoft.com/en-us/windows/win32/gdi/bitmap-storage.
+package bmp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// A FormatError reports that the input is not a valid BMP.
+type FormatError string
+
+func (e FormatError) Error() string { return "bmp: invalid format: " + string(e) }
+
+// An UnsupportedError reports that the input uses a valid but unimplemented BMP feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string { return "bmp: unsupported feature: " + string(e) }
+
+var errNoBuffer = errors.New("bmp: no callback buffer")
+
+// Compression methods.
+const (
+	biRGB            = 0
+	biRLE8           = 1
+	biRLE4           = 2
+	biBitFields      = 3
+	biAlphaBitFields = 6
+)
+
+const (
+	fileHeaderLen = 14
+	coreHeaderLen = 12 // OS/2 BITMAPCOREHEADER
+	infoHeaderLen = 40 // BITMAPINFOHEADER
+)
+
+type decoder struct {
+	out *Decoder
+	r   *bufio.Reader
+	pos int // bytes read so far
+	tmp [124]byte
+
+	width, height int
+	topDown       bool
+	bpp           int
+	compression   int
+	offset        int // of the pixel data
+
+	palette  [256]uint16
+	colors   int
+	entryLen int // of the color table
+	masks    [3]mask
+
+	row []byte
+}
+
+// mask is a bit field of a color channel.
+type mask struct {
+	shift, bits uint
+}
+
+func newMask(m uint32) mask {
+	if m == 0 {
+		return mask{}
+	}
+	var k mask
+	for m&1 == 0 {
+		m >>= 1
+		k.shift++
+	}
+	for m&1 != 0 {
+		m >>= 1
+		k.bits++
+	}
+	return k
+}
+
+// value returns the channel of pixel p, scaled to 8 bits.
+func (k mask) value(p uint32) uint8 {
+	if k.bits == 0 {
+		return 0
+	}
+	v := p >> k.shift & (1<<k.bits - 1)
+	if k.bits >= 8 {
+		return uint8(v >> (k.bits - 8))
+	}
+	return uint8(v * 0xFF / (1<<k.bits - 1))
+}
+
+// Decode reads a BMP image from r. Different from the standard package, the
+// decoded result will be received by the callback set by SetCallback().
+func Decode(r io.Reader) (image.Image, error) {
+	return nil, defaultDecoder.Decode(r)
+}
+
+// Decode reads a BMP image from r, and passes its pixels to the callback of
+// the decoder.
+func (dec *Decoder) Decode(r io.Reader) error {
+	if len(dec.buf) == 0 {
+		return errNoBuffer
+	}
+	d := newDecoder(r)
+	d.out = dec
+	if err := d.readHeader(); err != nil {
+		return err
+	}
+	err := d.readColorTable(func(i int, b, g, r uint8) {
+		d.palette[i] = rgb565(r, g, b)
+	})
+	if err != nil {
+		return err
+	}
+	if err := d.skip(d.offset - d.pos); err != nil {
+		return err
+	}
+	switch d.compression {
+	case biRLE8, biRLE4:
+		err = d.decodeRLE()
+	default:
+		err = d.decodeRows()
+	}
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	return nil
+}
+
+// DecodeConfig returns the color model and dimensions of a BMP image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := newDecoder(r)
+	if err := d.readHeader(); err != nil {
+		return image.Config{}, err
+	}
+	var cm color.Model = color.RGBAModel
+	if d.colors > 0 {
+		p := make(color.Palette, d.colors)
+		err := d.readColorTable(func(i int, b, g, r uint8) {
+			p[i] = color.RGBA{r, g, b, 0xFF}
+		})
+		if err != nil {
+			return image.Config{}, err
+		}
+		cm = p
+	}
+	return image.Config{
+		ColorModel: cm,
+		Width:      d.width,
+		Height:     d.height,
+	}, nil
+}
+
+func newDecoder(r io.Reader) *decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReaderSize(r, 256)
+	}
+	return &decoder{r: br}
+}
+
+func (d *decoder) readFull(p []byte) error {
+	n, err := io.ReadFull(d.r, p)
+	d.pos += n
+	return unexpectedEOF(err)
+}
+
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == nil {
+		d.pos++
+	}
+	return b, err
+}
+
+func (d *decoder) skip(n int) error {
+	if n < 0 {
+		return FormatError("pixel data offset")
+	}
+	m, err := d.r.Discard(n)
+	d.pos += m
+	return unexpectedEOF(err)
+}
+
+func (d *decoder) readHeader() error {
+	b := d.tmp[:]
+	if err := d.readFull(b[:fileHeaderLen+4]); err != nil {
+		return err
+	}
+	if string(b[:2]) != "BM" {
+		return FormatError("not a BMP file")
+	}
+	d.offset = int(le32(b[10:]))
+	size := int(le32(b[14:]))
+	if size < coreHeaderLen || size > len(d.tmp) {
+		return UnsupportedError(fmt.Sprintf("header size %d", size))
+	}
+	if err := d.readFull(b[4:size]); err != nil {
+		return err
+	}
+	d.entryLen = 4
+	if size == coreHeaderLen {
+		d.width = int(le16(b[4:]))
+		d.height = int(le16(b[6:]))
+		d.bpp = int(le16(b[10:]))
+		d.entryLen = 3
+	} else {
+		d.width = int(int32(le32(b[4:])))
+		d.height = int(int32(le32(b[8:])))
+		d.bpp = int(le16(b[14:]))
+		d.compression = int(le32(b[16:]))
+		d.colors = int(le32(b[32:]))
+	}
+	if d.height < 0 {
+		d.height = -d.height
+		d.topDown = true
+	}
+	if d.width <= 0 || d.height == 0 {
+		return FormatError("non-positive dimension")
+	}
+	if d.width > 0x7FFF || d.height > 0x7FFF {
+		return UnsupportedError("dimension overflow")
+	}
+
+	switch {
+	case d.bpp == 1 || d.bpp == 4 || d.bpp == 8:
+		if !(d.compression == biRGB ||
+			d.compression == biRLE8 && d.bpp == 8 ||
+			d.compression == biRLE4 && d.bpp == 4) {
+			return UnsupportedError(fmt.Sprintf("compression %d with %d bits per pixel", d.compression, d.bpp))
+		}
+		if d.colors == 0 || d.colors > 1<<uint(d.bpp) {
+			d.colors = 1 << uint(d.bpp)
+		}
+	case d.bpp == 16 || d.bpp == 24 || d.bpp == 32:
+		d.colors = 0
+		switch d.compression {
+		case biRGB:
+			if d.bpp == 16 {
+				d.setMasks(0x7C00, 0x03E0, 0x001F)
+			} else {
+				d.setMasks(0xFF0000, 0x00FF00, 0x0000FF)
+			}
+		case biBitFields, biAlphaBitFields:
+			if d.bpp == 24 {
+				return UnsupportedError("bit fields with 24 bits per pixel")
+			}
+			if size == infoHeaderLen {
+				// the masks follow the header
+				n := 12
+				if d.compression == biAlphaBitFields {
+					n = 16
+				}
+				if err := d.readFull(b[size : size+n]); err != nil {
+					return err
+				}
+			} else if size < infoHeaderLen+12 {
+				return FormatError("missing bit fields")
+			}
+			d.setMasks(le32(b[40:]), le32(b[44:]), le32(b[48:]))
+		default:
+			return UnsupportedError(fmt.Sprintf("compression %d with %d bits per pixel", d.compression, d.bpp))
+		}
+	default:
+		return UnsupportedError(fmt.Sprintf("%d bits per pixel", d.bpp))
+	}
+
+	return nil
+}
+
+// readColorTable reads the color table, calling fn with the blue, green and
+// red levels of each color.
+func (d *decoder) readColorTable(fn func(i int, b, g, r uint8)) error {
+	for i := 0; i < d.colors; i++ {
+		if err := d.readFull(d.tmp[:d.entryLen]); err != nil {
+			return err
+		}
+		fn(i, d.tmp[0], d.tmp[1], d.tmp[2])
+	}
+	return nil
+}
+
+func (d *decoder) setMasks(r, g, b uint32) {
+	d.masks = [3]mask{newMask(r), newMask(g), newMask(b)}
+}
+
+// decodeRows decodes uncompressed pixel data.
+func (d *decoder) decodeRows() error {
+	stride := (d.width*d.bpp + 31) / 32 * 4
+	if cap(d.row) < stride {
+		d.row = make([]byte, stride)
+	}
+	row := d.row[:stride]
+	for i := 0; i < d.height; i++ {
+		if err := d.readFull(row); err != nil {
+			return err
+		}
+		d.drawRow(d.rowY(i), row)
+	}
+	return nil
+}
+
+// rowY returns the position in the image of row i of the file.
+func (d *decoder) rowY(i int) int {
+	if d.topDown {
+		return i
+	}
+	return d.height - 1 - i
+}
+
+// drawRow converts a row of the file and draws it at line y.
+func (d *decoder) drawRow(y int, row []byte) {
+	buf := d.out.buf
+	for x := 0; x < d.width; x += len(buf) {
+		n := len(buf)
+		if x+n > d.width {
+			n = d.width - x
+		}
+		for i := 0; i < n; i++ {
+			buf[i] = d.pixel(row, x+i)
+		}
+		d.out.callback(buf[:n], int16(x), int16(y), int16(n), 1, int16(d.width), int16(d.height))
+	}
+}
+
+// pixel returns the color of pixel x of a row of the file.
+func (d *decoder) pixel(row []byte, x int) uint16 {
+	switch d.bpp {
+	case 1:
+		return d.palette[row[x/8]>>(7-uint(x%8))&1]
+	case 4:
+		return d.palette[row[x/2]>>(4-4*uint(x%2))&0xF]
+	case 8:
+		return d.palette[row[x]]
+	case 24:
+		return rgb565(row[3*x+2], row[3*x+1], row[3*x])
+	}
+	var p uint32
+	if d.bpp == 16 {
+		p = uint32(le16(row[2*x:]))
+	} else {
+		p = le32(row[4*x:])
+	}
+	return rgb565(d.masks[0].value(p), d.masks[1].value(p), d.masks[2].value(p))
+}
+
+// decodeRLE decodes pixel data compressed with RLE8 or RLE4. The pixels that
+// are skipped have the color of index 0.
+func (d *decoder) decodeRLE() error {
+	if d.topDown {
+		return FormatError("top-down image with RLE compression")
+	}
+	if cap(d.row) < d.width {
+		d.row = make([]byte, d.width)
+	}
+	// indexes, converted to 8 bits per pixel
+	row := d.row[:d.width]
+	zero(row)
+	bpp8 := d.bpp == 8
+	d.bpp = 8    // for drawRow
+	x, i := 0, 0 // position in row, row of the file
+	next := func() {
+		d.drawRow(d.rowY(i), row)
+		zero(row)
+		x = 0
+		i++
+	}
+	for i < d.height {
+		count, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		value, err := d.readByte()
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			// run of pixels
+			for j := 0; j < int(count); j++ {
+				c := value
+				if !bpp8 {
+					c = value >> (4 - 4*uint(j%2)) & 0xF
+				}
+				if x < len(row) {
+					row[x] = c
+				}
+				x++
+			}
+			continue
+		}
+		switch value {
+		case 0: // end of line
+			next()
+		case 1: // end of bitmap
+			for i < d.height {
+				next()
+			}
+		case 2: // delta
+			dx, err := d.readByte()
+			if err != nil {
+				return err
+			}
+			dy, err := d.readByte()
+			if err != nil {
+				return err
+			}
+			for j := 0; j < int(dy) && i < d.height; j++ {
+				oldX := x
+				next()
+				x = oldX
+			}
+			x += int(dx)
+		default: // absolute run of value pixels
+			n := int(value)
+			if !bpp8 {
+				n = (n + 1) / 2
+			}
+			for j := 0; j < n; j++ {
+				b, err := d.readByte()
+				if err != nil {
+					return err
+				}
+				if bpp8 {
+					if x < len(row) {
+						row[x] = b
+					}
+					x++
+					continue
+				}
+				for k := 0; k < 2 && 2*j+k < int(value); k++ {
+					if x < len(row) {
+						row[x] = b >> (4 - 4*uint(k)) & 0xF
+					}
+					x++
+				}
+			}
+			if n%2 != 0 {
+				// runs are padded to 16 bits
+				if _, err := d.readByte(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
+
+func le32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func rgb565(r, g, b uint8) uint16 {
+	return uint16(r&0xF8)<<8 | uint16(g&0xFC)<<3 | uint16(b)>>3
+}
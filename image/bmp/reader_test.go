@@ -0,0 +1,224 @@
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"io"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// file builds a BMP file with a BITMAPINFOHEADER.
+type file struct {
+	width, height int32 // negative height for top-down images
+	bpp           uint16
+	compression   uint32
+	palette       []color.RGBA
+	masks         []uint32
+	data          []byte
+}
+
+func (f file) bytes() []byte {
+	var info bytes.Buffer
+	w := func(v interface{}) { binary.Write(&info, binary.LittleEndian, v) }
+	w(uint32(40))
+	w(f.width)
+	w(f.height)
+	w(uint16(1))
+	w(f.bpp)
+	w(f.compression)
+	w(uint32(len(f.data)))
+	w([2]uint32{2835, 2835})
+	w(uint32(len(f.palette)))
+	w(uint32(0))
+	for _, m := range f.masks {
+		w(m)
+	}
+	for _, c := range f.palette {
+		info.Write([]byte{c.B, c.G, c.R, 0})
+	}
+	info.Write([]byte{0xAA, 0xAA}) // gap before the pixel data
+
+	var b bytes.Buffer
+	offset := 14 + info.Len()
+	b.WriteString("BM")
+	binary.Write(&b, binary.LittleEndian, [3]uint32{uint32(offset + len(f.data)), 0, uint32(offset)})
+	b.Write(info.Bytes())
+	b.Write(f.data)
+	return b.Bytes()
+}
+
+// decode decodes a BMP file and returns its pixels, line by line.
+func decode(c *qt.C, b []byte, bufSize int) []uint16 {
+	cfg, err := DecodeConfig(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	pix := make([]uint16, cfg.Width*cfg.Height)
+	SetCallback(make([]uint16, bufSize), func(data []uint16, x, y, w, h, width, height int16) {
+		c.Assert(int(width), qt.Equals, cfg.Width)
+		c.Assert(int(height), qt.Equals, cfg.Height)
+		c.Assert(h, qt.Equals, int16(1))
+		copy(pix[int(y)*cfg.Width+int(x):], data[:w])
+	})
+	_, err = Decode(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	return pix
+}
+
+var (
+	black = color.RGBA{0, 0, 0, 0xFF}
+	red   = color.RGBA{0xFF, 0, 0, 0xFF}
+	green = color.RGBA{0, 0xFF, 0, 0xFF}
+	blue  = color.RGBA{0, 0, 0xFF, 0xFF}
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+
+	K, R, G, B, W = rgb565(0, 0, 0), rgb565(0xFF, 0, 0), rgb565(0, 0xFF, 0), rgb565(0, 0, 0xFF), rgb565(0xFF, 0xFF, 0xFF)
+)
+
+func TestDecode(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range []struct {
+		name string
+		file file
+		want []uint16
+	}{{
+		name: "1-bit",
+		file: file{width: 10, height: 2, bpp: 1, palette: []color.RGBA{black, white},
+			data: []byte{
+				0xC0, 0x40, 0, 0, // bottom row
+				0x80, 0x00, 0, 0,
+			}},
+		want: []uint16{
+			W, K, K, K, K, K, K, K, K, K,
+			W, W, K, K, K, K, K, K, K, W,
+		},
+	}, {
+		name: "4-bit",
+		file: file{width: 3, height: -2, bpp: 4, palette: []color.RGBA{black, red, green, blue},
+			data: []byte{
+				0x12, 0x30, 0, 0,
+				0x00, 0x10, 0, 0,
+			}},
+		want: []uint16{
+			R, G, B,
+			K, K, R,
+		},
+	}, {
+		name: "8-bit",
+		file: file{width: 2, height: 1, bpp: 8, palette: []color.RGBA{black, red, green, blue},
+			data: []byte{3, 1, 0, 0}},
+		want: []uint16{B, R},
+	}, {
+		name: "16-bit",
+		file: file{width: 2, height: 1, bpp: 16,
+			data: []byte{0x00, 0x7C, 0x1F, 0x00}},
+		want: []uint16{R, B},
+	}, {
+		name: "16-bit 565",
+		file: file{width: 2, height: 1, bpp: 16, compression: biBitFields,
+			masks: []uint32{0xF800, 0x07E0, 0x001F},
+			data:  []byte{0xE0, 0x07, 0xFF, 0xFF}},
+		want: []uint16{G, W},
+	}, {
+		name: "24-bit",
+		file: file{width: 1, height: -2, bpp: 24,
+			data: []byte{
+				0xFF, 0, 0, 0,
+				0, 0, 0xFF, 0,
+			}},
+		want: []uint16{B, R},
+	}, {
+		name: "32-bit",
+		file: file{width: 2, height: 1, bpp: 32,
+			data: []byte{0, 0xFF, 0, 0x80, 0x10, 0x20, 0x30, 0}},
+		want: []uint16{G, rgb565(0x30, 0x20, 0x10)},
+	}, {
+		name: "32-bit RGBA",
+		file: file{width: 1, height: 1, bpp: 32, compression: biBitFields,
+			masks: []uint32{0xFF000000, 0x00FF0000, 0x0000FF00},
+			data:  []byte{0x80, 0x30, 0x20, 0x10}},
+		want: []uint16{rgb565(0x10, 0x20, 0x30)},
+	}, {
+		name: "RLE8",
+		file: file{width: 6, height: 3, bpp: 8, compression: biRLE8, palette: []color.RGBA{black, red, green, blue},
+			data: []byte{
+				3, 1, 0, 0, // bottom row: 3 red, end of line
+				0, 2, 2, 1, // delta: skip a row and 2 pixels
+				1, 2, // 1 green
+				0, 3, 2, 3, 1, 0, // absolute run, padded
+				0, 1, // end of bitmap
+			}},
+		want: []uint16{
+			K, K, G, G, B, R,
+			K, K, K, K, K, K,
+			R, R, R, K, K, K,
+		},
+	}, {
+		name: "RLE4",
+		file: file{width: 5, height: 2, bpp: 4, compression: biRLE4, palette: []color.RGBA{black, red, green, blue},
+			data: []byte{
+				5, 0x12, 0, 0, // bottom row
+				0, 3, 0x33, 0x10, // absolute run of 3 pixels
+				0, 1,
+			}},
+		want: []uint16{
+			B, B, R, K, K,
+			R, G, R, G, R,
+		},
+	}} {
+		c.Run(test.name, func(c *qt.C) {
+			c.Assert(decode(c, test.file.bytes(), 64), qt.DeepEquals, test.want)
+			c.Assert(decode(c, test.file.bytes(), 1), qt.DeepEquals, test.want)
+		})
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	c := qt.New(t)
+	f := file{width: 3, height: -2, bpp: 4, palette: []color.RGBA{black, red}, data: make([]byte, 8)}
+	cfg, err := DecodeConfig(bytes.NewReader(f.bytes()))
+	c.Assert(err, qt.IsNil)
+	c.Assert(cfg.Width, qt.Equals, 3)
+	c.Assert(cfg.Height, qt.Equals, 2)
+	c.Assert(cfg.ColorModel, qt.DeepEquals, color.Model(color.Palette{black, red}))
+}
+
+func TestDecodeError(t *testing.T) {
+	c := qt.New(t)
+	SetCallback(make([]uint16, 8), func(data []uint16, x, y, w, h, width, height int16) {})
+	f := file{width: 2, height: 2, bpp: 24, data: make([]byte, 16)}
+	b := f.bytes()
+	_, err := Decode(bytes.NewReader(b[:len(b)-1]))
+	c.Assert(err, qt.Equals, io.ErrUnexpectedEOF)
+
+	b[0] = 'X'
+	_, err = Decode(bytes.NewReader(b))
+	c.Assert(err, qt.ErrorMatches, "bmp: invalid format: not a BMP file")
+
+	f.bpp = 2
+	_, err = Decode(bytes.NewReader(f.bytes()))
+	c.Assert(err, qt.ErrorMatches, "bmp: unsupported feature: 2 bits per pixel")
+}
+
+func TestDecoder(t *testing.T) {
+	c := qt.New(t)
+	f := file{width: 2, height: 1, bpp: 8, palette: []color.RGBA{black, red, green, blue}, data: []byte{3, 1, 0, 0}}
+	var got []uint16
+	dec := NewDecoder(make([]uint16, 2), func(data []uint16, x, y, w, h, width, height int16) {
+		got = append(got, data[:w]...)
+	})
+
+	// the package-level callback is not used
+	SetCallback(make([]uint16, 2), func(data []uint16, x, y, w, h, width, height int16) {
+		c.Fatalf("unexpected call")
+	})
+	c.Assert(dec.Decode(bytes.NewReader(f.bytes())), qt.IsNil)
+	c.Assert(got, qt.DeepEquals, []uint16{B, R})
+
+	cfg, err := dec.DecodeConfig(bytes.NewReader(f.bytes()))
+	c.Assert(err, qt.IsNil)
+	c.Assert([]int{cfg.Width, cfg.Height}, qt.DeepEquals, []int{2, 1})
+
+	c.Assert(NewDecoder(nil, nil).Decode(bytes.NewReader(f.bytes())), qt.Equals, errNoBuffer)
+}
@@ -0,0 +1,215 @@
+// Package dither reduces colors to the small palettes of displays such as
+// monochrome, grayscale and multi-color e-paper panels, with ordered (Bayer)
+// or Floyd–Steinberg dithering.
+//
+// Ordered dithering works pixel by pixel, so it can wrap any display:
+//
+//	d := dither.Ordered{Displayer: &display, Palette: dither.Gray4}
+//	tinydraw.FilledCircle(&d, 50, 50, 20, color.RGBA{R: 128, G: 128, B: 128, A: 255})
+//
+// Floyd–Steinberg dithering gives better results for images, but needs to
+// see the pixels in order, a row at a time.
+package dither // import "tinygo.org/x/drivers/image/dither"
+
+import (
+	"image/color"
+
+	"tinygo.org/x/drivers"
+)
+
+// Palette is a set of colors that a display can show.
+type Palette []color.RGBA
+
+var (
+	// BlackWhite is the palette of monochrome displays.
+	BlackWhite = Palette{
+		{A: 0xFF},
+		{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+
+	// EPaperBlackWhite holds the colors to draw for the colors of BlackWhite
+	// on the monochrome e-paper drivers in black and white mode, such as
+	// uc8151, epd2in9, epd2in13 and epd4in2: they show RGBA(0,0,0, 255) as
+	// white and any other color as black. Use it as the Colors of Ordered or
+	// FloydSteinberg, with BlackWhite as the palette.
+	EPaperBlackWhite = []color.RGBA{
+		{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+		{A: 0xFF},
+	}
+
+	// Gray4 is the palette of displays with 4 levels of gray, from black to
+	// white, so that the index of a color is its gray level.
+	Gray4 = Palette{
+		{A: 0xFF},
+		{R: 0x55, G: 0x55, B: 0x55, A: 0xFF},
+		{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF},
+		{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+)
+
+// Index returns the index of the color of the palette that is closest to c.
+func (p Palette) Index(c color.RGBA) int {
+	return p.index(int32(c.R), int32(c.G), int32(c.B))
+}
+
+// Convert returns the color of the palette that is closest to c.
+func (p Palette) Convert(c color.RGBA) color.RGBA {
+	return p[p.Index(c)]
+}
+
+func (p Palette) index(r, g, b int32) int {
+	best, bestDist := 0, int32(-1)
+	for i, pc := range p {
+		dr, dg, db := r-int32(pc.R), g-int32(pc.G), b-int32(pc.B)
+		// weigh the channels roughly by how sensitive the eye is to them
+		dist := 2*dr*dr + 4*dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// bayer is the 4x4 Bayer threshold matrix.
+var bayer = [4][4]int8{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Ordered is a display that dithers colors to its palette with a 4x4 Bayer
+// matrix before setting them on the display it wraps.
+type Ordered struct {
+	drivers.Displayer
+
+	// Palette holds the colors of the display.
+	Palette Palette
+
+	// Spread is the strength of the dither pattern, normally the distance
+	// between neighbouring colors of the palette: 255 for black and white or
+	// 85 for 4 levels of gray. Zero means 255/(len(Palette)-1).
+	Spread int16
+
+	// Colors, if not nil, holds the colors to draw on the display for each
+	// color of the palette, for displays that don't show the colors they are
+	// given, such as EPaperBlackWhite.
+	Colors []color.RGBA
+}
+
+// SetPixel sets a pixel to the color of the palette chosen by the dither
+// pattern at x, y.
+func (o *Ordered) SetPixel(x, y int16, c color.RGBA) {
+	i := o.Index(x, y, c)
+	if o.Colors != nil {
+		o.Displayer.SetPixel(x, y, o.Colors[i])
+		return
+	}
+	o.Displayer.SetPixel(x, y, o.Palette[i])
+}
+
+// Index returns the index of the color of the palette chosen by the dither
+// pattern for c at x, y.
+func (o *Ordered) Index(x, y int16, c color.RGBA) int {
+	spread := int32(o.Spread)
+	if spread == 0 && len(o.Palette) > 1 {
+		spread = 255 / int32(len(o.Palette)-1)
+	}
+	// offset in the range (-spread/2, spread/2)
+	offset := (2*int32(bayer[y&3][x&3]) + 1 - 16) * spread / 32
+	return o.Palette.index(int32(c.R)+offset, int32(c.G)+offset, int32(c.B)+offset)
+}
+
+// FloydSteinberg dithers rows of pixels by diffusing the error of each pixel
+// to its neighbours to the right and below.
+type FloydSteinberg struct {
+	// Colors, if not nil, holds the colors that DrawRow draws for each color
+	// of the palette, see Ordered.Colors.
+	Colors []color.RGBA
+
+	palette Palette
+	width   int
+	cur     []int16 // errors for the current row, 3 per pixel with a pixel margin
+	next    []int16 // errors for the next row
+}
+
+// NewFloydSteinberg returns a dither for rows of at most width pixels.
+func NewFloydSteinberg(p Palette, width int) *FloydSteinberg {
+	return &FloydSteinberg{
+		palette: p,
+		width:   width,
+		cur:     make([]int16, 3*(width+2)),
+		next:    make([]int16, 3*(width+2)),
+	}
+}
+
+// Reset forgets the errors of the previous rows, to start a new image.
+func (f *FloydSteinberg) Reset() {
+	for i := range f.cur {
+		f.cur[i] = 0
+		f.next[i] = 0
+	}
+}
+
+// Dither replaces the pixels of row with colors of the palette. Rows must be
+// passed from top to bottom, without skipping any.
+func (f *FloydSteinberg) Dither(row []color.RGBA) {
+	f.dither(row, func(i, index int) {
+		row[i] = f.palette[index]
+	})
+}
+
+// DrawRow dithers row and draws it on d at x, y. Rows must be drawn from top
+// to bottom, without skipping any. row is not modified.
+func (f *FloydSteinberg) DrawRow(d drivers.Displayer, x, y int16, row []color.RGBA) {
+	colors := f.Colors
+	if colors == nil {
+		colors = f.palette
+	}
+	f.dither(row, func(i, index int) {
+		d.SetPixel(x+int16(i), y, colors[index])
+	})
+}
+
+// dither calls set with the index in the palette of each pixel of row.
+func (f *FloydSteinberg) dither(row []color.RGBA, set func(i, index int)) {
+	if len(row) > f.width {
+		row = row[:f.width]
+	}
+	for i := range f.next {
+		f.next[i] = 0
+	}
+	for i, c := range row {
+		e := 3 * (i + 1)
+		r := clamp(int32(c.R) + int32(f.cur[e]))
+		g := clamp(int32(c.G) + int32(f.cur[e+1]))
+		b := clamp(int32(c.B) + int32(f.cur[e+2]))
+		index := f.palette.index(r, g, b)
+		pc := f.palette[index]
+		set(i, index)
+		f.diffuse(e, r-int32(pc.R))
+		f.diffuse(e+1, g-int32(pc.G))
+		f.diffuse(e+2, b-int32(pc.B))
+	}
+	f.cur, f.next = f.next, f.cur
+}
+
+// diffuse spreads the error of the channel at index e of the current row.
+func (f *FloydSteinberg) diffuse(e int, err int32) {
+	f.cur[e+3] += int16(err * 7 / 16)
+	f.next[e-3] += int16(err * 3 / 16)
+	f.next[e] += int16(err * 5 / 16)
+	f.next[e+3] += int16(err / 16)
+}
+
+// clamp limits the error-adjusted value of a channel, so that errors don't
+// accumulate without bounds in areas that can't be matched by the palette.
+func clamp(v int32) int32 {
+	if v < -128 {
+		return -128
+	}
+	if v > 383 {
+		return 383
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+package dither_test
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/image/dither"
+	"tinygo.org/x/drivers/tester"
+)
+
+func gray(v uint8) color.RGBA {
+	return color.RGBA{R: v, G: v, B: v, A: 0xFF}
+}
+
+func TestPalette(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(dither.Gray4.Index(gray(0x10)), qt.Equals, 0)
+	c.Assert(dither.Gray4.Index(gray(0x60)), qt.Equals, 1)
+	c.Assert(dither.Gray4.Index(gray(0xB0)), qt.Equals, 2)
+	c.Assert(dither.Gray4.Index(gray(0xF0)), qt.Equals, 3)
+	c.Assert(dither.BlackWhite.Convert(color.RGBA{R: 0xFF, G: 0xC0, A: 0xFF}), qt.Equals, gray(0xFF))
+	c.Assert(dither.BlackWhite.Convert(color.RGBA{B: 0xFF, A: 0xFF}), qt.Equals, gray(0))
+}
+
+// mean returns the average red level of the pixels of d.
+func mean(d *tester.Display) int {
+	w, h := d.Size()
+	sum := 0
+	for y := int16(0); y < h; y++ {
+		for x := int16(0); x < w; x++ {
+			sum += int(d.GetPixel(x, y).R)
+		}
+	}
+	return sum / int(w) / int(h)
+}
+
+func TestOrdered(t *testing.T) {
+	c := qt.New(t)
+	for _, level := range []uint8{0, 0x40, 0x80, 0xC0, 0xFF} {
+		d := tester.NewDisplay(8, 8)
+		o := dither.Ordered{Displayer: d, Palette: dither.BlackWhite}
+		for y := int16(0); y < 8; y++ {
+			for x := int16(0); x < 8; x++ {
+				o.SetPixel(x, y, gray(level))
+			}
+		}
+		c.Assert(mean(d)-int(level), qt.Satisfies, within(16), qt.Commentf("level %d", level))
+	}
+
+	// Colors of the palette are not dithered.
+	d := tester.NewDisplay(4, 4)
+	o := dither.Ordered{Displayer: d, Palette: dither.Gray4}
+	for y := int16(0); y < 4; y++ {
+		for x := int16(0); x < 4; x++ {
+			o.SetPixel(x, y, gray(0x55))
+		}
+	}
+	c.Assert(mean(d), qt.Equals, 0x55)
+}
+
+func TestFloydSteinberg(t *testing.T) {
+	c := qt.New(t)
+	for _, level := range []uint8{0, 0x30, 0x80, 0xD0, 0xFF} {
+		d := tester.NewDisplay(16, 16)
+		f := dither.NewFloydSteinberg(dither.BlackWhite, 16)
+		row := make([]color.RGBA, 16)
+		for y := int16(0); y < 16; y++ {
+			for i := range row {
+				row[i] = gray(level)
+			}
+			f.DrawRow(d, 0, y, row)
+			c.Assert(row[0], qt.Equals, gray(level))
+		}
+		c.Assert(mean(d)-int(level), qt.Satisfies, within(8), qt.Commentf("level %d", level))
+	}
+
+	f := dither.NewFloydSteinberg(dither.Gray4, 2)
+	row := []color.RGBA{gray(0x55), gray(0xAA), gray(0x80)}
+	f.Dither(row)
+	// Pixels of the palette are kept and pixels beyond the width are ignored.
+	c.Assert(row, qt.DeepEquals, []color.RGBA{gray(0x55), gray(0xAA), gray(0x80)})
+}
+
+func TestColors(t *testing.T) {
+	c := qt.New(t)
+	// The colors of the palette are drawn as the colors of the display.
+	d := tester.NewDisplay(2, 1)
+	o := dither.Ordered{Displayer: d, Palette: dither.BlackWhite, Colors: dither.EPaperBlackWhite}
+	o.SetPixel(0, 0, gray(0))
+	o.SetPixel(1, 0, gray(0xFF))
+	c.Assert(d.GetPixel(0, 0), qt.Equals, gray(0xFF))
+	c.Assert(d.GetPixel(1, 0), qt.Equals, gray(0))
+
+	d = tester.NewDisplay(2, 1)
+	f := dither.NewFloydSteinberg(dither.BlackWhite, 2)
+	f.Colors = dither.EPaperBlackWhite
+	row := []color.RGBA{gray(0), gray(0xFF)}
+	f.DrawRow(d, 0, 0, row)
+	c.Assert(d.GetPixel(0, 0), qt.Equals, gray(0xFF))
+	c.Assert(d.GetPixel(1, 0), qt.Equals, gray(0))
+	// Dither still returns the colors of the palette.
+	f.Dither(row)
+	c.Assert(row, qt.DeepEquals, []color.RGBA{gray(0), gray(0xFF)})
+}
+
+func within(n int) func(int) bool {
+	return func(v int) bool {
+		return v >= -n && v <= n
+	}
+}
@@ -0,0 +1,95 @@
+package gif
+
+import (
+	"image"
+	"io"
+	"time"
+)
+
+// A portion of the image data consisting of data, x, y, w, and h is passed to
+// Callback. The size of the whole image is passed as width and height.
+type Callback func(data []uint16, x, y, w, h, width, height int16)
+
+// Disposal methods, which tell what happens to the area of a frame before the
+// next one is drawn.
+const (
+	DisposalNone       = 0x01
+	DisposalBackground = 0x02
+	DisposalPrevious   = 0x03
+)
+
+// Frame describes a frame of an animation.
+type Frame struct {
+	// Index is the number of the frame, from 0.
+	Index int
+
+	// X, Y, Width and Height are the area of the image covered by the frame.
+	X, Y, Width, Height int16
+
+	// Delay is the time to show the frame for.
+	Delay time.Duration
+
+	// Disposal is the disposal method of the frame.
+	Disposal byte
+
+	// LoopCount is the number of times to show the animation, as in the
+	// standard image/gif package: 0 means forever, -1 means once and n
+	// means n+1 times.
+	LoopCount int
+}
+
+// FrameCallback is called by DecodeAll after each frame has been drawn.
+type FrameCallback func(f Frame)
+
+// Decoder decodes GIF images and passes the pixels to its callback. Unlike
+// the package-level functions, each Decoder has its own buffer and callbacks,
+// so that several images can be decoded at the same time, for example to
+// different displays.
+type Decoder struct {
+	buf           []uint16
+	callback      Callback
+	frameCallback FrameCallback
+}
+
+// NewDecoder returns a decoder that passes the pixels to fn, in buf. The
+// buffer must hold at least 1 pixel, and is best at least as long as a row of
+// the image.
+func NewDecoder(buf []uint16, fn Callback) *Decoder {
+	return &Decoder{buf: buf, callback: fn, frameCallback: func(f Frame) {}}
+}
+
+// SetFrameCallback registers fn, called by DecodeAll after each frame. It
+// usually waits for f.Delay before returning, since the next frame is drawn
+// right after.
+func (dec *Decoder) SetFrameCallback(fn FrameCallback) {
+	dec.frameCallback = fn
+}
+
+// DecodeConfig returns the global color model and dimensions of a GIF image
+// without decoding the entire image.
+func (dec *Decoder) DecodeConfig(r io.Reader) (image.Config, error) {
+	return DecodeConfig(r)
+}
+
+// defaultDecoder is used by the package-level functions.
+var defaultDecoder = Decoder{
+	callback:      func(data []uint16, x, y, w, h, width, height int16) {},
+	frameCallback: func(f Frame) {},
+}
+
+// SetCallback registers the buffer and fn required for Callback. Callback can
+// be called multiple times by calling Decode().
+//
+// The buffer and callbacks are shared by the package-level functions: use a
+// Decoder to decode several images at the same time.
+func SetCallback(buf []uint16, fn Callback) {
+	defaultDecoder.buf = buf
+	defaultDecoder.callback = fn
+}
+
+// SetFrameCallback registers fn, called by DecodeAll after each frame. It
+// usually waits for f.Delay before returning, since the next frame is drawn
+// right after.
+func SetFrameCallback(fn FrameCallback) {
+	defaultDecoder.frameCallback = fn
+}
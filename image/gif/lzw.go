@@ -0,0 +1,176 @@
+package gif
+
+import (
+	"io"
+)
+
+const (
+	maxWidth    = 12
+	invalidCode = 0xffff
+)
+
+// lzwDecoder is a decoder for the variant of LZW used by GIF, with codes
+// packed from the least significant bit. Unlike compress/lzw, it writes its
+// output to a caller's buffer, one row of pixels at a time, and doesn't
+// allocate.
+type lzwDecoder struct {
+	r *blockReader
+
+	litWidth uint
+	width    uint
+	bits     uint32
+	nBits    uint
+
+	clear, eoi uint16
+	hi         uint16 // next code to be defined
+	overflow   uint16 // code at which width increases
+	last       uint16 // previous code, or invalidCode
+	eof        bool   // whether the end code was read
+
+	prefix [1 << maxWidth]uint16
+	suffix [1 << maxWidth]byte
+
+	// stack holds the output of the last code, from stack[top-1] down to
+	// stack[0].
+	stack [1 << maxWidth]byte
+	top   int
+}
+
+// init starts decoding the data of a frame.
+func (z *lzwDecoder) init(r *blockReader, litWidth uint) {
+	z.r = r
+	z.litWidth = litWidth
+	z.bits = 0
+	z.nBits = 0
+	z.clear = 1 << litWidth
+	z.eoi = z.clear + 1
+	z.eof = false
+	z.top = 0
+	z.reset()
+}
+
+func (z *lzwDecoder) reset() {
+	z.width = z.litWidth + 1
+	z.hi = z.eoi
+	z.overflow = 1 << z.width
+	z.last = invalidCode
+}
+
+func (z *lzwDecoder) readCode() (uint16, error) {
+	for z.nBits < z.width {
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		z.bits |= uint32(b) << z.nBits
+		z.nBits += 8
+	}
+	code := uint16(z.bits & (1<<z.width - 1))
+	z.bits >>= z.width
+	z.nBits -= z.width
+	return code, nil
+}
+
+// read fills dst with decoded bytes.
+func (z *lzwDecoder) read(dst []byte) error {
+	n := 0
+	for n < len(dst) {
+		for z.top > 0 && n < len(dst) {
+			z.top--
+			dst[n] = z.stack[z.top]
+			n++
+		}
+		if n == len(dst) {
+			break
+		}
+		if z.eof {
+			return errNotEnough
+		}
+		code, err := z.readCode()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		switch {
+		case code == z.clear:
+			z.reset()
+			continue
+		case code == z.eoi:
+			z.eof = true
+			continue
+		case code < z.hi || code == z.hi && z.last != invalidCode:
+			c := code
+			if code == z.hi {
+				// the code being defined: the output of the previous
+				// code followed by its first byte
+				c = z.last
+				for c >= z.clear {
+					c = z.prefix[c]
+				}
+				z.stack[z.top] = byte(c)
+				z.top++
+				c = z.last
+			}
+			for c >= z.clear {
+				z.stack[z.top] = z.suffix[c]
+				z.top++
+				c = z.prefix[c]
+			}
+			z.stack[z.top] = byte(c)
+			z.top++
+			if z.last != invalidCode {
+				z.suffix[z.hi] = byte(c)
+				z.prefix[z.hi] = z.last
+			}
+		default:
+			return errBadLZW
+		}
+		z.last, z.hi = code, z.hi+1
+		if z.hi >= z.overflow {
+			if z.width == maxWidth {
+				// the table is full: keep it until the next clear code
+				z.last = invalidCode
+				z.hi--
+			} else {
+				z.width++
+				z.overflow = 1 << z.width
+			}
+		}
+	}
+	return nil
+}
+
+// blockReader reads the data sub-blocks of a frame as a single stream.
+type blockReader struct {
+	r   io.ByteReader
+	n   int // bytes left in the current sub-block
+	end bool
+}
+
+func (b *blockReader) ReadByte() (byte, error) {
+	for b.n == 0 {
+		if b.end {
+			return 0, io.EOF
+		}
+		n, err := b.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		b.n = int(n)
+		b.end = n == 0
+	}
+	b.n--
+	return b.r.ReadByte()
+}
+
+// close skips the rest of the sub-blocks.
+func (b *blockReader) close() error {
+	for !b.end {
+		if _, err := b.ReadByte(); err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+// Package gif implements a GIF image decoder, for still images and
+// animations.
+//
+// Like the png and jpeg packages, it doesn't return an image.Image: the
+// frames are drawn row by row through the callback set by SetCallback, in
+// RGB565, so that decoding only needs memory for a row of pixels and the LZW
+// tables (about 16KB), whatever the size of the image.
+//
+// The GIF specification is at https://www.w3.org/Graphics/GIF/spec-gif89a.txt.
+package gif
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"time"
+)
+
+// A FormatError reports that the input is not a valid GIF.
+type FormatError string
+
+func (e FormatError) Error() string { return "gif: invalid format: " + string(e) }
+
+var (
+	errNotEnough = FormatError("not enough image data")
+	errBadLZW    = FormatError("invalid LZW code")
+	errNoBuffer  = errors.New("gif: no callback buffer")
+)
+
+// Fields of the packed bytes of the headers.
+const (
+	fColorTable         = 1 << 7
+	fInterlace          = 1 << 6
+	fColorTableBitsMask = 7
+
+	gcTransparentColorSet = 1 << 0
+	gcDisposalMethodMask  = 7 << 2
+)
+
+// Block types.
+const (
+	sExtension       = 0x21
+	sImageDescriptor = 0x2C
+	sTrailer         = 0x3B
+)
+
+// Extension labels.
+const (
+	eGraphicControl = 0xF9
+	eApplication    = 0xFF
+)
+
+// interlacing holds the first row and the step of the 4 passes of interlaced
+// frames.
+var interlacing = [4]struct{ start, step int }{
+	{0, 8},
+	{4, 8},
+	{2, 4},
+	{1, 2},
+}
+
+type decoder struct {
+	out *Decoder
+	r   io.ByteReader
+	tmp [3 * 256]byte
+
+	width, height int
+	background    byte
+	hasGlobal     bool
+	globalSize    int
+	global        [256]uint16
+	local         [256]uint16
+	loopCount     int
+
+	// graphic control extension of the next frame
+	delay       int
+	disposal    byte
+	transparent int // -1 if none
+
+	frame    Frame
+	previous Frame // for its disposal, if any
+
+	blocks blockReader
+	lzw    lzwDecoder
+	row    []byte
+}
+
+// Decode reads a GIF image from r and draws its first frame. Different from
+// the standard package, the decoded result will be received by the callback
+// set by SetCallback().
+func Decode(r io.Reader) (image.Image, error) {
+	return nil, defaultDecoder.Decode(r)
+}
+
+// DecodeAll reads a GIF animation from r and draws its frames one after the
+// other, calling the callback set by SetFrameCallback() after each of them.
+//
+// The frames are drawn on top of each other, so the display acts as the
+// canvas: transparent pixels are not drawn, and the area of a frame with the
+// DisposalBackground method is filled with the background color before the
+// next frame. DisposalPrevious would need a copy of the image, and is treated
+// as DisposalNone.
+func DecodeAll(r io.Reader) error {
+	return defaultDecoder.DecodeAll(r)
+}
+
+// Decode reads a GIF image from r and draws its first frame with the callback
+// of the decoder.
+func (dec *Decoder) Decode(r io.Reader) error {
+	return newDecoder(dec, r).decode(false)
+}
+
+// DecodeAll reads a GIF animation from r and draws all its frames, as the
+// package-level DecodeAll, with the callbacks of the decoder.
+func (dec *Decoder) DecodeAll(r io.Reader) error {
+	return newDecoder(dec, r).decode(true)
+}
+
+// DecodeConfig returns the global color model and dimensions of a GIF image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d := newDecoder(nil, r)
+	if err := d.readHeader(); err != nil {
+		return image.Config{}, err
+	}
+	var cm color.Model = color.RGBAModel
+	if d.hasGlobal {
+		// the table is still in tmp
+		p := make(color.Palette, d.globalSize)
+		for i := range p {
+			p[i] = color.RGBA{d.tmp[3*i], d.tmp[3*i+1], d.tmp[3*i+2], 0xFF}
+		}
+		cm = p
+	}
+	return image.Config{
+		ColorModel: cm,
+		Width:      d.width,
+		Height:     d.height,
+	}, nil
+}
+
+func newDecoder(dec *Decoder, r io.Reader) *decoder {
+	br, ok := r.(io.ByteReader)
+	if !ok {
+		br = bufio.NewReaderSize(r, 256)
+	}
+	return &decoder{out: dec, r: br, loopCount: -1, transparent: -1}
+}
+
+func (d *decoder) decode(all bool) error {
+	if len(d.out.buf) == 0 {
+		return errNoBuffer
+	}
+	if err := d.readHeader(); err != nil {
+		return err
+	}
+	for {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch c {
+		case sExtension:
+			if err := d.readExtension(); err != nil {
+				return err
+			}
+		case sImageDescriptor:
+			if err := d.readImage(); err != nil {
+				return err
+			}
+			if !all {
+				return nil
+			}
+			d.out.frameCallback(d.frame)
+			d.previous = d.frame
+			d.frame.Index++
+		case sTrailer:
+			if d.frame.Index == 0 {
+				return FormatError("missing image data")
+			}
+			return nil
+		default:
+			return FormatError("unknown block type")
+		}
+	}
+}
+
+func (d *decoder) readHeader() error {
+	if err := d.readFull(d.tmp[:13]); err != nil {
+		return err
+	}
+	if s := string(d.tmp[:6]); s != "GIF87a" && s != "GIF89a" {
+		return FormatError("not a GIF file")
+	}
+	d.width = int(d.tmp[6]) | int(d.tmp[7])<<8
+	d.height = int(d.tmp[8]) | int(d.tmp[9])<<8
+	d.background = d.tmp[11]
+	if fields := d.tmp[10]; fields&fColorTable != 0 {
+		d.hasGlobal = true
+		d.globalSize = 1 << (1 + fields&fColorTableBitsMask)
+		return d.readColorTable(&d.global, d.globalSize)
+	}
+	return nil
+}
+
+// readColorTable reads a color table of n colors to p, and leaves it in tmp.
+func (d *decoder) readColorTable(p *[256]uint16, n int) error {
+	if err := d.readFull(d.tmp[:3*n]); err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		p[i] = rgb565(d.tmp[3*i], d.tmp[3*i+1], d.tmp[3*i+2])
+	}
+	for i := n; i < 256; i++ {
+		p[i] = 0
+	}
+	return nil
+}
+
+func (d *decoder) readExtension() error {
+	label, err := d.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	switch label {
+	case eGraphicControl:
+		if err := d.readFull(d.tmp[:6]); err != nil {
+			return err
+		}
+		if d.tmp[0] != 4 || d.tmp[5] != 0 {
+			return FormatError("invalid graphic control extension")
+		}
+		d.disposal = (d.tmp[1] & gcDisposalMethodMask) >> 2
+		d.delay = int(d.tmp[2]) | int(d.tmp[3])<<8
+		d.transparent = -1
+		if d.tmp[1]&gcTransparentColorSet != 0 {
+			d.transparent = int(d.tmp[4])
+		}
+		return nil
+	case eApplication:
+		n, err := d.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if err := d.readFull(d.tmp[:n]); err != nil {
+			return err
+		}
+		netscape := string(d.tmp[:n]) == "NETSCAPE2.0"
+		for {
+			n, err := d.r.ReadByte()
+			if err != nil || n == 0 {
+				return unexpectedEOF(err)
+			}
+			if err := d.readFull(d.tmp[:n]); err != nil {
+				return err
+			}
+			if netscape && n == 3 && d.tmp[0] == 1 {
+				d.loopCount = int(d.tmp[1]) | int(d.tmp[2])<<8
+			}
+		}
+	}
+	// other extensions, such as text and comments, are skipped
+	return d.skipBlocks()
+}
+
+// skipBlocks skips sub-blocks up to the block terminator.
+func (d *decoder) skipBlocks() error {
+	d.blocks = blockReader{r: d.r}
+	return unexpectedEOF(d.blocks.close())
+}
+
+func (d *decoder) readImage() error {
+	if err := d.readFull(d.tmp[:9]); err != nil {
+		return err
+	}
+	x := int(d.tmp[0]) | int(d.tmp[1])<<8
+	y := int(d.tmp[2]) | int(d.tmp[3])<<8
+	w := int(d.tmp[4]) | int(d.tmp[5])<<8
+	h := int(d.tmp[6]) | int(d.tmp[7])<<8
+	fields := d.tmp[8]
+
+	palette := &d.global
+	if fields&fColorTable != 0 {
+		if err := d.readColorTable(&d.local, 1<<(1+fields&fColorTableBitsMask)); err != nil {
+			return err
+		}
+		palette = &d.local
+	} else if !d.hasGlobal {
+		return FormatError("no color table")
+	}
+
+	litWidth, err := d.r.ReadByte()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if litWidth < 2 || litWidth > 8 {
+		return FormatError("pixel size in decode out of range")
+	}
+
+	// the previous frame is disposed of just before the next one is drawn,
+	// after its delay
+	if d.frame.Index > 0 && d.previous.Disposal == DisposalBackground {
+		d.fill(d.previous, d.global[d.background])
+	}
+
+	d.frame.X, d.frame.Y = int16(x), int16(y)
+	d.frame.Width, d.frame.Height = int16(w), int16(h)
+	d.frame.Delay = time.Duration(d.delay) * 10 * time.Millisecond
+	d.frame.Disposal = d.disposal
+	d.frame.LoopCount = d.loopCount
+
+	if cap(d.row) < w {
+		d.row = make([]byte, w)
+	}
+	row := d.row[:w]
+	d.blocks = blockReader{r: d.r}
+	d.lzw.init(&d.blocks, uint(litWidth))
+	pass, ry := 0, 0
+	for i := 0; i < h; i++ {
+		if err := d.lzw.read(row); err != nil {
+			return err
+		}
+		if fields&fInterlace != 0 {
+			for ry >= h {
+				pass++
+				ry = interlacing[pass].start
+			}
+			d.drawRow(x, y+ry, row, palette)
+			ry += interlacing[pass].step
+		} else {
+			d.drawRow(x, y+i, row, palette)
+		}
+	}
+	if err := d.blocks.close(); err != nil {
+		return unexpectedEOF(err)
+	}
+
+	// the graphic control extension only applies to one frame
+	d.delay = 0
+	d.disposal = 0
+	d.transparent = -1
+	return nil
+}
+
+// drawRow draws the pixels of row at (x, y), except the transparent ones and
+// those out of the image.
+func (d *decoder) drawRow(x, y int, row []byte, palette *[256]uint16) {
+	if y >= d.height {
+		return
+	}
+	if n := d.width - x; len(row) > n {
+		if n <= 0 {
+			return
+		}
+		row = row[:n]
+	}
+	buf := d.out.buf
+	for i := 0; i < len(row); {
+		if int(row[i]) == d.transparent {
+			i++
+			continue
+		}
+		// draw a run of opaque pixels, as long as the buffer allows
+		start, n := i, 0
+		for i < len(row) && n < len(buf) && int(row[i]) != d.transparent {
+			buf[n] = palette[row[i]]
+			n++
+			i++
+		}
+		d.out.callback(buf[:n], int16(x+start), int16(y), int16(n), 1, int16(d.width), int16(d.height))
+	}
+}
+
+// fill fills the area of frame f with color c.
+func (d *decoder) fill(f Frame, c uint16) {
+	x, y, w, h := int(f.X), int(f.Y), int(f.Width), int(f.Height)
+	if x+w > d.width {
+		w = d.width - x
+	}
+	if y+h > d.height {
+		h = d.height - y
+	}
+	if w <= 0 || h <= 0 {
+		return
+	}
+	buf := d.out.buf
+	if len(buf) > w*h {
+		buf = buf[:w*h]
+	}
+	for i := range buf {
+		buf[i] = c
+	}
+	if len(buf) >= w {
+		// whole rows at a time
+		rows := len(buf) / w
+		for r := 0; r < h; r += rows {
+			n := rows
+			if r+n > h {
+				n = h - r
+			}
+			d.out.callback(buf[:n*w], int16(x), int16(y+r), int16(w), int16(n), int16(d.width), int16(d.height))
+		}
+		return
+	}
+	for r := 0; r < h; r++ {
+		for i := 0; i < w; i += len(buf) {
+			n := len(buf)
+			if i+n > w {
+				n = w - i
+			}
+			d.out.callback(buf[:n], int16(x+i), int16(y+r), int16(n), 1, int16(d.width), int16(d.height))
+		}
+	}
+}
+
+func (d *decoder) readFull(p []byte) error {
+	for i := range p {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		p[i] = b
+	}
+	return nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func rgb565(r, g, b uint8) uint16 {
+	return uint16(r&0xF8)<<8 | uint16(g&0xFC)<<3 | uint16(b)>>3
+}
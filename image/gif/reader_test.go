@@ -0,0 +1,226 @@
+package gif
+
+import (
+	"bytes"
+	"compress/lzw"
+	"image"
+	"image/color"
+	stdgif "image/gif"
+	"math/rand"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// canvas collects the output of the decoder.
+type canvas struct {
+	width, height int
+	pix           []uint16
+	calls         int
+}
+
+func newCanvas(c *qt.C, width, height int, bufSize int) *canvas {
+	cv := &canvas{width: width, height: height, pix: make([]uint16, width*height)}
+	for i := range cv.pix {
+		cv.pix[i] = 0xDEAD // not drawn
+	}
+	SetCallback(make([]uint16, bufSize), func(data []uint16, x, y, w, h, width, height int16) {
+		c.Assert([]int{int(width), int(height)}, qt.DeepEquals, []int{cv.width, cv.height})
+		c.Assert(len(data), qt.Equals, int(w)*int(h))
+		for j := 0; j < int(h); j++ {
+			copy(cv.pix[(int(y)+j)*cv.width+int(x):], data[j*int(w):(j+1)*int(w)])
+		}
+		cv.calls++
+	})
+	SetFrameCallback(func(f Frame) {})
+	return cv
+}
+
+func rgb565Of(c color.Color) uint16 {
+	r, g, b, _ := c.RGBA()
+	return rgb565(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+}
+
+func randomPaletted(r *rand.Rand, w, h, colors int) *image.Paletted {
+	p := make(color.Palette, colors)
+	for i := range p {
+		p[i] = color.RGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 0xFF}
+	}
+	img := image.NewPaletted(image.Rect(0, 0, w, h), p)
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.Intn(colors))
+	}
+	return img
+}
+
+func TestDecode(t *testing.T) {
+	c := qt.New(t)
+	r := rand.New(rand.NewSource(1))
+	for _, test := range []struct {
+		w, h, colors, buf int
+	}{
+		{37, 23, 4, 64},
+		{37, 23, 16, 5},
+		{300, 200, 256, 300}, // fills the LZW table
+	} {
+		img := randomPaletted(r, test.w, test.h, test.colors)
+		var b bytes.Buffer
+		c.Assert(stdgif.Encode(&b, img, nil), qt.IsNil)
+
+		cfg, err := DecodeConfig(bytes.NewReader(b.Bytes()))
+		c.Assert(err, qt.IsNil)
+		c.Assert(cfg.Width, qt.Equals, test.w)
+		c.Assert(cfg.Height, qt.Equals, test.h)
+
+		cv := newCanvas(c, test.w, test.h, test.buf)
+		_, err = Decode(&b)
+		c.Assert(err, qt.IsNil)
+		for i, p := range img.Pix {
+			if cv.pix[i] != rgb565Of(img.Palette[p]) {
+				c.Fatalf("%dx%d: wrong pixel at %d", test.w, test.h, i)
+			}
+		}
+	}
+}
+
+func TestDecodeInterlaced(t *testing.T) {
+	c := qt.New(t)
+	const w, h = 3, 11
+	img := randomPaletted(rand.New(rand.NewSource(2)), w, h, 4)
+
+	// stdgif doesn't write interlaced images
+	var data bytes.Buffer
+	z := lzw.NewWriter(&data, lzw.LSB, 2)
+	for _, pass := range interlacing {
+		for y := pass.start; y < h; y += pass.step {
+			z.Write(img.Pix[y*w : (y+1)*w])
+		}
+	}
+	z.Close()
+	b := []byte("GIF89a\x03\x00\x0b\x00\x81\x00\x00")
+	for _, col := range img.Palette {
+		rgba := col.(color.RGBA)
+		b = append(b, rgba.R, rgba.G, rgba.B)
+	}
+	b = append(b, "\x2c\x00\x00\x00\x00\x03\x00\x0b\x00\x40\x02"...)
+	b = append(b, byte(data.Len()))
+	b = append(b, data.Bytes()...)
+	b = append(b, 0, 0x3b)
+
+	cv := newCanvas(c, w, h, 16)
+	_, err := Decode(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	for i, p := range img.Pix {
+		c.Assert(cv.pix[i], qt.Equals, rgb565Of(img.Palette[p]), qt.Commentf("pixel %d", i))
+	}
+}
+
+func TestDecodeAll(t *testing.T) {
+	c := qt.New(t)
+	red := color.RGBA{0xFF, 0, 0, 0xFF}
+	blue := color.RGBA{0, 0, 0xFF, 0xFF}
+	p := color.Palette{color.RGBA{}, red, blue}
+	frame := func(x0, y0, x1, y1 int, idx uint8) *image.Paletted {
+		img := image.NewPaletted(image.Rect(x0, y0, x1, y1), p)
+		for i := range img.Pix {
+			img.Pix[i] = idx
+		}
+		return img
+	}
+	f1 := frame(0, 0, 4, 4, 1)
+	f2 := frame(1, 1, 3, 3, 2)
+	f2.Pix[0] = 0 // transparent
+	f3 := frame(0, 0, 1, 1, 2)
+	anim := &stdgif.GIF{
+		Image:           []*image.Paletted{f1, f2, f3},
+		Delay:           []int{10, 50, 0},
+		Disposal:        []byte{DisposalNone, DisposalBackground, DisposalNone},
+		LoopCount:       2,
+		Config:          image.Config{ColorModel: p, Width: 4, Height: 4},
+		BackgroundIndex: 2,
+	}
+	var b bytes.Buffer
+	c.Assert(stdgif.EncodeAll(&b, anim), qt.IsNil)
+
+	cv := newCanvas(c, 4, 4, 4)
+	R, B := rgb565Of(red), rgb565Of(blue)
+	want := [][]uint16{
+		{
+			R, R, R, R,
+			R, R, R, R,
+			R, R, R, R,
+			R, R, R, R,
+		},
+		{
+			R, R, R, R,
+			R, R, B, R,
+			R, B, B, R,
+			R, R, R, R,
+		},
+		{
+			// the area of frame 2 is filled with the background
+			B, R, R, R,
+			R, B, B, R,
+			R, B, B, R,
+			R, R, R, R,
+		},
+	}
+	var frames []Frame
+	SetFrameCallback(func(f Frame) {
+		c.Assert(cv.pix, qt.DeepEquals, want[f.Index])
+		frames = append(frames, f)
+	})
+	c.Assert(DecodeAll(&b), qt.IsNil)
+	c.Assert(frames, qt.DeepEquals, []Frame{
+		{Index: 0, X: 0, Y: 0, Width: 4, Height: 4, Delay: 100 * time.Millisecond, Disposal: DisposalNone, LoopCount: 2},
+		{Index: 1, X: 1, Y: 1, Width: 2, Height: 2, Delay: 500 * time.Millisecond, Disposal: DisposalBackground, LoopCount: 2},
+		{Index: 2, X: 0, Y: 0, Width: 1, Height: 1, Disposal: DisposalNone, LoopCount: 2},
+	})
+}
+
+func TestDecodeError(t *testing.T) {
+	c := qt.New(t)
+	img := randomPaletted(rand.New(rand.NewSource(3)), 8, 8, 4)
+	var b bytes.Buffer
+	c.Assert(stdgif.Encode(&b, img, nil), qt.IsNil)
+
+	newCanvas(c, 8, 8, 8)
+	_, err := Decode(bytes.NewReader(b.Bytes()[:b.Len()-6]))
+	c.Assert(err, qt.Not(qt.IsNil))
+	_, err = Decode(bytes.NewReader([]byte("GIF89b\x01\x00\x01\x00\x00\x00\x00")))
+	c.Assert(err, qt.ErrorMatches, "gif: invalid format: not a GIF file")
+
+	SetCallback(nil, nil)
+	_, err = Decode(&b)
+	c.Assert(err, qt.Equals, errNoBuffer)
+}
+
+func TestDecoder(t *testing.T) {
+	c := qt.New(t)
+	r := rand.New(rand.NewSource(4))
+	imgs := []*image.Paletted{randomPaletted(r, 5, 3, 4), randomPaletted(r, 7, 2, 16)}
+
+	// decode both images at the same time, each with its own decoder
+	errs := make(chan error)
+	pix := make([][]uint16, len(imgs))
+	for i, img := range imgs {
+		var b bytes.Buffer
+		c.Assert(stdgif.Encode(&b, img, nil), qt.IsNil)
+		w := img.Rect.Dx()
+		pix[i] = make([]uint16, len(img.Pix))
+		out := pix[i]
+		dec := NewDecoder(make([]uint16, 3), func(data []uint16, x, y, _, h, width, height int16) {
+			copy(out[int(y)*w+int(x):], data)
+		})
+		go func() { errs <- dec.Decode(&b) }()
+	}
+	for range imgs {
+		c.Assert(<-errs, qt.IsNil)
+	}
+	for i, img := range imgs {
+		for j, p := range img.Pix {
+			c.Assert(pix[i][j], qt.Equals, rgb565Of(img.Palette[p]), qt.Commentf("image %d, pixel %d", i, j))
+		}
+	}
+}
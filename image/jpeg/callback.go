@@ -1,19 +1,73 @@
 package jpeg
 
-var (
-	callback    Callback = func(data []uint16, x, y, w, h, width, height int16) {}
-	callbackBuf []uint16
+import (
+	"image"
+	"io"
+
+	"tinygo.org/x/drivers/image/output"
 )
 
 // A portion of the image data consisting of data, x, y, w, and h is passed to
 // Callback. The size of the whole image is passed as width and height.
-// If the callback is not called, add the implementation to
-// image/png.readImagePass.
 type Callback func(data []uint16, x, y, w, h, width, height int16)
 
+// Decoder decodes JPEG images and passes the pixels to its callback. Unlike
+// the package-level functions, each Decoder has its own buffer, callback and
+// options, so that several images can be decoded at the same time, for
+// example to different displays.
+type Decoder struct {
+	out         output.Writer
+	memoryLimit int
+}
+
+// DefaultMemoryLimit is the default number of bytes used for the coefficients
+// of progressive images.
+const DefaultMemoryLimit = 16 * 1024
+
+// NewDecoder returns a decoder that passes the pixels to fn in the RGB565
+// format, in buf. Pixels are passed in several parts when they don't fit in
+// the buffer.
+func NewDecoder(buf []uint16, fn Callback) *Decoder {
+	return &Decoder{out: output.Writer{Buf: buf, Callback: output.Callback(fn)}}
+}
+
+// NewByteDecoder returns a decoder that passes the pixels to fn in the
+// format of opts, such as output.Mono, in buf.
+func NewByteDecoder(buf []byte, fn output.ByteCallback, opts output.Options) *Decoder {
+	return &Decoder{out: output.Writer{Options: opts, Bytes: buf, ByteCallback: fn}}
+}
+
+// SetOptions sets the format, scale and viewport of the pixels passed to the
+// callback. The RGB565 format needs a decoder made by NewDecoder, and the
+// others one made by NewByteDecoder.
+func (dec *Decoder) SetOptions(opts output.Options) {
+	dec.out.Options = opts
+}
+
+// SetMemoryLimit sets the number of bytes used for the coefficients of
+// progressive images, DefaultMemoryLimit if n is 0. Each block of 8x8
+// coefficients takes 128 bytes: a 320 pixels wide 4:2:0 image needs 15KB for
+// each band of 16 rows. Smaller limits decode the image in more bands,
+// reading it again for each.
+func (dec *Decoder) SetMemoryLimit(n int) {
+	dec.memoryLimit = n
+}
+
+// DecodeConfig returns the color model and dimensions of a JPEG image without
+// decoding the entire image.
+func (dec *Decoder) DecodeConfig(r io.Reader) (image.Config, error) {
+	return DecodeConfig(r)
+}
+
+// defaultDecoder is used by the package-level functions. It discards the
+// pixels until SetCallback is called.
+var defaultDecoder = *NewDecoder(make([]uint16, 8), func(data []uint16, x, y, w, h, width, height int16) {})
+
 // SetCallback registers the buffer and fn required for Callback. Callback can
 // be called multiple times by calling Decode().
+//
+// The buffer and callback are shared by the package-level functions: use a
+// Decoder to decode several images at the same time.
 func SetCallback(buf []uint16, fn Callback) {
-	callbackBuf = buf
-	callback = fn
+	defaultDecoder = *NewDecoder(buf, fn)
 }
@@ -90,6 +90,48 @@ func TestDCT(t *testing.T) {
 	}
 }
 
+func TestIDCTScaled(t *testing.T) {
+	r := rand.New(rand.NewSource(123))
+	blocks := testBlocks[:]
+	for i := 0; i < 20; i++ {
+		b := block{}
+		for j := range b {
+			b[j] = r.Int31()%512 - 256
+		}
+		blocks = append(blocks, b)
+	}
+
+	// Check that the scaled IDCT matches the slow n*n IDCT of the top-left
+	// coefficients.
+	for _, n := range []int{4, 2, 1} {
+		for i, b := range blocks {
+			got := b
+			idctScaled(&got, n)
+			var want block
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					sum := 0.0
+					for v := 0; v < n; v++ {
+						for u := 0; u < n; u++ {
+							sum += alpha(u) * alpha(v) * float64(b[8*v+u]) *
+								cosines[((2*x+1)*u*8/n)%32] *
+								cosines[((2*y+1)*v*8/n)%32]
+						}
+					}
+					want[8*y+x] = int32(math.Floor(sum/8 + 0.5))
+				}
+			}
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					if d := got[8*y+x] - want[8*y+x]; d < -1 || d > 1 {
+						t.Errorf("n=%d, i=%d: IDCT at %d,%d: got %d, want %d", n, i, x, y, got[8*y+x], want[8*y+x])
+					}
+				}
+			}
+		}
+	}
+}
+
 // differ reports whether any pair-wise elements in b0 and b1 differ by 2 or
 // more. That tolerance is because there isn't a single definitive decoding of
 // a given JPEG image, even before the YCbCr to RGB conversion; implementations
@@ -0,0 +1,293 @@
+package jpeg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	stdjpeg "image/jpeg"
+	"io"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/image/output"
+)
+
+// gradient returns a smooth image, which is encoded without large errors and
+// whose chroma doesn't change much within an MCU, since it is upsampled
+// differently by the standard package.
+func gradient(w, h int, gray bool) image.Image {
+	var img draw
+	if gray {
+		img = image.NewGray(image.Rect(0, 0, w, h))
+	} else {
+		img = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(2 * x), uint8(3 * y), uint8(100 + x - y), 0xFF})
+		}
+	}
+	return img
+}
+
+type draw interface {
+	image.Image
+	Set(x, y int, c color.Color)
+}
+
+func encode(c *qt.C, img image.Image) []byte {
+	var b bytes.Buffer
+	c.Assert(stdjpeg.Encode(&b, img, &stdjpeg.Options{Quality: 95}), qt.IsNil)
+	return b.Bytes()
+}
+
+// decodeRGB decodes r with opts in the RGB888 format, and the memory limit of
+// progressive images.
+func decodeRGB(c *qt.C, r io.Reader, opts output.Options, bufSize, limit int) (*image.RGBA, error) {
+	var img *image.RGBA
+	opts.Format = output.RGB888
+	dec := NewByteDecoder(make([]byte, bufSize), func(data []byte, x, y, w, h, width, height int16) {
+		if img == nil {
+			img = image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+		}
+		for j := 0; j < int(h); j++ {
+			for i := 0; i < int(w); i++ {
+				p := data[3*(j*int(w)+i):]
+				c.Assert(img.RGBAAt(int(x)+i, int(y)+j).A, qt.Equals, uint8(0), qt.Commentf("pixel drawn twice"))
+				img.SetRGBA(int(x)+i, int(y)+j, color.RGBA{p[0], p[1], p[2], 0xFF})
+			}
+		}
+	}, opts)
+	dec.SetMemoryLimit(limit)
+	err := dec.Decode(r)
+	return img, err
+}
+
+// decodeImage decodes r to an image, for the tests of the standard package.
+// Grayscale images are decoded to an *image.Gray.
+func decodeImage(r io.Reader) (image.Image, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	gray := image.NewGray(image.Rect(0, 0, cfg.Width, cfg.Height))
+	rgba := image.NewRGBA(gray.Rect)
+	opts := output.Options{Format: output.RGB888}
+	if cfg.ColorModel == color.GrayModel {
+		opts.Format = output.Gray8
+	}
+	dec := NewByteDecoder(make([]byte, 3*256), func(data []byte, x, y, w, h, width, height int16) {
+		for j := 0; j < int(h); j++ {
+			for i := 0; i < int(w); i++ {
+				if opts.Format == output.Gray8 {
+					gray.SetGray(int(x)+i, int(y)+j, color.Gray{data[j*int(w)+i]})
+					continue
+				}
+				p := data[3*(j*int(w)+i):]
+				rgba.SetRGBA(int(x)+i, int(y)+j, color.RGBA{p[0], p[1], p[2], 0xFF})
+			}
+		}
+	}, opts)
+	if err := dec.Decode(bytes.NewReader(b)); err != nil {
+		return nil, err
+	}
+	if opts.Format == output.Gray8 {
+		return gray, nil
+	}
+	return rgba, nil
+}
+
+// assertClose checks that got is close to the area r of want, scaled down by
+// 1<<scale.
+func assertClose(c *qt.C, got *image.RGBA, want image.Image, r image.Rectangle, scale uint, tolerance int) {
+	c.Assert(got.Bounds(), qt.Equals, image.Rect(0, 0, r.Dx(), r.Dy()))
+	f := 1 << scale
+	wb := want.Bounds()
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			var sum [3]int
+			n := 0
+			for j := (r.Min.Y + y) * f; j < (r.Min.Y+y+1)*f && j < wb.Max.Y; j++ {
+				for i := (r.Min.X + x) * f; i < (r.Min.X+x+1)*f && i < wb.Max.X; i++ {
+					cr, cg, cb, _ := want.At(i, j).RGBA()
+					sum[0] += int(cr >> 8)
+					sum[1] += int(cg >> 8)
+					sum[2] += int(cb >> 8)
+					n++
+				}
+			}
+			p := got.RGBAAt(x, y)
+			for k, v := range []uint8{p.R, p.G, p.B} {
+				if d := int(v) - sum[k]/n; d < -tolerance || d > tolerance {
+					c.Fatalf("pixel %d,%d channel %d: got %d, want %d", x, y, k, v, sum[k]/n)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeScaled(t *testing.T) {
+	c := qt.New(t)
+	for _, gray := range []bool{false, true} {
+		b := encode(c, gradient(70, 37, gray))
+		want, err := stdjpeg.Decode(bytes.NewReader(b))
+		c.Assert(err, qt.IsNil)
+		for scale := uint8(0); scale <= output.MaxScale; scale++ {
+			c.Run("", func(c *qt.C) {
+				c.Logf("gray %v, scale %d", gray, scale)
+				opts := output.Options{Scale: scale}
+				got, err := decodeRGB(c, bytes.NewReader(b), opts, 3*16*16, 0)
+				c.Assert(err, qt.IsNil)
+				tolerance := 12
+				if scale == output.MaxScale {
+					// a single chroma sample is left for 2x2 pixels
+					tolerance = 16
+				}
+				assertClose(c, got, want, opts.Bounds(70, 37), uint(scale), tolerance)
+			})
+		}
+	}
+}
+
+func TestDecodeViewport(t *testing.T) {
+	c := qt.New(t)
+	b := encode(c, gradient(64, 48, false))
+	want, err := stdjpeg.Decode(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	for _, opts := range []output.Options{
+		{Viewport: image.Rect(5, 7, 40, 20)},
+		{Viewport: image.Rect(20, 30, 100, 100)},
+		{Scale: 1, Viewport: image.Rect(3, 3, 10, 9)},
+	} {
+		for _, size := range []int{3 * 7, 3 * 256} {
+			got, err := decodeRGB(c, bytes.NewReader(b), opts, size, 0)
+			c.Assert(err, qt.IsNil)
+			assertClose(c, got, want, opts.Bounds(64, 48), uint(opts.Scale), 12)
+		}
+	}
+}
+
+func TestDecodeProgressiveBands(t *testing.T) {
+	c := qt.New(t)
+	for _, name := range []string{
+		"video-001.q50.420",
+		"video-001.q50.444",
+		"video-005.gray.q50",
+	} {
+		sequential, err := os.ReadFile("testdata/" + name + ".jpeg")
+		c.Assert(err, qt.IsNil)
+		b, err := os.ReadFile("testdata/" + name + ".progressive.jpeg")
+		c.Assert(err, qt.IsNil)
+		// Both images have the same pixels.
+		want, err := stdjpeg.Decode(bytes.NewReader(b))
+		c.Assert(err, qt.IsNil)
+		got, err := decodeRGB(c, bytes.NewReader(b), output.Options{}, 3*64, 0)
+		c.Assert(err, qt.IsNil)
+		assertClose(c, got, want, want.Bounds(), 0, 12)
+
+		for _, test := range []struct {
+			opts  output.Options
+			limit int
+		}{
+			{output.Options{}, 1 << 20},
+			// bands of a single row of MCUs
+			{output.Options{}, 8000},
+			{output.Options{Scale: 1}, 8000},
+			{output.Options{Scale: 2, Viewport: image.Rect(5, 3, 20, 12)}, 8000},
+		} {
+			comment := qt.Commentf("%s, %+v, limit %d", name, test.opts, test.limit)
+			want, err := decodeRGB(c, bytes.NewReader(sequential), test.opts, 3*64, 0)
+			c.Assert(err, qt.IsNil)
+			got, err := decodeRGB(c, bytes.NewReader(b), test.opts, 3*64, test.limit)
+			c.Assert(err, qt.IsNil, comment)
+			c.Assert(got.Pix, qt.DeepEquals, want.Pix, comment)
+		}
+	}
+}
+
+func TestDecodeProgressiveLimit(t *testing.T) {
+	c := qt.New(t)
+	b, err := os.ReadFile("testdata/video-001.q50.420.progressive.jpeg")
+	c.Assert(err, qt.IsNil)
+	want, err := stdjpeg.Decode(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+
+	// Without io.Seeker, the image is decoded only in a single band.
+	var r struct{ io.Reader }
+	r.Reader = bytes.NewReader(b)
+	got, err := decodeRGB(c, r, output.Options{}, 3*64, 1<<20)
+	c.Assert(err, qt.IsNil)
+	assertClose(c, got, want, want.Bounds(), 0, 12)
+
+	r.Reader = bytes.NewReader(b)
+	_, err = decodeRGB(c, r, output.Options{}, 3*64, 8000)
+	c.Assert(err, qt.ErrorMatches, "unsupported JPEG feature: progressive image larger than the memory limit, .*")
+
+	// A row of 10 MCUs of 6 blocks takes 7680 bytes.
+	_, err = decodeRGB(c, bytes.NewReader(b), output.Options{}, 3*64, 7679)
+	c.Assert(err, qt.ErrorMatches, "unsupported JPEG feature: progressive image too wide for the memory limit")
+}
+
+func TestDecodeFormats(t *testing.T) {
+	c := qt.New(t)
+	b := encode(c, gradient(32, 16, false))
+
+	// RGB565, with the package-level decoder
+	defer func(d Decoder) { defaultDecoder = d }(defaultDecoder)
+	var calls int
+	SetCallback(make([]uint16, 256), func(data []uint16, x, y, w, h, width, height int16) {
+		c.Assert([]int16{w, h, width, height}, qt.DeepEquals, []int16{16, 16, 32, 16})
+		c.Assert(data, qt.HasLen, 256)
+		calls++
+	})
+	_, err := Decode(bytes.NewReader(b))
+	c.Assert(err, qt.IsNil)
+	c.Assert(calls, qt.Equals, 2)
+
+	ramp := image.NewGray(image.Rect(0, 0, 32, 16))
+	for i := range ramp.Pix {
+		ramp.Pix[i] = uint8(i % 32 * 8)
+	}
+	b = encode(c, ramp)
+	for _, dither := range []output.Dither{output.Threshold, output.Ordered, output.Diffusion} {
+		pix := make([]byte, 4*16)
+		dec := NewByteDecoder(make([]byte, 16), func(data []byte, x, y, w, h, width, height int16) {
+			c.Assert(x%8, qt.Equals, int16(0))
+			n := (int(w) + 7) / 8
+			for j := 0; j < int(h); j++ {
+				copy(pix[(int(y)+j)*4+int(x)/8:], data[j*n:(j+1)*n])
+			}
+		}, output.Options{Format: output.Mono, Dither: dither})
+		c.Assert(dec.Decode(bytes.NewReader(b)), qt.IsNil)
+		// the left of the image is dark and the right light
+		var left, right int
+		for y := 0; y < 16; y++ {
+			left += ones(pix[4*y])
+			right += ones(pix[4*y+3])
+		}
+		c.Assert(left < 16 && right > 100, qt.IsTrue, qt.Commentf("dither %d: %d, %d", dither, left, right))
+	}
+}
+
+func ones(b byte) int {
+	n := 0
+	for ; b != 0; b >>= 1 {
+		n += int(b & 1)
+	}
+	return n
+}
+
+func TestDecodeErrors(t *testing.T) {
+	c := qt.New(t)
+	b := encode(c, gradient(8, 8, false))
+	dec := NewByteDecoder(nil, nil, output.Options{Format: output.Gray8})
+	c.Assert(dec.Decode(bytes.NewReader(b)), qt.Equals, output.ErrNoBuffer)
+
+	dec.SetOptions(output.Options{Scale: output.MaxScale + 1})
+	c.Assert(dec.Decode(bytes.NewReader(b)), qt.ErrorMatches, "output: scale out of range")
+}
@@ -0,0 +1,160 @@
+package jpeg
+
+import (
+	"io"
+)
+
+// band holds the coefficients of the blocks of the MCU rows start to end of a
+// progressive image, row by row for each component.
+type band struct {
+	start, end int
+	// rows is the number of MCU rows of a band, and mxx the number of MCUs
+	// in a row.
+	rows, mxx int
+	// offset is the index of the first block of each component in coeffs.
+	offset [maxComponents]int
+	coeffs []int16
+}
+
+// scanState is where the decoding of a progressive scan stopped at the end
+// of a band.
+type scanState struct {
+	pos         int64 // see decoder.pos
+	nUnreadable int
+	bits        bits
+	eobRun      uint16
+	dc          [maxComponents]int32
+	mcu         int
+	expectedRST uint8
+	blockCount  int
+}
+
+// makeBand allocates the coefficients of the largest band of MCU rows that
+// fits in the memory limit.
+func (d *decoder) makeBand(mxx, myy int) error {
+	limit := d.memoryLimit
+	if limit <= 0 {
+		limit = DefaultMemoryLimit
+	}
+	// The coefficients are stored as int16, since they have at most 11 bits
+	// before dequantization for 8-bit images.
+	blocks := 0
+	for i := 0; i < d.nComp; i++ {
+		blocks += mxx * d.comp[i].h * d.comp[i].v
+	}
+	rows := limit / (2 * blockSize * blocks)
+	if rows == 0 {
+		return UnsupportedError("progressive image too wide for the memory limit")
+	}
+	if rows < myy {
+		if _, ok := d.r.(io.Seeker); !ok {
+			return UnsupportedError("progressive image larger than the memory limit, from an io.Reader that can't seek")
+		}
+	} else {
+		rows = myy
+	}
+	d.band = band{end: rows, rows: rows, mxx: mxx}
+	n := 0
+	for i := 0; i < d.nComp; i++ {
+		d.band.offset[i] = n
+		n += rows * mxx * d.comp[i].h * d.comp[i].v
+	}
+	d.band.coeffs = make([]int16, n*blockSize)
+	return nil
+}
+
+// block returns the coefficients of the block at bx, by of a component.
+func (b *band) block(compIndex, bx, by int, comp *component) []int16 {
+	i := b.offset[compIndex] + (by-b.start*comp.v)*b.mxx*comp.h + bx
+	return b.coeffs[i*blockSize : (i+1)*blockSize]
+}
+
+// nextBand passes the current band to the output, and prepares to read the
+// image again for the next one. It returns false after the last band.
+func (d *decoder) nextBand() (bool, error) {
+	if err := d.reconstructBand(); err != nil {
+		return false, err
+	}
+	myy := (d.height + 8*d.comp[0].v - 1) / (8 * d.comp[0].v)
+	size := 8 >> d.out.Scale
+	next := d.mcuBounds(0, d.band.end, size)
+	if d.band.end == myy || next.Min.Y >= d.out.Bounds().Max.Y {
+		return false, nil
+	}
+	d.band.start = d.band.end
+	d.band.end += d.band.rows
+	if d.band.end > myy {
+		d.band.end = myy
+	}
+	for i := range d.band.coeffs {
+		d.band.coeffs[i] = 0
+	}
+	d.scan = 0
+	return true, d.seek(d.start, 0)
+}
+
+// reconstructBand passes the MCUs of the current band to the output.
+func (d *decoder) reconstructBand() error {
+	size := 8 >> d.out.Scale
+	rgb := d.nComp == 3 && d.isRGB()
+	var b block
+	for my := d.band.start; my < d.band.end; my++ {
+		for mx := 0; mx < d.band.mxx; mx++ {
+			area := d.mcuBounds(mx, my, size)
+			if !area.Overlaps(d.out.Bounds()) {
+				continue
+			}
+			for i := 0; i < d.nComp; i++ {
+				comp := &d.comp[i]
+				for j := 0; j < comp.h*comp.v; j++ {
+					bx := comp.h*mx + j%comp.h
+					by := comp.v*my + j/comp.h
+					for k, c := range d.band.block(i, bx, by, comp) {
+						b[k] = int32(c)
+					}
+					dst, err := d.reconstructBlock(&b, bx, by, i)
+					if err != nil {
+						return err
+					}
+					if d.nComp == 1 {
+						d.out.Block(area.Min.X, area.Min.Y, size, size, dst, 1, size)
+						continue
+					}
+					d.storeBlock(dst, i, j, size)
+				}
+			}
+			if d.nComp != 1 {
+				d.writeMCU(area, rgb)
+			}
+		}
+	}
+	return nil
+}
+
+// pos returns the offset of the next byte to read from the start of r.
+func (d *decoder) pos() int64 {
+	return d.offset - int64(d.bytes.j-d.bytes.i)
+}
+
+// seek moves to the offset pos from the start of r, with the n bytes before
+// it to unread, as in d.bytes.nUnreadable.
+func (d *decoder) seek(pos int64, n int) error {
+	if pos == d.pos() && n == d.bytes.nUnreadable {
+		return nil
+	}
+	from := pos - int64(n)
+	if _, err := d.r.(io.Seeker).Seek(from-d.offset, io.SeekCurrent); err != nil {
+		return err
+	}
+	d.offset = from
+	d.bytes.i, d.bytes.j = 0, 0
+	for d.bytes.j < n {
+		d.bytes.i = d.bytes.j
+		if err := d.fill(); err != nil {
+			return err
+		}
+	}
+	d.bytes.i = n
+	d.bytes.nUnreadable = n
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"io"
 
 	"tinygo.org/x/drivers/image/internal/imageutil"
+	"tinygo.org/x/drivers/image/output"
 )
 
 // A FormatError reports that the input is not a valid JPEG.
@@ -104,8 +105,12 @@ type bits struct {
 }
 
 type decoder struct {
+	out  *output.Writer
 	r    io.Reader
 	bits bits
+	// offset is the number of bytes read from r, and start the offset of the
+	// data after the SOI marker.
+	offset, start int64
 	// bytes is a byte buffer, similar to a bufio.Reader, except that it
 	// has to be able to unread more than 1 byte, due to byte stuffing.
 	// Byte stuffing is specified in section F.1.2.3.
@@ -141,11 +146,24 @@ type decoder struct {
 	adobeTransform      uint8
 	eobRun              uint16 // End-of-Band run, specified in section G.1.2.2.
 
-	comp       [maxComponents]component
-	progCoeffs [maxComponents][]block // Saved state between progressive-mode scans.
-	huff       [maxTc + 1][maxTh + 1]huffman
-	quant      [maxTq + 1]block // Quantization tables, in zig-zag order.
-	tmp        [2 * blockSize]byte
+	comp  [maxComponents]component
+	huff  [maxTc + 1][maxTh + 1]huffman
+	quant [maxTq + 1]block // Quantization tables, in zig-zag order.
+	tmp   [2 * blockSize]byte
+
+	// Progressive images are decoded one band of MCU rows at a time, whose
+	// coefficients fit in memoryLimit bytes. The scans are read again for
+	// each band, from where they stopped at the end of the previous one.
+	memoryLimit int
+	band        band
+	scans       []scanState
+	scan        int // index of the current scan in scans
+
+	// processSOSBuf holds the pixels of an MCU of up to 4 blocks, 3 bytes
+	// per pixel, built by processSOS.
+	processSOSBuf [3 * 8 * 8 * 4]byte
+	// reconstructBlockBuf holds the pixels returned by reconstructBlock.
+	reconstructBlockBuf [64]byte
 }
 
 // fill fills up the d.bytes.buf buffer from the underlying io.Reader. It
@@ -164,6 +182,7 @@ func (d *decoder) fill() error {
 	// Fill in the rest of the buffer.
 	n, err := d.r.Read(d.bytes.buf[d.bytes.j:])
 	d.bytes.j += n
+	d.offset += int64(n)
 	if n > 0 {
 		err = nil
 	}
@@ -531,6 +550,7 @@ func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 	if d.tmp[0] != 0xff || d.tmp[1] != soiMarker {
 		return nil, FormatError("missing SOI marker")
 	}
+	d.start = d.pos()
 
 	// Process the remaining segments until the End Of Image marker.
 	for {
@@ -579,6 +599,15 @@ func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 			}
 		}
 		if marker == eoiMarker { // End Of Image.
+			if d.progressive && d.band.coeffs != nil {
+				more, err := d.nextBand()
+				if err != nil {
+					return nil, err
+				}
+				if more {
+					continue
+				}
+			}
 			break
 		}
 		if rst0Marker <= marker && marker <= rst7Marker {
@@ -603,6 +632,11 @@ func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 
 		switch marker {
 		case sof0Marker, sof1Marker, sof2Marker:
+			if d.band.start > 0 {
+				// The frame was read with the first band.
+				err = d.ignore(n)
+				break
+			}
 			d.baseline = marker == sof0Marker
 			d.progressive = marker == sof2Marker
 			err = d.processSOF(n)
@@ -650,11 +684,6 @@ func (d *decoder) decode(r io.Reader, configOnly bool) (image.Image, error) {
 		}
 	}
 
-	if d.progressive {
-		if err := d.reconstructProgressiveImage(); err != nil {
-			return nil, err
-		}
-	}
 	if d.img1 != nil {
 		return d.img1, nil
 	}
@@ -774,9 +803,18 @@ func (d *decoder) convertToRGB() (image.Image, error) {
 // Decode reads a JPEG image from r. Different from the standard package, the
 // decoded result will be received by the callback set by SetCallback().
 func Decode(r io.Reader) (image.Image, error) {
-	var d decoder
+	return nil, defaultDecoder.Decode(r)
+}
+
+// Decode reads a JPEG image from r, and passes its pixels to the callback of
+// the decoder.
+//
+// Progressive images whose coefficients don't fit in the memory limit are
+// decoded in several bands, which needs r to be an io.ReadSeeker.
+func (dec *Decoder) Decode(r io.Reader) error {
+	d := &decoder{out: &dec.out, memoryLimit: dec.memoryLimit}
 	_, err := d.decode(r, false)
-	return nil, err
+	return err
 }
 
 // DecodeConfig returns the color model and dimensions of a JPEG image without
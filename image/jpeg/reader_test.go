@@ -472,7 +472,7 @@ func TestExtraneousData(t *testing.T) {
 		buf.WriteString("\xff\xd9")
 
 		// Check that we can still decode the resultant image.
-		got, err := Decode(buf)
+		got, err := decodeImage(buf)
 		if err != nil {
 			t.Errorf("could not decode image #%d: %v", i, err)
 			nerr++
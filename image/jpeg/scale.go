@@ -0,0 +1,95 @@
+package jpeg
+
+import (
+	"image"
+	"image/color"
+)
+
+// idctCos4 and idctCos2 are the weights of the coefficients u of the n-point
+// inverse DCT for the pixels x, at x*n+u: 4096*c(u)*cos((2x+1)uπ/2n), where
+// c(0) = 1/√2 and c(u) = 1 otherwise.
+var (
+	idctCos4 = [16]int32{
+		2896, 3784, 2896, 1567,
+		2896, 1567, -2896, -3784,
+		2896, -1567, -2896, 3784,
+		2896, -3784, 2896, -1567,
+	}
+	idctCos2 = [4]int32{
+		2896, 2896,
+		2896, -2896,
+	}
+)
+
+// idctScaled performs the inverse DCT of the top-left n×n coefficients of src,
+// for n = 4, 2 or 1, which gives the block scaled down to n×n pixels. The
+// pixels are stored in the top-left n×n values of src, at the same level as
+// for idct.
+//
+// This is much faster than scaling the output of idct, since the higher
+// frequencies that would be lost are not computed.
+func idctScaled(src *block, n int) {
+	if n == 1 {
+		src[0] = (src[0] + 4) >> 3
+		return
+	}
+	cos := idctCos2[:]
+	if n == 4 {
+		cos = idctCos4[:]
+	}
+	var tmp [16]int32
+	for v := 0; v < n; v++ {
+		for x := 0; x < n; x++ {
+			s := int32(0)
+			for u := 0; u < n; u++ {
+				s += cos[x*n+u] * src[v*8+u]
+			}
+			tmp[v*n+x] = (s + 1<<11) >> 12
+		}
+	}
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			s := int32(0)
+			for v := 0; v < n; v++ {
+				s += cos[y*n+v] * tmp[v*n+x]
+			}
+			// the 2-D inverse DCT is scaled by 1/4
+			src[y*8+x] = (s + 1<<13) >> 14
+		}
+	}
+}
+
+// storeBlock copies the n×n pixels of the block j of a component to the
+// current MCU, in processSOSBuf, upsampling the chroma.
+func (d *decoder) storeBlock(src []byte, compIndex, j, n int) {
+	h0, v0 := d.comp[0].h, d.comp[0].v
+	hi, vi := d.comp[compIndex].h, d.comp[compIndex].v
+	sx, sy := h0/hi, v0/vi
+	stride := 3 * h0 * n
+	x0, y0 := (j%hi)*n*sx, (j/hi)*n*sy
+	for y := 0; y < n*sy; y++ {
+		row := d.processSOSBuf[(y0+y)*stride+compIndex:]
+		s := src[(y/sy)*n:]
+		for x := 0; x < n*sx; x++ {
+			row[3*(x0+x)] = s[x/sx]
+		}
+	}
+}
+
+// mcuBounds returns the area of the MCU at mx, my in the scaled image, for
+// MCUs of n×n blocks.
+func (d *decoder) mcuBounds(mx, my, n int) image.Rectangle {
+	w, h := d.comp[0].h*n, d.comp[0].v*n
+	return image.Rect(mx*w, my*h, (mx+1)*w, (my+1)*h)
+}
+
+// writeMCU converts the current MCU to RGB and passes it to the output.
+func (d *decoder) writeMCU(r image.Rectangle, rgb bool) {
+	buf := d.processSOSBuf[:3*r.Dx()*r.Dy()]
+	if !rgb {
+		for i := 0; i < len(buf); i += 3 {
+			buf[i], buf[i+1], buf[i+2] = color.YCbCrToRGB(buf[i], buf[i+1], buf[i+2])
+		}
+	}
+	d.out.Block(r.Min.X, r.Min.Y, r.Dx(), r.Dy(), buf, 3, 3*r.Dx())
+}
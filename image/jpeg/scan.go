@@ -6,7 +6,6 @@ package jpeg
 
 import (
 	"image"
-	"image/color"
 )
 
 // makeImg allocates and initializes the destination image.
@@ -48,10 +47,6 @@ func (d *decoder) makeImg(mxx, myy int) {
 	}
 }
 
-// processSOSBuf is a Buffer for creating RGBBitmap in processSOS. It needs to
-// hold four 8 x 8 pix 24bit color images.
-var processSOSBuf [3 * 8 * 8 * 4]byte
-
 // Specified in section B.2.3.
 func (d *decoder) processSOS(n int) error {
 	if d.nComp == 0 {
@@ -156,15 +151,24 @@ func (d *decoder) processSOS(n int) error {
 		// the amount of code changes down, the image is created as a 1 x 1
 		// image at this point.
 		d.makeImg(1, 1)
-	}
-	if d.progressive {
-		for i := 0; i < nComp; i++ {
-			compIndex := scan[i].compIndex
-			if d.progCoeffs[compIndex] == nil {
-				d.progCoeffs[compIndex] = make([]block, mxx*myy*d.comp[compIndex].h*d.comp[compIndex].v)
-			}
+		if d.nComp == 4 {
+			return UnsupportedError("CMYK image")
 		}
+		if h0*v0 > 4 {
+			// processSOSBuf holds MCUs of up to 4 blocks of luma.
+			return errUnsupportedSubsamplingRatio
+		}
+		if err := d.out.Begin(d.width, d.height); err != nil {
+			return err
+		}
+	}
+	if !d.progressive && d.nComp != 1 && nComp != d.nComp {
+		// The blocks of the components are drawn together, by MCU.
+		return UnsupportedError("non-interleaved color scan")
 	}
+	// size is the size of the blocks of pixels, scaled down by the output.
+	size := 8 >> d.out.Scale
+	rgb := d.nComp == 3 && d.isRGB()
 
 	d.bits = bits{}
 	mcu, expectedRST := 0, uint8(rst0Marker)
@@ -177,8 +181,32 @@ func (d *decoder) processSOS(n int) error {
 		bx, by     int
 		blockCount int
 	)
-	for my := 0; my < myy; my++ {
+	// Progressive scans are decoded for the MCU rows of the current band,
+	// from where the previous band stopped.
+	my0, my1 := 0, myy
+	if d.progressive {
+		if d.band.coeffs == nil {
+			if err := d.makeBand(mxx, myy); err != nil {
+				return err
+			}
+		}
+		if d.scan == len(d.scans) {
+			d.scans = append(d.scans, scanState{pos: d.pos(), expectedRST: rst0Marker})
+		}
+		s := &d.scans[d.scan]
+		if err := d.seek(s.pos, s.nUnreadable); err != nil {
+			return err
+		}
+		d.bits, d.eobRun, dc = s.bits, s.eobRun, s.dc
+		mcu, expectedRST, blockCount = s.mcu, s.expectedRST, s.blockCount
+		my0, my1 = d.band.start, d.band.end
+	}
+	for my := my0; my < my1; my++ {
 		for mx := 0; mx < mxx; mx++ {
+			// The blocks of MCUs outside of the output are decoded but
+			// not reconstructed.
+			area := d.mcuBounds(mx, my, size)
+			visible := area.Overlaps(d.out.Bounds())
 			for i := 0; i < nComp; i++ {
 				compIndex := scan[i].compIndex
 				hi := d.comp[compIndex].h
@@ -224,7 +252,9 @@ func (d *decoder) processSOS(n int) error {
 
 					// Load the previous partially decoded coefficients, if applicable.
 					if d.progressive {
-						b = d.progCoeffs[compIndex][by*mxx*hi+bx]
+						for k, c := range d.band.block(int(compIndex), bx, by, &d.comp[compIndex]) {
+							b[k] = int32(c)
+						}
 					} else {
 						b = block{}
 					}
@@ -296,73 +326,42 @@ func (d *decoder) processSOS(n int) error {
 
 					if d.progressive {
 						// Save the coefficients.
-						d.progCoeffs[compIndex][by*mxx*hi+bx] = b
+						c := d.band.block(int(compIndex), bx, by, &d.comp[compIndex])
+						for k := range c {
+							c[k] = int16(b[k])
+						}
 						// At this point, we could call reconstructBlock to dequantize and perform the
 						// inverse DCT, to save early stages of a progressive image to the *image.YCbCr
 						// buffers (the whole point of progressive encoding), but in Go, the jpeg.Decode
 						// function does not return until the entire image is decoded, so we "continue"
 						// here to avoid wasted computation. Instead, reconstructBlock is called on each
-						// accumulated block by the reconstructProgressiveImage method after all of the
-						// SOS markers are processed.
+						// accumulated block by the reconstructBand method after all of the SOS
+						// markers are processed for the band.
 						continue
 					}
-					if dst, err := d.reconstructBlock(&b, bx, by, int(compIndex)); err != nil {
-						return err
-					} else {
-						// Currently, only the YCbCr422 format is supported.
-						switch compIndex {
-						case 0: // Y
-							bx8 := bx * 8
-							by8 := by * 8
-							bx16 := bx8 % 16
-							by16 := by8 % 16
-							for cy := 0; cy < 8; cy++ {
-								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy+by16)*16+(cx+bx16))*3+0] = dst[cy*8+cx]
-								}
-							}
-						case 1: // Cb
-							bx8 := bx * 8 * 2
-							by8 := by * 8 * 2
-							bx16 := bx8 % 16
-							by16 := by8 % 16
-
-							for cy := 0; cy < 8; cy++ {
-								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+1] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+1] = dst[cy*8+cx]
-								}
-							}
-						case 2: // Cr
-							bx8 := bx * 8 * 2
-							by8 := by * 8 * 2
-							bx16 := bx8 % 16
-							by16 := by8 % 16
-
-							for cy := 0; cy < 8; cy++ {
-								for cx := 0; cx < 8; cx++ {
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+0+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+0+bx16))*3+2] = dst[cy*8+cx]
-									processSOSBuf[((cy*2+1+by16)*16+(cx*2+1+bx16))*3+2] = dst[cy*8+cx]
-								}
-							}
-
-							for cy := 0; cy < 16; cy++ {
-								for cx := 0; cx < 16; cx++ {
-									yy := processSOSBuf[(cy*16+cx)*3+0]
-									cb := processSOSBuf[(cy*16+cx)*3+1]
-									cr := processSOSBuf[(cy*16+cx)*3+2]
-									r, g, b := color.YCbCrToRGB(yy, cb, cr)
-									callbackBuf[cy*16+cx] = uint16(((uint16(r) << 8) & 0xF800) +
-										(((uint16(g) << 8) & 0xFC00) >> 5) +
-										(((uint16(b) << 8) & 0xF800) >> 11))
-								}
-							}
-							callback(callbackBuf[:8*8*4], int16(bx8-bx16), int16(by8-by16), 16, 16, int16(d.width), int16(d.height))
+					if nComp == 1 {
+						// A grayscale image, whose blocks are passed one by one.
+						r := image.Rect(bx*size, by*size, (bx+1)*size, (by+1)*size)
+						if !r.Overlaps(d.out.Bounds()) {
+							continue
+						}
+						dst, err := d.reconstructBlock(&b, bx, by, int(compIndex))
+						if err != nil {
+							return err
 						}
+						d.out.Block(r.Min.X, r.Min.Y, size, size, dst, 1, size)
+						continue
+					}
+					if !visible {
+						continue
+					}
+					dst, err := d.reconstructBlock(&b, bx, by, int(compIndex))
+					if err != nil {
+						return err
+					}
+					d.storeBlock(dst, int(compIndex), j, size)
+					if i == nComp-1 && j == hi*vi-1 {
+						d.writeMCU(area, rgb)
 					}
 				} // for j
 			} // for i
@@ -409,6 +408,20 @@ func (d *decoder) processSOS(n int) error {
 		} // for mx
 	} // for my
 
+	if d.progressive {
+		// The rest of the scan is skipped as extraneous data by decode.
+		d.scans[d.scan] = scanState{
+			pos:         d.pos(),
+			nUnreadable: d.bytes.nUnreadable,
+			bits:        d.bits,
+			eobRun:      d.eobRun,
+			dc:          dc,
+			mcu:         mcu,
+			expectedRST: expectedRST,
+			blockCount:  blockCount,
+		}
+		d.scan++
+	}
 	return nil
 }
 
@@ -518,34 +531,6 @@ func (d *decoder) refineNonZeroes(b *block, zig, zigEnd, nz, delta int32) (int32
 	return zig, nil
 }
 
-func (d *decoder) reconstructProgressiveImage() error {
-	// The h0, mxx, by and bx variables have the same meaning as in the
-	// processSOS method.
-	h0 := d.comp[0].h
-	mxx := (d.width + 8*h0 - 1) / (8 * h0)
-	for i := 0; i < d.nComp; i++ {
-		if d.progCoeffs[i] == nil {
-			continue
-		}
-		v := 8 * d.comp[0].v / d.comp[i].v
-		h := 8 * d.comp[0].h / d.comp[i].h
-		stride := mxx * d.comp[i].h
-		for by := 0; by*v < d.height; by++ {
-			for bx := 0; bx*h < d.width; bx++ {
-				if _, err := d.reconstructBlock(&d.progCoeffs[i][by*stride+bx], bx, by, i); err != nil {
-					return err
-				}
-			}
-		}
-	}
-	return nil
-}
-
-// reconstructBlockBuf is a Buffer for the 8x8 pix data to be processed by
-// reconstructBlock. It is defined and used as a package variable to reduce
-// memory usage.
-var reconstructBlockBuf [64]byte
-
 // reconstructBlock dequantizes, performs the inverse DCT and stores the block
 // to the image.
 // In the original Go source, it was expanded to a position that matched the
@@ -556,12 +541,18 @@ func (d *decoder) reconstructBlock(b *block, bx, by, compIndex int) ([]byte, err
 	for zig := 0; zig < blockSize; zig++ {
 		b[unzig[zig]] *= qt[zig]
 	}
-	idct(b)
+	// The output can scale the image down with the IDCT.
+	n := 8 >> d.out.Scale
+	if n == 8 {
+		idct(b)
+	} else {
+		idctScaled(b, n)
+	}
 	// Level shift by +128, clip to [0, 255], and write to dst.
-	var buf = reconstructBlockBuf[:]
-	for y := 0; y < 8; y++ {
+	var buf = d.reconstructBlockBuf[:n*n]
+	for y := 0; y < n; y++ {
 		y8 := y * 8
-		for x := 0; x < 8; x++ {
+		for x := 0; x < n; x++ {
 			c := b[y8+x]
 			if c < -128 {
 				c = 0
@@ -570,7 +561,7 @@ func (d *decoder) reconstructBlock(b *block, bx, by, compIndex int) ([]byte, err
 			} else {
 				c += 128
 			}
-			buf[y*8+x] = uint8(c)
+			buf[y*n+x] = uint8(c)
 		}
 	}
 	return buf, nil
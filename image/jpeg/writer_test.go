@@ -171,7 +171,7 @@ func TestWriteGrayscale(t *testing.T) {
 	if err := Encode(&buf, m0, nil); err != nil {
 		t.Fatal(err)
 	}
-	m1, err := Decode(&buf)
+	m1, err := decodeImage(&buf)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,394 @@
+// Package output converts the pixels produced by the image decoders to the
+// format of a display, scaling them down and cropping them on the way, so that
+// large images can be drawn to small screens without preprocessing them.
+//
+// The options are set on the decoders of the png and jpeg packages:
+//
+//	dec := jpeg.NewByteDecoder(buf, fn, output.Options{
+//		Format: output.Mono,
+//		Dither: output.Diffusion,
+//		Scale:  2, // a quarter of the width and height
+//	})
+package output // import "tinygo.org/x/drivers/image/output"
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"tinygo.org/x/drivers/image/dither"
+)
+
+// Format is the format of the pixels passed to the callbacks.
+type Format uint8
+
+const (
+	// RGB565 is 16 bits per pixel, passed to a Callback.
+	RGB565 Format = iota
+
+	// RGB888 is 3 bytes per pixel, red, green and blue.
+	RGB888
+
+	// Gray8 is 1 byte per pixel, from black to white.
+	Gray8
+
+	// Mono is 1 bit per pixel, 1 for white, with the leftmost pixel in the
+	// most significant bit. Each row starts on a new byte.
+	Mono
+)
+
+// Dither is the method used to reduce pixels to the Mono format.
+type Dither uint8
+
+const (
+	// Threshold makes pixels lighter than mid-gray white.
+	Threshold Dither = iota
+
+	// Ordered uses a 4x4 Bayer pattern, which is fast and stable.
+	Ordered
+
+	// Diffusion uses Floyd–Steinberg error diffusion, which looks better
+	// for photos but needs memory for a row of pixels, or a row of blocks
+	// for JPEG images.
+	Diffusion
+)
+
+// MaxScale is the largest Scale: 1/8 of the width and height.
+const MaxScale = 3
+
+// Options select the format, size and area of the pixels passed to the
+// callbacks.
+type Options struct {
+	Format Format
+	Dither Dither
+
+	// Scale divides the width and height of the image by 1<<Scale.
+	Scale uint8
+
+	// Viewport is the area of the scaled image to draw. Coordinates passed to
+	// the callbacks are relative to it, and the size of the image passed to
+	// them is its size. The zero value draws the whole image.
+	Viewport image.Rectangle
+}
+
+// Bounds returns the area drawn of an image of the given size, in the
+// coordinates of the scaled image.
+func (o Options) Bounds(width, height int) image.Rectangle {
+	f := 1<<o.Scale - 1
+	r := image.Rect(0, 0, (width+f)>>o.Scale, (height+f)>>o.Scale)
+	if o.Viewport.Empty() {
+		return r
+	}
+	return o.Viewport.Intersect(r)
+}
+
+// A portion of the image data consisting of data, x, y, w, and h is passed to
+// Callback, in the RGB565 format. The size of the whole image is passed as
+// width and height.
+type Callback func(data []uint16, x, y, w, h, width, height int16)
+
+// ByteCallback is like Callback, for the RGB888, Gray8 and Mono formats.
+type ByteCallback func(data []byte, x, y, w, h, width, height int16)
+
+var (
+	// ErrNoBuffer is returned when there is no buffer for the format of the
+	// options, or when it doesn't hold a pixel.
+	ErrNoBuffer = errors.New("output: no buffer for the format")
+
+	errScale = errors.New("output: scale out of range")
+)
+
+// ordered only computes indexes, so it doesn't wrap a display.
+var ordered = dither.Ordered{Palette: dither.BlackWhite}
+
+// Writer converts, scales and crops pixels as set by its options, and passes
+// them to its callbacks in Buf or Bytes. When the buffer is too small for the
+// pixels given to the writer, they are passed in several parts.
+type Writer struct {
+	Options
+
+	// Buf and Callback receive the pixels in the RGB565 format.
+	Buf      []uint16
+	Callback Callback
+
+	// Bytes and ByteCallback receive the pixels in the other formats.
+	Bytes        []byte
+	ByteCallback ByteCallback
+
+	width, height int
+	bounds        image.Rectangle
+
+	// sums adds up the pixels of the rows being scaled, 3 values per pixel.
+	sums []uint16
+	rows int
+	rgb  []byte
+
+	// band holds gray pixels of a row of blocks until it can be dithered.
+	band []byte
+	fs   *dither.FloydSteinberg
+	line []color.RGBA
+}
+
+// Begin starts an image of the given size.
+func (w *Writer) Begin(width, height int) error {
+	if w.Scale > MaxScale {
+		return errScale
+	}
+	if w.capacity() < w.rowSize(1) {
+		return ErrNoBuffer
+	}
+	w.width, w.height = width, height
+	w.bounds = w.Options.Bounds(width, height)
+	w.rows = 0
+	if w.Format == Mono && w.Dither == Diffusion {
+		n := w.bounds.Dx()
+		if w.fs == nil || len(w.line) != n {
+			w.fs = dither.NewFloydSteinberg(dither.BlackWhite, n)
+			w.line = make([]color.RGBA, n)
+		} else {
+			w.fs.Reset()
+		}
+	}
+	return nil
+}
+
+// Bounds returns the area of the scaled image that is drawn, as computed by
+// Begin.
+func (w *Writer) Bounds() image.Rectangle {
+	return w.bounds
+}
+
+// Row passes row y of the image, at its full size, in src. Pixels are 1 byte
+// for gray, 3 for RGB and 4 for RGBA, whose alpha is ignored. The rows must be
+// passed from top to bottom.
+func (w *Writer) Row(y int, src []byte, bpp int) {
+	if w.Scale == 0 {
+		w.Block(0, y, w.width, 1, src, bpp, len(src))
+		return
+	}
+	sy := y >> w.Scale
+	if sy < w.bounds.Min.Y || sy >= w.bounds.Max.Y {
+		return
+	}
+	n := w.bounds.Dx()
+	if len(w.sums) < 3*n {
+		w.sums = make([]uint16, 3*n)
+		w.rgb = make([]byte, 3*n)
+	}
+	if w.rows == 0 {
+		for i := range w.sums {
+			w.sums[i] = 0
+		}
+	}
+	f := 1 << w.Scale
+	for i := 0; i < n; i++ {
+		x0 := (w.bounds.Min.X + i) << w.Scale
+		x1 := x0 + f
+		if x1 > w.width {
+			x1 = w.width
+		}
+		s := w.sums[3*i : 3*i+3]
+		for x := x0; x < x1; x++ {
+			r, g, b := rgb(src[x*bpp:], bpp)
+			s[0] += uint16(r)
+			s[1] += uint16(g)
+			s[2] += uint16(b)
+		}
+	}
+	w.rows++
+	if w.rows < f && y < w.height-1 {
+		return
+	}
+	for i := 0; i < n; i++ {
+		cols := w.width - (w.bounds.Min.X+i)<<w.Scale
+		if cols > f {
+			cols = f
+		}
+		div := uint16(cols * w.rows)
+		for c := 3 * i; c < 3*i+3; c++ {
+			w.rgb[c] = byte((w.sums[c] + div/2) / div)
+		}
+	}
+	w.rows = 0
+	w.Block(w.bounds.Min.X, sy, n, 1, w.rgb, 3, 3*n)
+}
+
+// Block passes a block of bw x bh pixels of the scaled image at x, y, in src,
+// with rows of stride bytes. Pixels are as for Row. The blocks must be passed
+// from left to right and top to bottom, and the blocks of a row must have the
+// same y and height.
+func (w *Writer) Block(x, y, bw, bh int, src []byte, bpp, stride int) {
+	r := image.Rect(x, y, x+bw, y+bh).Intersect(w.bounds)
+	if r.Empty() {
+		return
+	}
+	src = src[(r.Min.Y-y)*stride+(r.Min.X-x)*bpp:]
+	if w.Format == Mono && w.Dither == Diffusion {
+		w.diffuse(r, src, bpp, stride)
+		return
+	}
+	w.write(r, src, bpp, stride)
+}
+
+// diffuse collects the gray pixels of a row of blocks, and dithers them once
+// the row reaches the right of the viewport.
+func (w *Writer) diffuse(r image.Rectangle, src []byte, bpp, stride int) {
+	n := w.bounds.Dx()
+	if len(w.band) < n*r.Dy() {
+		w.band = make([]byte, n*r.Dy())
+	}
+	for j := 0; j < r.Dy(); j++ {
+		line := w.band[j*n+r.Min.X-w.bounds.Min.X:]
+		for i := 0; i < r.Dx(); i++ {
+			line[i] = gray(src[j*stride+i*bpp:], bpp)
+		}
+	}
+	if r.Max.X < w.bounds.Max.X {
+		return
+	}
+	for j := 0; j < r.Dy(); j++ {
+		line := w.band[j*n : (j+1)*n]
+		for i, v := range line {
+			w.line[i] = color.RGBA{v, v, v, 0xFF}
+		}
+		w.fs.Dither(w.line)
+		for i, c := range w.line {
+			line[i] = c.R
+		}
+	}
+	// the pixels are black or white now, so the threshold keeps them
+	r.Min.X, r.Max.X = w.bounds.Min.X, w.bounds.Max.X
+	w.write(r, w.band, 1, n)
+}
+
+// write converts the pixels of r and passes them to the callback, in as many
+// parts as needed to fit in the buffer.
+func (w *Writer) write(r image.Rectangle, src []byte, bpp, stride int) {
+	bw, bh := r.Dx(), r.Dy()
+	x, y := r.Min.X-w.bounds.Min.X, r.Min.Y-w.bounds.Min.Y
+	size := w.capacity()
+	cw := bw
+	if w.rowSize(bw) > size {
+		cw = w.fit(size)
+	}
+	rows := size / w.rowSize(cw)
+	for j := 0; j < bh; j += rows {
+		ch := bh - j
+		if ch > rows {
+			ch = rows
+		}
+		for i := 0; i < bw; i += cw {
+			n := bw - i
+			if n > cw {
+				n = cw
+			}
+			rs := w.rowSize(n)
+			for k := 0; k < ch; k++ {
+				w.convert(k*rs, x+i, y+j+k, n, src[(j+k)*stride+i*bpp:], bpp)
+			}
+			w.call(x+i, y+j, n, ch)
+		}
+	}
+}
+
+// convert writes n pixels of src at offset off of the buffer, for the
+// position x, y of the output.
+func (w *Writer) convert(off, x, y, n int, src []byte, bpp int) {
+	switch w.Format {
+	case RGB565:
+		dst := w.Buf[off : off+n]
+		for i := range dst {
+			r, g, b := rgb(src[i*bpp:], bpp)
+			dst[i] = rgb565(r, g, b)
+		}
+	case RGB888:
+		dst := w.Bytes[off : off+3*n]
+		for i := 0; i < n; i++ {
+			dst[3*i], dst[3*i+1], dst[3*i+2] = rgb(src[i*bpp:], bpp)
+		}
+	case Gray8:
+		dst := w.Bytes[off : off+n]
+		for i := range dst {
+			dst[i] = gray(src[i*bpp:], bpp)
+		}
+	case Mono:
+		dst := w.Bytes[off : off+(n+7)/8]
+		for i := range dst {
+			dst[i] = 0
+		}
+		for i := 0; i < n; i++ {
+			v := gray(src[i*bpp:], bpp)
+			lit := v >= 0x80
+			if w.Dither == Ordered {
+				lit = ordered.Index(int16(x+i), int16(y), color.RGBA{v, v, v, 0xFF}) == 1
+			}
+			if lit {
+				dst[i/8] |= 0x80 >> uint(i%8)
+			}
+		}
+	}
+}
+
+func (w *Writer) call(x, y, cw, ch int) {
+	width, height := int16(w.bounds.Dx()), int16(w.bounds.Dy())
+	if w.Format == RGB565 {
+		w.Callback(w.Buf[:cw*ch], int16(x), int16(y), int16(cw), int16(ch), width, height)
+	} else {
+		w.ByteCallback(w.Bytes[:w.rowSize(cw)*ch], int16(x), int16(y), int16(cw), int16(ch), width, height)
+	}
+}
+
+// capacity returns the size of the buffer of the format.
+func (w *Writer) capacity() int {
+	if w.Format == RGB565 {
+		if w.Callback == nil {
+			return 0
+		}
+		return len(w.Buf)
+	}
+	if w.ByteCallback == nil {
+		return 0
+	}
+	return len(w.Bytes)
+}
+
+// rowSize returns the size of n pixels in the buffer.
+func (w *Writer) rowSize(n int) int {
+	switch w.Format {
+	case RGB888:
+		return 3 * n
+	case Mono:
+		return (n + 7) / 8
+	}
+	return n
+}
+
+// fit returns the number of pixels that fit in size.
+func (w *Writer) fit(size int) int {
+	switch w.Format {
+	case RGB888:
+		return size / 3
+	case Mono:
+		return 8 * size
+	}
+	return size
+}
+
+func rgb(p []byte, bpp int) (r, g, b byte) {
+	if bpp < 3 {
+		return p[0], p[0], p[0]
+	}
+	return p[0], p[1], p[2]
+}
+
+// gray uses the same weights as color.GrayModel.
+func gray(p []byte, bpp int) byte {
+	if bpp < 3 {
+		return p[0]
+	}
+	return byte((19595*uint32(p[0]) + 38470*uint32(p[1]) + 7471*uint32(p[2]) + 1<<15) >> 16)
+}
+
+func rgb565(r, g, b byte) uint16 {
+	return uint16(r&0xF8)<<8 | uint16(g&0xFC)<<3 | uint16(b)>>3
+}
@@ -0,0 +1,234 @@
+package output
+
+import (
+	"image"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// screen collects the pixels passed to a writer, one byte per pixel for the
+// byte formats, and checks the parts passed to the callbacks.
+type screen struct {
+	width, height int
+	pix           []int
+}
+
+func newScreen(c *qt.C, w *Writer, size int) *screen {
+	s := &screen{}
+	check := func(x, y, cw, ch, width, height int16) {
+		if s.pix == nil {
+			s.width, s.height = int(width), int(height)
+			s.pix = make([]int, s.width*s.height)
+			for i := range s.pix {
+				s.pix[i] = -1
+			}
+		}
+		c.Assert([]int{int(width), int(height)}, qt.DeepEquals, []int{s.width, s.height})
+		c.Assert(x >= 0 && y >= 0 && int(x+cw) <= s.width && int(y+ch) <= s.height, qt.IsTrue,
+			qt.Commentf("%d,%d %dx%d", x, y, cw, ch))
+	}
+	w.Callback = func(data []uint16, x, y, cw, ch, width, height int16) {
+		check(x, y, cw, ch, width, height)
+		c.Assert(len(data), qt.Equals, int(cw)*int(ch))
+		for j := 0; j < int(ch); j++ {
+			for i := 0; i < int(cw); i++ {
+				s.pix[(int(y)+j)*s.width+int(x)+i] = int(data[j*int(cw)+i])
+			}
+		}
+	}
+	w.ByteCallback = func(data []byte, x, y, cw, ch, width, height int16) {
+		check(x, y, cw, ch, width, height)
+		c.Assert(len(data), qt.Equals, w.rowSize(int(cw))*int(ch))
+		for j := 0; j < int(ch); j++ {
+			row := data[j*w.rowSize(int(cw)):]
+			for i := 0; i < int(cw); i++ {
+				v := 0
+				switch w.Format {
+				case RGB888:
+					v = int(row[3*i])<<16 | int(row[3*i+1])<<8 | int(row[3*i+2])
+				case Gray8:
+					v = int(row[i])
+				case Mono:
+					v = int(row[i/8]>>(7-uint(i%8))) & 1
+				}
+				s.pix[(int(y)+j)*s.width+int(x)+i] = v
+			}
+		}
+	}
+	w.Buf = make([]uint16, size)
+	w.Bytes = make([]byte, size)
+	return s
+}
+
+// mean returns the average value of the pixels, in percent.
+func (s *screen) mean() int {
+	sum := 0
+	for _, v := range s.pix {
+		sum += v
+	}
+	return 100 * sum / len(s.pix)
+}
+
+// rows passes an image of width x height RGB pixels to w, row by row.
+func rows(w *Writer, width, height int, pixel func(x, y int) (r, g, b byte)) {
+	row := make([]byte, 3*width)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			row[3*x], row[3*x+1], row[3*x+2] = pixel(x, y)
+		}
+		w.Row(y, row, 3)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	c := qt.New(t)
+	row := []byte{0xFF, 0, 0, 0x10, 0x80, 0xF0, 0x60, 0x60, 0x60}
+	for _, test := range []struct {
+		format Format
+		want   []int
+	}{
+		{RGB565, []int{0xF800, 0x141E, 0x630C}},
+		{RGB888, []int{0xFF0000, 0x1080F0, 0x606060}},
+		{Gray8, []int{0x4C, 0x6B, 0x60}},
+		{Mono, []int{0, 0, 0}},
+	} {
+		w := &Writer{Options: Options{Format: test.format}}
+		s := newScreen(c, w, 16)
+		c.Assert(w.Begin(3, 1), qt.IsNil)
+		w.Row(0, row, 3)
+		c.Assert(s.pix, qt.DeepEquals, test.want, qt.Commentf("format %d", test.format))
+	}
+
+	// gray sources
+	w := &Writer{Options: Options{Format: RGB888}}
+	s := newScreen(c, w, 16)
+	c.Assert(w.Begin(2, 1), qt.IsNil)
+	w.Row(0, []byte{0x12, 0xFF}, 1)
+	c.Assert(s.pix, qt.DeepEquals, []int{0x121212, 0xFFFFFF})
+}
+
+func TestScale(t *testing.T) {
+	c := qt.New(t)
+	w := &Writer{Options: Options{Format: Gray8, Scale: 1}}
+	s := newScreen(c, w, 16)
+	c.Assert(w.Begin(5, 3), qt.IsNil)
+	c.Assert(w.Bounds(), qt.Equals, image.Rect(0, 0, 3, 2))
+	rows(w, 5, 3, func(x, y int) (r, g, b byte) {
+		v := byte(10*x + 100*y)
+		return v, v, v
+	})
+	c.Assert(s.pix, qt.DeepEquals, []int{
+		55, 75, 90,
+		205, 225, 240,
+	})
+}
+
+func TestViewport(t *testing.T) {
+	c := qt.New(t)
+	pixel := func(x, y int) (r, g, b byte) {
+		return byte(x), byte(y), 0
+	}
+	w := &Writer{Options: Options{Format: RGB888, Viewport: image.Rect(2, 1, 5, 3)}}
+	s := newScreen(c, w, 64)
+	c.Assert(w.Begin(8, 4), qt.IsNil)
+	rows(w, 8, 4, pixel)
+	c.Assert(s.pix, qt.DeepEquals, []int{
+		0x020100, 0x030100, 0x040100,
+		0x020200, 0x030200, 0x040200,
+	})
+
+	// The viewport is in the coordinates of the scaled image, and clipped to
+	// it.
+	w = &Writer{Options: Options{Format: RGB888, Scale: 2, Viewport: image.Rect(1, 0, 10, 10)}}
+	s = newScreen(c, w, 64)
+	c.Assert(w.Begin(8, 4), qt.IsNil)
+	rows(w, 8, 4, pixel)
+	c.Assert(s.pix, qt.DeepEquals, []int{0x060200})
+
+	w = &Writer{Options: Options{Format: Gray8, Viewport: image.Rect(1, 1, 3, 2)}}
+	s = newScreen(c, w, 64)
+	c.Assert(w.Begin(4, 4), qt.IsNil)
+	w.Block(0, 0, 2, 2, []byte{1, 2, 3, 4}, 1, 2)
+	w.Block(2, 0, 2, 2, []byte{5, 6, 7, 8}, 1, 2)
+	w.Block(0, 2, 4, 2, make([]byte, 8), 1, 4)
+	c.Assert(s.pix, qt.DeepEquals, []int{4, 7})
+}
+
+func TestBufferSize(t *testing.T) {
+	c := qt.New(t)
+	for _, format := range []Format{RGB565, RGB888, Gray8, Mono} {
+		for _, size := range []int{3, 4, 7, 64} {
+			w := &Writer{Options: Options{Format: format}}
+			s := newScreen(c, w, size)
+			c.Assert(w.Begin(20, 20), qt.IsNil)
+			block := make([]byte, 10*10)
+			for i := range block {
+				block[i] = byte(i%3) * 0x80
+			}
+			for y := 0; y < 20; y += 10 {
+				for x := 0; x < 20; x += 10 {
+					w.Block(x, y, 10, 10, block, 1, 10)
+				}
+			}
+			for i, v := range s.pix {
+				c.Assert(v, qt.Not(qt.Equals), -1, qt.Commentf("format %d, size %d: pixel %d", format, size, i))
+			}
+		}
+	}
+
+	w := &Writer{Options: Options{Format: RGB888}}
+	newScreen(c, w, 2)
+	c.Assert(w.Begin(1, 1), qt.Equals, ErrNoBuffer)
+	w = &Writer{Options: Options{Format: Mono}, Buf: make([]uint16, 64)}
+	c.Assert(w.Begin(1, 1), qt.Equals, ErrNoBuffer)
+	w = &Writer{Options: Options{Scale: MaxScale + 1}}
+	newScreen(c, w, 64)
+	c.Assert(w.Begin(1, 1), qt.Equals, errScale)
+}
+
+func TestDither(t *testing.T) {
+	c := qt.New(t)
+	for _, dither := range []Dither{Threshold, Ordered, Diffusion} {
+		for _, level := range []byte{0, 0x40, 0x90, 0xFF} {
+			gray := func(x, y int) (r, g, b byte) {
+				return level, level, level
+			}
+			want := int(level) * 100 / 255
+			if dither == Threshold {
+				want = 0
+				if level >= 0x80 {
+					want = 100
+				}
+			}
+			comment := qt.Commentf("dither %d, level %d", dither, level)
+
+			w := &Writer{Options: Options{Format: Mono, Dither: dither}}
+			s := newScreen(c, w, 4)
+			c.Assert(w.Begin(16, 16), qt.IsNil)
+			rows(w, 16, 16, gray)
+			c.Assert(s.mean()-want, qt.Satisfies, within(5), comment)
+
+			// in blocks
+			w = &Writer{Options: Options{Format: Mono, Dither: dither}}
+			s = newScreen(c, w, 4)
+			c.Assert(w.Begin(16, 16), qt.IsNil)
+			block := make([]byte, 3*8*8)
+			for i := range block {
+				block[i] = level
+			}
+			for y := 0; y < 16; y += 8 {
+				for x := 0; x < 16; x += 8 {
+					w.Block(x, y, 8, 8, block, 3, 3*8)
+				}
+			}
+			c.Assert(s.mean()-want, qt.Satisfies, within(5), comment)
+		}
+	}
+}
+
+func within(d int) func(int) bool {
+	return func(v int) bool {
+		return -d <= v && v <= d
+	}
+}
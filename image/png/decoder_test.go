@@ -0,0 +1,182 @@
+package png
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	stdpng "image/png"
+	"io"
+	"math/rand"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/image/output"
+)
+
+// decodeRGB decodes b with opts in the RGB888 format, and returns the pixels
+// and the size of the output.
+func decodeRGB(c *qt.C, b []byte, opts output.Options, bufSize int) (pix []byte, width, height int) {
+	opts.Format = output.RGB888
+	dec := NewByteDecoder(make([]byte, bufSize), func(data []byte, x, y, w, h, wd, ht int16) {
+		if pix == nil {
+			width, height = int(wd), int(ht)
+			pix = make([]byte, 3*width*height)
+		}
+		for j := 0; j < int(h); j++ {
+			copy(pix[3*((int(y)+j)*width+int(x)):], data[3*j*int(w):3*(j+1)*int(w)])
+		}
+	}, opts)
+	c.Assert(dec.Decode(bytes.NewReader(b)), qt.IsNil)
+	return pix, width, height
+}
+
+// decodeImage decodes r to an image, for the tests of the standard package.
+func decodeImage(r io.Reader) (image.Image, error) {
+	var img *image.RGBA
+	dec := NewByteDecoder(make([]byte, 256), func(data []byte, x, y, w, h, width, height int16) {
+		if img == nil {
+			img = image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+		}
+		for j := 0; j < int(h); j++ {
+			for i := 0; i < int(w); i++ {
+				p := data[3*(j*int(w)+i):]
+				img.SetRGBA(int(x)+i, int(y)+j, color.RGBA{p[0], p[1], p[2], 0xFF})
+			}
+		}
+	}, output.Options{Format: output.RGB888})
+	if err := dec.Decode(r); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func encode(c *qt.C, img image.Image) []byte {
+	var b bytes.Buffer
+	c.Assert(stdpng.Encode(&b, img), qt.IsNil)
+	return b.Bytes()
+}
+
+// rgbOf returns the pixels of img, with 8 bits per channel.
+func rgbOf(img image.Image) []byte {
+	r := img.Bounds()
+	pix := make([]byte, 0, 3*r.Dx()*r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pix = append(pix, c.R, c.G, c.B)
+		}
+	}
+	return pix
+}
+
+func TestDecodeColorTypes(t *testing.T) {
+	c := qt.New(t)
+	rnd := rand.New(rand.NewSource(1))
+	const w, h = 13, 5
+	r := image.Rect(0, 0, w, h)
+	paletted := func(n int) image.Image {
+		p := make(color.Palette, n)
+		for i := range p {
+			p[i] = color.RGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 0xFF}
+		}
+		img := image.NewPaletted(r, p)
+		for i := range img.Pix {
+			img.Pix[i] = uint8(rnd.Intn(n))
+		}
+		return img
+	}
+	gray := image.NewGray(r)
+	gray16 := image.NewGray16(r)
+	rgba := image.NewRGBA(r)
+	nrgba := image.NewNRGBA(r)
+	rgba64 := image.NewRGBA64(r)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(rnd.Intn(256))
+			gray.SetGray(x, y, color.Gray{v})
+			gray16.SetGray16(x, y, color.Gray16{uint16(v)<<8 | 0x42})
+			c := color.RGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 0xFF}
+			rgba.SetRGBA(x, y, c)
+			nrgba.SetNRGBA(x, y, color.NRGBA{c.R, c.G, c.B, uint8(rnd.Intn(256))})
+			rgba64.SetRGBA64(x, y, color.RGBA64{uint16(c.R) << 8, uint16(c.G) << 8, uint16(c.B) << 8, 0xFFFF})
+		}
+	}
+	for name, img := range map[string]image.Image{
+		"gray":      gray,
+		"gray16":    gray16,
+		"rgb":       rgba,
+		"rgba":      nrgba,
+		"rgb16":     rgba64,
+		"paletted1": paletted(2),
+		"paletted2": paletted(4),
+		"paletted4": paletted(16),
+		"paletted8": paletted(200),
+		"black&white": func() image.Image {
+			img := image.NewGray(r)
+			for i := range img.Pix {
+				img.Pix[i] = uint8(rnd.Intn(2)) * 0xFF
+			}
+			return img
+		}(),
+	} {
+		c.Run(name, func(c *qt.C) {
+			pix, width, height := decodeRGB(c, encode(c, img), output.Options{}, 3*w)
+			c.Assert([]int{width, height}, qt.DeepEquals, []int{w, h})
+			want := rgbOf(img)
+			if _, ok := img.(*image.NRGBA); ok {
+				// the alpha is ignored
+				for i := range want {
+					want[i] = nrgba.Pix[i/3*4+i%3]
+				}
+			}
+			c.Assert(pix, qt.DeepEquals, want)
+		})
+	}
+}
+
+func TestDecodeOptions(t *testing.T) {
+	c := qt.New(t)
+	img := image.NewRGBA(image.Rect(0, 0, 9, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 9; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(20 * x), uint8(40 * y), 0, 0xFF})
+		}
+	}
+	b := encode(c, img)
+
+	pix, width, height := decodeRGB(c, b, output.Options{Scale: 1}, 16)
+	c.Assert([]int{width, height}, qt.DeepEquals, []int{5, 3})
+	c.Assert(pix[:6], qt.DeepEquals, []byte{10, 20, 0, 50, 20, 0})
+	c.Assert(pix[3*14:], qt.DeepEquals, []byte{160, 180, 0})
+
+	pix, width, height = decodeRGB(c, b, output.Options{Viewport: image.Rect(7, 4, 20, 5)}, 16)
+	c.Assert([]int{width, height}, qt.DeepEquals, []int{2, 1})
+	c.Assert(pix, qt.DeepEquals, []byte{140, 160, 0, 160, 160, 0})
+
+	// The RGB565 decoder gets the options too.
+	var got []uint16
+	dec := NewDecoder(make([]uint16, 4), func(data []uint16, x, y, w, h, width, height int16) {
+		got = append(got, data...)
+	})
+	dec.SetOptions(output.Options{Scale: 3})
+	c.Assert(dec.Decode(bytes.NewReader(b)), qt.IsNil)
+	c.Assert(got, qt.HasLen, 2)
+
+	dec.SetOptions(output.Options{Format: output.Mono})
+	c.Assert(dec.Decode(bytes.NewReader(b)), qt.Equals, output.ErrNoBuffer)
+}
+
+func TestDecodeMono(t *testing.T) {
+	c := qt.New(t)
+	img := image.NewGray(image.Rect(0, 0, 10, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i%2) * 0xC0
+	}
+	var rows [][]byte
+	dec := NewByteDecoder(make([]byte, 8), func(data []byte, x, y, w, h, width, height int16) {
+		c.Assert([]int16{x, w, h}, qt.DeepEquals, []int16{0, 10, 1})
+		rows = append(rows, append([]byte(nil), data...))
+	}, output.Options{Format: output.Mono})
+	c.Assert(dec.Decode(bytes.NewReader(encode(c, img))), qt.IsNil)
+	c.Assert(rows, qt.DeepEquals, [][]byte{{0x55, 0x40}, {0x55, 0x40}})
+}
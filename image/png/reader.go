@@ -17,6 +17,7 @@ import (
 	"io"
 
 	"tinygo.org/x/drivers/image/internal/compress/zlib"
+	"tinygo.org/x/drivers/image/output"
 )
 
 // Color type, as per the PNG spec.
@@ -68,23 +69,6 @@ const (
 	itAdam7 = 1
 )
 
-// interlaceScan defines the placement and size of a pass for Adam7 interlacing.
-type interlaceScan struct {
-	xFactor, yFactor, xOffset, yOffset int
-}
-
-// interlacing defines Adam7 interlacing, with 7 passes of reduced images.
-// See https://www.w3.org/TR/PNG/#8Interlace
-var interlacing = []interlaceScan{
-	{8, 8, 0, 0},
-	{8, 8, 4, 0},
-	{4, 8, 0, 4},
-	{4, 4, 2, 0},
-	{2, 4, 0, 2},
-	{2, 2, 1, 0},
-	{1, 2, 0, 1},
-}
-
 // Decoding stage.
 // The PNG specification says that the IHDR, PLTE (if present), tRNS (if
 // present), IDAT and IEND chunks must appear in that order. There may be
@@ -103,8 +87,8 @@ const (
 const pngHeader = "\x89PNG\r\n\x1a\n"
 
 type decoder struct {
+	out           *output.Writer
 	r             io.Reader
-	img           image.Image
 	crc           hash.Hash32
 	width, height int
 	depth         int
@@ -361,168 +345,102 @@ func (d *decoder) Read(p []byte) (int, error) {
 	return n, err
 }
 
-// decode decodes the IDAT data into an image.
-func (d *decoder) decode() (image.Image, error) {
+// decode decodes the IDAT data, and passes the rows of the image to the
+// output.
+func (d *decoder) decode() error {
+	if d.interlace == itAdam7 {
+		// The passes of an interlaced image only make whole rows at the end,
+		// which needs the image in memory.
+		return UnsupportedError("interlaced image")
+	}
 	r, err := zlib.NewReader(d)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer r.Close()
-	var img image.Image
-	if d.interlace == itNone {
-		img, err = d.readImagePass(r, 0, false)
-		if err != nil {
-			return nil, err
-		}
-	} else if d.interlace == itAdam7 {
-		// Allocate a blank image of the full size.
-		img, err = d.readImagePass(nil, 0, true)
-		if err != nil {
-			return nil, err
-		}
-		for pass := 0; pass < 7; pass++ {
-			imagePass, err := d.readImagePass(r, pass, false)
-			if err != nil {
-				return nil, err
-			}
-			if imagePass != nil {
-				d.mergePassInto(img, imagePass, pass)
-			}
-		}
+	if err := d.readImage(r); err != nil {
+		return err
 	}
 
 	// Check for EOF, to verify the zlib checksum.
 	n := 0
 	for i := 0; n == 0 && err == nil; i++ {
 		if i == 100 {
-			return nil, io.ErrNoProgress
+			return io.ErrNoProgress
 		}
 		n, err = r.Read(d.tmp[:1])
 	}
 	if err != nil && err != io.EOF {
-		return nil, FormatError(err.Error())
+		return FormatError(err.Error())
 	}
 	if n != 0 || d.idatLength != 0 {
-		return nil, FormatError("too much pixel data")
+		return FormatError("too much pixel data")
 	}
-
-	return img, nil
+	return nil
 }
 
-// readImagePass reads a single image pass, sized according to the pass number.
-func (d *decoder) readImagePass(r io.Reader, pass int, allocateOnly bool) (image.Image, error) {
-	bitsPerPixel := 0
-	pixOffset := 0
-	var (
-		gray     *image.Gray
-		rgba     *image.RGBA
-		paletted *image.Paletted
-		nrgba    *image.NRGBA
-		gray16   *image.Gray16
-		rgba64   *image.RGBA64
-		nrgba64  *image.NRGBA64
-		img      image.Image
-	)
-	width, height := d.width, d.height
-	if d.interlace == itAdam7 && !allocateOnly {
-		p := interlacing[pass]
-		// Add the multiplication factor and subtract one, effectively rounding up.
-		width = (width - p.xOffset + p.xFactor - 1) / p.xFactor
-		height = (height - p.yOffset + p.yFactor - 1) / p.yFactor
-		// A PNG image can't have zero width or height, but for an interlaced
-		// image, an individual pass might have zero width or height. If so, we
-		// shouldn't even read a per-row filter type byte, so return early.
-		if width == 0 || height == 0 {
-			return nil, nil
-		}
+// readImage reads the rows of the image, and passes them to the output with
+// 8 bits per channel. Transparency is ignored.
+func (d *decoder) readImage(r io.Reader) error {
+	if err := d.out.Begin(d.width, d.height); err != nil {
+		return err
 	}
-
-	// Minimizes memory usage in order to run on TinyGo. In order to keep the
-	// amount of code changes down, the image is created as a 1 x 1 image at
-	// this point.
-	width2, height2 := width, height
-	width, height = 1, 1
+	bitsPerPixel := 0
 	switch d.cb {
-	case cbG1, cbG2, cbG4, cbG8:
+	case cbG1, cbG2, cbG4, cbG8, cbP1, cbP2, cbP4, cbP8:
 		bitsPerPixel = d.depth
-		if d.useTransparent {
-			nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
-			img = nrgba
-		} else {
-			gray = image.NewGray(image.Rect(0, 0, width, height))
-			img = gray
-		}
-	case cbGA8:
+	case cbGA8, cbG16:
 		bitsPerPixel = 16
-		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
-		img = nrgba
 	case cbTC8:
 		bitsPerPixel = 24
-		if d.useTransparent {
-			nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
-			img = nrgba
-		} else {
-			rgba = image.NewRGBA(image.Rect(0, 0, width, height))
-			img = rgba
-		}
-	case cbP1, cbP2, cbP4, cbP8:
-		bitsPerPixel = d.depth
-		paletted = image.NewPaletted(image.Rect(0, 0, width, height), d.palette)
-		img = paletted
-	case cbTCA8:
+	case cbTCA8, cbGA16:
 		bitsPerPixel = 32
-		nrgba = image.NewNRGBA(image.Rect(0, 0, width, height))
-		img = nrgba
-	case cbG16:
-		bitsPerPixel = 16
-		if d.useTransparent {
-			nrgba64 = image.NewNRGBA64(image.Rect(0, 0, width, height))
-			img = nrgba64
-		} else {
-			gray16 = image.NewGray16(image.Rect(0, 0, width, height))
-			img = gray16
-		}
-	case cbGA16:
-		bitsPerPixel = 32
-		nrgba64 = image.NewNRGBA64(image.Rect(0, 0, width, height))
-		img = nrgba64
 	case cbTC16:
 		bitsPerPixel = 48
-		if d.useTransparent {
-			nrgba64 = image.NewNRGBA64(image.Rect(0, 0, width, height))
-			img = nrgba64
-		} else {
-			rgba64 = image.NewRGBA64(image.Rect(0, 0, width, height))
-			img = rgba64
-		}
 	case cbTCA16:
 		bitsPerPixel = 64
-		nrgba64 = image.NewNRGBA64(image.Rect(0, 0, width, height))
-		img = nrgba64
-	}
-	width, height = width2, height2
-	if allocateOnly {
-		return img, nil
 	}
 	bytesPerPixel := (bitsPerPixel + 7) / 8
+	width := d.width
 
 	// The +1 is for the per-row filter type, which is at cr[0].
 	rowSize := 1 + (int64(bitsPerPixel)*int64(width)+7)/8
 	if rowSize != int64(int(rowSize)) {
-		return nil, UnsupportedError("dimension overflow")
+		return UnsupportedError("dimension overflow")
 	}
 	// cr and pr are the bytes for the current and previous row.
 	cr := make([]uint8, rowSize)
 	pr := make([]uint8, rowSize)
 
-	for y := 0; y < height; y++ {
+	// row holds the pixels of the color types that can't be passed as they
+	// are, and palette the colors of the paletted ones.
+	var row, palette []byte
+	switch d.cb {
+	case cbG1, cbG2, cbG4, cbGA16:
+		row = make([]byte, width)
+	case cbP1, cbP2, cbP4, cbP8:
+		row = make([]byte, 3*width)
+		palette = make([]byte, 3*256)
+		for i, c := range d.palette {
+			switch c := c.(type) {
+			case color.RGBA:
+				palette[3*i], palette[3*i+1], palette[3*i+2] = c.R, c.G, c.B
+			case color.NRGBA:
+				palette[3*i], palette[3*i+1], palette[3*i+2] = c.R, c.G, c.B
+			}
+		}
+	case cbTC16, cbTCA16:
+		row = make([]byte, 3*width)
+	}
+
+	for y := 0; y < d.height; y++ {
 		// Read the decompressed bytes.
 		_, err := io.ReadFull(r, cr)
 		if err != nil {
 			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				return nil, FormatError("not enough pixel data")
+				return FormatError("not enough pixel data")
 			}
-			return nil, err
+			return err
 		}
 
 		// Apply the filter.
@@ -552,315 +470,61 @@ func (d *decoder) readImagePass(r io.Reader, pass int, allocateOnly bool) (image
 		case ftPaeth:
 			filterPaeth(cdat, pdat, bytesPerPixel)
 		default:
-			return nil, FormatError("bad filter type")
+			return FormatError("bad filter type")
 		}
 
-		// Convert from bytes to colors.
+		// Convert the row to 8 bits per channel, when needed. Gray with
+		// alpha and 16-bit gray have the gray value in their first byte.
 		switch d.cb {
-		case cbG1:
-			if d.useTransparent {
-				ty := d.transparent[1]
-				for x := 0; x < width; x += 8 {
-					b := cdat[x/8]
-					for x2 := 0; x2 < 8 && x+x2 < width; x2++ {
-						ycol := (b >> 7) * 0xff
-						acol := uint8(0xff)
-						if ycol == ty {
-							acol = 0x00
-						}
-						nrgba.SetNRGBA(x+x2, y, color.NRGBA{ycol, ycol, ycol, acol})
-						b <<= 1
-					}
-				}
-			} else {
-				for x := 0; x < width; x += 8 {
-					b := cdat[x/8]
-					for x2 := 0; x2 < 8 && x+x2 < width; x2++ {
-						gray.SetGray(x+x2, y, color.Gray{(b >> 7) * 0xff})
-						b <<= 1
-					}
-				}
-			}
-		case cbG2:
-			if d.useTransparent {
-				ty := d.transparent[1]
-				for x := 0; x < width; x += 4 {
-					b := cdat[x/4]
-					for x2 := 0; x2 < 4 && x+x2 < width; x2++ {
-						ycol := (b >> 6) * 0x55
-						acol := uint8(0xff)
-						if ycol == ty {
-							acol = 0x00
-						}
-						nrgba.SetNRGBA(x+x2, y, color.NRGBA{ycol, ycol, ycol, acol})
-						b <<= 2
-					}
-				}
-			} else {
-				for x := 0; x < width; x += 4 {
-					b := cdat[x/4]
-					for x2 := 0; x2 < 4 && x+x2 < width; x2++ {
-						gray.SetGray(x+x2, y, color.Gray{(b >> 6) * 0x55})
-						b <<= 2
-					}
-				}
-			}
-		case cbG4:
-			if d.useTransparent {
-				ty := d.transparent[1]
-				for x := 0; x < width; x += 2 {
-					b := cdat[x/2]
-					for x2 := 0; x2 < 2 && x+x2 < width; x2++ {
-						ycol := (b >> 4) * 0x11
-						acol := uint8(0xff)
-						if ycol == ty {
-							acol = 0x00
-						}
-						nrgba.SetNRGBA(x+x2, y, color.NRGBA{ycol, ycol, ycol, acol})
-						b <<= 4
-					}
-				}
-			} else {
-				for x := 0; x < width; x += 2 {
-					b := cdat[x/2]
-					for x2 := 0; x2 < 2 && x+x2 < width; x2++ {
-						gray.SetGray(x+x2, y, color.Gray{(b >> 4) * 0x11})
-						b <<= 4
-					}
-				}
+		case cbG1, cbG2, cbG4:
+			// the pixels of a byte, their mask and the scale to 8 bits
+			n := 8 / d.depth
+			mask := byte(1)<<uint(d.depth) - 1
+			scale := 0xFF / mask
+			for x := 0; x < width; x++ {
+				shift := uint(8 - d.depth*(x%n+1))
+				row[x] = (cdat[x/n] >> shift & mask) * scale
 			}
+			d.out.Row(y, row, 1)
 		case cbG8:
-			if d.useTransparent {
-				ty := d.transparent[1]
-				for x := 0; x < width; x++ {
-					ycol := cdat[x]
-					acol := uint8(0xff)
-					if ycol == ty {
-						acol = 0x00
-					}
-					nrgba.SetNRGBA(x, y, color.NRGBA{ycol, ycol, ycol, acol})
-				}
-			} else {
-				copy(gray.Pix[pixOffset:], cdat)
-				pixOffset += gray.Stride
-			}
-		case cbGA8:
-			for x := 0; x < width; x++ {
-				ycol := cdat[2*x+0]
-				nrgba.SetNRGBA(x, y, color.NRGBA{ycol, ycol, ycol, cdat[2*x+1]})
+			d.out.Row(y, cdat, 1)
+		case cbGA8, cbG16:
+			d.out.Row(y, cdat, 2)
+		case cbGA16:
+			for x := range row {
+				row[x] = cdat[4*x]
 			}
+			d.out.Row(y, row, 1)
 		case cbTC8:
-			if d.useTransparent {
-				pix, i, j := nrgba.Pix, pixOffset, 0
-				tr, tg, tb := d.transparent[1], d.transparent[3], d.transparent[5]
-				for x := 0; x < width; x++ {
-					r := cdat[j+0]
-					g := cdat[j+1]
-					b := cdat[j+2]
-					a := uint8(0xff)
-					if r == tr && g == tg && b == tb {
-						a = 0x00
-					}
-					pix[i+0] = r
-					pix[i+1] = g
-					pix[i+2] = b
-					pix[i+3] = a
-					i += 4
-					j += 3
-				}
-				pixOffset += nrgba.Stride
-			} else {
-				for x := 0; x < width; x++ {
-					r := uint16(cdat[x*3+0]) << 8
-					g := uint16(cdat[x*3+1]) << 8
-					b := uint16(cdat[x*3+2]) << 8
-					callbackBuf[x] = uint16((r & 0xF800) + ((g & 0xFC00) >> 5) + ((b & 0xF800) >> 11))
-				}
-				callback(callbackBuf[:width*1], 0, int16(y), int16(width), 1, int16(width), int16(height))
-				pixOffset += rgba.Stride
-			}
-		case cbP1:
-			for x := 0; x < width; x += 8 {
-				b := cdat[x/8]
-				for x2 := 0; x2 < 8 && x+x2 < width; x2++ {
-					idx := b >> 7
-					if len(paletted.Palette) <= int(idx) {
-						paletted.Palette = paletted.Palette[:int(idx)+1]
-					}
-					paletted.SetColorIndex(x+x2, y, idx)
-					b <<= 1
-				}
-			}
-		case cbP2:
-			for x := 0; x < width; x += 4 {
-				b := cdat[x/4]
-				for x2 := 0; x2 < 4 && x+x2 < width; x2++ {
-					idx := b >> 6
-					if len(paletted.Palette) <= int(idx) {
-						paletted.Palette = paletted.Palette[:int(idx)+1]
-					}
-					paletted.SetColorIndex(x+x2, y, idx)
-					b <<= 2
-				}
-			}
-		case cbP4:
-			for x := 0; x < width; x += 2 {
-				b := cdat[x/2]
-				for x2 := 0; x2 < 2 && x+x2 < width; x2++ {
-					idx := b >> 4
-					if len(paletted.Palette) <= int(idx) {
-						paletted.Palette = paletted.Palette[:int(idx)+1]
-					}
-					paletted.SetColorIndex(x+x2, y, idx)
-					b <<= 4
-				}
-			}
-		case cbP8:
-			if len(paletted.Palette) != 256 {
-				for x := 0; x < width; x++ {
-					if len(paletted.Palette) <= int(cdat[x]) {
-						paletted.Palette = paletted.Palette[:int(cdat[x])+1]
-					}
-				}
-			}
-			copy(paletted.Pix[pixOffset:], cdat)
-			pixOffset += paletted.Stride
+			d.out.Row(y, cdat, 3)
 		case cbTCA8:
-			copy(nrgba.Pix[:], cdat)
+			d.out.Row(y, cdat, 4)
+		case cbP1, cbP2, cbP4, cbP8:
+			n := 8 / d.depth
+			mask := byte(1)<<uint(d.depth) - 1
 			for x := 0; x < width; x++ {
-				r := uint16(cdat[x*3+0]) << 8
-				g := uint16(cdat[x*3+1]) << 8
-				b := uint16(cdat[x*3+2]) << 8
-				callbackBuf[x] = uint16((r & 0xF800) + ((g & 0xFC00) >> 5) + ((b & 0xF800) >> 11))
+				shift := uint(8 - d.depth*(x%n+1))
+				i := 3 * int(cdat[x/n]>>shift&mask)
+				copy(row[3*x:3*x+3], palette[i:i+3])
 			}
-			callback(callbackBuf[:width*1], 0, int16(y), int16(width), 1, int16(width), int16(height))
-			pixOffset += nrgba.Stride
-		case cbG16:
-			if d.useTransparent {
-				ty := uint16(d.transparent[0])<<8 | uint16(d.transparent[1])
-				for x := 0; x < width; x++ {
-					ycol := uint16(cdat[2*x+0])<<8 | uint16(cdat[2*x+1])
-					acol := uint16(0xffff)
-					if ycol == ty {
-						acol = 0x0000
-					}
-					nrgba64.SetNRGBA64(x, y, color.NRGBA64{ycol, ycol, ycol, acol})
-				}
-			} else {
-				for x := 0; x < width; x++ {
-					ycol := uint16(cdat[2*x+0])<<8 | uint16(cdat[2*x+1])
-					gray16.SetGray16(x, y, color.Gray16{ycol})
-				}
-			}
-		case cbGA16:
-			for x := 0; x < width; x++ {
-				ycol := uint16(cdat[4*x+0])<<8 | uint16(cdat[4*x+1])
-				acol := uint16(cdat[4*x+2])<<8 | uint16(cdat[4*x+3])
-				nrgba64.SetNRGBA64(x, y, color.NRGBA64{ycol, ycol, ycol, acol})
-			}
-		case cbTC16:
-			if d.useTransparent {
-				tr := uint16(d.transparent[0])<<8 | uint16(d.transparent[1])
-				tg := uint16(d.transparent[2])<<8 | uint16(d.transparent[3])
-				tb := uint16(d.transparent[4])<<8 | uint16(d.transparent[5])
-				for x := 0; x < width; x++ {
-					rcol := uint16(cdat[6*x+0])<<8 | uint16(cdat[6*x+1])
-					gcol := uint16(cdat[6*x+2])<<8 | uint16(cdat[6*x+3])
-					bcol := uint16(cdat[6*x+4])<<8 | uint16(cdat[6*x+5])
-					acol := uint16(0xffff)
-					if rcol == tr && gcol == tg && bcol == tb {
-						acol = 0x0000
-					}
-					nrgba64.SetNRGBA64(x, y, color.NRGBA64{rcol, gcol, bcol, acol})
-				}
-			} else {
-				for x := 0; x < width; x++ {
-					rcol := uint16(cdat[6*x+0])<<8 | uint16(cdat[6*x+1])
-					gcol := uint16(cdat[6*x+2])<<8 | uint16(cdat[6*x+3])
-					bcol := uint16(cdat[6*x+4])<<8 | uint16(cdat[6*x+5])
-					rgba64.SetRGBA64(x, y, color.RGBA64{rcol, gcol, bcol, 0xffff})
-				}
-			}
-		case cbTCA16:
+			d.out.Row(y, row, 3)
+		case cbTC16, cbTCA16:
 			for x := 0; x < width; x++ {
-				rcol := uint16(cdat[8*x+0])<<8 | uint16(cdat[8*x+1])
-				gcol := uint16(cdat[8*x+2])<<8 | uint16(cdat[8*x+3])
-				bcol := uint16(cdat[8*x+4])<<8 | uint16(cdat[8*x+5])
-				acol := uint16(cdat[8*x+6])<<8 | uint16(cdat[8*x+7])
-				nrgba64.SetNRGBA64(x, y, color.NRGBA64{rcol, gcol, bcol, acol})
+				p := cdat[x*bytesPerPixel:]
+				row[3*x], row[3*x+1], row[3*x+2] = p[0], p[2], p[4]
 			}
+			d.out.Row(y, row, 3)
 		}
 
 		// The current row for y is the previous row for y+1.
 		pr, cr = cr, pr
 	}
-
-	return img, nil
-}
-
-// mergePassInto merges a single pass into a full sized image.
-func (d *decoder) mergePassInto(dst image.Image, src image.Image, pass int) {
-	p := interlacing[pass]
-	var (
-		srcPix        []uint8
-		dstPix        []uint8
-		stride        int
-		rect          image.Rectangle
-		bytesPerPixel int
-	)
-	switch target := dst.(type) {
-	case *image.Alpha:
-		srcPix = src.(*image.Alpha).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 1
-	case *image.Alpha16:
-		srcPix = src.(*image.Alpha16).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 2
-	case *image.Gray:
-		srcPix = src.(*image.Gray).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 1
-	case *image.Gray16:
-		srcPix = src.(*image.Gray16).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 2
-	case *image.NRGBA:
-		srcPix = src.(*image.NRGBA).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 4
-	case *image.NRGBA64:
-		srcPix = src.(*image.NRGBA64).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 8
-	case *image.Paletted:
-		srcPix = src.(*image.Paletted).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 1
-	case *image.RGBA:
-		srcPix = src.(*image.RGBA).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 4
-	case *image.RGBA64:
-		srcPix = src.(*image.RGBA64).Pix
-		dstPix, stride, rect = target.Pix, target.Stride, target.Rect
-		bytesPerPixel = 8
-	}
-	s, bounds := 0, src.Bounds()
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		dBase := (y*p.yFactor+p.yOffset-rect.Min.Y)*stride + (p.xOffset-rect.Min.X)*bytesPerPixel
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			d := dBase + x*p.xFactor*bytesPerPixel
-			copy(dstPix[d:], srcPix[s:s+bytesPerPixel])
-			s += bytesPerPixel
-		}
-	}
+	return nil
 }
 
-func (d *decoder) parseIDAT(length uint32) (err error) {
+func (d *decoder) parseIDAT(length uint32) error {
 	d.idatLength = length
-	d.img, err = d.decode()
-	if err != nil {
+	if err := d.decode(); err != nil {
 		return err
 	}
 	return d.verifyChecksum()
@@ -966,7 +630,14 @@ func (d *decoder) checkHeader() error {
 // Decode reads a PNG image from r. Different from the standard package, the
 // decoded result will be received by the callback set by SetCallback().
 func Decode(r io.Reader) (image.Image, error) {
+	return nil, defaultDecoder.Decode(r)
+}
+
+// Decode reads a PNG image from r, and passes its pixels to the callback of
+// the decoder.
+func (dec *Decoder) Decode(r io.Reader) error {
 	d := &decoder{
+		out: &dec.out,
 		r:   r,
 		crc: crc32.NewIEEE(),
 	}
@@ -974,17 +645,17 @@ func Decode(r io.Reader) (image.Image, error) {
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-		return nil, err
+		return err
 	}
 	for d.stage != dsSeenIEND {
 		if err := d.parseChunk(); err != nil {
 			if err == io.EOF {
 				err = io.ErrUnexpectedEOF
 			}
-			return nil, err
+			return err
 		}
 	}
-	return nil, nil
+	return nil
 }
 
 // DecodeConfig returns the color model and dimensions of a PNG image without
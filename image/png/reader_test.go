@@ -75,7 +75,7 @@ func readPNG(filename string) (image.Image, error) {
 		return nil, err
 	}
 	defer f.Close()
-	return Decode(f)
+	return decodeImage(f)
 }
 
 // fakebKGDs maps from filenames to fake bKGD chunks for our approximation to
@@ -504,7 +504,7 @@ func TestTrailingIDATChunks(t *testing.T) {
 	// The following chunk contains a single pixel with color.Gray{0}.
 	const idatBlack = "\x00\x00\x00\x0eIDAT\x78\x9c\x62\x62\x00\x04\x00\x00\xff\xff\x00\x06\x00\x03\xfa\xd0\x59\xae"
 
-	img, err := Decode(strings.NewReader(pngHeader + ihdr + idatWhite + idatBlack + iend))
+	img, err := decodeImage(strings.NewReader(pngHeader + ihdr + idatWhite + idatBlack + iend))
 	if err != nil {
 		t.Fatalf("trailing IDAT not ignored: %v", err)
 	}
@@ -545,7 +545,7 @@ func TestMultipletRNSChunks(t *testing.T) {
 		b = append(b, iend...)
 
 		var want color.Color
-		m, err := Decode(bytes.NewReader(b))
+		m, err := decodeImage(bytes.NewReader(b))
 		switch i {
 		case 0:
 			if err != nil {
@@ -558,7 +558,8 @@ func TestMultipletRNSChunks(t *testing.T) {
 				t.Errorf("%d tRNS chunks: %v", i, err)
 				continue
 			}
-			want = color.NRGBA{0xff, 0x00, 0x00, 0x7f}
+			// The alpha is ignored by the output.
+			want = color.RGBA{0xff, 0x00, 0x00, 0xff}
 		default:
 			if err == nil {
 				t.Errorf("%d tRNS chunks: got nil error, want non-nil", i)
@@ -600,7 +601,7 @@ func TestPaletted8OutOfRangePixel(t *testing.T) {
 
 func TestGray8Transparent(t *testing.T) {
 	// These bytes come from https://golang.org/issues/19553
-	m, err := Decode(bytes.NewReader([]byte{
+	m, err := decodeImage(bytes.NewReader([]byte{
 		0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x0d, 0x49, 0x48, 0x44, 0x52,
 		0x00, 0x00, 0x00, 0x0f, 0x00, 0x00, 0x00, 0x0b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x85, 0x2c, 0x88,
 		0x80, 0x00, 0x00, 0x00, 0x02, 0x74, 0x52, 0x4e, 0x53, 0x00, 0xff, 0x5b, 0x91, 0x22, 0xb5, 0x00,
@@ -641,18 +642,20 @@ func TestGray8Transparent(t *testing.T) {
 		got = append(got, '\n')
 	}
 
+	// The transparency is ignored by the output: transparent pixels have the
+	// gray of the tRNS chunk.
 	const want = "" +
-		".. .. .. ce bd bd bd bd bd bd bd bd bd bd e6 \n" +
-		".. .. .. 7b 84 94 94 94 94 94 94 94 94 6b bd \n" +
-		".. .. .. 7b d6 .. .. .. .. .. .. .. .. 8c bd \n" +
-		".. .. .. 7b d6 .. .. .. .. .. .. .. .. 8c bd \n" +
-		".. .. .. 7b d6 .. .. .. .. .. .. .. .. 8c bd \n" +
-		"e6 bd bd 7b a5 bd bd f7 .. .. .. .. .. 8c bd \n" +
-		"bd 6b 94 94 94 94 5a ef .. .. .. .. .. 8c bd \n" +
-		"bd 8c .. .. .. .. 63 ad ad ad ad ad ad 73 bd \n" +
-		"bd 8c .. .. .. .. 63 9c 9c 9c 9c 9c 9c 9c de \n" +
-		"bd 6b 94 94 94 94 5a ef .. .. .. .. .. .. .. \n" +
-		"e6 b5 b5 b5 b5 b5 b5 f7 .. .. .. .. .. .. .. \n"
+		"ff ff ff ce bd bd bd bd bd bd bd bd bd bd e6 \n" +
+		"ff ff ff 7b 84 94 94 94 94 94 94 94 94 6b bd \n" +
+		"ff ff ff 7b d6 ff ff ff ff ff ff ff ff 8c bd \n" +
+		"ff ff ff 7b d6 ff ff ff ff ff ff ff ff 8c bd \n" +
+		"ff ff ff 7b d6 ff ff ff ff ff ff ff ff 8c bd \n" +
+		"e6 bd bd 7b a5 bd bd f7 ff ff ff ff ff 8c bd \n" +
+		"bd 6b 94 94 94 94 5a ef ff ff ff ff ff 8c bd \n" +
+		"bd 8c ff ff ff ff 63 ad ad ad ad ad ad 73 bd \n" +
+		"bd 8c ff ff ff ff 63 9c 9c 9c 9c 9c 9c 9c de \n" +
+		"bd 6b 94 94 94 94 5a ef ff ff ff ff ff ff ff \n" +
+		"e6 b5 b5 b5 b5 b5 b5 f7 ff ff ff ff ff ff ff \n"
 
 	if string(got) != want {
 		t.Errorf("got:\n%swant:\n%s", got, want)
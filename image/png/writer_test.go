@@ -42,7 +42,7 @@ func encodeDecode(m image.Image) (image.Image, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Decode(&b)
+	return decodeImage(&b)
 }
 
 func TestWriter(t *testing.T) {
@@ -0,0 +1,113 @@
+// Package dirty tracks the area of a framebuffer that changed since it was
+// last sent to a display, so that drivers only need to send that area.
+package dirty
+
+// Rect is a rectangle in the coordinates of a framebuffer, from X0, Y0
+// inclusive to X1, Y1 exclusive. The zero value is an empty rectangle.
+type Rect struct {
+	X0, Y0, X1, Y1 int16
+}
+
+// Empty returns whether the rectangle contains no pixels.
+func (r Rect) Empty() bool {
+	return r.X0 >= r.X1 || r.Y0 >= r.Y1
+}
+
+// Area returns the number of pixels in the rectangle.
+func (r Rect) Area() int32 {
+	if r.Empty() {
+		return 0
+	}
+	return int32(r.X1-r.X0) * int32(r.Y1-r.Y0)
+}
+
+// Add grows the rectangle to include the pixel at x, y.
+func (r *Rect) Add(x, y int16) {
+	r.AddRect(x, y, 1, 1)
+}
+
+// AddRect grows the rectangle to include the given rectangle.
+func (r *Rect) AddRect(x, y, width, height int16) {
+	*r = r.Union(Rect{x, y, x + width, y + height})
+}
+
+// Union returns the smallest rectangle that contains both r and s.
+func (r Rect) Union(s Rect) Rect {
+	if s.Empty() {
+		return r
+	}
+	if r.Empty() {
+		return s
+	}
+	if s.X0 < r.X0 {
+		r.X0 = s.X0
+	}
+	if s.Y0 < r.Y0 {
+		r.Y0 = s.Y0
+	}
+	if s.X1 > r.X1 {
+		r.X1 = s.X1
+	}
+	if s.Y1 > r.Y1 {
+		r.Y1 = s.Y1
+	}
+	return r
+}
+
+// AlignX returns the rectangle grown horizontally so that X0 and X1 are
+// multiples of n, for example to cover whole bytes of a buffer with 8 pixels
+// per byte.
+func (r Rect) AlignX(n int16) Rect {
+	r.X0 -= r.X0 % n
+	r.X1 += (n - r.X1%n) % n
+	return r
+}
+
+// AlignY returns the rectangle grown vertically so that Y0 and Y1 are
+// multiples of n, for example to cover whole pages of 8 rows.
+func (r Rect) AlignY(n int16) Rect {
+	r.Y0 -= r.Y0 % n
+	r.Y1 += (n - r.Y1%n) % n
+	return r
+}
+
+// Reset empties the rectangle, after it has been sent to the display.
+func (r *Rect) Reset() {
+	*r = Rect{}
+}
+
+// Refresher tracks the changed area of an e-paper display and decides
+// whether the next refresh can be a partial refresh of that area, which is
+// faster but leaves some ghosting, or must be a full refresh of the display,
+// which removes it.
+type Refresher struct {
+	Rect // pixels changed since the last refresh
+
+	// Interval is the maximum number of partial refreshes between two full
+	// refreshes. Zero disables partial refreshes.
+	Interval int
+
+	partials int // since the last full refresh
+}
+
+// Next returns the area to refresh, given the rectangle of the whole display,
+// and whether it is a partial refresh, and empties the changed area. A
+// partial refresh is done if the changed area is at most half of the display
+// and fewer than Interval partial refreshes were done since the last full
+// refresh. The area is empty if partial refreshes are enabled and nothing
+// changed.
+func (r *Refresher) Next(full Rect) (Rect, bool) {
+	changed := r.Rect
+	r.Reset()
+	if r.Interval > 0 {
+		if changed.Empty() {
+			return Rect{}, false
+		}
+		if r.partials < r.Interval && changed.Area() <= full.Area()/2 {
+			r.partials++
+			return changed, true
+		}
+	}
+	r.partials = 0
+	return full, false
+}
@@ -0,0 +1,66 @@
+package dirty
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestRect(t *testing.T) {
+	c := qt.New(t)
+	var r Rect
+	c.Assert(r.Empty(), qt.IsTrue)
+	c.Assert(r.Area(), qt.Equals, int32(0))
+
+	r.Add(3, 9)
+	c.Assert(r, qt.Equals, Rect{3, 9, 4, 10})
+	r.Add(10, 2)
+	c.Assert(r, qt.Equals, Rect{3, 2, 11, 10})
+	c.Assert(r.Area(), qt.Equals, int32(64))
+
+	r.AddRect(0, 0, 0, 5) // empty
+	c.Assert(r, qt.Equals, Rect{3, 2, 11, 10})
+	c.Assert(r.AlignX(8), qt.Equals, Rect{0, 2, 16, 10})
+	c.Assert(r.AlignY(8), qt.Equals, Rect{3, 0, 11, 16})
+	c.Assert(Rect{8, 0, 16, 1}.AlignX(8), qt.Equals, Rect{8, 0, 16, 1})
+
+	c.Assert(Rect{}.Union(r), qt.Equals, r)
+	r.Reset()
+	c.Assert(r.Empty(), qt.IsTrue)
+}
+
+func TestRefresher(t *testing.T) {
+	c := qt.New(t)
+	full := Rect{0, 0, 16, 16}
+	next := func(r *Refresher) []interface{} {
+		rect, partial := r.Next(full)
+		return []interface{}{rect, partial}
+	}
+
+	// Without an interval, every refresh is full.
+	var r Refresher
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{full, false})
+	r.Add(1, 2)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{full, false})
+	c.Assert(r.Empty(), qt.IsTrue)
+
+	r = Refresher{Interval: 2}
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{}, false})
+	r.Add(1, 2)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{1, 2, 2, 3}, true})
+	c.Assert(r.Empty(), qt.IsTrue)
+	r.Add(3, 4)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{3, 4, 4, 5}, true})
+	r.Add(3, 4)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{full, false}) // interval
+	r.Add(3, 4)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{3, 4, 4, 5}, true})
+
+	// A large change is a full refresh, which restarts the interval.
+	r.AddRect(0, 0, 16, 9)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{full, false})
+	r.AddRect(0, 0, 16, 8)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{0, 0, 16, 8}, true})
+	r.Add(0, 0)
+	c.Assert(next(&r), qt.DeepEquals, []interface{}{Rect{0, 0, 1, 1}, true})
+}
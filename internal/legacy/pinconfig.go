@@ -0,0 +1,32 @@
+//go:build tinygo
+// +build tinygo
+
+// Package legacy helps drivers that accept a drivers.PinOutput or
+// drivers.PinInput keep configuring machine.Pin values for their callers, as
+// they did when they accepted a machine.Pin. For other pin types, and outside
+// of TinyGo, the caller configures the pins. The functions take any pin type,
+// because some drivers switch a pin between output and input.
+package legacy
+
+import "machine"
+
+// ConfigurePinOut configures p as an output if it is a machine.Pin.
+func ConfigurePinOut(p interface{}) {
+	if pin, ok := p.(machine.Pin); ok && pin != machine.NoPin {
+		pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	}
+}
+
+// ConfigurePinInput configures p as an input if it is a machine.Pin.
+func ConfigurePinInput(p interface{}) {
+	if pin, ok := p.(machine.Pin); ok && pin != machine.NoPin {
+		pin.Configure(machine.PinConfig{Mode: machine.PinInput})
+	}
+}
+
+// IsNoPin returns whether p is nil or machine.NoPin, which drivers accept for
+// optional pins.
+func IsNoPin(p interface{}) bool {
+	pin, ok := p.(machine.Pin)
+	return p == nil || ok && pin == machine.NoPin
+}
@@ -0,0 +1,15 @@
+//go:build !tinygo
+// +build !tinygo
+
+package legacy
+
+// ConfigurePinOut does nothing outside of TinyGo.
+func ConfigurePinOut(p interface{}) {}
+
+// ConfigurePinInput does nothing outside of TinyGo.
+func ConfigurePinInput(p interface{}) {}
+
+// IsNoPin returns whether p is nil.
+func IsNoPin(p interface{}) bool {
+	return p == nil
+}
@@ -0,0 +1,418 @@
+// Package kvstore implements a small, wear-leveled key/value store for
+// persisting settings, calibration constants and counters on flash memory or
+// EEPROM.
+//
+// The store is log structured: every Set or Delete appends a CRC protected
+// record to the active sector. When the active sector is full, the live
+// records are copied to the next sector, which then becomes the active one.
+// Sectors are used in turn, spreading erases evenly over the device. A
+// sector only becomes active once all records have been copied and its
+// header has been written, so an interrupted compaction leaves the previous
+// sector, and all values in it, intact.
+//
+// All live records must fit in a single sector.
+package kvstore // import "tinygo.org/x/drivers/kvstore"
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// BlockDevice is the storage used by a Store. It is implemented by
+// flash.Device and at24cx.Device.
+//
+// Erased bytes must read as 0xFF, which rules out the SD cards that erase
+// to 0x00.
+type BlockDevice interface {
+	io.ReaderAt
+	io.WriterAt
+
+	// Size returns the size of the device in bytes.
+	Size() int64
+
+	// EraseBlockSize returns the size of an erase block in bytes.
+	EraseBlockSize() int64
+
+	// EraseBlocks erases len erase blocks starting at block start.
+	EraseBlocks(start, len int64) error
+}
+
+var (
+	ErrNotFound    = errors.New("kvstore: key not found")
+	ErrFull        = errors.New("kvstore: store is full")
+	ErrKeyTooLong  = errors.New("kvstore: key too long")
+	ErrValueTooBig = errors.New("kvstore: value too big")
+	ErrBufferSize  = errors.New("kvstore: buffer too small")
+	ErrInvalidSize = errors.New("kvstore: invalid size")
+	ErrNotMounted  = errors.New("kvstore: not mounted")
+)
+
+const (
+	// MaxKeyLen is the maximum length of a key.
+	MaxKeyLen = 64
+
+	sectorMagic   = 0x564B4754 // "TGKV", little endian
+	sectorHdrSize = 12
+	recordHdrSize = 8
+	recordAlign   = 4
+
+	flagDelete = 0x01
+)
+
+// Store is a key/value store on a block device.
+type Store struct {
+	dev        BlockDevice
+	offset     int64
+	sectorSize int64
+	sectors    int
+
+	mounted bool
+	active  int    // index of the active sector
+	seq     uint32 // sequence number of the active sector
+	end     int64  // offset of the first free byte in the active sector
+
+	buf [recordHdrSize + MaxKeyLen]byte
+}
+
+// Config is the configuration of a Store.
+type Config struct {
+	// Offset is the byte offset of the store on the device. It must be a
+	// multiple of the erase block size.
+	Offset int64
+
+	// SectorSize is the size of a sector in bytes. It must be a multiple
+	// of the erase block size. The default is the erase block size, or its
+	// smallest multiple that holds a record with the longest key.
+	SectorSize int64
+
+	// Sectors is the number of sectors used by the store, at least 2. The
+	// default is to use all of the device after Offset.
+	Sectors int
+}
+
+// New returns a new store on the given device. Call Configure to mount it.
+func New(dev BlockDevice) *Store {
+	return &Store{dev: dev}
+}
+
+// Configure sets the location of the store on the device and mounts it. If
+// no valid store is found, the device is formatted.
+func (s *Store) Configure(cfg Config) error {
+	s.mounted = false
+	erase := s.dev.EraseBlockSize()
+	s.offset = cfg.Offset
+	s.sectorSize = cfg.SectorSize
+	if s.sectorSize == 0 {
+		min := int64(sectorHdrSize + recordHdrSize + MaxKeyLen)
+		s.sectorSize = (min + erase - 1) / erase * erase
+	}
+	s.sectors = cfg.Sectors
+	if s.sectors == 0 && s.sectorSize > 0 {
+		s.sectors = int((s.dev.Size() - s.offset) / s.sectorSize)
+	}
+	if s.offset%erase != 0 || s.sectorSize%erase != 0 || s.sectors < 2 ||
+		s.sectorSize < sectorHdrSize+recordHdrSize+MaxKeyLen ||
+		s.offset+int64(s.sectors)*s.sectorSize > s.dev.Size() {
+		return ErrInvalidSize
+	}
+
+	err := s.mount()
+	if err == errNoSector {
+		err = s.Format()
+	}
+	return err
+}
+
+var errNoSector = errors.New("kvstore: no valid sector")
+
+// mount finds the active sector and the end of its log.
+func (s *Store) mount() error {
+	found := false
+	for i := 0; i < s.sectors; i++ {
+		seq, ok, err := s.readSectorHeader(i)
+		if err != nil {
+			return err
+		}
+		if ok && (!found || int32(seq-s.seq) > 0) {
+			found = true
+			s.active = i
+			s.seq = seq
+		}
+	}
+	if !found {
+		return errNoSector
+	}
+
+	// Find the end of the log.
+	s.end = sectorHdrSize
+	for {
+		r, err := s.readRecord(s.end)
+		if err == errEndOfLog {
+			break
+		}
+		if err == errCorrupt {
+			// Nothing more can be appended safely; the next write moves
+			// the valid records to a fresh sector.
+			s.end = s.sectorSize
+			break
+		}
+		if err != nil {
+			return err
+		}
+		s.end = r.next
+	}
+	s.mounted = true
+	return nil
+}
+
+// Format erases the store, removing all keys.
+func (s *Store) Format() error {
+	s.mounted = false
+	if err := s.eraseSector(0); err != nil {
+		return err
+	}
+	if err := s.writeSectorHeader(0, 1); err != nil {
+		return err
+	}
+	// Invalidate any other sector, so it is not mistaken for a newer one.
+	for i := 1; i < s.sectors; i++ {
+		_, ok, err := s.readSectorHeader(i)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := s.eraseSector(i); err != nil {
+				return err
+			}
+		}
+	}
+	s.active = 0
+	s.seq = 1
+	s.end = sectorHdrSize
+	s.mounted = true
+	return nil
+}
+
+// Get reads the value of key into buf and returns its length. If buf is too
+// small, ErrBufferSize is returned together with the length of the value.
+func (s *Store) Get(key string, buf []byte) (int, error) {
+	r, err := s.find(key)
+	if err != nil {
+		return 0, err
+	}
+	if int(r.valueLen) > len(buf) {
+		return int(r.valueLen), ErrBufferSize
+	}
+	err = s.readAt(buf[:r.valueLen], s.valueAddr(r))
+	return int(r.valueLen), err
+}
+
+// Len returns the length of the value of key.
+func (s *Store) Len(key string) (int, error) {
+	r, err := s.find(key)
+	return int(r.valueLen), err
+}
+
+// Has reports whether key is in the store.
+func (s *Store) Has(key string) bool {
+	_, err := s.find(key)
+	return err == nil
+}
+
+// Set stores value under key, replacing any previous value.
+func (s *Store) Set(key string, value []byte) error {
+	return s.append(key, value, 0)
+}
+
+// Delete removes key from the store. Deleting a key that does not exist is
+// not an error.
+func (s *Store) Delete(key string) error {
+	if !s.Has(key) {
+		return nil
+	}
+	return s.append(key, nil, flagDelete)
+}
+
+// Keys calls fn for every key in the store, until fn returns false.
+func (s *Store) Keys(fn func(key string) bool) error {
+	live, err := s.live()
+	if err != nil {
+		return err
+	}
+	for _, r := range live {
+		if !fn(r.key) {
+			break
+		}
+	}
+	return nil
+}
+
+// find returns the latest record of key.
+func (s *Store) find(key string) (record, error) {
+	if !s.mounted {
+		return record{}, ErrNotMounted
+	}
+	var found record
+	ok := false
+	for off := int64(sectorHdrSize); off < s.end; {
+		r, err := s.readRecord(off)
+		if err != nil {
+			if err == errEndOfLog || err == errCorrupt {
+				break
+			}
+			return record{}, err
+		}
+		if string(s.buf[recordHdrSize:recordHdrSize+int(r.keyLen)]) == key {
+			found, ok = r, true
+		}
+		off = r.next
+	}
+	if !ok || found.flags&flagDelete != 0 {
+		return record{}, ErrNotFound
+	}
+	return found, nil
+}
+
+// live returns the latest record of every key that is not deleted, in log
+// order.
+func (s *Store) live() ([]record, error) {
+	if !s.mounted {
+		return nil, ErrNotMounted
+	}
+	var records []record
+	index := map[string]int{}
+	for off := int64(sectorHdrSize); off < s.end; {
+		r, err := s.readRecord(off)
+		if err != nil {
+			if err == errEndOfLog || err == errCorrupt {
+				break
+			}
+			return nil, err
+		}
+		r.key = string(s.buf[recordHdrSize : recordHdrSize+int(r.keyLen)])
+		if i, ok := index[r.key]; ok {
+			records[i].key = "" // superseded
+		}
+		index[r.key] = len(records)
+		records = append(records, r)
+		off = r.next
+	}
+	n := 0
+	for _, r := range records {
+		if r.key != "" && r.flags&flagDelete == 0 {
+			records[n] = r
+			n++
+		}
+	}
+	return records[:n], nil
+}
+
+// append adds a record to the log, compacting the store if the active
+// sector is full.
+func (s *Store) append(key string, value []byte, flags byte) error {
+	if !s.mounted {
+		return ErrNotMounted
+	}
+	if len(key) == 0 || len(key) > MaxKeyLen {
+		return ErrKeyTooLong
+	}
+	size := recordSize(len(key), len(value))
+	if len(value) > 0xFFFF || size > s.sectorSize-sectorHdrSize {
+		return ErrValueTooBig
+	}
+	if s.end+size > s.sectorSize {
+		if flags&flagDelete != 0 {
+			// Leaving the key behind during compaction deletes it, without
+			// the need for room for a record.
+			return s.compact(key)
+		}
+		if err := s.compact(""); err != nil {
+			return err
+		}
+		if s.end+size > s.sectorSize {
+			return ErrFull
+		}
+	}
+	if err := s.writeRecord(s.active, s.end, key, value, flags); err != nil {
+		// Whatever was written can't be overwritten; skip past it.
+		s.end = s.sectorSize
+		return err
+	}
+	s.end += size
+	return nil
+}
+
+// compact copies the live records of the active sector, except for the
+// record of drop, to the next sector and makes it the active sector.
+func (s *Store) compact(drop string) error {
+	live, err := s.live()
+	if err != nil {
+		return err
+	}
+	next := (s.active + 1) % s.sectors
+	if err := s.eraseSector(next); err != nil {
+		return err
+	}
+	end := int64(sectorHdrSize)
+	var value []byte
+	for _, r := range live {
+		if r.key == drop {
+			continue
+		}
+		size := recordSize(len(r.key), int(r.valueLen))
+		if cap(value) < int(r.valueLen) {
+			value = make([]byte, r.valueLen)
+		}
+		value = value[:r.valueLen]
+		if err := s.readAt(value, s.valueAddr(r)); err != nil {
+			return err
+		}
+		if err := s.writeRecord(next, end, r.key, value, 0); err != nil {
+			return err
+		}
+		end += size
+	}
+	// Writing the header commits the new sector.
+	if err := s.writeSectorHeader(next, s.seq+1); err != nil {
+		return err
+	}
+	s.active = next
+	s.seq++
+	s.end = end
+	return nil
+}
+
+func (s *Store) sectorAddr(sector int) int64 {
+	return s.offset + int64(sector)*s.sectorSize
+}
+
+func (s *Store) eraseSector(sector int) error {
+	erase := s.dev.EraseBlockSize()
+	return s.dev.EraseBlocks(s.sectorAddr(sector)/erase, s.sectorSize/erase)
+}
+
+func (s *Store) readSectorHeader(sector int) (seq uint32, ok bool, err error) {
+	var hdr [sectorHdrSize]byte
+	if err := s.readAt(hdr[:], s.sectorAddr(sector)); err != nil {
+		return 0, false, err
+	}
+	ok = binary.LittleEndian.Uint32(hdr[0:]) == sectorMagic &&
+		binary.LittleEndian.Uint32(hdr[8:]) == crc32.ChecksumIEEE(hdr[:8])
+	return binary.LittleEndian.Uint32(hdr[4:]), ok, nil
+}
+
+func (s *Store) writeSectorHeader(sector int, seq uint32) error {
+	var hdr [sectorHdrSize]byte
+	binary.LittleEndian.PutUint32(hdr[0:], sectorMagic)
+	binary.LittleEndian.PutUint32(hdr[4:], seq)
+	binary.LittleEndian.PutUint32(hdr[8:], crc32.ChecksumIEEE(hdr[:8]))
+	_, err := s.dev.WriteAt(hdr[:], s.sectorAddr(sector))
+	return err
+}
+
+func (s *Store) readAt(buf []byte, off int64) error {
+	_, err := s.dev.ReadAt(buf, off)
+	return err
+}
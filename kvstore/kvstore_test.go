@@ -0,0 +1,295 @@
+package kvstore
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/at24cx"
+	"tinygo.org/x/drivers/flash"
+	"tinygo.org/x/drivers/tester"
+)
+
+// newFlash returns a simulated 2MiB NOR flash chip with its driver. The chip
+// fails the test if the store programs memory that isn't erased.
+func newFlash(c *qt.C) (*tester.NORFlash, *flash.Device) {
+	chip := tester.NewNORFlash(c, 1<<21)
+	chip.StrictProgram = true
+	return chip, newFlashDevice(c, chip)
+}
+
+// newFlashDevice configures a driver for chip, as after a reboot.
+func newFlashDevice(c *qt.C, chip *tester.NORFlash) *flash.Device {
+	dev := flash.NewSPI(chip, chip.CS())
+	c.Assert(dev.Configure(&flash.DeviceConfig{Identifier: flash.DefaultDeviceIdentifier}), qt.IsNil)
+	return dev
+}
+
+// newTestStore mounts a store in the first sectors of dev.
+func newTestStore(c *qt.C, dev BlockDevice, sectors int) *Store {
+	s := New(dev)
+	c.Assert(s.Configure(Config{Sectors: sectors}), qt.IsNil)
+	return s
+}
+
+func TestSetGet(t *testing.T) {
+	c := qt.New(t)
+	_, dev := newFlash(c)
+	s := newTestStore(c, dev, 4)
+
+	_, err := s.Get("missing", nil)
+	c.Assert(err, qt.Equals, ErrNotFound)
+
+	c.Assert(s.Set("ssid", []byte("tinygo")), qt.IsNil)
+	c.Assert(s.Set("pass", []byte("secret")), qt.IsNil)
+	c.Assert(s.Set("ssid", []byte("tinygo-5g")), qt.IsNil)
+
+	buf := make([]byte, 32)
+	n, err := s.Get("ssid", buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(buf[:n]), qt.Equals, "tinygo-5g")
+
+	n, err = s.Get("pass", buf[:3])
+	c.Assert(err, qt.Equals, ErrBufferSize)
+	c.Assert(n, qt.Equals, 6)
+
+	c.Assert(s.Delete("pass"), qt.IsNil)
+	c.Assert(s.Has("pass"), qt.IsFalse)
+	c.Assert(s.Delete("pass"), qt.IsNil)
+
+	// Values survive a remount.
+	s = newTestStore(c, dev, 4)
+	str, err := s.GetString("ssid")
+	c.Assert(err, qt.IsNil)
+	c.Assert(str, qt.Equals, "tinygo-5g")
+	c.Assert(s.Has("pass"), qt.IsFalse)
+
+	var keys []string
+	c.Assert(s.Keys(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	}), qt.IsNil)
+	c.Assert(keys, qt.DeepEquals, []string{"ssid"})
+
+	c.Assert(s.Set("", nil), qt.Equals, ErrKeyTooLong)
+	c.Assert(s.Set(string(make([]byte, MaxKeyLen+1)), nil), qt.Equals, ErrKeyTooLong)
+	c.Assert(s.Set("big", make([]byte, 4096)), qt.Equals, ErrValueTooBig)
+}
+
+func TestTyped(t *testing.T) {
+	c := qt.New(t)
+	_, dev := newFlash(c)
+	s := newTestStore(c, dev, 2)
+
+	c.Assert(s.SetUint32("u32", 0xDEADBEEF), qt.IsNil)
+	c.Assert(s.SetInt64("i64", -1234567890123), qt.IsNil)
+	c.Assert(s.SetFloat32("f32", 1.25), qt.IsNil)
+	c.Assert(s.SetBool("bool", true), qt.IsNil)
+
+	u, err := s.GetUint32("u32")
+	c.Assert(err, qt.IsNil)
+	c.Assert(u, qt.Equals, uint32(0xDEADBEEF))
+	i, err := s.GetInt64("i64")
+	c.Assert(err, qt.IsNil)
+	c.Assert(i, qt.Equals, int64(-1234567890123))
+	f, err := s.GetFloat32("f32")
+	c.Assert(err, qt.IsNil)
+	c.Assert(f, qt.Equals, float32(1.25))
+	b, err := s.GetBool("bool")
+	c.Assert(err, qt.IsNil)
+	c.Assert(b, qt.IsTrue)
+
+	// A value of the wrong size is reported.
+	_, err = s.GetUint32("i64")
+	c.Assert(err, qt.Equals, ErrBufferSize)
+
+	for want := uint32(1); want <= 3; want++ {
+		n, err := s.Increment("boots")
+		c.Assert(err, qt.IsNil)
+		c.Assert(n, qt.Equals, want)
+	}
+}
+
+func TestWearLeveling(t *testing.T) {
+	c := qt.New(t)
+	chip, dev := newFlash(c)
+	s := newTestStore(c, dev, 4)
+	c.Assert(s.SetString("name", "sensor-1"), qt.IsNil)
+
+	for i := 0; i < 5000; i++ {
+		c.Assert(s.SetUint32("counter", uint32(i)), qt.IsNil)
+	}
+	v, err := s.GetUint32("counter")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, uint32(4999))
+	name, err := s.GetString("name")
+	c.Assert(err, qt.IsNil)
+	c.Assert(name, qt.Equals, "sensor-1")
+
+	// All sectors are erased about equally often.
+	erases := chip.Erases[:4]
+	min, max := erases[0], erases[0]
+	for _, n := range erases {
+		if n < min {
+			min = n
+		}
+		if n > max {
+			max = n
+		}
+	}
+	c.Assert(min > 0, qt.IsTrue)
+	c.Assert(max-min <= 1, qt.IsTrue, qt.Commentf("erases: %v", erases))
+}
+
+func TestFull(t *testing.T) {
+	c := qt.New(t)
+	_, dev := newFlash(c)
+	s := newTestStore(c, dev, 2)
+	var err error
+	for i := 0; err == nil; i++ {
+		err = s.Set(fmt.Sprintf("key%d", i), make([]byte, 100))
+	}
+	c.Assert(err, qt.Equals, ErrFull)
+	// Existing keys can still be deleted to make room.
+	c.Assert(s.Delete("key0"), qt.IsNil)
+	c.Assert(s.Set("new", make([]byte, 100)), qt.IsNil)
+}
+
+func TestPowerLoss(t *testing.T) {
+	// Cut the power after every possible number of written bytes while
+	// updating a value, through a compaction, and check that the store
+	// always holds either the old or the new value after a reboot.
+	c := qt.New(t)
+	chip, dev := newFlash(c)
+	s := newTestStore(c, dev, 2)
+	c.Assert(s.SetString("const", "calibration"), qt.IsNil)
+	// Fill most of the first sector, so that the cuts hit the compaction.
+	for i := 0; i < 200; i++ {
+		c.Assert(s.SetUint32("counter", uint32(i)), qt.IsNil)
+	}
+	image := append([]byte(nil), chip.Data[:2*4096]...)
+
+	for cut := 0; cut < 400; cut++ {
+		copy(chip.Data, image)
+		chip.PowerCycle()
+		dev = newFlashDevice(c, chip)
+		s = newTestStore(c, dev, 2)
+
+		chip.WriteLimit = cut
+		last := 199
+		for i := 200; i < 300; i++ {
+			err := s.SetUint32("counter", uint32(i))
+			if chip.Err == tester.ErrPowerLoss {
+				// The power was lost during this update, which may have
+				// returned before the program completed.
+				break
+			}
+			c.Assert(err, qt.IsNil)
+			last = i
+		}
+
+		// reboot
+		chip.PowerCycle()
+		dev = newFlashDevice(c, chip)
+		s = newTestStore(c, dev, 2)
+		str, err := s.GetString("const")
+		c.Assert(err, qt.IsNil, qt.Commentf("cut=%d", cut))
+		c.Assert(str, qt.Equals, "calibration")
+		v, err := s.GetUint32("counter")
+		c.Assert(err, qt.IsNil, qt.Commentf("cut=%d", cut))
+		c.Assert(v == uint32(last) || v == uint32(last+1), qt.IsTrue, qt.Commentf("cut=%d v=%d last=%d", cut, v, last))
+
+		// The store keeps working after the power loss.
+		c.Assert(s.SetUint32("counter", 1000), qt.IsNil)
+		for i := 0; i < 300; i++ {
+			c.Assert(s.SetUint32("other", uint32(i)), qt.IsNil)
+		}
+		v, err = s.GetUint32("counter")
+		c.Assert(err, qt.IsNil)
+		c.Assert(v, qt.Equals, uint32(1000))
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	c := qt.New(t)
+	chip, dev := newFlash(c)
+
+	// A store in the second half of the device.
+	s := New(dev)
+	c.Assert(s.Configure(Config{Offset: 4 * 4096, SectorSize: 8192, Sectors: 2}), qt.IsNil)
+	c.Assert(s.Set("k", []byte("v")), qt.IsNil)
+	c.Assert(chip.Data[:4*4096], qt.DeepEquals, bytes.Repeat([]byte{0xFF}, 4*4096))
+
+	c.Assert(s.Configure(Config{Sectors: 1}), qt.Equals, ErrInvalidSize)
+	c.Assert(s.Configure(Config{Offset: 100}), qt.Equals, ErrInvalidSize)
+	c.Assert(s.Configure(Config{Offset: 1<<21 - 4*4096, Sectors: 5}), qt.Equals, ErrInvalidSize)
+
+	// Format removes all keys.
+	c.Assert(s.Configure(Config{Offset: 4 * 4096, SectorSize: 8192, Sectors: 2}), qt.IsNil)
+	c.Assert(s.Has("k"), qt.IsTrue)
+	c.Assert(s.Format(), qt.IsNil)
+	c.Assert(s.Has("k"), qt.IsFalse)
+}
+
+func TestEEPROM(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	eeprom := tester.NewEEPROM(c, at24cx.Address, 4096, 32)
+	bus.AddDevice(eeprom)
+	dev := at24cx.New(bus)
+	dev.Configure(at24cx.Config{})
+
+	s := New(&dev)
+	c.Assert(s.Configure(Config{SectorSize: 1024}), qt.IsNil)
+	for i := 0; i < 50; i++ {
+		c.Assert(s.SetUint32("counter", uint32(i)), qt.IsNil)
+	}
+	c.Assert(s.SetString("ssid", "tinygo"), qt.IsNil)
+
+	s = New(&dev)
+	c.Assert(s.Configure(Config{SectorSize: 1024}), qt.IsNil)
+	v, err := s.GetUint32("counter")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, uint32(49))
+	str, err := s.GetString("ssid")
+	c.Assert(err, qt.IsNil)
+	c.Assert(str, qt.Equals, "tinygo")
+
+	// The default sectors are made of enough pages for the longest key.
+	bus = tester.NewI2CBus(c)
+	bus.AddDevice(tester.NewEEPROM(c, at24cx.Address, 4096, 32))
+	dev = at24cx.New(bus)
+	dev.Configure(at24cx.Config{})
+	s = New(&dev)
+	c.Assert(s.Configure(Config{}), qt.IsNil)
+	c.Assert(s.sectorSize, qt.Equals, int64(96))
+	key := strings.Repeat("k", MaxKeyLen)
+	c.Assert(s.Set(key, nil), qt.IsNil)
+	c.Assert(s.Has(key), qt.IsTrue)
+}
+
+func TestFlash(t *testing.T) {
+	c := qt.New(t)
+	chip, dev := newFlash(c)
+
+	// A store in the last 16KiB of the chip.
+	cfg := Config{Offset: 1<<21 - 4*4096}
+	s := New(dev)
+	c.Assert(s.Configure(cfg), qt.IsNil)
+	for i := 0; i < 1000; i++ {
+		c.Assert(s.SetUint32("counter", uint32(i)), qt.IsNil)
+	}
+	for i, n := range chip.Erases {
+		if i < len(chip.Erases)-4 {
+			c.Assert(n, qt.Equals, 0, qt.Commentf("sector %d", i))
+		}
+	}
+
+	s = New(dev)
+	c.Assert(s.Configure(cfg), qt.IsNil)
+	v, err := s.GetUint32("counter")
+	c.Assert(err, qt.IsNil)
+	c.Assert(v, qt.Equals, uint32(999))
+}
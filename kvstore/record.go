@@ -0,0 +1,115 @@
+package kvstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// A record is stored as an 8 byte header followed by the key and the value,
+// padded to a multiple of 4 bytes:
+//
+//	0: key length (1..MaxKeyLen)
+//	1: flags
+//	2: value length, 16 bits little endian
+//	4: CRC32 (IEEE) of bytes 0-3, the key and the value
+type record struct {
+	off      int64 // offset in the sector
+	next     int64 // offset of the next record
+	keyLen   uint8
+	flags    uint8
+	valueLen uint16
+	key      string // only set by live
+}
+
+var (
+	errEndOfLog = errors.New("kvstore: end of log")
+	errCorrupt  = errors.New("kvstore: corrupt record")
+)
+
+func recordSize(keyLen, valueLen int) int64 {
+	size := int64(recordHdrSize + keyLen + valueLen)
+	return (size + recordAlign - 1) &^ (recordAlign - 1)
+}
+
+// readRecord reads and verifies the record at off in the active sector. The
+// key is left in s.buf[recordHdrSize:].
+func (s *Store) readRecord(off int64) (record, error) {
+	if off+recordHdrSize > s.sectorSize {
+		return record{}, errEndOfLog
+	}
+	base := s.sectorAddr(s.active)
+	hdr := s.buf[:recordHdrSize]
+	if err := s.readAt(hdr, base+off); err != nil {
+		return record{}, err
+	}
+	if binary.LittleEndian.Uint64(hdr) == 0xFFFFFFFFFFFFFFFF {
+		return record{}, errEndOfLog
+	}
+	r := record{
+		off:      off,
+		keyLen:   hdr[0],
+		flags:    hdr[1],
+		valueLen: binary.LittleEndian.Uint16(hdr[2:]),
+	}
+	r.next = off + recordSize(int(r.keyLen), int(r.valueLen))
+	if r.keyLen == 0 || r.keyLen > MaxKeyLen || r.next > s.sectorSize {
+		return record{}, errCorrupt
+	}
+	want := binary.LittleEndian.Uint32(hdr[4:])
+
+	key := s.buf[recordHdrSize : recordHdrSize+int(r.keyLen)]
+	if err := s.readAt(key, base+off+recordHdrSize); err != nil {
+		return record{}, err
+	}
+	crc := crc32.ChecksumIEEE(hdr[:4])
+	crc = crc32.Update(crc, crc32.IEEETable, key)
+	var chunk [32]byte
+	addr := base + off + recordHdrSize + int64(r.keyLen)
+	for n := int(r.valueLen); n > 0; {
+		c := chunk[:]
+		if n < len(c) {
+			c = c[:n]
+		}
+		if err := s.readAt(c, addr); err != nil {
+			return record{}, err
+		}
+		crc = crc32.Update(crc, crc32.IEEETable, c)
+		addr += int64(len(c))
+		n -= len(c)
+	}
+	if crc != want {
+		return record{}, errCorrupt
+	}
+	return r, nil
+}
+
+// writeRecord writes a record at off in the given sector.
+func (s *Store) writeRecord(sector int, off int64, key string, value []byte, flags byte) error {
+	buf := s.buf[:recordHdrSize+len(key)]
+	buf[0] = byte(len(key))
+	buf[1] = flags
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(value)))
+	copy(buf[recordHdrSize:], key)
+	crc := crc32.ChecksumIEEE(buf[:4])
+	crc = crc32.Update(crc, crc32.IEEETable, buf[recordHdrSize:])
+	crc = crc32.Update(crc, crc32.IEEETable, value)
+	binary.LittleEndian.PutUint32(buf[4:], crc)
+
+	addr := s.sectorAddr(sector) + off
+	if _, err := s.dev.WriteAt(buf, addr); err != nil {
+		return err
+	}
+	if len(value) > 0 {
+		if _, err := s.dev.WriteAt(value, addr+int64(len(buf))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueAddr returns the device address of the value of r in the active
+// sector.
+func (s *Store) valueAddr(r record) int64 {
+	return s.sectorAddr(s.active) + r.off + recordHdrSize + int64(r.keyLen)
+}
@@ -0,0 +1,105 @@
+package kvstore
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// getFixed reads a value that must be exactly len(buf) bytes long.
+func (s *Store) getFixed(key string, buf []byte) error {
+	n, err := s.Get(key, buf)
+	if err == nil && n != len(buf) {
+		err = ErrBufferSize
+	}
+	return err
+}
+
+// GetUint32 returns the value of key stored with SetUint32.
+func (s *Store) GetUint32(key string) (uint32, error) {
+	var buf [4]byte
+	if err := s.getFixed(key, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(buf[:]), nil
+}
+
+// SetUint32 stores a uint32 under key.
+func (s *Store) SetUint32(key string, v uint32) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return s.Set(key, buf[:])
+}
+
+// GetInt64 returns the value of key stored with SetInt64.
+func (s *Store) GetInt64(key string) (int64, error) {
+	var buf [8]byte
+	if err := s.getFixed(key, buf[:]); err != nil {
+		return 0, err
+	}
+	return int64(binary.LittleEndian.Uint64(buf[:])), nil
+}
+
+// SetInt64 stores an int64 under key.
+func (s *Store) SetInt64(key string, v int64) error {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(v))
+	return s.Set(key, buf[:])
+}
+
+// GetFloat32 returns the value of key stored with SetFloat32.
+func (s *Store) GetFloat32(key string) (float32, error) {
+	v, err := s.GetUint32(key)
+	return math.Float32frombits(v), err
+}
+
+// SetFloat32 stores a float32 under key.
+func (s *Store) SetFloat32(key string, v float32) error {
+	return s.SetUint32(key, math.Float32bits(v))
+}
+
+// GetBool returns the value of key stored with SetBool.
+func (s *Store) GetBool(key string) (bool, error) {
+	var buf [1]byte
+	if err := s.getFixed(key, buf[:]); err != nil {
+		return false, err
+	}
+	return buf[0] != 0, nil
+}
+
+// SetBool stores a bool under key.
+func (s *Store) SetBool(key string, v bool) error {
+	var buf [1]byte
+	if v {
+		buf[0] = 1
+	}
+	return s.Set(key, buf[:])
+}
+
+// GetString returns the value of key as a string.
+func (s *Store) GetString(key string) (string, error) {
+	n, err := s.Len(key)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := s.Get(key, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// SetString stores a string under key.
+func (s *Store) SetString(key string, v string) error {
+	return s.Set(key, []byte(v))
+}
+
+// Increment adds one to the uint32 counter stored under key and returns the
+// new value. A missing counter starts at zero.
+func (s *Store) Increment(key string) (uint32, error) {
+	v, err := s.GetUint32(key)
+	if err != nil && err != ErrNotFound {
+		return 0, err
+	}
+	v++
+	return v, s.SetUint32(key, v)
+}
@@ -0,0 +1,159 @@
+// Package ledstrip provides a pixel buffer for addressable LED strips, such
+// as WS2812, SK6812 and APA102 strips. It encodes the colors into the bytes
+// expected by the LEDs, with their channel order, an optional white channel,
+// global brightness and gamma correction.
+//
+// The alpha channel of the colors is ignored.
+package ledstrip // import "tinygo.org/x/drivers/ledstrip"
+
+import (
+	"image/color"
+	"math"
+)
+
+// ColorOrder is the order in which the LEDs expect the color channels.
+type ColorOrder uint8
+
+const (
+	GRB ColorOrder = iota // WS2812, SK6812 RGB
+	RGB
+	BRG
+	BGR // APA102
+	RBG
+	GBR
+	GRBW // SK6812 RGBW
+	RGBW
+)
+
+// channel indexes for each order: 0 is red, 1 green, 2 blue and 3 white
+var orders = [...][4]uint8{
+	GRB:  {1, 0, 2},
+	RGB:  {0, 1, 2},
+	BRG:  {2, 0, 1},
+	BGR:  {2, 1, 0},
+	RBG:  {0, 2, 1},
+	GBR:  {1, 2, 0},
+	GRBW: {1, 0, 2, 3},
+	RGBW: {0, 1, 2, 3},
+}
+
+// Channels returns the number of bytes per LED: 4 for orders with a white
+// channel, 3 for the others.
+func (o ColorOrder) Channels() int {
+	if o == GRBW || o == RGBW {
+		return 4
+	}
+	return 3
+}
+
+// Buffer holds the colors of the LEDs of a strip.
+type Buffer struct {
+	// Pixels holds the color of each LED, starting from the one connected
+	// to the microcontroller.
+	Pixels []color.RGBA
+
+	order      ColorOrder
+	brightness uint8
+	gamma      float32
+	lut        [256]uint8 // gamma and brightness
+}
+
+// NewBuffer returns a buffer for n LEDs with the given color order, at full
+// brightness and without gamma correction.
+func NewBuffer(n int, order ColorOrder) *Buffer {
+	b := &Buffer{
+		Pixels:     make([]color.RGBA, n),
+		order:      order,
+		brightness: 255,
+	}
+	b.buildLUT()
+	return b
+}
+
+// Order returns the color order of the LEDs.
+func (b *Buffer) Order() ColorOrder {
+	return b.order
+}
+
+// Brightness returns the global brightness, 255 being full brightness.
+func (b *Buffer) Brightness() uint8 {
+	return b.brightness
+}
+
+// SetBrightness sets the global brightness, that scales all channels. 255 is
+// full brightness.
+func (b *Buffer) SetBrightness(brightness uint8) {
+	b.brightness = brightness
+	b.buildLUT()
+}
+
+// SetGamma sets the gamma correction, 2.2 to 2.8 being common values for
+// LEDs. Zero disables gamma correction.
+func (b *Buffer) SetGamma(gamma float32) {
+	b.gamma = gamma
+	b.buildLUT()
+}
+
+// buildLUT computes the table of corrected channel values.
+func (b *Buffer) buildLUT() {
+	for i := range b.lut {
+		v := float64(i) / 255
+		if b.gamma > 0 {
+			v = math.Pow(v, float64(b.gamma))
+		}
+		b.lut[i] = uint8(v*float64(b.brightness) + 0.5)
+	}
+}
+
+// Len returns the number of LEDs.
+func (b *Buffer) Len() int {
+	return len(b.Pixels)
+}
+
+// Fill sets all LEDs to the same color.
+func (b *Buffer) Fill(c color.RGBA) {
+	for i := range b.Pixels {
+		b.Pixels[i] = c
+	}
+}
+
+// EncodedLen returns the number of bytes of the encoded colors of all LEDs.
+func (b *Buffer) EncodedLen() int {
+	return len(b.Pixels) * b.order.Channels()
+}
+
+// Encode writes the bytes for the LED at index i to dst, which must hold at
+// least Order().Channels() bytes, and returns the number of bytes written.
+// With a white channel, the white part of the color is shown by the white
+// LED only.
+func (b *Buffer) Encode(dst []byte, i int) int {
+	c := b.Pixels[i]
+	ch := [4]uint8{c.R, c.G, c.B}
+	n := b.order.Channels()
+	if n == 4 {
+		w := c.R
+		if c.G < w {
+			w = c.G
+		}
+		if c.B < w {
+			w = c.B
+		}
+		ch = [4]uint8{c.R - w, c.G - w, c.B - w, w}
+	}
+	order := &orders[b.order]
+	for j := 0; j < n; j++ {
+		dst[j] = b.lut[ch[order[j]]]
+	}
+	return n
+}
+
+// AppendBytes appends the bytes for all LEDs to dst and returns the extended
+// slice.
+func (b *Buffer) AppendBytes(dst []byte) []byte {
+	var buf [4]byte
+	for i := range b.Pixels {
+		n := b.Encode(buf[:], i)
+		dst = append(dst, buf[:n]...)
+	}
+	return dst
+}
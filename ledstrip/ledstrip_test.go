@@ -0,0 +1,68 @@
+package ledstrip
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEncodeOrder(t *testing.T) {
+	c := qt.New(t)
+	px := color.RGBA{R: 1, G: 2, B: 3}
+	for _, tc := range []struct {
+		order ColorOrder
+		want  []byte
+	}{
+		{GRB, []byte{2, 1, 3}},
+		{RGB, []byte{1, 2, 3}},
+		{BRG, []byte{3, 1, 2}},
+		{BGR, []byte{3, 2, 1}},
+		{RBG, []byte{1, 3, 2}},
+		{GBR, []byte{2, 3, 1}},
+	} {
+		b := NewBuffer(2, tc.order)
+		b.Pixels[1] = px
+		buf := make([]byte, 4)
+		n := b.Encode(buf, 1)
+		c.Assert(buf[:n], qt.DeepEquals, tc.want, qt.Commentf("order %d", tc.order))
+		c.Assert(b.AppendBytes(nil), qt.DeepEquals, append([]byte{0, 0, 0}, tc.want...))
+		c.Assert(b.EncodedLen(), qt.Equals, 6)
+	}
+}
+
+func TestEncodeWhite(t *testing.T) {
+	c := qt.New(t)
+	b := NewBuffer(3, GRBW)
+	b.Pixels[0] = color.RGBA{R: 0x80, G: 0x40, B: 0x20}
+	b.Pixels[1] = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF}
+	b.Pixels[2] = color.RGBA{R: 0x10}
+	c.Assert(b.AppendBytes(nil), qt.DeepEquals, []byte{
+		0x20, 0x60, 0x00, 0x20,
+		0x00, 0x00, 0x00, 0xFF,
+		0x00, 0x10, 0x00, 0x00,
+	})
+	c.Assert(b.EncodedLen(), qt.Equals, 12)
+
+	b = NewBuffer(1, RGBW)
+	b.Pixels[0] = color.RGBA{R: 0x80, G: 0x40, B: 0x20}
+	c.Assert(b.AppendBytes(nil), qt.DeepEquals, []byte{0x60, 0x20, 0x00, 0x20})
+}
+
+func TestBrightnessGamma(t *testing.T) {
+	c := qt.New(t)
+	b := NewBuffer(1, RGB)
+	b.Pixels[0] = color.RGBA{R: 0xFF, G: 0x80, B: 0x00}
+	b.SetBrightness(0x80)
+	c.Assert(b.Brightness(), qt.Equals, uint8(0x80))
+	c.Assert(b.AppendBytes(nil), qt.DeepEquals, []byte{0x80, 0x40, 0x00})
+
+	b.SetBrightness(0xFF)
+	b.SetGamma(2)
+	// (128/255)^2 * 255 = 64.25
+	c.Assert(b.AppendBytes(nil), qt.DeepEquals, []byte{0xFF, 0x40, 0x00})
+
+	b.SetGamma(0)
+	b.Fill(color.RGBA{R: 7, G: 8, B: 9})
+	c.Assert(b.AppendBytes(nil), qt.DeepEquals, []byte{7, 8, 9})
+}
@@ -0,0 +1,162 @@
+package max72xx
+
+import (
+	"errors"
+	"image/color"
+
+	"tinygo.org/x/drivers"
+)
+
+var errNoModules = errors.New("max72xx: no modules")
+
+// MatrixConfig is the configuration of a Matrix.
+type MatrixConfig struct {
+	// Modules is the number of 8x8 modules in the chain, side by side. Zero
+	// means 1.
+	Modules int
+
+	// Rotation is the rotation of the modules, clock-wise. With Rotation0,
+	// the digit registers drive the rows from the top, and the most
+	// significant bit the leftmost column. Mirrored rotations are supported.
+	Rotation drivers.Rotation
+
+	// Rotations, if not nil, overrides Rotation for each module, in chain
+	// order, for chains of modules mounted in different orientations.
+	Rotations []drivers.Rotation
+
+	// Reverse is true if the module connected to the microcontroller is the
+	// rightmost one, instead of the leftmost one.
+	Reverse bool
+
+	// Intensity is the brightness of the LEDs, from 0 to 15.
+	Intensity uint8
+}
+
+// Matrix is a display made of cascaded 8x8 LED matrix modules, such as the
+// common FC-16 modules. It implements drivers.Displayer: SetPixel draws into
+// a buffer, and Display sends it to the modules.
+type Matrix struct {
+	dev       *Device
+	rotations []drivers.Rotation
+	reverse   bool
+	buffer    []byte // 8 rows per module, in chain order
+	row       []byte
+}
+
+// NewMatrix returns a new cascade of matrix modules. The SPI bus must already
+// be configured, with a frequency not higher than 10MHz.
+func NewMatrix(bus drivers.SPI, cs drivers.PinOutput) *Matrix {
+	return &Matrix{dev: NewDevice(bus, cs)}
+}
+
+// Configure configures the pins and the modules, and clears them.
+func (m *Matrix) Configure(cfg MatrixConfig) error {
+	n := cfg.Modules
+	if n == 0 {
+		n = 1
+	}
+	if n < 0 {
+		return errNoModules
+	}
+	m.rotations = make([]drivers.Rotation, n)
+	for i := range m.rotations {
+		m.rotations[i] = cfg.Rotation
+		if i < len(cfg.Rotations) {
+			m.rotations[i] = cfg.Rotations[i]
+		}
+	}
+	m.reverse = cfg.Reverse
+	m.buffer = make([]byte, 8*n)
+	m.row = make([]byte, n)
+
+	m.dev.Configure()
+	if err := m.dev.setup(n, 0x00, 7, cfg.Intensity); err != nil {
+		return err
+	}
+	return m.Display()
+}
+
+// SetIntensity sets the brightness of all modules, from 0 to 15.
+func (m *Matrix) SetIntensity(intensity uint8) error {
+	if intensity > 0x0F {
+		intensity = 0x0F
+	}
+	return m.dev.writeAll(REG_INTENSITY, intensity, len(m.row))
+}
+
+// Size returns the size of the display in pixels.
+func (m *Matrix) Size() (x, y int16) {
+	return int16(8 * len(m.row)), 8
+}
+
+// locate returns the index in the buffer and the bit of the pixel at x, y.
+func (m *Matrix) locate(x, y int16) (int, byte, bool) {
+	w, h := m.Size()
+	if x < 0 || x >= w || y < 0 || y >= h {
+		return 0, 0, false
+	}
+	module := int(x / 8)
+	if m.reverse {
+		module = len(m.row) - 1 - module
+	}
+	x %= 8
+	rotation := m.rotations[module]
+	if rotation >= drivers.Rotation0Mirror {
+		x = 7 - x
+	}
+	switch rotation % 4 {
+	case drivers.Rotation90:
+		x, y = 7-y, x
+	case drivers.Rotation180:
+		x, y = 7-x, 7-y
+	case drivers.Rotation270:
+		x, y = y, 7-x
+	}
+	return 8*module + int(y), 0x80 >> uint(x), true
+}
+
+// SetPixel sets the pixel at x, y in the buffer. Any color other than black
+// turns the LED on.
+func (m *Matrix) SetPixel(x, y int16, c color.RGBA) {
+	i, bit, ok := m.locate(x, y)
+	if !ok {
+		return
+	}
+	if c.R != 0 || c.G != 0 || c.B != 0 {
+		m.buffer[i] |= bit
+	} else {
+		m.buffer[i] &^= bit
+	}
+}
+
+// GetPixel returns whether the LED at x, y is on in the buffer.
+func (m *Matrix) GetPixel(x, y int16) bool {
+	i, bit, ok := m.locate(x, y)
+	return ok && m.buffer[i]&bit != 0
+}
+
+// ClearBuffer turns all LEDs off in the buffer.
+func (m *Matrix) ClearBuffer() {
+	for i := range m.buffer {
+		m.buffer[i] = 0
+	}
+}
+
+// ClearDisplay turns all LEDs off.
+func (m *Matrix) ClearDisplay() error {
+	m.ClearBuffer()
+	return m.Display()
+}
+
+// Display sends the buffer to the modules.
+func (m *Matrix) Display() error {
+	for row := 0; row < 8; row++ {
+		for i := range m.row {
+			m.row[i] = m.buffer[8*i+row]
+		}
+		if err := m.dev.WriteCommands(REG_DIGIT0+byte(row), m.row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
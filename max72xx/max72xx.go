@@ -1,20 +1,26 @@
 // Driver works for max7219 and 7221
+//
+// Device writes the registers of a single chip. Matrix and SevenSegment drive
+// cascades of chips, with 8x8 LED matrices or 7-segment digits.
+//
 // Datasheet: https://datasheets.maximintegrated.com/en/ds/MAX7219-MAX7221.pdf
 package max72xx
 
 import (
-	"machine"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 type Device struct {
-	bus machine.SPI
-	cs  machine.Pin
+	bus drivers.SPI
+	cs  drivers.PinOutput
+	buf []byte // for WriteCommands
 }
 
 // NewDriver creates a new max7219 connection. The SPI wire must already be configured
 // The SPI frequency must not be higher than 10MHz.
 // parameter cs: the datasheet also refers to this pin as "load" pin.
-func NewDevice(bus machine.SPI, cs machine.Pin) *Device {
+func NewDevice(bus drivers.SPI, cs drivers.PinOutput) *Device {
 	return &Device{
 		bus: bus,
 		cs:  cs,
@@ -23,9 +29,7 @@ func NewDevice(bus machine.SPI, cs machine.Pin) *Device {
 
 // Configure setups the pins.
 func (driver *Device) Configure() {
-	outPutConfig := machine.PinConfig{Mode: machine.PinOutput}
-
-	driver.cs.Configure(outPutConfig)
+	legacy.ConfigurePinOut(driver.cs)
 }
 
 // SetScanLimit sets the scan limit. Maximum is 8.
@@ -92,3 +96,62 @@ func (driver *Device) WriteCommand(register, data byte) {
 	driver.writeByte(data)
 	driver.cs.High()
 }
+
+// WriteCommands writes data to a given register of each chip of a cascade,
+// with a single load pulse. data[0] goes to the chip connected to the
+// microcontroller, data[1] to the next one, and so on.
+func (driver *Device) WriteCommands(register byte, data []byte) error {
+	buf := driver.buffer(len(data))
+	// the first bytes sent are shifted to the last chip
+	for i, b := range data {
+		j := len(buf) - 2 - 2*i
+		buf[j] = register
+		buf[j+1] = b
+	}
+	return driver.load(buf)
+}
+
+// writeAll writes data to a given register of the n chips of a cascade.
+func (driver *Device) writeAll(register, data byte, n int) error {
+	buf := driver.buffer(n)
+	for i := 0; i < len(buf); i += 2 {
+		buf[i] = register
+		buf[i+1] = data
+	}
+	return driver.load(buf)
+}
+
+// setup configures the n chips of a cascade for normal operation.
+func (driver *Device) setup(n int, decodeMode, scanLimit, intensity byte) error {
+	if intensity > 0x0F {
+		intensity = 0x0F
+	}
+	for _, cmd := range [...][2]byte{
+		{REG_DISPLAY_TEST, 0x00},
+		{REG_DECODE_MODE, decodeMode},
+		{REG_SCANLIMIT, scanLimit},
+		{REG_INTENSITY, intensity},
+		{REG_SHUTDOWN, 0x01},
+	} {
+		if err := driver.writeAll(cmd[0], cmd[1], n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buffer returns the buffer for a command to n chips.
+func (driver *Device) buffer(n int) []byte {
+	if cap(driver.buf) < 2*n {
+		driver.buf = make([]byte, 2*n)
+	}
+	return driver.buf[:2*n]
+}
+
+// load sends buf, then latches the data into the chips.
+func (driver *Device) load(buf []byte) error {
+	driver.cs.Low()
+	err := driver.bus.Tx(buf, nil)
+	driver.cs.High()
+	return err
+}
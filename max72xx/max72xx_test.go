@@ -0,0 +1,161 @@
+package max72xx
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/segment"
+	"tinygo.org/x/drivers/tester"
+	"tinygo.org/x/tinyfont"
+)
+
+// cascade simulates a chain of chips: the bytes sent on the bus are shifted
+// through the chips, which latch them on the rising edge of the load pin.
+type cascade struct {
+	shift     [][2]byte // shift register of each chip, in chain order
+	registers [][16]byte
+}
+
+func newCascade(c *qt.C, n int) (*cascade, *tester.Pin) {
+	s := &cascade{shift: make([][2]byte, n), registers: make([][16]byte, n)}
+	cs := tester.NewPin(c)
+	cs.Level = true
+	cs.OnChange = func(level bool) {
+		if level {
+			for i, w := range s.shift {
+				s.registers[i][w[0]&0x0F] = w[1]
+			}
+		}
+	}
+	return s, cs
+}
+
+func (s *cascade) Tx(w, r []byte) error {
+	for _, b := range w {
+		s.Transfer(b)
+	}
+	return nil
+}
+
+func (s *cascade) Transfer(b byte) (byte, error) {
+	for i := len(s.shift) - 1; i > 0; i-- {
+		s.shift[i] = [2]byte{s.shift[i][1], s.shift[i-1][0]}
+	}
+	s.shift[0] = [2]byte{s.shift[0][1], b}
+	return 0, nil
+}
+
+// digits returns the digit registers of chip i.
+func (s *cascade) digits(i int) []byte {
+	return s.registers[i][REG_DIGIT0 : REG_DIGIT7+1]
+}
+
+func TestWriteCommands(t *testing.T) {
+	c := qt.New(t)
+	bus, cs := newCascade(c, 3)
+	d := NewDevice(bus, cs)
+	c.Assert(d.WriteCommands(REG_INTENSITY, []byte{1, 2, 3}), qt.IsNil)
+	cs.AssertTransitions(false, true)
+	for i, want := range []byte{1, 2, 3} {
+		c.Assert(bus.registers[i][REG_INTENSITY], qt.Equals, want)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	c := qt.New(t)
+	bus, cs := newCascade(c, 2)
+	m := NewMatrix(bus, cs)
+	c.Assert(m.Configure(MatrixConfig{Modules: 2, Intensity: 20}), qt.IsNil)
+	c.Assert(bus.registers[1][REG_SHUTDOWN], qt.Equals, byte(0x01))
+	c.Assert(bus.registers[1][REG_SCANLIMIT], qt.Equals, byte(7))
+	c.Assert(bus.registers[0][REG_INTENSITY], qt.Equals, byte(0x0F))
+
+	w, h := m.Size()
+	c.Assert([]int16{w, h}, qt.DeepEquals, []int16{16, 8})
+	on := color.RGBA{R: 255, A: 255}
+	m.SetPixel(0, 0, on)
+	m.SetPixel(9, 2, on)
+	m.SetPixel(16, 0, on) // outside
+	c.Assert(m.GetPixel(9, 2), qt.IsTrue)
+	c.Assert(m.Display(), qt.IsNil)
+	c.Assert(bus.digits(0), qt.DeepEquals, []byte{0x80, 0, 0, 0, 0, 0, 0, 0})
+	c.Assert(bus.digits(1), qt.DeepEquals, []byte{0, 0, 0x40, 0, 0, 0, 0, 0})
+
+	m.SetPixel(0, 0, color.RGBA{A: 255})
+	c.Assert(m.GetPixel(0, 0), qt.IsFalse)
+	c.Assert(m.ClearDisplay(), qt.IsNil)
+	c.Assert(bus.digits(1), qt.DeepEquals, make([]byte, 8))
+}
+
+func TestMatrixRotation(t *testing.T) {
+	c := qt.New(t)
+	bus, cs := newCascade(c, 2)
+	m := NewMatrix(bus, cs)
+	c.Assert(m.Configure(MatrixConfig{
+		Modules:   2,
+		Rotation:  drivers.Rotation90,
+		Rotations: []drivers.Rotation{drivers.Rotation180},
+		Reverse:   true,
+	}), qt.IsNil)
+	on := color.RGBA{R: 255, A: 255}
+	m.SetPixel(1, 0, on) // left module, the second one of the chain
+	m.SetPixel(9, 0, on) // right module, the first one of the chain
+	c.Assert(m.Display(), qt.IsNil)
+	c.Assert(bus.digits(0), qt.DeepEquals, []byte{0, 0, 0, 0, 0, 0, 0, 0x02})
+	c.Assert(bus.digits(1), qt.DeepEquals, []byte{0, 1, 0, 0, 0, 0, 0, 0})
+}
+
+func TestScroller(t *testing.T) {
+	c := qt.New(t)
+	bus, cs := newCascade(c, 1)
+	m := NewMatrix(bus, cs)
+	c.Assert(m.Configure(MatrixConfig{}), qt.IsNil)
+	s := &Scroller{Matrix: m, Font: &tinyfont.TomThumb, Text: "I", Y: 6}
+	_, width := tinyfont.LineWidth(s.Font, s.Text)
+
+	steps := 0
+	lit := false
+	for {
+		done, err := s.Step()
+		c.Assert(err, qt.IsNil)
+		steps++
+		for _, row := range bus.digits(0) {
+			lit = lit || row != 0
+		}
+		if done {
+			break
+		}
+	}
+	c.Assert(lit, qt.IsTrue)
+	c.Assert(steps, qt.Equals, 8+int(width))
+	c.Assert(bus.digits(0), qt.DeepEquals, make([]byte, 8))
+}
+
+func TestSevenSegment(t *testing.T) {
+	c := qt.New(t)
+	bus, cs := newCascade(c, 2)
+	d := NewSevenSegment(bus, cs)
+	c.Assert(d.Configure(SevenSegmentConfig{Chips: 2, Digits: 4}), qt.IsNil)
+	c.Assert(bus.registers[1][REG_SCANLIMIT], qt.Equals, byte(3))
+	c.Assert(bus.registers[1][REG_DECODE_MODE], qt.Equals, byte(0))
+	c.Assert(d.Len(), qt.Equals, 8)
+
+	d.SetText("1.2..H", segment.AlignLeft)
+	c.Assert(d.Digits()[:5], qt.DeepEquals, []byte{0x86, 0xDB, 0x80, 0x76, 0})
+	c.Assert(d.Display(), qt.IsNil)
+	// the first digit register drives the rightmost digit
+	c.Assert(bus.digits(0)[:4], qt.DeepEquals, []byte{0x37, 0x80, 0xED, 0xB0})
+	c.Assert(bus.digits(1)[:4], qt.DeepEquals, []byte{0, 0, 0, 0})
+
+	c.Assert(d.SetNumber(-42), qt.IsNil)
+	c.Assert(d.Display(), qt.IsNil)
+	c.Assert(bus.digits(0)[:4], qt.DeepEquals, []byte{0, 0, 0, 0})
+	c.Assert(bus.digits(1)[:4], qt.DeepEquals, []byte{0x6D, 0x33, 0x01, 0})
+
+	c.Assert(d.SetFloat(1.5, 2), qt.IsNil)
+	c.Assert(d.Digits()[4:], qt.DeepEquals, []byte{0, segment.Char('1') | segment.DP, segment.Char('5'), segment.Char('0')})
+}
+
+var _ drivers.Displayer = (*Matrix)(nil)
@@ -0,0 +1,55 @@
+package max72xx
+
+import (
+	"image/color"
+	"time"
+
+	"tinygo.org/x/tinyfont"
+)
+
+// Scroller scrolls a line of text across a Matrix, from right to left, one
+// column at a time.
+type Scroller struct {
+	Matrix *Matrix
+	Font   tinyfont.Fonter
+	Text   string
+
+	// Y is the position of the baseline of the text, such as 6 for the
+	// tinyfont.TomThumb font.
+	Y int16
+
+	pos int16 // columns scrolled so far
+}
+
+// Reset starts scrolling the text again from the right edge of the matrix.
+func (s *Scroller) Reset() {
+	s.pos = 0
+}
+
+// Step draws the text one column further to the left and displays it. It
+// returns true after the text has left the matrix, and the next step starts
+// again from the right edge.
+func (s *Scroller) Step() (done bool, err error) {
+	w, _ := s.Matrix.Size()
+	_, textWidth := tinyfont.LineWidth(s.Font, s.Text)
+	s.pos++
+	x := w - s.pos
+	s.Matrix.ClearBuffer()
+	tinyfont.WriteLine(s.Matrix, s.Font, x, s.Y, s.Text, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	if x+int16(textWidth) <= 0 {
+		s.pos = 0
+		done = true
+	}
+	return done, s.Matrix.Display()
+}
+
+// Scroll scrolls the whole text once, waiting for delay between steps.
+func (s *Scroller) Scroll(delay time.Duration) error {
+	for {
+		done, err := s.Step()
+		if err != nil || done {
+			return err
+		}
+		time.Sleep(delay)
+	}
+}
@@ -0,0 +1,130 @@
+package max72xx
+
+import (
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/segment"
+)
+
+// SevenSegmentConfig is the configuration of a SevenSegment display.
+type SevenSegmentConfig struct {
+	// Chips is the number of chips in the chain. Zero means 1.
+	Chips int
+
+	// Digits is the number of digits of each chip, from 1 to 8. Zero means 8.
+	Digits int
+
+	// Intensity is the brightness of the segments, from 0 to 15.
+	Intensity uint8
+}
+
+// SevenSegment is a display made of the 7-segment digits of cascaded chips,
+// driven without the BCD decoder, to show text. The digits are numbered from
+// the left, starting with the leftmost digit of the chip connected to the
+// microcontroller. As on the usual modules, the first digit register of each
+// chip drives its rightmost digit.
+//
+// The digits are encoded as in the segment package, which can also write to
+// the buffer returned by Digits.
+type SevenSegment struct {
+	dev    *Device
+	digits int    // per chip
+	buffer []byte // segments of each digit
+	row    []byte
+}
+
+// NewSevenSegment returns a new cascade of 7-segment displays. The SPI bus
+// must already be configured, with a frequency not higher than 10MHz.
+func NewSevenSegment(bus drivers.SPI, cs drivers.PinOutput) *SevenSegment {
+	return &SevenSegment{dev: NewDevice(bus, cs)}
+}
+
+// Configure configures the pins and the chips, and clears the digits.
+func (d *SevenSegment) Configure(cfg SevenSegmentConfig) error {
+	n := cfg.Chips
+	if n == 0 {
+		n = 1
+	}
+	if n < 0 {
+		return errNoModules
+	}
+	d.digits = cfg.Digits
+	if d.digits <= 0 || d.digits > 8 {
+		d.digits = 8
+	}
+	d.buffer = make([]byte, n*d.digits)
+	d.row = make([]byte, n)
+
+	d.dev.Configure()
+	if err := d.dev.setup(n, 0x00, byte(d.digits-1), cfg.Intensity); err != nil {
+		return err
+	}
+	return d.Display()
+}
+
+// SetIntensity sets the brightness of all digits, from 0 to 15.
+func (d *SevenSegment) SetIntensity(intensity uint8) error {
+	if intensity > 0x0F {
+		intensity = 0x0F
+	}
+	return d.dev.writeAll(REG_INTENSITY, intensity, len(d.row))
+}
+
+// Len returns the number of digits.
+func (d *SevenSegment) Len() int {
+	return len(d.buffer)
+}
+
+// Digits returns the buffer, with the segments of each digit.
+func (d *SevenSegment) Digits() []byte {
+	return d.buffer
+}
+
+// ClearBuffer turns all segments off in the buffer.
+func (d *SevenSegment) ClearBuffer() {
+	for i := range d.buffer {
+		d.buffer[i] = 0
+	}
+}
+
+// SetText writes s in the buffer with the given alignment, and clears the
+// other digits.
+func (d *SevenSegment) SetText(s string, align segment.Align) {
+	segment.Text(d.buffer, s, align)
+}
+
+// SetNumber writes n in the buffer, aligned on the right.
+func (d *SevenSegment) SetNumber(n int) error {
+	return segment.Int(d.buffer, n)
+}
+
+// SetFloat writes f in the buffer, rounded to the given number of decimals
+// and aligned on the right.
+func (d *SevenSegment) SetFloat(f float32, decimals int) error {
+	return segment.Float(d.buffer, f, decimals)
+}
+
+// Display sends the buffer to the chips.
+func (d *SevenSegment) Display() error {
+	for digit := 0; digit < d.digits; digit++ {
+		for chip := range d.row {
+			d.row[chip] = segmentsRegister(d.buffer[chip*d.digits+d.digits-1-digit])
+		}
+		if err := d.dev.WriteCommands(REG_DIGIT0+byte(digit), d.row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// segmentsRegister returns the value of a digit register for the given
+// segments: the chip expects the decimal point in bit 7, then A to G from
+// bit 6 to bit 0.
+func segmentsRegister(segments byte) byte {
+	r := segments & 0x80
+	for i := uint(0); i < 7; i++ {
+		if segments&(1<<i) != 0 {
+			r |= 0x40 >> i
+		}
+	}
+	return r
+}
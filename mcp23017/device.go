@@ -342,6 +342,39 @@ func (p Pin) GetMode() (PinMode, error) {
 	return modes[p.pin], nil
 }
 
+// Output returns p as a pin that implements drivers.PinOutput, so that it can
+// be used as a control line of other drivers, such as a chip select or reset
+// line.
+func (p Pin) Output() *OutputPin {
+	return &OutputPin{Pin: p}
+}
+
+// OutputPin is a Pin used as an output by drivers that don't expect errors
+// from their pins. It implements drivers.PinOutput.
+type OutputPin struct {
+	Pin Pin
+
+	// Err holds the most recent error from setting the pin, if any.
+	Err error
+}
+
+// Set sets the pin to the given value.
+func (p *OutputPin) Set(value bool) {
+	if err := p.Pin.Set(value); err != nil {
+		p.Err = err
+	}
+}
+
+// High is short for p.Set(true).
+func (p *OutputPin) High() {
+	p.Set(true)
+}
+
+// Low is short for p.Set(false).
+func (p *OutputPin) Low() {
+	p.Set(false)
+}
+
 // Pins represents a bitmask of pin values.
 // Port A values are in bits 0-8 (numbered from least significant bit)
 // Port B values are in bits 9-15.
@@ -6,6 +6,7 @@ import (
 
 	qt "github.com/frankban/quicktest"
 
+	"tinygo.org/x/drivers"
 	"tinygo.org/x/drivers/tester"
 )
 
@@ -147,6 +148,25 @@ func TestPinToggle(t *testing.T) {
 	c.Assert(fdev.Registers[rGPIO], qt.Equals, uint8(0))
 }
 
+func TestPinOutput(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewI2CBus(c)
+	fdev := newDevice(bus, 0x20)
+	dev, err := NewI2C(bus, 0x20)
+	c.Assert(err, qt.IsNil)
+	out := dev.Pin(9).Output()
+	var pin drivers.PinOutput = out
+	pin.High()
+	c.Assert(fdev.Registers[rGPIO|portB], qt.Equals, uint8(0b10))
+	pin.Low()
+	c.Assert(fdev.Registers[rGPIO|portB], qt.Equals, uint8(0))
+	c.Assert(out.Err, qt.IsNil)
+
+	fdev.Err = fmt.Errorf("some error")
+	pin.High()
+	c.Assert(out.Err, qt.ErrorMatches, "some error")
+}
+
 func TestPinMode(t *testing.T) {
 	c := qt.New(t)
 	bus := tester.NewI2CBus(c)
@@ -8,16 +8,16 @@ package mcp2515 // import "tinygo.org/x/drivers/mcp2515"
 import (
 	"errors"
 	"fmt"
-	"machine"
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 // Device wraps MCP2515 SPI CAN Module.
 type Device struct {
 	spi     SPI
-	cs      machine.Pin
+	cs      drivers.PinOutput
 	msg     *CANMsg
 	mcpMode byte
 }
@@ -36,7 +36,7 @@ const (
 )
 
 // New returns a new MCP2515 driver. Pass in a fully configured SPI bus.
-func New(b drivers.SPI, csPin machine.Pin) *Device {
+func New(b drivers.SPI, csPin drivers.PinOutput) *Device {
 	d := &Device{
 		spi: SPI{
 			bus: b,
@@ -50,9 +50,11 @@ func New(b drivers.SPI, csPin machine.Pin) *Device {
 	return d
 }
 
-// Configure sets up the device for communication.
+// Configure sets up the device for communication. The chip select pin is
+// configured as an output if it is a machine.Pin, other pins must already be
+// configured.
 func (d *Device) Configure() {
-	d.cs.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	legacy.ConfigurePinOut(d.cs)
 }
 
 const beginTimeoutValue int = 10
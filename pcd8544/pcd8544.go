@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/dirty"
 )
 
 // Device wraps an SPI connection.
@@ -23,8 +24,17 @@ type Device struct {
 	width      int16
 	height     int16
 	bufferSize int16
+	rotation   drivers.Rotation
+	dirty      dirty.Rect // pixels changed since the last Display
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
 type Config struct {
 	Width  int16
 	Height int16
@@ -54,6 +64,7 @@ func (d *Device) Configure(cfg Config) {
 	}
 	d.bufferSize = d.width * d.height / 8
 	d.buffer = make([]byte, d.bufferSize)
+	d.dirty.AddRect(0, 0, d.width, d.height)
 
 	d.rstPin.Low()
 	time.Sleep(100 * time.Nanosecond)
@@ -69,6 +80,7 @@ func (d *Device) Configure(cfg Config) {
 // ClearBuffer clears the image buffer
 func (d *Device) ClearBuffer() {
 	d.buffer = make([]byte, d.bufferSize)
+	d.dirty.AddRect(0, 0, d.width, d.height)
 }
 
 // ClearDisplay clears the image buffer and clear the display
@@ -77,18 +89,45 @@ func (d *Device) ClearDisplay() {
 	d.Display()
 }
 
-// Display sends the whole buffer to the screen
+// Display sends the banks and columns of the buffer that changed since the
+// previous call to the screen.
 func (d *Device) Display() error {
-	d.SendCommand(FUNCTIONSET) // H = 0
-	d.SendCommand(SETXADDR)
-	d.SendCommand(SETYADDR)
+	if d.dirty.Empty() {
+		return nil
+	}
+	d.displayWindow(d.dirty.AlignY(8))
+	d.dirty.Reset()
+	return nil
+}
 
-	for i := int16(0); i < d.bufferSize; i++ {
-		d.SendData(d.buffer[i])
+// DisplayRect sends the banks and columns of the buffer that contain the
+// given rectangle to the screen.
+func (d *Device) DisplayRect(x, y, width, height int16) error {
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > w || y+height > h {
+		return errors.New("rectangle coordinates outside display area")
 	}
+	var r dirty.Rect
+	r.Add(d.xy(x, y))
+	r.Add(d.xy(x+width-1, y+height-1))
+	d.displayWindow(r.AlignY(8))
 	return nil
 }
 
+// displayWindow sends a window of the buffer to the screen. Y0 and Y1 must
+// be multiples of 8.
+func (d *Device) displayWindow(r dirty.Rect) {
+	d.SendCommand(FUNCTIONSET) // H = 0
+	for bank := r.Y0 / 8; bank < r.Y1/8; bank++ {
+		d.SendCommand(SETXADDR | uint8(r.X0))
+		d.SendCommand(SETYADDR | uint8(bank))
+		for i := bank*d.width + r.X0; i < bank*d.width+r.X1; i++ {
+			d.SendData(d.buffer[i])
+		}
+	}
+}
+
 // sendDataCommand sends image data or a command to the screen
 func (d *Device) sendDataCommand(isCommand bool, data uint8) {
 	if isCommand {
@@ -105,22 +144,44 @@ func (d *Device) sendDataCommand(isCommand bool, data uint8) {
 // color.RGBA{0, 0, 0, 255} is consider transparent, anything else
 // with enable a pixel on the screen
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+	if w, h := d.Size(); x < 0 || x >= w || y < 0 || y >= h {
 		return
 	}
+	x, y = d.xy(x, y)
 	byteIndex := x + (y/8)*d.width
+	old := d.buffer[byteIndex]
 	if c.R != 0 || c.G != 0 || c.B != 0 {
 		d.buffer[byteIndex] |= 1 << uint8(y%8)
 	} else {
 		d.buffer[byteIndex] &^= 1 << uint8(y%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
+}
+
+// FillRectangle sets the pixels of a rectangle in the buffer, on for any
+// color other than black.
+func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > w || y+height > h {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	for j := y; j < y+height; j++ {
+		for i := x; i < x+width; i++ {
+			d.SetPixel(i, j, c)
+		}
+	}
+	return nil
 }
 
 // GetPixel returns if the specified pixel is on (true) or off (false)
 func (d *Device) GetPixel(x int16, y int16) bool {
-	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+	if w, h := d.Size(); x < 0 || x >= w || y < 0 || y >= h {
 		return false
 	}
+	x, y = d.xy(x, y)
 	byteIndex := x + (y/8)*d.width
 	return (d.buffer[byteIndex] >> uint8(y%8) & 0x1) == 1
 }
@@ -134,6 +195,7 @@ func (d *Device) SetBuffer(buffer []byte) error {
 	for i := int16(0); i < d.bufferSize; i++ {
 		d.buffer[i] = buffer[i]
 	}
+	d.dirty.AddRect(0, 0, d.width, d.height)
 	return nil
 }
 
@@ -149,5 +211,37 @@ func (d *Device) SendData(data uint8) {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (w, h int16) {
+	if d.rotation == drivers.Rotation90 || d.rotation == drivers.Rotation270 {
+		return d.height, d.width
+	}
 	return d.width, d.height
 }
+
+// Rotation returns the current rotation of the display.
+func (d *Device) Rotation() drivers.Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation of the display. The controller can't
+// rotate the display, so the rotation is done when drawing in the buffer and
+// only applies to the pixels drawn afterwards.
+func (d *Device) SetRotation(rotation drivers.Rotation) error {
+	if rotation > drivers.Rotation270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	return nil
+}
+
+// xy returns the position in the buffer of a pixel of the rotated display.
+func (d *Device) xy(x, y int16) (int16, int16) {
+	switch d.rotation {
+	case drivers.Rotation90:
+		return d.width - y - 1, x
+	case drivers.Rotation180:
+		return d.width - x - 1, d.height - y - 1
+	case drivers.Rotation270:
+		return y, d.height - x - 1
+	}
+	return x, y
+}
@@ -0,0 +1,23 @@
+package drivers
+
+// PinOutput is a digital output, such as the chip select, data/command or
+// reset line of a device. It is implemented by machine.Pin, and by the pins of
+// GPIO expanders such as shiftregister.ShiftPin.
+type PinOutput interface {
+	High()
+	Low()
+}
+
+// PinInput is a digital input, such as a busy or interrupt line of a device.
+// It is implemented by machine.Pin.
+type PinInput interface {
+	// Get returns true if the pin is high.
+	Get() bool
+}
+
+// Pin is a digital pin that can be used both as an output and as an input.
+// It is implemented by machine.Pin.
+type Pin interface {
+	PinOutput
+	PinInput
+}
@@ -0,0 +1,41 @@
+package sdcard
+
+// crc7 returns the CRC7 of a command, shifted left by one with the end bit
+// set, as it is sent in the last command byte.
+func crc7(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if (b^crc)&0x80 != 0 {
+				crc ^= 0x09
+			}
+			b <<= 1
+		}
+	}
+	return crc<<1 | 1
+}
+
+// crc16 returns the CRC16-CCITT of a data block.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
@@ -15,7 +15,8 @@ type CSD struct {
 	WRITE_BLK_MISALIGN byte   //  1 R  [78:78]     0x00 : Write Block Misalignment
 	READ_BLK_MISALIGN  byte   //  1 R  [77:77]     0x00 : Read Block Misalignment
 	DSR_IMP            byte   //  1 R  [76:76]     0x00 : DSR Implemented
-	C_SIZE             uint32 // 22 R  [69:48] 0xXXXXXX : Device Size
+	C_SIZE             uint32 // 22 R  [69:48] 0xXXXXXX : Device Size (12 bits [73:62] in CSD 1.0)
+	C_SIZE_MULT        byte   //  3 R  [49:47]        : Device Size Multiplier (CSD 1.0 only)
 	ERASE_BLK_EN       byte   //  1 R  [46:46]     0x01 : Erase Single Block Enable
 	SECTOR_SIZE        byte   //  7 R  [45:39]     0x7F : Erase Sector Size
 	WP_GRP_SIZE        byte   //  7 R  [38:32]     0x00 : Write Protect Group Size
@@ -32,7 +33,7 @@ type CSD struct {
 }
 
 func NewCSD(buf []byte) *CSD {
-	return &CSD{
+	csd := &CSD{
 		CSD_STRUCTURE:      (buf[0] & 0xC0) >> 6,
 		TAAC:               buf[1],
 		NSAC:               buf[2],
@@ -58,6 +59,11 @@ func NewCSD(buf []byte) *CSD {
 		FILE_FORMAT:        (buf[14] & 0x0C) >> 2,
 		CRC:                (buf[15] & 0xFE) >> 1,
 	}
+	if csd.CSD_STRUCTURE == 0x00 {
+		csd.C_SIZE = uint32(buf[6]&0x03)<<10 | uint32(buf[7])<<2 | uint32(buf[8])>>6
+		csd.C_SIZE_MULT = (buf[9]&0x03)<<1 | buf[10]>>7
+	}
+	return csd
 }
 
 func (c *CSD) Dump() {
@@ -72,6 +78,7 @@ func (c *CSD) Dump() {
 	fmt.Printf("READ_BLK_MISALIGN:  %X\r\n", c.READ_BLK_MISALIGN)
 	fmt.Printf("DSR_IMP:            %X\r\n", c.DSR_IMP)
 	fmt.Printf("C_SIZE:             %X\r\n", c.C_SIZE)
+	fmt.Printf("C_SIZE_MULT:        %X\r\n", c.C_SIZE_MULT)
 	fmt.Printf("ERASE_BLK_EN:       %X\r\n", c.ERASE_BLK_EN)
 	fmt.Printf("SECTOR_SIZE:        %X\r\n", c.SECTOR_SIZE)
 	fmt.Printf("WP_GRP_SIZE:        %X\r\n", c.WP_GRP_SIZE)
@@ -87,20 +94,34 @@ func (c *CSD) Dump() {
 	fmt.Printf("CRC:                %X\r\n", c.CRC)
 }
 
+// Sectors returns the number of 512 byte sectors on the card.
 func (c *CSD) Sectors() (int64, error) {
-	sectors := int64(0)
-	if c.CSD_STRUCTURE == 0x01 {
-		// CSD version 2.0
-		sectors = (int64(c.C_SIZE) + 1) * 1024
-	} else if c.CSD_STRUCTURE == 0x00 {
-		// CSD version 1.0 (old, <=2GB)
-		return 0, fmt.Errorf("CSD format version 1.0 is not supported")
-	} else {
-		return 0, fmt.Errorf("unknown CSD format")
+	switch c.CSD_STRUCTURE {
+	case 0x00, 0x01:
+		return int64(c.Size() / 512), nil
 	}
-	return sectors, nil
+	return 0, fmt.Errorf("unknown CSD format")
 }
 
+// Size returns the capacity of the card in bytes.
 func (c *CSD) Size() uint64 {
-	return uint64(c.C_SIZE) * 512 * 1024
+	if c.CSD_STRUCTURE == 0x00 {
+		// CSD version 1.0 (old, <=2GB)
+		return (uint64(c.C_SIZE) + 1) << (c.C_SIZE_MULT + 2 + c.READ_BL_LEN)
+	}
+	// CSD version 2.0
+	return (uint64(c.C_SIZE) + 1) * 512 * 1024
+}
+
+// MaxTransferRate returns the maximum data transfer rate of the card in bits
+// per second, which is the highest SPI clock frequency it supports.
+func (c *CSD) MaxTransferRate() uint32 {
+	units := [...]uint32{100e3, 1e6, 10e6, 100e6}
+	// time values multiplied by 10
+	values := [...]uint32{0, 10, 12, 13, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 70, 80}
+	unit := c.TRAN_SPEED & 0x07
+	if int(unit) >= len(units) {
+		return 0
+	}
+	return units[unit] / 10 * values[c.TRAN_SPEED>>3&0x0F]
 }
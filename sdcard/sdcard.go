@@ -1,9 +1,12 @@
 package sdcard
 
 import (
+	"errors"
 	"fmt"
-	"machine"
 	"time"
+
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 const (
@@ -27,27 +30,39 @@ var (
 	dummy [512]byte
 )
 
+var (
+	// ErrCRC is returned when a data block read from the card does not match
+	// its CRC. It is only detected when CRC checking is enabled with
+	// EnableCRC.
+	ErrCRC = errors.New("sdcard: CRC error")
+
+	errBlockSize = errors.New("sdcard: buffer length must be a multiple of 512")
+)
+
 type Device struct {
-	bus        *machine.SPI
-	sck        machine.Pin
-	sdo        machine.Pin
-	sdi        machine.Pin
-	cs         machine.Pin
+	bus        drivers.SPI
+	cs         drivers.PinOutput
 	cmdbuf     []byte
 	dummybuf   []byte
 	tokenbuf   []byte
 	sdCardType byte
+	crc        bool
 	CID        *CID
 	CSD        *CSD
 }
 
-func New(b *machine.SPI, sck, sdo, sdi, cs machine.Pin) Device {
+// New returns a new SD card driver for the card on the given SPI bus. The bus
+// must be configured in SPI mode 0 before calling Configure. The chip select
+// pin is configured as an output if it is a machine.Pin, other pins must
+// already be configured.
+//
+// Cards must be initialized with a clock between 100 kHz and 400 kHz. Once
+// Configure returns, the bus can be reconfigured with a higher frequency
+// (up to 25 MHz).
+func New(b drivers.SPI, cs drivers.PinOutput) Device {
 	return Device{
 		bus:        b,
 		cs:         cs,
-		sck:        sck,
-		sdo:        sdo,
-		sdi:        sdi,
 		cmdbuf:     make([]byte, 6),
 		dummybuf:   make([]byte, 512),
 		tokenbuf:   make([]byte, 1),
@@ -60,17 +75,7 @@ func (d *Device) Configure() error {
 }
 
 func (d *Device) initCard() error {
-	d.bus.Configure(machine.SPIConfig{
-		SCK:       d.sck,
-		SDO:       d.sdo,
-		SDI:       d.sdi,
-		Frequency: 250000,
-		LSBFirst:  false,
-		Mode:      0, // phase=0, polarity=0
-	})
-
-	// set pin modes
-	d.cs.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	legacy.ConfigurePinOut(d.cs)
 	d.cs.High()
 
 	for i := range dummy {
@@ -88,7 +93,7 @@ func (d *Device) initCard() error {
 	tm := setTimeout(0, 2*time.Second)
 	for !tm.expired() {
 		// Wait up to 2 seconds to be the same as the Arduino
-		if d.cmd(CMD0_GO_IDLE_STATE, 0, 0x95) == _R1_IDLE_STATE {
+		if d.cmd(CMD0_GO_IDLE_STATE, 0) == _R1_IDLE_STATE {
 			ok = true
 			break
 		}
@@ -98,7 +103,7 @@ func (d *Device) initCard() error {
 	}
 
 	// CMD8: determine card version
-	r := d.cmd(CMD8_SEND_IF_COND, 0x01AA, 0x87)
+	r := d.cmd(CMD8_SEND_IF_COND, 0x01AA)
 	if (r & _R1_ILLEGAL_COMMAND) == _R1_ILLEGAL_COMMAND {
 		d.sdCardType = SD_CARD_TYPE_SD1
 		return fmt.Errorf("init_card_v1 not impl\r\n")
@@ -151,7 +156,7 @@ func (d *Device) initCard() error {
 
 	// if SD2 read OCR register to check for SDHC card
 	if d.sdCardType == SD_CARD_TYPE_SD2 {
-		if d.cmd(CMD58_READ_OCR, 0, 0xFF) != 0 {
+		if d.cmd(CMD58_READ_OCR, 0) != 0 {
 			return fmt.Errorf("SD_CARD_ERROR_CMD58")
 		}
 
@@ -168,7 +173,7 @@ func (d *Device) initCard() error {
 		}
 	}
 
-	if d.cmd(CMD16_SET_BLOCKLEN, 0x0200, 0xFF) != 0 {
+	if d.cmd(CMD16_SET_BLOCKLEN, 0x0200) != 0 {
 		return fmt.Errorf("SD_CARD_ERROR_CMD16")
 	}
 
@@ -189,27 +194,36 @@ func (d *Device) initCard() error {
 
 	d.cs.High()
 
-	d.bus.Configure(machine.SPIConfig{
-		SCK:       d.sck,
-		SDO:       d.sdo,
-		SDI:       d.sdi,
-		Frequency: 4000000,
-		LSBFirst:  false,
-		Mode:      0, // phase=0, polarity=0
-	})
+	return nil
+}
 
+// EnableCRC turns CRC checking on or off using CMD59. With CRC checking
+// enabled, the card rejects commands and written blocks with a bad CRC, and
+// blocks read from the card are verified against their CRC16, returning
+// ErrCRC on a mismatch. CRC checking is off after Configure.
+func (d *Device) EnableCRC(enable bool) error {
+	arg := uint32(0)
+	if enable {
+		arg = 1
+	}
+	r := d.cmd(CMD59_CRC_ON_OFF, arg)
+	d.cs.High()
+	if r != 0 {
+		return fmt.Errorf("SD_CARD_ERROR_CMD59 %02X", r)
+	}
+	d.crc = enable
 	return nil
 }
 
 func (d Device) acmd(cmd byte, arg uint32) byte {
-	d.cmd(CMD55_APP_CMD, 0, 0xFF)
-	return d.cmd(cmd, arg, 0xFF)
+	d.cmd(CMD55_APP_CMD, 0)
+	return d.cmd(cmd, arg)
 }
 
-func (d Device) cmd(cmd byte, arg uint32, crc byte) byte {
+func (d Device) cmd(cmd byte, arg uint32) byte {
 	d.cs.Low()
 
-	if cmd != 12 {
+	if cmd != CMD12_STOP_TRANSMISSION {
 		d.waitNotBusy(300 * time.Millisecond)
 	}
 
@@ -220,25 +234,24 @@ func (d Device) cmd(cmd byte, arg uint32, crc byte) byte {
 	buf[2] = byte(arg >> 16)
 	buf[3] = byte(arg >> 8)
 	buf[4] = byte(arg)
-	buf[5] = crc
+	buf[5] = crc7(buf[:5])
 	d.bus.Tx(buf, nil)
 
-	if cmd == 12 {
+	if cmd == CMD12_STOP_TRANSMISSION {
 		// skip 1 byte
 		d.bus.Transfer(byte(0xFF))
 	}
 
 	// wait for the response (response[7] == 0)
 	for i := 0; i < 0xFFFF; i++ {
-		d.bus.Tx([]byte{0xFF}, d.tokenbuf)
+		d.bus.Tx(dummy[:1], d.tokenbuf)
 		response := d.tokenbuf[0]
 		if (response & 0x80) == 0 {
 			return response
 		}
 	}
 
-	// TODO
-	//// timeout
+	// timeout
 	d.cs.High()
 	d.bus.Transfer(byte(0xFF))
 
@@ -256,7 +269,7 @@ func (d Device) waitNotBusy(timeout time.Duration) error {
 			return nil
 		}
 	}
-	return nil
+	return fmt.Errorf("SD_CARD_ERROR_BUSY_TIMEOUT")
 }
 
 func (d Device) waitStartBlock() error {
@@ -283,6 +296,74 @@ func (d Device) waitStartBlock() error {
 	return nil
 }
 
+// readBlock reads a data block of len(dst) bytes, including its start token
+// and CRC, directly into dst.
+func (d Device) readBlock(dst []byte) error {
+	if err := d.waitStartBlock(); err != nil {
+		return err
+	}
+	if err := d.bus.Tx(dummy[:len(dst)], dst); err != nil {
+		d.cs.High()
+		return err
+	}
+	var crc [2]byte
+	if err := d.bus.Tx(dummy[:2], crc[:]); err != nil {
+		d.cs.High()
+		return err
+	}
+	if d.crc && uint16(crc[0])<<8|uint16(crc[1]) != crc16(dst) {
+		d.cs.High()
+		return ErrCRC
+	}
+	return nil
+}
+
+// writeBlock sends a 512 byte data block with the given start token and
+// waits until the card has programmed it.
+func (d Device) writeBlock(token byte, src []byte) error {
+	d.bus.Transfer(token)
+
+	if err := d.bus.Tx(src[:512], nil); err != nil {
+		return err
+	}
+
+	// CRC is ignored by the card unless enabled with CMD59
+	crc := uint16(0xFFFF)
+	if d.crc {
+		crc = crc16(src[:512])
+	}
+	d.bus.Transfer(byte(crc >> 8))
+	d.bus.Transfer(byte(crc))
+
+	// Data Resp.
+	r, err := d.bus.Transfer(byte(0xFF))
+	if err != nil {
+		return err
+	}
+	switch r & 0x1F {
+	case 0x05:
+	case 0x0B:
+		return ErrCRC
+	default:
+		return fmt.Errorf("SD_CARD_ERROR_WRITE")
+	}
+
+	// wait no busy
+	if err := d.waitNotBusy(600 * time.Millisecond); err != nil {
+		return fmt.Errorf("SD_CARD_ERROR_WRITE_TIMEOUT")
+	}
+	return nil
+}
+
+// address converts a block number to the command argument, which is a byte
+// address for standard capacity cards.
+func (d Device) address(block uint32) uint32 {
+	if d.sdCardType != SD_CARD_TYPE_SDHC {
+		return block << 9
+	}
+	return block
+}
+
 // ReadCSD reads the CSD using CMD9.
 func (d Device) ReadCSD(csd []byte) error {
 	return d.readRegister(CMD9_SEND_CSD, csd)
@@ -294,66 +375,113 @@ func (d Device) ReadCID(csd []byte) error {
 }
 
 func (d Device) readRegister(cmd uint8, dst []byte) error {
-	if d.cmd(cmd, 0, 0xFF) != 0 {
+	if d.cmd(cmd, 0) != 0 {
+		d.cs.High()
 		return fmt.Errorf("SD_CARD_ERROR_READ_REG")
 	}
-	if err := d.waitStartBlock(); err != nil {
+	if err := d.readBlock(dst[:16]); err != nil {
 		return err
 	}
-	// transfer data
-	for i := uint16(0); i < 16; i++ {
-		r, err := d.bus.Transfer(byte(0xFF))
-		if err != nil {
-			return err
-		}
-		dst[i] = r
+	d.cs.High()
+
+	return nil
+}
+
+// ReadSDStatus reads the 64 byte SD status register using ACMD13.
+func (d Device) ReadSDStatus(dst []byte) error {
+	if len(dst) < 64 {
+		return fmt.Errorf("len(dst) must be greater than or equal to 64")
 	}
+	if d.acmd(ACMD13_SD_STATUS, 0) != 0 {
+		d.cs.High()
+		return fmt.Errorf("SD_CARD_ERROR_ACMD13")
+	}
+	// second byte of the R2 response
 	d.bus.Transfer(byte(0xFF))
-	d.bus.Transfer(byte(0xFF))
+	if err := d.readBlock(dst[:64]); err != nil {
+		return err
+	}
 	d.cs.High()
-
 	return nil
 }
 
+// SpeedClass returns the speed class of the card (0, 2, 4, 6 or 10) as
+// reported in the SD status register.
+func (d Device) SpeedClass() (int, error) {
+	var status [64]byte
+	if err := d.ReadSDStatus(status[:]); err != nil {
+		return 0, err
+	}
+	switch status[8] {
+	case 0, 1, 2, 3:
+		return int(status[8]) * 2, nil
+	case 4:
+		return 10, nil
+	}
+	return 0, fmt.Errorf("unknown speed class %d", status[8])
+}
+
 // ReadData reads 512 bytes from sdcard into dst.
 func (d Device) ReadData(block uint32, dst []byte) error {
 	if len(dst) < 512 {
 		return fmt.Errorf("len(dst) must be greater than or equal to 512")
 	}
 
-	// use address if not SDHC card
-	if d.sdCardType != SD_CARD_TYPE_SDHC {
-		block <<= 9
-	}
-	if d.cmd(CMD17_READ_SINGLE_BLOCK, block, 0xFF) != 0 {
+	if d.cmd(CMD17_READ_SINGLE_BLOCK, d.address(block)) != 0 {
+		d.cs.High()
 		return fmt.Errorf("CMD17 error")
 	}
-	if err := d.waitStartBlock(); err != nil {
-		return fmt.Errorf("waitStartBlock()")
-	}
-
-	err := d.bus.Tx(dummy[:512], dst)
-	if err != nil {
+	if err := d.readBlock(dst[:512]); err != nil {
 		return err
 	}
 
-	// skip CRC (2byte)
-	d.bus.Transfer(byte(0xFF))
-	d.bus.Transfer(byte(0xFF))
-
-	// TODO: probably not necessary
 	d.cs.High()
 
 	return nil
 }
 
+// ReadBlocks reads len(dst)/512 consecutive blocks starting at the given
+// block directly into dst, using a single CMD18 multiple block read when
+// more than one block is requested. The length of dst must be a multiple
+// of 512.
+func (d Device) ReadBlocks(block uint32, dst []byte) error {
+	if len(dst)%512 != 0 {
+		return errBlockSize
+	}
+	switch len(dst) {
+	case 0:
+		return nil
+	case 512:
+		return d.ReadData(block, dst)
+	}
+
+	if d.cmd(CMD18_READ_MULTIPLE_BLOCK, d.address(block)) != 0 {
+		d.cs.High()
+		return fmt.Errorf("CMD18 error")
+	}
+	for i := 0; i < len(dst); i += 512 {
+		if err := d.readBlock(dst[i : i+512]); err != nil {
+			d.stopTransmission()
+			return err
+		}
+	}
+	return d.stopTransmission()
+}
+
+// stopTransmission ends a multiple block read with CMD12.
+func (d Device) stopTransmission() error {
+	defer d.cs.High()
+
+	if r := d.cmd(CMD12_STOP_TRANSMISSION, 0); r != 0 {
+		return fmt.Errorf("SD_CARD_ERROR_CMD12 %02X", r)
+	}
+	return d.waitNotBusy(300 * time.Millisecond)
+}
+
 // WriteMultiStart starts the continuous write mode using CMD25.
 func (d Device) WriteMultiStart(block uint32) error {
-	// use address if not SDHC card
-	if d.sdCardType != SD_CARD_TYPE_SDHC {
-		block <<= 9
-	}
-	if d.cmd(CMD25_WRITE_MULTIPLE_BLOCK, block, 0xFF) != 0 {
+	if d.cmd(CMD25_WRITE_MULTIPLE_BLOCK, d.address(block)) != 0 {
+		d.cs.High()
 		return fmt.Errorf("CMD25 error")
 	}
 
@@ -366,36 +494,11 @@ func (d Device) WriteMultiStart(block uint32) error {
 // WriteMulti performs continuous writing. It is necessary to call
 // WriteMultiStart() in prior.
 func (d Device) WriteMulti(buf []byte) error {
-	// send Data Token for CMD25
-	d.bus.Transfer(byte(0xFC))
-
-	for i := 0; i < 512; i++ {
-		_, err := d.bus.Transfer(buf[i])
-		if err != nil {
-			return err
-		}
-	}
-
-	// send dummy CRC (2 byte)
-	d.bus.Transfer(byte(0xFF))
-	d.bus.Transfer(byte(0xFF))
-
-	// Data Resp.
-	r, err := d.bus.Transfer(byte(0xFF))
-	if err != nil {
-		return err
-	}
-	if (r & 0x1F) != 0x05 {
-		return fmt.Errorf("SD_CARD_ERROR_WRITE")
+	if len(buf) < 512 {
+		return fmt.Errorf("len(buf) must be greater than or equal to 512")
 	}
-
-	// wait no busy
-	err = d.waitNotBusy(600 * time.Millisecond)
-	if err != nil {
-		return fmt.Errorf("SD_CARD_ERROR_WRITE_TIMEOUT")
-	}
-
-	return nil
+	// Data Token for CMD25
+	return d.writeBlock(0xFC, buf)
 }
 
 // WriteMultiStop exits the continuous write mode.
@@ -408,12 +511,7 @@ func (d Device) WriteMultiStop() error {
 	// skip 1 byte
 	d.bus.Transfer(byte(0xFF))
 
-	err := d.waitNotBusy(600 * time.Millisecond)
-	if err != nil {
-		return nil
-	}
-
-	return nil
+	return d.waitNotBusy(600 * time.Millisecond)
 }
 
 // WriteData writes 512 bytes from dst to sdcard.
@@ -422,190 +520,127 @@ func (d Device) WriteData(block uint32, src []byte) error {
 		return fmt.Errorf("len(src) must be greater than or equal to 512")
 	}
 
-	// use address if not SDHC card
-	if d.sdCardType != SD_CARD_TYPE_SDHC {
-		block <<= 9
-	}
-	if d.cmd(CMD24_WRITE_BLOCK, block, 0xFF) != 0 {
+	if d.cmd(CMD24_WRITE_BLOCK, d.address(block)) != 0 {
+		d.cs.High()
 		return fmt.Errorf("CMD24 error")
 	}
 
-	// wait 1 byte?
-	token := byte(0xFE)
-	d.bus.Transfer(token)
+	err := d.writeBlock(0xFE, src)
+	d.cs.High()
+	return err
+}
 
-	err := d.bus.Tx(src[:512], nil)
-	if err != nil {
-		return err
+// WriteBlocks writes len(src)/512 consecutive blocks starting at the given
+// block, using a CMD25 multiple block write when more than one block is
+// written. The length of src must be a multiple of 512.
+func (d Device) WriteBlocks(block uint32, src []byte) error {
+	if len(src)%512 != 0 {
+		return errBlockSize
+	}
+	switch len(src) {
+	case 0:
+		return nil
+	case 512:
+		return d.WriteData(block, src)
 	}
 
-	// send dummy CRC (2 byte)
-	d.bus.Transfer(byte(0xFF))
-	d.bus.Transfer(byte(0xFF))
-
-	// Data Resp.
-	r, err := d.bus.Transfer(byte(0xFF))
-	if err != nil {
+	if err := d.WriteMultiStart(block); err != nil {
 		return err
 	}
-	if (r & 0x1F) != 0x05 {
-		return fmt.Errorf("SD_CARD_ERROR_WRITE")
-	}
-
-	// wait no busy
-	err = d.waitNotBusy(600 * time.Millisecond)
-	if err != nil {
-		return fmt.Errorf("SD_CARD_ERROR_WRITE_TIMEOUT")
+	for i := 0; i < len(src); i += 512 {
+		if err := d.WriteMulti(src[i : i+512]); err != nil {
+			d.WriteMultiStop()
+			return err
+		}
 	}
-
-	// TODO: probably not necessary
-	d.cs.High()
-	return nil
+	return d.WriteMultiStop()
 }
 
-// ReadAt reads the given number of bytes from the sdcard.
+// ReadAt reads the given number of bytes from the sdcard. Whole blocks are
+// read straight into buf; only a partial first or last block goes through
+// an intermediate buffer.
 func (dev *Device) ReadAt(buf []byte, addr int64) (int, error) {
-	block := uint64(addr)
-	// use address if not SDHC card
-	if dev.sdCardType == SD_CARD_TYPE_SDHC {
-		block >>= 9
-	}
-
-	idx := uint32(0)
-
-	start := uint32(addr % 512)
-	end := uint32(0)
-	remain := uint32(len(buf))
+	block := uint32(uint64(addr) >> 9)
+	idx := 0
 
 	// If data starts in the middle
-	if 0 < start {
-		if start+remain <= 512 {
-			end = start + remain
-		} else {
-			end = 512
-		}
-
-		err := dev.ReadData(uint32(block), dev.dummybuf)
+	if start := int(addr % 512); 0 < start {
+		err := dev.ReadData(block, dev.dummybuf)
 		if err != nil {
 			return 0, err
 		}
-		copy(buf[idx:], dev.dummybuf[start:end])
-
-		remain -= end - start
-		idx += end - start
+		idx = copy(buf, dev.dummybuf[start:])
 		block++
 	}
 
-	// If more than 512 bytes left
-	for 512 <= remain {
-		start = 0
-		end = 512
-
-		err := dev.ReadData(uint32(block), dev.dummybuf)
+	// Whole blocks
+	if n := (len(buf) - idx) &^ 511; n > 0 {
+		err := dev.ReadBlocks(block, buf[idx:idx+n])
 		if err != nil {
-			return 0, err
+			return idx, err
 		}
-		copy(buf[idx:], dev.dummybuf[start:end])
-
-		remain -= end - start
-		idx += end - start
-		block++
+		idx += n
+		block += uint32(n / 512)
 	}
 
 	// Read to the end
-	if 0 < remain {
-		start = 0
-		end = remain
-
-		err := dev.ReadData(uint32(block), dev.dummybuf)
+	if idx < len(buf) {
+		err := dev.ReadData(block, dev.dummybuf)
 		if err != nil {
-			return 0, err
+			return idx, err
 		}
-		copy(buf[idx:], dev.dummybuf[start:end])
-
-		remain -= end - start
-		idx += end - start
-		block++
+		idx += copy(buf[idx:], dev.dummybuf)
 	}
 
-	return int(idx), nil
+	return idx, nil
 }
 
 // WriteAt writes the given number of bytes to sdcard.
 func (dev *Device) WriteAt(buf []byte, addr int64) (n int, err error) {
-	block := uint64(addr)
-	// use address if not SDHC card
-	if dev.sdCardType == SD_CARD_TYPE_SDHC {
-		block >>= 9
-	}
-
-	idx := uint32(0)
-
-	start := uint32(addr % 512)
-	end := uint32(0)
-	remain := uint32(len(buf))
+	block := uint32(uint64(addr) >> 9)
+	idx := 0
 
 	// If data starts in the middle
-	if 0 < start {
-		if start+remain <= 512 {
-			end = start + remain
-		} else {
-			end = 512
-		}
-
-		err := dev.ReadData(uint32(block), dev.dummybuf)
+	if start := int(addr % 512); 0 < start {
+		err := dev.ReadData(block, dev.dummybuf)
 		if err != nil {
 			return 0, err
 		}
-		copy(dev.dummybuf[start:end], buf[idx:])
+		idx = copy(dev.dummybuf[start:], buf)
 
-		err = dev.WriteData(uint32(block), dev.dummybuf)
+		err = dev.WriteData(block, dev.dummybuf)
 		if err != nil {
 			return 0, err
 		}
-
-		remain -= end - start
-		idx += end - start
 		block++
 	}
 
-	// If more than 512 bytes left
-	for 512 <= remain {
-		start = 0
-		end = 512
-
-		err := dev.WriteData(uint32(block), buf[idx:idx+512])
+	// Whole blocks
+	if n := (len(buf) - idx) &^ 511; n > 0 {
+		err := dev.WriteBlocks(block, buf[idx:idx+n])
 		if err != nil {
-			return 0, err
+			return idx, err
 		}
-
-		remain -= end - start
-		idx += end - start
-		block++
+		idx += n
+		block += uint32(n / 512)
 	}
 
 	// Write to the end
-	if 0 < remain {
-		start = 0
-		end = remain
-
-		err := dev.ReadData(uint32(block), dev.dummybuf)
+	if idx < len(buf) {
+		err := dev.ReadData(block, dev.dummybuf)
 		if err != nil {
-			return 0, err
+			return idx, err
 		}
-		copy(dev.dummybuf[start:end], buf[idx:])
+		n := copy(dev.dummybuf, buf[idx:])
 
-		err = dev.WriteData(uint32(block), dev.dummybuf)
+		err = dev.WriteData(block, dev.dummybuf)
 		if err != nil {
-			return 0, err
+			return idx, err
 		}
-
-		remain -= end - start
-		idx += end - start
-		block++
+		idx += n
 	}
 
-	return int(idx), nil
+	return idx, nil
 }
 
 // Size returns the number of bytes in this sdcard.
@@ -624,18 +659,32 @@ func (dev *Device) EraseBlockSize() int64 {
 	return 512
 }
 
-// EraseBlocks erases the given number of blocks.
+// EraseBlocks erases the given number of blocks using CMD32, CMD33 and
+// CMD38. Depending on the card, erased blocks read back as all zeros or all
+// ones.
 func (dev *Device) EraseBlocks(start, len int64) error {
-	dev.WriteMultiStart(uint32(start))
-
-	for i := range dev.dummybuf {
-		dev.dummybuf[i] = 0
+	if len <= 0 {
+		return nil
 	}
+	first := dev.address(uint32(start))
+	last := dev.address(uint32(start + len - 1))
 
-	for i := 0; i < int(len); i++ {
-		dev.WriteMulti(dev.dummybuf)
+	defer dev.cs.High()
+	if r := dev.cmd(CMD32_ERASE_WR_BLK_START_ADDR, first); r != 0 {
+		return fmt.Errorf("SD_CARD_ERROR_CMD32 %02X", r)
+	}
+	if r := dev.cmd(CMD33_ERASE_WR_BLK_END_ADDR, last); r != 0 {
+		return fmt.Errorf("SD_CARD_ERROR_CMD33 %02X", r)
+	}
+	if r := dev.cmd(CMD38_ERASE, 0); r != 0 {
+		return fmt.Errorf("SD_CARD_ERROR_CMD38 %02X", r)
 	}
 
-	dev.WriteMultiStop()
+	// Erasing can take a long time: allow 250ms per 4 MiB allocation unit,
+	// with a minimum of one second.
+	timeout := time.Second + time.Duration(len/8192)*250*time.Millisecond
+	if err := dev.waitNotBusy(timeout); err != nil {
+		return fmt.Errorf("SD_CARD_ERROR_ERASE_TIMEOUT")
+	}
 	return nil
 }
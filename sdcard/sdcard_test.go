@@ -0,0 +1,244 @@
+package sdcard
+
+import (
+	"bytes"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/tester"
+)
+
+func newTestCard(c *qt.C, highCapacity bool) (*tester.SDCard, *Device) {
+	card := tester.NewSDCard(c, 4*1024*1024)
+	card.HighCapacity = highCapacity
+	for i := range card.Data {
+		card.Data[i] = byte(i) ^ byte(i>>9)
+	}
+	dev := New(card, card.CS())
+	c.Assert(dev.Configure(), qt.IsNil)
+	return card, &dev
+}
+
+func TestInitCard(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+
+	c.Assert(dev.sdCardType, qt.Equals, byte(SD_CARD_TYPE_SDHC))
+	c.Assert(card.Commands[CMD0_GO_IDLE_STATE] > 0, qt.IsTrue)
+	c.Assert(card.Commands[CMD8_SEND_IF_COND], qt.Equals, 1)
+	// The simulated card needs a few ACMD41 before leaving the idle state.
+	c.Assert(card.AppCommands[ACMD41_SD_APP_OP_COND], qt.Equals, card.InitRetries+1)
+	c.Assert(card.Commands[CMD58_READ_OCR], qt.Equals, 1)
+
+	c.Assert(dev.CID.ProductName, qt.Equals, "TGSIM")
+	c.Assert(dev.CSD.CSD_STRUCTURE, qt.Equals, byte(1))
+	c.Assert(dev.Size(), qt.Equals, int64(len(card.Data)))
+}
+
+func TestInitStandardCapacity(t *testing.T) {
+	c := qt.New(t)
+	_, dev := newTestCard(c, false)
+	c.Assert(dev.sdCardType, qt.Equals, byte(SD_CARD_TYPE_SD2))
+}
+
+func TestReadAt(t *testing.T) {
+	for _, hc := range []bool{true, false} {
+		c := qt.New(t)
+		card, dev := newTestCard(c, hc)
+		tests := []struct {
+			off int64
+			n   int
+		}{
+			{0, 512},     // one aligned block
+			{1024, 2048}, // several aligned blocks
+			{10, 20},     // inside a block
+			{500, 30},    // across a block boundary
+			{700, 1500},  // unaligned start and end
+			{1536, 100},  // aligned start, partial end
+			{3000, 72},   // partial start, aligned end
+			{4095 * 512, 512},
+		}
+		for _, tt := range tests {
+			buf := make([]byte, tt.n)
+			n, err := dev.ReadAt(buf, tt.off)
+			c.Assert(err, qt.IsNil)
+			c.Assert(n, qt.Equals, tt.n)
+			c.Assert(buf, qt.DeepEquals, card.Data[tt.off:tt.off+int64(tt.n)], qt.Commentf("off=%d n=%d hc=%v", tt.off, tt.n, hc))
+		}
+	}
+}
+
+func TestWriteAt(t *testing.T) {
+	for _, hc := range []bool{true, false} {
+		c := qt.New(t)
+		card, dev := newTestCard(c, hc)
+		want := append([]byte(nil), card.Data...)
+		tests := []struct {
+			off int64
+			n   int
+		}{
+			{0, 512},
+			{1024, 2048},
+			{10, 20},
+			{500, 30},
+			{700, 1500},
+			{8192, 100},
+			{10000, 240},
+		}
+		for i, tt := range tests {
+			data := bytes.Repeat([]byte{byte(0xA0 + i)}, tt.n)
+			copy(want[tt.off:], data)
+			n, err := dev.WriteAt(data, tt.off)
+			c.Assert(err, qt.IsNil)
+			c.Assert(n, qt.Equals, tt.n)
+			// qt.DeepEquals is too slow for the whole card.
+			c.Assert(bytes.Equal(card.Data, want), qt.IsTrue, qt.Commentf("off=%d n=%d hc=%v", tt.off, tt.n, hc))
+		}
+	}
+}
+
+func TestWriteMulti(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+	before := append([]byte(nil), card.Data...)
+
+	c.Assert(dev.WriteMultiStart(3), qt.IsNil)
+	for i := 0; i < 4; i++ {
+		c.Assert(dev.WriteMulti(bytes.Repeat([]byte{byte(i + 1)}, 512)), qt.IsNil)
+	}
+	c.Assert(dev.WriteMultiStop(), qt.IsNil)
+	c.Assert(card.Commands[CMD25_WRITE_MULTIPLE_BLOCK], qt.Equals, 1)
+
+	for i := 0; i < 4; i++ {
+		block := card.Data[(3+i)*512 : (4+i)*512]
+		c.Assert(block, qt.DeepEquals, bytes.Repeat([]byte{byte(i + 1)}, 512))
+	}
+	c.Assert(card.Data[:3*512], qt.DeepEquals, before[:3*512])
+	c.Assert(bytes.Equal(card.Data[7*512:], before[7*512:]), qt.IsTrue)
+
+	// The card accepts single block commands after the multi block write.
+	buf := make([]byte, 512)
+	c.Assert(dev.ReadData(4, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, bytes.Repeat([]byte{2}, 512))
+}
+
+func TestReadMultiBlock(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, false)
+
+	buf := make([]byte, 5*512+100)
+	n, err := dev.ReadAt(buf, 200)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, len(buf))
+	c.Assert(bytes.Equal(buf, card.Data[200:200+len(buf)]), qt.IsTrue)
+	// The four whole blocks in the middle are read with a single CMD18.
+	c.Assert(card.Commands[CMD18_READ_MULTIPLE_BLOCK], qt.Equals, 1)
+	c.Assert(card.Commands[CMD12_STOP_TRANSMISSION], qt.Equals, 1)
+	c.Assert(card.Commands[CMD17_READ_SINGLE_BLOCK], qt.Equals, 2)
+
+	// Reading the last blocks of the card.
+	buf = make([]byte, 3*512)
+	c.Assert(dev.ReadBlocks(8189, buf), qt.IsNil)
+	c.Assert(bytes.Equal(buf, card.Data[8189*512:]), qt.IsTrue)
+
+	c.Assert(dev.ReadBlocks(0, make([]byte, 100)), qt.Equals, errBlockSize)
+}
+
+func TestWriteBlocks(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+	data := make([]byte, 3*512)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	n, err := dev.WriteAt(data, 512)
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, len(data))
+	c.Assert(bytes.Equal(card.Data[512:4*512], data), qt.IsTrue)
+	c.Assert(card.Commands[CMD25_WRITE_MULTIPLE_BLOCK], qt.Equals, 1)
+	c.Assert(card.Commands[CMD24_WRITE_BLOCK], qt.Equals, 0)
+}
+
+func TestCRC(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+	c.Assert(dev.EnableCRC(true), qt.IsNil)
+
+	// Commands and data are accepted with a valid CRC.
+	data := bytes.Repeat([]byte{0x5A, 0xC3}, 1024)
+	_, err := dev.WriteAt(data, 1000)
+	c.Assert(err, qt.IsNil)
+	buf := make([]byte, len(data))
+	_, err = dev.ReadAt(buf, 1000)
+	c.Assert(err, qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, data)
+
+	// A corrupted block is detected, in single and multiple block reads.
+	card.ReadCRCErrors = 1
+	c.Assert(dev.ReadData(0, buf), qt.Equals, ErrCRC)
+	card.ReadCRCErrors = 1
+	c.Assert(dev.ReadBlocks(0, buf[:2048]), qt.Equals, ErrCRC)
+
+	// The card still works after an error.
+	c.Assert(dev.ReadBlocks(0, buf[:2048]), qt.IsNil)
+	c.Assert(bytes.Equal(buf[:2048], card.Data[:2048]), qt.IsTrue)
+
+	// Without CRC checking, corrupted blocks go unnoticed.
+	c.Assert(dev.EnableCRC(false), qt.IsNil)
+	card.ReadCRCErrors = 1
+	c.Assert(dev.ReadData(0, buf), qt.IsNil)
+}
+
+func TestCRCTable(t *testing.T) {
+	c := qt.New(t)
+	data := make([]byte, 512)
+	for i := range data {
+		data[i] = byte(i * 13)
+	}
+	c.Assert(crc16(data), qt.Equals, tester.SDCRC16(data))
+	c.Assert(crc7([]byte{0x40, 0, 0, 0, 0}), qt.Equals, byte(0x95))
+}
+
+func TestSpeedClass(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+	card.SpeedClass = 4
+	class, err := dev.SpeedClass()
+	c.Assert(err, qt.IsNil)
+	c.Assert(class, qt.Equals, 10)
+	c.Assert(dev.CSD.MaxTransferRate(), qt.Equals, uint32(25e6))
+}
+
+func TestCSDVersion1(t *testing.T) {
+	c := qt.New(t)
+	// 1 GB card: C_SIZE=3959 (0xF77), C_SIZE_MULT=7, READ_BL_LEN=9
+	csd := NewCSD([]byte{0x00, 0x26, 0x00, 0x5A, 0x5F, 0x59, 0x83,
+		0xDD, 0xC0, 0x03, 0x80, 0x9F, 0xFF, 0x80, 0x00, 0x01})
+	c.Assert(csd.C_SIZE, qt.Equals, uint32(0xF77))
+	c.Assert(csd.C_SIZE_MULT, qt.Equals, byte(7))
+	c.Assert(csd.Size(), qt.Equals, uint64(3960*512*512))
+	sectors, err := csd.Sectors()
+	c.Assert(err, qt.IsNil)
+	c.Assert(sectors, qt.Equals, int64(3960*512))
+	c.Assert(csd.MaxTransferRate(), qt.Equals, uint32(50e6))
+}
+
+func TestEraseBlocks(t *testing.T) {
+	c := qt.New(t)
+	card, dev := newTestCard(c, true)
+	c.Assert(dev.EraseBlocks(2, 3), qt.IsNil)
+	c.Assert(card.Data[2*512:5*512], qt.DeepEquals, make([]byte, 3*512))
+	c.Assert(card.Data[5*512] != 0 || card.Data[5*512+1] != 0, qt.IsTrue)
+	c.Assert(card.Commands[CMD38_ERASE], qt.Equals, 1)
+	c.Assert(card.Commands[CMD25_WRITE_MULTIPLE_BLOCK], qt.Equals, 0)
+
+	// Standard capacity cards use byte addresses for the erase range.
+	card, dev = newTestCard(c, false)
+	card.EraseValue = 0xFF
+	c.Assert(dev.EraseBlocks(8190, 2), qt.IsNil)
+	c.Assert(card.Data[8190*512:], qt.DeepEquals, bytes.Repeat([]byte{0xFF}, 1024))
+	c.Assert(card.Data[8189*512+511] != 0xFF, qt.IsTrue)
+
+	// Erasing past the end of the card is reported.
+	c.Assert(dev.EraseBlocks(8191, 2), qt.ErrorMatches, "SD_CARD_ERROR_CMD33 20")
+}
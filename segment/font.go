@@ -0,0 +1,33 @@
+package segment
+
+// font7 holds the 7-segment digits of the ASCII characters.
+var font7 = [128]byte{
+	' ': 0x00, '!': 0x86, '"': 0x22, '#': 0x7E, '$': 0x6D, '%': 0xD2, '&': 0x46, '\'': 0x20,
+	'(': 0x29, ')': 0x0B, '*': 0x63, '+': 0x70, ',': 0x10, '-': 0x40, '.': 0x80, '/': 0x52,
+	'0': 0x3F, '1': 0x06, '2': 0x5B, '3': 0x4F, '4': 0x66, '5': 0x6D, '6': 0x7D, '7': 0x07,
+	'8': 0x7F, '9': 0x6F, ':': 0x09, ';': 0x0D, '<': 0x61, '=': 0x48, '>': 0x43, '?': 0xD3,
+	'@': 0x5F, 'A': 0x77, 'B': 0x7C, 'C': 0x39, 'D': 0x5E, 'E': 0x79, 'F': 0x71, 'G': 0x3D,
+	'H': 0x76, 'I': 0x30, 'J': 0x1E, 'K': 0x75, 'L': 0x38, 'M': 0x15, 'N': 0x37, 'O': 0x3F,
+	'P': 0x73, 'Q': 0x6B, 'R': 0x33, 'S': 0x6D, 'T': 0x78, 'U': 0x3E, 'V': 0x3E, 'W': 0x2A,
+	'X': 0x76, 'Y': 0x6E, 'Z': 0x5B, '[': 0x39, '\\': 0x64, ']': 0x0F, '^': 0x23, '_': 0x08,
+	'`': 0x02, 'a': 0x5F, 'b': 0x7C, 'c': 0x58, 'd': 0x5E, 'e': 0x7B, 'f': 0x71, 'g': 0x6F,
+	'h': 0x74, 'i': 0x10, 'j': 0x0C, 'k': 0x75, 'l': 0x30, 'm': 0x14, 'n': 0x54, 'o': 0x5C,
+	'p': 0x73, 'q': 0x67, 'r': 0x50, 's': 0x6D, 't': 0x78, 'u': 0x1C, 'v': 0x1C, 'w': 0x14,
+	'x': 0x76, 'y': 0x6E, 'z': 0x5B, '{': 0x46, '|': 0x30, '}': 0x70, '~': 0x01,
+}
+
+// font14 holds the 14-segment digits of the ASCII characters.
+var font14 = [128]uint16{
+	' ': 0x0000, '!': 0x0006, '"': 0x0220, '#': 0x12CE, '$': 0x12ED, '%': 0x0C24, '&': 0x235D, '\'': 0x0400,
+	'(': 0x2400, ')': 0x0900, '*': 0x3FC0, '+': 0x12C0, ',': 0x0800, '-': 0x00C0, '.': 0x4000, '/': 0x0C00,
+	'0': 0x0C3F, '1': 0x0006, '2': 0x00DB, '3': 0x008F, '4': 0x00E6, '5': 0x2069, '6': 0x00FD, '7': 0x0007,
+	'8': 0x00FF, '9': 0x00EF, ':': 0x1200, ';': 0x0A00, '<': 0x2400, '=': 0x00C8, '>': 0x0900, '?': 0x1083,
+	'@': 0x02BB, 'A': 0x00F7, 'B': 0x128F, 'C': 0x0039, 'D': 0x120F, 'E': 0x00F9, 'F': 0x0071, 'G': 0x00BD,
+	'H': 0x00F6, 'I': 0x1209, 'J': 0x001E, 'K': 0x2470, 'L': 0x0038, 'M': 0x0536, 'N': 0x2136, 'O': 0x003F,
+	'P': 0x00F3, 'Q': 0x203F, 'R': 0x20F3, 'S': 0x00ED, 'T': 0x1201, 'U': 0x003E, 'V': 0x0C30, 'W': 0x2836,
+	'X': 0x2D00, 'Y': 0x1500, 'Z': 0x0C09, '[': 0x0039, '\\': 0x2100, ']': 0x000F, '^': 0x0C03, '_': 0x0008,
+	'`': 0x0100, 'a': 0x1058, 'b': 0x2078, 'c': 0x00D8, 'd': 0x088E, 'e': 0x0858, 'f': 0x0071, 'g': 0x048E,
+	'h': 0x1070, 'i': 0x1000, 'j': 0x000E, 'k': 0x3600, 'l': 0x0030, 'm': 0x10D4, 'n': 0x1050, 'o': 0x00DC,
+	'p': 0x0170, 'q': 0x0486, 'r': 0x0050, 's': 0x2088, 't': 0x0078, 'u': 0x001C, 'v': 0x2004, 'w': 0x2814,
+	'x': 0x28C0, 'y': 0x200C, 'z': 0x0848, '{': 0x0949, '|': 0x1200, '}': 0x2489, '~': 0x0520,
+}
@@ -0,0 +1,264 @@
+// Package segment encodes text and numbers for 7-segment and 14-segment
+// displays, for drivers such as tm1637, tm1638 and max72xx.
+//
+// The segments of a 7-segment digit are the bits of a byte, from A (bit 0) to
+// G (bit 6), with the decimal point in bit 7:
+//
+//	 -A-
+//	F   B
+//	 -G-
+//	E   C
+//	 -D-  DP
+//
+// The segments of a 14-segment digit are the bits of a uint16, in the same
+// order as on the Adafruit alphanumeric displays: A to F (bits 0 to 5), the
+// two halves of the middle segment G1 and G2 (bits 6 and 7), the diagonal
+// and vertical segments of the center H, J, K, L, M, N (bits 8 to 13) and the
+// decimal point (bit 14).
+//
+// Text is encoded character by character, except that a '.' lights the
+// decimal point of the previous digit instead of taking a digit of its own.
+package segment // import "tinygo.org/x/drivers/segment"
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Segments of a 7-segment digit.
+const (
+	A byte = 1 << iota
+	B
+	C
+	D
+	E
+	F
+	G
+	DP
+)
+
+// DP14 is the decimal point of a 14-segment digit.
+const DP14 uint16 = 1 << 14
+
+// ErrOverflow is returned when a number doesn't fit on a display.
+var ErrOverflow = errors.New("segment: number too large for the display")
+
+// Align is the alignment of text on a display.
+type Align uint8
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+// Char returns the 7-segment encoding of the ASCII character c, or blank for
+// other characters. Some letters, such as M or W, are only approximations,
+// and '*' is a degree sign.
+func Char(c byte) byte {
+	if c >= 0x80 {
+		return 0
+	}
+	return font7[c]
+}
+
+// Char14 returns the 14-segment encoding of the ASCII character c, or blank
+// for other characters.
+func Char14(c byte) uint16 {
+	if c >= 0x80 {
+		return 0
+	}
+	return font14[c]
+}
+
+// Len returns the number of digits needed to show s.
+func Len(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if !mergeDot(s, i) {
+			n++
+		}
+	}
+	return n
+}
+
+// mergeDot returns whether s[i] is a '.' shown as the decimal point of the
+// previous digit.
+func mergeDot(s string, i int) bool {
+	return s[i] == '.' && i > 0 && s[i-1] != '.'
+}
+
+// Encode writes the 7-segment digits of s to dst, and returns the number of
+// digits written. It stops when dst is full.
+func Encode(dst []byte, s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if mergeDot(s, i) {
+			if n > 0 {
+				dst[n-1] |= DP
+			}
+			continue
+		}
+		if n == len(dst) {
+			break
+		}
+		dst[n] = Char(s[i])
+		n++
+	}
+	return n
+}
+
+// Encode14 writes the 14-segment digits of s to dst, and returns the number of
+// digits written. It stops when dst is full.
+func Encode14(dst []uint16, s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		if mergeDot(s, i) {
+			if n > 0 {
+				dst[n-1] |= DP14
+			}
+			continue
+		}
+		if n == len(dst) {
+			break
+		}
+		dst[n] = Char14(s[i])
+		n++
+	}
+	return n
+}
+
+// Text writes s to dst with the given alignment, and clears the other
+// digits. Text that doesn't fit is cut on the right.
+func Text(dst []byte, s string, align Align) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	start := 0
+	if n := Len(s); align == AlignRight && n < len(dst) {
+		start = len(dst) - n
+	}
+	Encode(dst[start:], s)
+}
+
+// Text14 writes s to dst with the given alignment, and clears the other
+// digits. Text that doesn't fit is cut on the right.
+func Text14(dst []uint16, s string, align Align) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	start := 0
+	if n := Len(s); align == AlignRight && n < len(dst) {
+		start = len(dst) - n
+	}
+	Encode14(dst[start:], s)
+}
+
+// Int writes n to dst, aligned on the right. If it doesn't fit, dst shows
+// dashes and Int returns ErrOverflow.
+func Int(dst []byte, n int) error {
+	return number(dst, strconv.Itoa(n))
+}
+
+// Fixed writes the fixed-point number n / 10^decimals to dst, aligned on the
+// right, with the given number of decimals. For example, Fixed(dst, -5, 2)
+// shows "-0.05". If it doesn't fit, dst shows dashes and Fixed returns
+// ErrOverflow.
+func Fixed(dst []byte, n int, decimals int) error {
+	if decimals <= 0 {
+		return Int(dst, n)
+	}
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for len(s) <= decimals {
+		s = "0" + s
+	}
+	return number(dst, sign+s[:len(s)-decimals]+"."+s[len(s)-decimals:])
+}
+
+// Float writes f to dst, rounded to the given number of decimals and aligned
+// on the right. If it doesn't fit, dst shows dashes and Float returns
+// ErrOverflow.
+func Float(dst []byte, f float32, decimals int) error {
+	if decimals < 0 {
+		decimals = 0
+	}
+	return number(dst, strconv.FormatFloat(float64(f), 'f', decimals, 32))
+}
+
+// number writes the number s to dst, aligned on the right.
+func number(dst []byte, s string) error {
+	if Len(s) > len(dst) {
+		for i := range dst {
+			dst[i] = G
+		}
+		return ErrOverflow
+	}
+	Text(dst, s, AlignRight)
+	return nil
+}
+
+// Marquee scrolls text across a display from right to left, one digit at a
+// time.
+type Marquee struct {
+	Text string
+
+	pos      int // digits scrolled so far
+	digits   []byte
+	digits14 []uint16
+}
+
+// Reset starts scrolling the text again from the right edge of the display.
+func (m *Marquee) Reset() {
+	m.pos = 0
+}
+
+// Step writes the text one digit further to the left to dst. It returns true
+// after the text has left the display, and the next step starts again from
+// the right edge.
+func (m *Marquee) Step(dst []byte) (done bool) {
+	n := Len(m.Text)
+	if cap(m.digits) < n {
+		m.digits = make([]byte, n)
+	}
+	m.digits = m.digits[:n]
+	Encode(m.digits, m.Text)
+	m.pos++
+	for i := range dst {
+		dst[i] = 0
+		if j := m.pos - len(dst) + i; j >= 0 && j < n {
+			dst[i] = m.digits[j]
+		}
+	}
+	return m.next(n, len(dst))
+}
+
+// Step14 is like Step, for 14-segment displays.
+func (m *Marquee) Step14(dst []uint16) (done bool) {
+	n := Len(m.Text)
+	if cap(m.digits14) < n {
+		m.digits14 = make([]uint16, n)
+	}
+	m.digits14 = m.digits14[:n]
+	Encode14(m.digits14, m.Text)
+	m.pos++
+	for i := range dst {
+		dst[i] = 0
+		if j := m.pos - len(dst) + i; j >= 0 && j < n {
+			dst[i] = m.digits14[j]
+		}
+	}
+	return m.next(n, len(dst))
+}
+
+// next returns whether the text of n digits has left a display of the given
+// width, and restarts it if so.
+func (m *Marquee) next(n, width int) bool {
+	if m.pos < n+width {
+		return false
+	}
+	m.pos = 0
+	return true
+}
@@ -0,0 +1,88 @@
+package segment
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEncode(t *testing.T) {
+	c := qt.New(t)
+	dst := make([]byte, 4)
+	c.Assert(Encode(dst, "1.2..H"), qt.Equals, 4)
+	c.Assert(dst, qt.DeepEquals, []byte{Char('1') | DP, Char('2') | DP, DP, Char('H')})
+	c.Assert(Len("1.2..H"), qt.Equals, 4)
+	c.Assert(Len(".5"), qt.Equals, 2)
+
+	c.Assert(Encode(dst, "abcde."), qt.Equals, 4)
+	c.Assert(dst[3], qt.Equals, Char('d'))
+	c.Assert(Char('8'), qt.Equals, A|B|C|D|E|F|G)
+	c.Assert(Char(0xC3), qt.Equals, byte(0))
+
+	dst14 := make([]uint16, 2)
+	c.Assert(Encode14(dst14, "1.T"), qt.Equals, 2)
+	c.Assert(dst14, qt.DeepEquals, []uint16{0x0006 | DP14, 0x1201})
+}
+
+func TestText(t *testing.T) {
+	c := qt.New(t)
+	dst := []byte{1, 2, 3, 4}
+	Text(dst, "Hi", AlignLeft)
+	c.Assert(dst, qt.DeepEquals, []byte{Char('H'), Char('i'), 0, 0})
+	Text(dst, "Hi.", AlignRight)
+	c.Assert(dst, qt.DeepEquals, []byte{0, 0, Char('H'), Char('i') | DP})
+	Text(dst, "Hello", AlignRight)
+	c.Assert(dst, qt.DeepEquals, []byte{Char('H'), Char('e'), Char('l'), Char('l')})
+
+	dst14 := make([]uint16, 3)
+	Text14(dst14, "A", AlignRight)
+	c.Assert(dst14, qt.DeepEquals, []uint16{0, 0, Char14('A')})
+}
+
+func TestNumbers(t *testing.T) {
+	c := qt.New(t)
+	dst := make([]byte, 4)
+	c.Assert(Int(dst, -42), qt.IsNil)
+	c.Assert(dst, qt.DeepEquals, []byte{0, Char('-'), Char('4'), Char('2')})
+	c.Assert(Int(dst, 12345), qt.Equals, ErrOverflow)
+	c.Assert(dst, qt.DeepEquals, []byte{G, G, G, G})
+
+	c.Assert(Fixed(dst, -5, 2), qt.IsNil)
+	c.Assert(dst, qt.DeepEquals, []byte{Char('-'), Char('0') | DP, Char('0'), Char('5')})
+	c.Assert(Fixed(dst, 1234, 1), qt.IsNil)
+	c.Assert(dst, qt.DeepEquals, []byte{Char('1'), Char('2'), Char('3') | DP, Char('4')})
+	c.Assert(Fixed(dst, 7, 0), qt.IsNil)
+	c.Assert(dst[3], qt.Equals, Char('7'))
+
+	c.Assert(Float(dst, 3.14159, 2), qt.IsNil)
+	c.Assert(dst, qt.DeepEquals, []byte{0, Char('3') | DP, Char('1'), Char('4')})
+	c.Assert(Float(dst, -0.25, 1), qt.IsNil)
+	c.Assert(dst, qt.DeepEquals, []byte{0, Char('-'), Char('0') | DP, Char('2')})
+	c.Assert(Float(dst, 99999, 0), qt.Equals, ErrOverflow)
+}
+
+func TestMarquee(t *testing.T) {
+	c := qt.New(t)
+	m := &Marquee{Text: "ab"}
+	dst := make([]byte, 3)
+	a, b := Char('a'), Char('b')
+	want := [][]byte{
+		{0, 0, a},
+		{0, a, b},
+		{a, b, 0},
+		{b, 0, 0},
+		{0, 0, 0},
+	}
+	for i, frame := range want {
+		done := m.Step(dst)
+		c.Assert(dst, qt.DeepEquals, frame)
+		c.Assert(done, qt.Equals, i == len(want)-1)
+	}
+	m.Step(dst)
+	c.Assert(dst, qt.DeepEquals, want[0])
+
+	m.Reset()
+	dst14 := make([]uint16, 1)
+	m.Step14(dst14)
+	c.Assert(dst14[0], qt.Equals, Char14('a'))
+}
@@ -11,3 +11,18 @@ type SPI interface {
 	// If you want to transfer multiple bytes, it is more efficient to use Tx instead.
 	Transfer(b byte) (byte, error)
 }
+
+// AsyncSPI is a SPI bus that can send data in the background, for example
+// using DMA. Drivers use it when the bus passed to them implements it, and
+// fall back to Tx otherwise.
+type AsyncSPI interface {
+	SPI
+
+	// StartTx starts transmitting w and returns without waiting for the
+	// transfer to complete. w must not be modified until Wait returns.
+	StartTx(w []byte) error
+
+	// Wait blocks until the transfer started with StartTx has completed, and
+	// returns its error if any.
+	Wait() error
+}
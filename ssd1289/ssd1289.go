@@ -5,9 +5,12 @@
 package ssd1289
 
 import (
+	"errors"
 	"image/color"
 	"machine"
 	"time"
+
+	"tinygo.org/x/drivers"
 )
 
 type Bus interface {
@@ -22,6 +25,11 @@ type Device struct {
 	bus Bus
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+} = (*Device)(nil)
+
 const width = int16(240)
 const height = int16(320)
 
@@ -190,6 +198,15 @@ func (d *Device) FillRect(x, y, w, h int16, c color.RGBA) {
 
 }
 
+// FillRectangle fills a rectangle at the given coordinates with a color.
+func (d *Device) FillRectangle(x, y, w, h int16, c color.RGBA) error {
+	if x < 0 || y < 0 || w <= 0 || h <= 0 || x+w > width || y+h > height {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	d.FillRect(x, y, w, h, c)
+	return nil
+}
+
 func (d *Device) Display() error {
 	//Not enough memory to store an entire screen on most microcontrollers
 	return nil
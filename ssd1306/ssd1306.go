@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/dirty"
 )
 
 // Device wraps I2C or SPI connection.
@@ -22,8 +23,17 @@ type Device struct {
 	bufferSize int16
 	vccState   VccMode
 	canReset   bool
+	rotation   drivers.Rotation
+	dirty      dirty.Rect // pixels changed since the last Display
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
 // Config is the configuration for the display
 type Config struct {
 	Width    int16
@@ -99,6 +109,7 @@ func (d *Device) Configure(cfg Config) {
 	}
 	d.bufferSize = d.width * d.height / 8
 	d.buffer = make([]byte, d.bufferSize)
+	d.dirty.AddRect(0, 0, d.width, d.height)
 	d.canReset = cfg.Address != 0 || d.width != 128 || d.height != 64 // I2C or not 128x64
 
 	d.bus.configure()
@@ -120,8 +131,7 @@ func (d *Device) Configure(cfg Config) {
 	}
 	d.Command(MEMORYMODE)
 	d.Command(0x00)
-	d.Command(SEGREMAP | 0x1)
-	d.Command(COMSCANDEC)
+	d.sendRotation()
 
 	if (d.width == 128 && d.height == 64) || (d.width == 64 && d.height == 48) { // 128x64 or 64x48
 		d.Command(SETCOMPINS)
@@ -170,6 +180,7 @@ func (d *Device) ClearBuffer() {
 	for i := int16(0); i < d.bufferSize; i++ {
 		d.buffer[i] = 0
 	}
+	d.dirty.AddRect(0, 0, d.width, d.height)
 }
 
 // ClearDisplay clears the image buffer and clear the display
@@ -178,25 +189,60 @@ func (d *Device) ClearDisplay() {
 	d.Display()
 }
 
-// Display sends the whole buffer to the screen
+// Display sends the pages and columns of the buffer that changed since the
+// previous call to the screen.
 func (d *Device) Display() error {
-	// Reset the screen to 0x0
-	// This works fine with I2C
+	if d.dirty.Empty() {
+		return nil
+	}
 	// In the 128x64 (SPI) screen resetting to 0x0 after 128 times corrupt the buffer
-	// Since we're printing the whole buffer, avoid resetting it in this case
-	if d.canReset {
-		d.Command(COLUMNADDR)
-		d.Command(0)
-		d.Command(uint8(d.width - 1))
-		d.Command(PAGEADDR)
-		d.Command(0)
-		d.Command(uint8(d.height/8) - 1)
+	// Since we can't set the address window, print the whole buffer in this case
+	if !d.canReset {
+		d.Tx(d.buffer, false)
+		d.dirty.Reset()
+		return nil
 	}
 
-	d.Tx(d.buffer, false)
+	d.displayWindow(d.dirty.AlignY(8))
+	d.dirty.Reset()
+	return nil
+}
+
+// DisplayRect sends the pages and columns of the buffer that contain the
+// given rectangle to the screen.
+func (d *Device) DisplayRect(x, y, width, height int16) error {
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > d.width || y+height > d.height {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if !d.canReset {
+		d.Tx(d.buffer, false)
+		return nil
+	}
+	r := dirty.Rect{X0: x, Y0: y, X1: x + width, Y1: y + height}
+	d.displayWindow(r.AlignY(8))
 	return nil
 }
 
+// displayWindow sends a window of the buffer to the screen. Y0 and Y1 must
+// be multiples of 8.
+func (d *Device) displayWindow(r dirty.Rect) {
+	d.Command(COLUMNADDR)
+	d.Command(uint8(r.X0))
+	d.Command(uint8(r.X1 - 1))
+	d.Command(PAGEADDR)
+	d.Command(uint8(r.Y0 / 8))
+	d.Command(uint8(r.Y1/8 - 1))
+	if r.X0 == 0 && r.X1 == d.width {
+		// the pages are contiguous in the buffer
+		d.Tx(d.buffer[r.Y0/8*d.width:r.Y1/8*d.width], false)
+	} else {
+		for page := r.Y0 / 8; page < r.Y1/8; page++ {
+			d.Tx(d.buffer[page*d.width+r.X0:page*d.width+r.X1], false)
+		}
+	}
+}
+
 // SetPixel enables or disables a pixel in the buffer
 // color.RGBA{0, 0, 0, 255} is consider transparent, anything else
 // with enable a pixel on the screen
@@ -205,11 +251,30 @@ func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 		return
 	}
 	byteIndex := x + (y/8)*d.width
+	old := d.buffer[byteIndex]
 	if c.R != 0 || c.G != 0 || c.B != 0 {
 		d.buffer[byteIndex] |= 1 << uint8(y%8)
 	} else {
 		d.buffer[byteIndex] &^= 1 << uint8(y%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
+}
+
+// FillRectangle sets the pixels of a rectangle in the buffer, on for any
+// color other than black.
+func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > d.width || y+height > d.height {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	for j := y; j < y+height; j++ {
+		for i := x; i < x+width; i++ {
+			d.SetPixel(i, j, c)
+		}
+	}
+	return nil
 }
 
 // GetPixel returns if the specified pixel is on (true) or off (false)
@@ -230,6 +295,7 @@ func (d *Device) SetBuffer(buffer []byte) error {
 	for i := int16(0); i < d.bufferSize; i++ {
 		d.buffer[i] = buffer[i]
 	}
+	d.dirty.AddRect(0, 0, d.width, d.height)
 	return nil
 }
 
@@ -304,3 +370,31 @@ func (b *SPIBus) tx(data []byte, isCommand bool) {
 func (d *Device) Size() (w, h int16) {
 	return d.width, d.height
 }
+
+// Rotation returns the current rotation of the display.
+func (d *Device) Rotation() drivers.Rotation {
+	return d.rotation
+}
+
+// SetRotation flips the display. The controller can only flip the segments
+// and the scan direction, so only Rotation0 and Rotation180 are supported.
+func (d *Device) SetRotation(rotation drivers.Rotation) error {
+	if rotation != drivers.Rotation0 && rotation != drivers.Rotation180 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	d.sendRotation()
+	return nil
+}
+
+// sendRotation sets the segment remap and the scan direction of the
+// current rotation.
+func (d *Device) sendRotation() {
+	if d.rotation == drivers.Rotation180 {
+		d.Command(SEGREMAP)
+		d.Command(COMSCANINC)
+	} else {
+		d.Command(SEGREMAP | 0x1)
+		d.Command(COMSCANDEC)
+	}
+}
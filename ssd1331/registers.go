@@ -30,4 +30,17 @@ const (
 	PRECHARGEC     = 0x8C
 	PRECHARGELEVEL = 0xBB
 	VCOMH          = 0xBE
+
+	// SETREMAP bits
+	REMAP_VERTICAL       = 0x01 // vertical address increment
+	REMAP_COLUMN_REVERSE = 0x02 // column 95 is mapped to SEG0
+	REMAP_BGR            = 0x04
+	REMAP_COM_REVERSE    = 0x10 // scan from COM63 to COM0
+	REMAP_COM_SPLIT      = 0x20 // odd/even split of the COMs
+	REMAP_65K            = 0x40 // 65k colors
+
+	NO_ROTATION  Rotation = 0
+	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
+	ROTATION_180 Rotation = 2
+	ROTATION_270 Rotation = 3
 )
@@ -15,7 +15,7 @@ import (
 )
 
 type Model uint8
-type Rotation uint8
+type Rotation = drivers.Rotation
 
 // Device wraps an SPI connection.
 type Device struct {
@@ -28,12 +28,21 @@ type Device struct {
 	batchLength int16
 	isBGR       bool
 	batchData   []uint8
+	rotation    Rotation
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+} = (*Device)(nil)
+
 // Config is the configuration for the display
 type Config struct {
-	Width  int16
-	Height int16
+	Width    int16
+	Height   int16
+	Rotation Rotation
 }
 
 // New creates a new SSD1331 connection. The SPI wire must already be configured.
@@ -61,6 +70,7 @@ func (d *Device) Configure(cfg Config) {
 	} else {
 		d.height = 64
 	}
+	d.rotation = cfg.Rotation
 
 	d.batchLength = d.width
 	if d.height > d.width {
@@ -79,9 +89,7 @@ func (d *Device) Configure(cfg Config) {
 
 	// Initialization
 	d.Command(DISPLAYOFF)
-	d.Command(SETREMAP)
-	d.Command(0x72) // RGB
-	//d.Command(0x76) // BGR
+	d.SetRotation(d.rotation)
 	d.Command(STARTLINE)
 	d.Command(0x0)
 	d.Command(DISPLAYOFFSET)
@@ -125,7 +133,8 @@ func (d *Device) Display() error {
 
 // SetPixel sets a pixel in the screen
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	if x < 0 || y < 0 || x >= d.width || y >= d.height {
+	w, h := d.Size()
+	if x < 0 || y < 0 || x >= w || y >= h {
 		return
 	}
 	d.FillRectangle(x, y, 1, 1, c)
@@ -133,6 +142,10 @@ func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 
 // setWindow prepares the screen to be modified at a given rectangle
 func (d *Device) setWindow(x, y, w, h int16) {
+	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
+		// the addresses are incremented vertically
+		x, y, w, h = y, x, h, w
+	}
 	/*d.Tx([]uint8{SETCOLUMN}, true)
 	d.Tx([]uint8{uint8(x), uint8(x + w - 1)}, false)
 	d.Tx([]uint8{SETROW}, true)
@@ -147,8 +160,9 @@ func (d *Device) setWindow(x, y, w, h int16) {
 
 // FillRectangle fills a rectangle at a given coordinates with a color
 func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	w, h := d.Size()
 	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
-		x >= d.width || (x+width) > d.width || y >= d.height || (y+height) > d.height {
+		x >= w || (x+width) > w || y >= h || (y+height) > h {
 		return errors.New("rectangle coordinates outside display area")
 	}
 	d.setWindow(x, y, width, height)
@@ -175,8 +189,9 @@ func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 
 // FillRectangle fills a rectangle at a given coordinates with a buffer
 func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []color.RGBA) error {
+	w, h := d.Size()
 	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
-		x >= d.width || (x+width) > d.width || y >= d.height || (y+height) > d.height {
+		x >= w || (x+width) > w || y >= h || (y+height) > h {
 		return errors.New("rectangle coordinates outside display area")
 	}
 	k := width * height
@@ -209,6 +224,36 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	return nil
 }
 
+// DrawRGBBitmap copies an RGB565 bitmap to the display at the given
+// coordinates.
+func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if int32(len(data)) < int32(w)*int32(h) {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+
+	d.setWindow(x, y, w, h)
+
+	data = data[:int32(w)*int32(h)]
+	for len(data) > 0 {
+		n := len(data)
+		if n > int(d.batchLength) {
+			n = int(d.batchLength)
+		}
+		for i, c := range data[:n] {
+			d.batchData[i*2] = uint8(c >> 8)
+			d.batchData[i*2+1] = uint8(c)
+		}
+		d.Tx(d.batchData[:n*2], false)
+		data = data[n:]
+	}
+	return nil
+}
+
 // DrawFastVLine draws a vertical line faster than using SetPixel
 func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
 	if y0 > y1 {
@@ -227,7 +272,8 @@ func (d *Device) DrawFastHLine(x0, x1, y int16, c color.RGBA) {
 
 // FillScreen fills the screen with a given color
 func (d *Device) FillScreen(c color.RGBA) {
-	d.FillRectangle(0, 0, d.width, d.height, c)
+	w, h := d.Size()
+	d.FillRectangle(0, 0, w, h, c)
 }
 
 // SetContrast sets the three contrast values (A, B & C)
@@ -258,7 +304,39 @@ func (d *Device) Tx(data []byte, isCommand bool) {
 
 // Size returns the current size of the display.
 func (d *Device) Size() (w, h int16) {
-	return d.width, d.height
+	if d.rotation == NO_ROTATION || d.rotation == ROTATION_180 {
+		return d.width, d.height
+	}
+	return d.height, d.width
+}
+
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation of the device (clock-wise). Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	remap := uint8(REMAP_65K | REMAP_COM_SPLIT)
+	switch rotation {
+	case NO_ROTATION:
+		remap |= REMAP_COM_REVERSE | REMAP_COLUMN_REVERSE
+	case ROTATION_90:
+		remap |= REMAP_COLUMN_REVERSE | REMAP_VERTICAL
+	case ROTATION_270:
+		remap |= REMAP_COM_REVERSE | REMAP_VERTICAL
+	}
+	if d.isBGR {
+		remap |= REMAP_BGR
+	}
+	d.Command(SETREMAP)
+	d.Command(remap)
+	return nil
 }
 
 // IsBGR changes the color mode (RGB/BGR)
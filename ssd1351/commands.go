@@ -35,4 +35,16 @@ const (
 	HORIZONTAL_SCROLL           = 0x96
 	STOP_MOVING                 = 0x9E
 	START_MOVING                = 0x9F
+
+	// SET_REMAP_COLORDEPTH bits
+	REMAP_VERTICAL       = 0x01 // vertical address increment
+	REMAP_COLUMN_REVERSE = 0x02 // column 127 is mapped to SEG0
+	REMAP_COM_REVERSE    = 0x10 // scan from COM127 to COM0
+	REMAP_COM_SPLIT      = 0x20 // odd/even split of the COMs
+	REMAP_65K            = 0x40 // 65k colors
+
+	NO_ROTATION  Rotation = 0
+	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
+	ROTATION_180 Rotation = 2
+	ROTATION_270 Rotation = 3
 )
@@ -31,14 +31,25 @@ type Device struct {
 	rowOffset    int16
 	columnOffset int16
 	bufferLength int16
+	rotation     Rotation
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+} = (*Device)(nil)
+
+type Rotation = drivers.Rotation
+
 // Config is the configuration for the display
 type Config struct {
 	Width        int16
 	Height       int16
 	RowOffset    int16
 	ColumnOffset int16
+	Rotation     Rotation
 }
 
 // New creates a new SSD1351 connection. The SPI wire must already be configured.
@@ -67,6 +78,7 @@ func (d *Device) Configure(cfg Config) {
 	d.height = cfg.Height
 	d.rowOffset = cfg.RowOffset
 	d.columnOffset = cfg.ColumnOffset
+	d.rotation = cfg.Rotation
 
 	d.bufferLength = d.width
 	if d.height > d.width {
@@ -102,16 +114,13 @@ func (d *Device) Configure(cfg Config) {
 	d.Data(0xF1)
 	d.Command(SET_MUX_RATIO)
 	d.Data(0x7F)
-	d.Command(SET_REMAP_COLORDEPTH)
-	d.Data(0x62)
+	d.SetRotation(d.rotation)
 	d.Command(SET_COLUMN_ADDRESS)
 	d.Data(0x00)
 	d.Data(0x7F)
 	d.Command(SET_ROW_ADDRESS)
 	d.Data(0x00)
 	d.Data(0x7F)
-	d.Command(SET_DISPLAY_START_LINE)
-	d.Data(0x00)
 	d.Command(SET_DISPLAY_OFFSET)
 	d.Data(0x00)
 	d.Command(SET_GPIO)
@@ -148,7 +157,8 @@ func (d *Device) Display() error {
 
 // SetPixel sets a pixel in the buffer
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
-	if x < 0 || y < 0 || x >= d.width || y >= d.height {
+	w, h := d.Size()
+	if x < 0 || y < 0 || x >= w || y >= h {
 		return
 	}
 	d.FillRectangle(x, y, 1, 1, c)
@@ -156,6 +166,10 @@ func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 
 // setWindow prepares the screen memory to be modified at given coordinates
 func (d *Device) setWindow(x, y, w, h int16) {
+	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
+		// the addresses are incremented vertically
+		x, y, w, h = y, x, h, w
+	}
 	x += d.columnOffset
 	y += d.rowOffset
 	d.Command(SET_COLUMN_ADDRESS)
@@ -167,8 +181,9 @@ func (d *Device) setWindow(x, y, w, h int16) {
 
 // FillRectangle fills a rectangle at given coordinates with a color
 func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	w, h := d.Size()
 	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
-		x >= d.width || (x+width) > d.width || y >= d.height || (y+height) > d.height {
+		x >= w || (x+width) > w || y >= h || (y+height) > h {
 		return errDrawingOutOfBounds
 	}
 	d.setWindow(x, y, width, height)
@@ -200,8 +215,9 @@ func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 
 // FillRectangleWithBuffer fills a rectangle at given coordinates with a buffer
 func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []color.RGBA) error {
+	w, h := d.Size()
 	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
-		x >= d.width || (x+width) > d.width || y >= d.height || (y+height) > d.height {
+		x >= w || (x+width) > w || y >= h || (y+height) > h {
 		return errDrawingOutOfBounds
 	}
 	dim := int16(width * height)
@@ -240,6 +256,37 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	return nil
 }
 
+// DrawRGBBitmap copies an RGB565 bitmap to the display at the given
+// coordinates.
+func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errDrawingOutOfBounds
+	}
+	if int32(len(data)) < int32(w)*int32(h) {
+		return errBufferSizeMismatch
+	}
+
+	d.setWindow(x, y, w, h)
+
+	buf := make([]uint8, d.bufferLength*2)
+	data = data[:int32(w)*int32(h)]
+	for len(data) > 0 {
+		n := len(data)
+		if n > int(d.bufferLength) {
+			n = int(d.bufferLength)
+		}
+		for i, c := range data[:n] {
+			buf[i*2] = uint8(c >> 8)
+			buf[i*2+1] = uint8(c)
+		}
+		d.Tx(buf[:n*2], false)
+		data = data[n:]
+	}
+	return nil
+}
+
 // DrawFastVLine draws a vertical line faster than using SetPixel
 func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
 	if y0 > y1 {
@@ -258,7 +305,8 @@ func (d *Device) DrawFastHLine(x0, x1, y int16, c color.RGBA) {
 
 // FillScreen fills the screen with a given color
 func (d *Device) FillScreen(c color.RGBA) {
-	d.FillRectangle(0, 0, d.width, d.height, c)
+	w, h := d.Size()
+	d.FillRectangle(0, 0, w, h, c)
 }
 
 // SetContrast sets the three contrast values (A, B & C)
@@ -287,7 +335,43 @@ func (d *Device) Tx(data []byte, isCommand bool) {
 
 // Size returns the current size of the display
 func (d *Device) Size() (w, h int16) {
-	return d.width, d.height
+	if d.rotation == NO_ROTATION || d.rotation == ROTATION_180 {
+		return d.width, d.height
+	}
+	return d.height, d.width
+}
+
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation of the device (clock-wise). Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	remap := uint8(REMAP_65K | REMAP_COM_SPLIT)
+	startLine := uint8(0)
+	switch rotation {
+	case NO_ROTATION:
+		remap |= REMAP_COLUMN_REVERSE
+	case ROTATION_90:
+		remap |= REMAP_VERTICAL
+	case ROTATION_180:
+		remap |= REMAP_COM_REVERSE
+		startLine = uint8(d.height % 128) // the scan starts at COM127
+	case ROTATION_270:
+		remap |= REMAP_COM_REVERSE | REMAP_COLUMN_REVERSE | REMAP_VERTICAL
+		startLine = uint8(d.height % 128)
+	}
+	d.Command(SET_REMAP_COLORDEPTH)
+	d.Data(remap)
+	d.Command(SET_DISPLAY_START_LINE)
+	d.Data(startLine)
+	return nil
 }
 
 // RGBATo565 converts a color.RGBA to uint16 used in the display
@@ -15,7 +15,7 @@ import (
 )
 
 type Model uint8
-type Rotation uint8
+type Rotation = drivers.Rotation
 
 // Device wraps an SPI connection.
 type Device struct {
@@ -33,8 +33,19 @@ type Device struct {
 	model        Model
 	isBGR        bool
 	batchData    []uint8
+	async        drivers.AsyncSPI // nil if the bus doesn't support it
+	drawing      bool             // an asynchronous transfer is pending
 }
 
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+	drivers.Scroller
+	drivers.AsyncBitmapDrawer
+} = (*Device)(nil)
+
 // Config is the configuration for the display
 type Config struct {
 	Width        int16
@@ -51,8 +62,10 @@ func New(bus drivers.SPI, resetPin, dcPin, csPin, blPin machine.Pin) Device {
 	resetPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
 	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
 	blPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	async, _ := bus.(drivers.AsyncSPI)
 	return Device{
 		bus:      bus,
+		async:    async,
 		dcPin:    dcPin,
 		resetPin: resetPin,
 		csPin:    csPin,
@@ -312,6 +325,86 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	return nil
 }
 
+// DrawRGBBitmap copies an RGB565 bitmap to the display at the given
+// coordinates.
+func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	k, l := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= l || (y+h) > l {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if int32(len(data)) < int32(w)*int32(h) {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+	d.setWindow(x, y, w, h)
+
+	data = data[:int32(w)*int32(h)]
+	for len(data) > 0 {
+		n := len(data)
+		if n > int(d.batchLength) {
+			n = int(d.batchLength)
+		}
+		for i, c := range data[:n] {
+			d.batchData[i*2] = uint8(c >> 8)
+			d.batchData[i*2+1] = uint8(c)
+		}
+		d.Tx(d.batchData[:n*2], false)
+		data = data[n:]
+	}
+	return nil
+}
+
+// DrawRGBBitmap8 copies a bitmap of big-endian RGB565 pixels to the display
+// at the given coordinates.
+func (d *Device) DrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
+	if err := d.StartDrawRGBBitmap8(x, y, data, w, h); err != nil {
+		return err
+	}
+	return d.WaitDraw()
+}
+
+// StartDrawRGBBitmap8 starts copying a bitmap of big-endian RGB565 pixels to
+// the display at the given coordinates. If the bus implements
+// drivers.AsyncSPI, it returns as soon as the transfer has started, so that
+// the next bitmap can be prepared in the meantime; data must not be modified
+// until WaitDraw or the next call to StartDrawRGBBitmap8 returns. Otherwise
+// the bitmap is sent before it returns.
+func (d *Device) StartDrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
+	k, l := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= l || (y+h) > l {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	n := int32(w) * int32(h) * 2
+	if int32(len(data)) < n {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+	if err := d.WaitDraw(); err != nil {
+		return err
+	}
+	d.setWindow(x, y, w, h)
+	if d.async == nil {
+		d.Tx(data[:n], false)
+		return nil
+	}
+	d.dcPin.High()
+	if err := d.async.StartTx(data[:n]); err != nil {
+		return err
+	}
+	d.drawing = true
+	return nil
+}
+
+// WaitDraw waits until the bitmap passed to StartDrawRGBBitmap8 has been sent
+// to the display.
+func (d *Device) WaitDraw() error {
+	if !d.drawing {
+		return nil
+	}
+	d.drawing = false
+	return d.async.Wait()
+}
+
 // DrawFastVLine draws a vertical line faster than using SetPixel
 func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
 	if y0 > y1 {
@@ -337,10 +430,20 @@ func (d *Device) FillScreen(c color.RGBA) {
 	}
 }
 
-// SetRotation changes the rotation of the device (clock-wise)
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation of the device (clock-wise). Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
 	madctl := uint8(0)
-	switch rotation % 4 {
+	switch rotation {
 	case 0:
 		madctl = MADCTL_MX | MADCTL_MY
 		break
@@ -358,7 +461,7 @@ func (d *Device) SetRotation(rotation Rotation) {
 	}
 	d.Command(MADCTL)
 	d.Data(madctl)
-
+	return nil
 }
 
 // Command sends a command to the display
@@ -373,6 +476,7 @@ func (d *Device) Data(data uint8) {
 
 // Tx sends data to the display
 func (d *Device) Tx(data []byte, isCommand bool) {
+	d.WaitDraw()
 	d.dcPin.Set(!isCommand)
 	d.bus.Tx(data, nil)
 }
@@ -7,17 +7,17 @@ package st7789 // import "tinygo.org/x/drivers/st7789"
 
 import (
 	"image/color"
-	"machine"
 	"math"
 	"time"
 
 	"errors"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 // Rotation controls the rotation used by the display.
-type Rotation uint8
+type Rotation = drivers.Rotation
 
 // FrameRate controls the frame rate used by the display.
 type FrameRate uint8
@@ -25,10 +25,10 @@ type FrameRate uint8
 // Device wraps an SPI connection.
 type Device struct {
 	bus             drivers.SPI
-	dcPin           machine.Pin
-	resetPin        machine.Pin
-	csPin           machine.Pin
-	blPin           machine.Pin
+	dcPin           drivers.PinOutput
+	resetPin        drivers.PinOutput
+	csPin           drivers.PinOutput
+	blPin           drivers.PinOutput
 	width           int16
 	height          int16
 	columnOffsetCfg int16
@@ -38,8 +38,11 @@ type Device struct {
 	rotation        Rotation
 	frameRate       FrameRate
 	batchLength     int32
+	batchData       []uint8
 	isBGR           bool
 	vSyncLines      int16
+	async           drivers.AsyncSPI // nil if the bus doesn't support it
+	drawing         bool             // an asynchronous transfer is pending
 }
 
 // Config is the configuration for the display
@@ -54,13 +57,17 @@ type Config struct {
 }
 
 // New creates a new ST7789 connection. The SPI wire must already be configured.
-func New(bus drivers.SPI, resetPin, dcPin, csPin, blPin machine.Pin) Device {
-	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	resetPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	blPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+// The pins are configured as outputs if they are a machine.Pin, other pins
+// must already be configured.
+func New(bus drivers.SPI, resetPin, dcPin, csPin, blPin drivers.PinOutput) Device {
+	legacy.ConfigurePinOut(dcPin)
+	legacy.ConfigurePinOut(resetPin)
+	legacy.ConfigurePinOut(csPin)
+	legacy.ConfigurePinOut(blPin)
+	async, _ := bus.(drivers.AsyncSPI)
 	return Device{
 		bus:      bus,
+		async:    async,
 		dcPin:    dcPin,
 		resetPin: resetPin,
 		csPin:    csPin,
@@ -102,6 +109,7 @@ func (d *Device) Configure(cfg Config) {
 		d.batchLength = int32(d.height)
 	}
 	d.batchLength += d.batchLength & 1
+	d.batchData = make([]uint8, d.batchLength*2)
 
 	// Reset the device
 	d.resetPin.High()
@@ -263,7 +271,7 @@ func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
 	c1 := uint8(c565 >> 8)
 	c2 := uint8(c565)
 
-	data := make([]uint8, d.batchLength*2)
+	data := d.batchData
 	for i := int32(0); i < d.batchLength; i++ {
 		data[i*2] = c1
 		data[i*2+1] = c2
@@ -293,7 +301,7 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	d.setWindow(x, y, width, height)
 
 	k := int32(width) * int32(height)
-	data := make([]uint8, d.batchLength*2)
+	data := d.batchData
 	offset := int32(0)
 	for k > 0 {
 		for i := int32(0); i < d.batchLength; i++ {
@@ -316,6 +324,95 @@ func (d *Device) FillRectangleWithBuffer(x, y, width, height int16, buffer []col
 	return nil
 }
 
+// DrawRGBBitmap copies an RGB565 bitmap to the display at the given
+// coordinates.
+func (d *Device) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	if int32(len(data)) < int32(w)*int32(h) {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+	d.setWindow(x, y, w, h)
+
+	buf := d.batchData
+	data = data[:int32(w)*int32(h)]
+	for len(data) > 0 {
+		n := int32(len(data))
+		if n > d.batchLength {
+			n = d.batchLength
+		}
+		for i, c := range data[:n] {
+			buf[i*2] = uint8(c >> 8)
+			buf[i*2+1] = uint8(c)
+		}
+		d.Tx(buf[:n*2], false)
+		data = data[n:]
+	}
+	return nil
+}
+
+// DrawRGBBitmap8 copies a bitmap of big-endian RGB565 pixels to the display
+// at the given coordinates.
+func (d *Device) DrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
+	if err := d.StartDrawRGBBitmap8(x, y, data, w, h); err != nil {
+		return err
+	}
+	return d.WaitDraw()
+}
+
+// StartDrawRGBBitmap8 starts copying a bitmap of big-endian RGB565 pixels to
+// the display at the given coordinates. If the bus implements
+// drivers.AsyncSPI, it returns as soon as the transfer has started, so that
+// the next bitmap can be prepared in the meantime; data must not be modified
+// until WaitDraw or the next call to StartDrawRGBBitmap8 returns. Otherwise
+// the bitmap is sent before it returns.
+//
+// All other methods wait for the transfer to complete before using the bus,
+// so SyncToScanLine can be called before drawing the first part of a frame
+// to avoid tearing.
+func (d *Device) StartDrawRGBBitmap8(x, y int16, data []uint8, w, h int16) error {
+	k, i := d.Size()
+	if x < 0 || y < 0 || w <= 0 || h <= 0 ||
+		x >= k || (x+w) > k || y >= i || (y+h) > i {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	n := int32(w) * int32(h) * 2
+	if int32(len(data)) < n {
+		return errors.New("bitmap data is shorter than the rectangle size")
+	}
+	if err := d.WaitDraw(); err != nil {
+		return err
+	}
+	d.setWindow(x, y, w, h)
+	if d.async == nil {
+		d.Tx(data[:n], false)
+		return nil
+	}
+	d.dcPin.High()
+	d.csPin.Low()
+	if err := d.async.StartTx(data[:n]); err != nil {
+		d.csPin.High()
+		return err
+	}
+	d.drawing = true
+	return nil
+}
+
+// WaitDraw waits until the bitmap passed to StartDrawRGBBitmap8 has been sent
+// to the display.
+func (d *Device) WaitDraw() error {
+	if !d.drawing {
+		return nil
+	}
+	d.drawing = false
+	err := d.async.Wait()
+	d.csPin.High()
+	return err
+}
+
 // DrawFastVLine draws a vertical line faster than using SetPixel
 func (d *Device) DrawFastVLine(x, y0, y1 int16, c color.RGBA) {
 	if y0 > y1 {
@@ -341,10 +438,20 @@ func (d *Device) FillScreen(c color.RGBA) {
 	}
 }
 
-// SetRotation changes the rotation of the device (clock-wise)
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation of the device (clock-wise). Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
 	madctl := uint8(0)
-	switch rotation % 4 {
+	switch rotation {
 	case 0:
 		madctl = MADCTL_MX | MADCTL_MY
 		d.rowOffset = d.rowOffsetCfg
@@ -370,6 +477,7 @@ func (d *Device) SetRotation(rotation Rotation) {
 	}
 	d.Command(MADCTL)
 	d.Data(madctl)
+	return nil
 }
 
 // Command sends a command to the display.
@@ -384,6 +492,7 @@ func (d *Device) Data(data uint8) {
 
 // Tx sends data to the display
 func (d *Device) Tx(data []byte, isCommand bool) {
+	d.WaitDraw()
 	if isCommand {
 		d.dcPin.Low()
 	} else {
@@ -396,6 +505,7 @@ func (d *Device) Tx(data []byte, isCommand bool) {
 
 // Rx reads data from the display
 func (d *Device) Rx(command uint8, data []byte) {
+	d.WaitDraw()
 	d.dcPin.Low()
 	d.csPin.Low()
 	d.bus.Transfer(command)
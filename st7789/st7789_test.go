@@ -0,0 +1,119 @@
+package st7789
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/tester"
+)
+
+func TestPins(t *testing.T) {
+	c := qt.New(t)
+	rst, dc, cs, bl := tester.NewPin(c), tester.NewPin(c), tester.NewPin(c), tester.NewPin(c)
+	cs.Level = true // deselected
+	bus := tester.NewSPIBus(dc)
+	d := New(bus, rst, dc, cs, bl)
+
+	d.Command(INVON)
+	d.Data(0x12)
+	c.Assert(bus.Commands, qt.DeepEquals, []byte{INVON})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{0x12})
+	cs.AssertTransitions(false, true, false, true)
+	dc.AssertTransitions(true)
+
+	d.EnableBacklight(true)
+	bl.AssertTransitions(true)
+	rst.AssertTransitions()
+}
+
+var _ interface {
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+	drivers.Scroller
+	drivers.AsyncBitmapDrawer
+} = (*Device)(nil)
+
+func TestDrawRGBBitmap(t *testing.T) {
+	c := qt.New(t)
+	dc, cs := tester.NewPin(c), tester.NewPin(c)
+	bus := tester.NewSPIBus(dc)
+	d := New(bus, tester.NewPin(c), dc, cs, tester.NewPin(c))
+	d.width, d.height, d.batchLength, d.batchData = 240, 320, 2, make([]uint8, 4)
+
+	err := d.DrawRGBBitmap(1, 2, []uint16{0x1234, 0x5678, 0x9ABC}, 3, 1)
+	c.Assert(err, qt.IsNil)
+	c.Assert(bus.Commands, qt.DeepEquals, []byte{CASET, RASET, RAMWR})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{
+		0, 1, 0, 3, // columns
+		0, 2, 0, 2, // rows
+		0x12, 0x34, 0x56, 0x78, 0x9A, 0xBC,
+	})
+
+	c.Assert(d.DrawRGBBitmap(0, 0, []uint16{1, 2}, 3, 1), qt.Not(qt.IsNil))
+	c.Assert(d.DrawRGBBitmap(239, 0, []uint16{1, 2}, 2, 1), qt.Not(qt.IsNil))
+}
+
+func TestRotation(t *testing.T) {
+	c := qt.New(t)
+	dc := tester.NewPin(c)
+	bus := tester.NewSPIBus(dc)
+	d := New(bus, tester.NewPin(c), dc, tester.NewPin(c), tester.NewPin(c))
+	d.width, d.height = 240, 320
+
+	c.Assert(d.SetRotation(ROTATION_90), qt.IsNil)
+	c.Assert(d.Rotation(), qt.Equals, ROTATION_90)
+	c.Assert(bus.Commands, qt.DeepEquals, []byte{MADCTL})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{MADCTL_MY | MADCTL_MV})
+	w, h := d.Size()
+	c.Assert([]int16{w, h}, qt.DeepEquals, []int16{320, 240})
+
+	c.Assert(d.SetRotation(drivers.Rotation90Mirror), qt.Equals, drivers.ErrInvalidRotation)
+	c.Assert(d.Rotation(), qt.Equals, ROTATION_90)
+}
+
+func TestStartDrawRGBBitmap8(t *testing.T) {
+	c := qt.New(t)
+	dc, cs := tester.NewPin(c), tester.NewPin(c)
+	cs.Level = true
+	bus := tester.NewAsyncSPIBus(dc)
+	d := New(bus, tester.NewPin(c), dc, cs, tester.NewPin(c))
+	d.width, d.height = 240, 320
+
+	strip1, strip2 := []byte{1, 2, 3, 4}, []byte{5, 6, 7, 8}
+	c.Assert(d.StartDrawRGBBitmap8(0, 0, strip1, 2, 1), qt.IsNil)
+	c.Assert(bus.Pending, qt.DeepEquals, strip1)
+	c.Assert(cs.Level, qt.IsFalse)
+	c.Assert(dc.Level, qt.IsTrue)
+
+	// Starting the next strip waits for the first one.
+	c.Assert(d.StartDrawRGBBitmap8(0, 1, strip2, 2, 1), qt.IsNil)
+	c.Assert(bus.Pending, qt.DeepEquals, strip2)
+	c.Assert(d.WaitDraw(), qt.IsNil)
+	c.Assert(cs.Level, qt.IsTrue)
+	c.Assert(bus.Commands, qt.DeepEquals, []byte{CASET, RASET, RAMWR, CASET, RASET, RAMWR})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{
+		0, 0, 0, 1, 0, 0, 0, 0, 1, 2, 3, 4,
+		0, 0, 0, 1, 0, 1, 0, 1, 5, 6, 7, 8,
+	})
+
+	// Other commands also wait for the transfer.
+	c.Assert(d.StartDrawRGBBitmap8(0, 0, strip1, 2, 1), qt.IsNil)
+	d.Command(NORON)
+	c.Assert(bus.Pending, qt.IsNil)
+	c.Assert(bus.Commands[len(bus.Commands)-1], qt.Equals, byte(NORON))
+
+	c.Assert(d.StartDrawRGBBitmap8(0, 0, strip1, 3, 1), qt.Not(qt.IsNil))
+}
+
+func TestDrawRGBBitmap8Sync(t *testing.T) {
+	c := qt.New(t)
+	dc := tester.NewPin(c)
+	bus := tester.NewSPIBus(dc)
+	d := New(bus, tester.NewPin(c), dc, tester.NewPin(c), tester.NewPin(c))
+	d.width, d.height = 240, 320
+
+	c.Assert(d.DrawRGBBitmap8(1, 0, []byte{1, 2}, 1, 1), qt.IsNil)
+	c.Assert(bus.Data, qt.DeepEquals, []byte{0, 1, 0, 1, 0, 0, 0, 0, 1, 2})
+}
@@ -0,0 +1,316 @@
+package tester
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	stdpng "image/png"
+	"io"
+	"os"
+
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/image/png"
+)
+
+// DisplayMode selects how a simulated display quantises colors, to mimic the
+// panel that is being simulated.
+type DisplayMode uint8
+
+const (
+	// DisplayRGB888 shows colors as they are, like a 24-bit panel.
+	DisplayRGB888 DisplayMode = iota
+
+	// DisplayRGB565 drops the low bits of each channel, like the 16-bit
+	// colors of TFT panels such as the ST7789 and ILI9341.
+	DisplayRGB565
+
+	// DisplayMonochrome shows pixels in OnColor if any of R, G or B is
+	// non-zero and black otherwise, like the SSD1306 and other monochrome
+	// panels.
+	DisplayMonochrome
+)
+
+// Display simulates a display. It implements drivers.Displayer and renders
+// into an image.RGBA, so that graphics code can be developed and tested on a
+// host computer. It also implements the optional drivers.RectFiller,
+// drivers.BitmapDrawer, drivers.Rotater and drivers.PartialDisplayer
+// interfaces.
+type Display struct {
+	// Image holds what is shown on the display, not rotated.
+	Image *image.RGBA
+
+	// Mode is the color quantisation of the display.
+	Mode DisplayMode
+
+	// OnColor is the color of pixels that are on in DisplayMonochrome mode.
+	OnColor color.RGBA
+
+	// Frames counts the calls to Display and DisplayRect.
+	Frames int
+
+	// If Err is non-nil, it will be returned by Display, DisplayRect and the
+	// drawing methods that return an error.
+	Err error
+
+	buffer   *image.RGBA // pixels that are not shown yet, if buffered
+	rotation drivers.Rotation
+}
+
+var errOutsideDisplay = errors.New("tester: rectangle outside display area")
+
+// NewDisplay returns a new simulated display of the given size, which is
+// black. Pixels are shown as soon as they are set, like on TFT displays that
+// have their own memory.
+func NewDisplay(width, height int16) *Display {
+	d := &Display{
+		Image:   image.NewRGBA(image.Rect(0, 0, int(width), int(height))),
+		OnColor: color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+	for i := 3; i < len(d.Image.Pix); i += 4 {
+		d.Image.Pix[i] = 0xFF
+	}
+	return d
+}
+
+// NewBufferedDisplay returns a new simulated display like NewDisplay, except
+// that pixels are only shown after a call to Display, like on displays whose
+// driver keeps a framebuffer such as the SSD1306.
+func NewBufferedDisplay(width, height int16) *Display {
+	d := NewDisplay(width, height)
+	d.buffer = image.NewRGBA(d.Image.Rect)
+	copy(d.buffer.Pix, d.Image.Pix)
+	return d
+}
+
+// Size returns the size of the display, which is swapped when it is rotated
+// by 90 or 270 degrees.
+func (d *Display) Size() (x, y int16) {
+	size := d.Image.Rect.Size()
+	if d.rotation%2 == 1 {
+		return int16(size.Y), int16(size.X)
+	}
+	return int16(size.X), int16(size.Y)
+}
+
+// SetPixel sets a pixel. Pixels outside of the display are ignored.
+func (d *Display) SetPixel(x, y int16, c color.RGBA) {
+	if w, h := d.Size(); x < 0 || y < 0 || x >= w || y >= h {
+		return
+	}
+	d.set(x, y, d.quantise(c))
+}
+
+// set sets a pixel that is known to be within the display.
+func (d *Display) set(x, y int16, c color.RGBA) {
+	img := d.Image
+	if d.buffer != nil {
+		img = d.buffer
+	}
+	p := d.point(x, y)
+	img.SetRGBA(p.X, p.Y, c)
+}
+
+// point returns the position in Image of the pixel at x, y in the rotated
+// display.
+func (d *Display) point(x, y int16) image.Point {
+	w, h := d.Image.Rect.Dx(), d.Image.Rect.Dy()
+	px, py := int(x), int(y)
+	if d.rotation >= drivers.Rotation0Mirror {
+		rw, _ := d.Size()
+		px = int(rw) - px - 1
+	}
+	switch d.rotation % 4 {
+	case drivers.Rotation90:
+		px, py = w-py-1, px
+	case drivers.Rotation180:
+		px, py = w-px-1, h-py-1
+	case drivers.Rotation270:
+		px, py = py, h-px-1
+	}
+	return image.Point{px, py}
+}
+
+// GetPixel returns the color of a pixel as it is shown on the display, in the
+// coordinates of the rotated display.
+func (d *Display) GetPixel(x, y int16) color.RGBA {
+	if w, h := d.Size(); x < 0 || y < 0 || x >= w || y >= h {
+		return color.RGBA{}
+	}
+	p := d.point(x, y)
+	return d.Image.RGBAAt(p.X, p.Y)
+}
+
+// FillRectangle fills a rectangle with a single color.
+func (d *Display) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	if err := d.checkRect(x, y, width, height); err != nil {
+		return err
+	}
+	c = d.quantise(c)
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			d.set(px, py, c)
+		}
+	}
+	return nil
+}
+
+// DrawRGBBitmap draws a bitmap of RGB565 pixels.
+func (d *Display) DrawRGBBitmap(x, y int16, data []uint16, w, h int16) error {
+	if err := d.checkRect(x, y, w, h); err != nil {
+		return err
+	}
+	if len(data) < int(w)*int(h) {
+		return errors.New("tester: bitmap data is shorter than the rectangle size")
+	}
+	for py := int16(0); py < h; py++ {
+		for px := int16(0); px < w; px++ {
+			c := data[int(py)*int(w)+int(px)]
+			d.set(x+px, y+py, d.quantise(color.RGBA{
+				R: uint8(c>>8) & 0xF8,
+				G: uint8(c>>3) & 0xFC,
+				B: uint8(c << 3),
+			}))
+		}
+	}
+	return nil
+}
+
+// Rotation returns the current rotation of the display.
+func (d *Display) Rotation() drivers.Rotation {
+	return d.rotation
+}
+
+// SetRotation rotates the display. Like on a real display, the pixels that
+// are already shown are not moved.
+func (d *Display) SetRotation(rotation drivers.Rotation) error {
+	if rotation > drivers.Rotation270Mirror {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	return nil
+}
+
+func (d *Display) checkRect(x, y, width, height int16) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 || x+width > w || y+height > h {
+		return errOutsideDisplay
+	}
+	return nil
+}
+
+// Display shows the pixels set since the previous call, if the display is
+// buffered.
+func (d *Display) Display() error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if d.buffer != nil {
+		copy(d.Image.Pix, d.buffer.Pix)
+	}
+	d.Frames++
+	return nil
+}
+
+// DisplayRect shows the pixels within a rectangle that were set since the
+// previous call, if the display is buffered.
+func (d *Display) DisplayRect(x, y, width, height int16) error {
+	if err := d.checkRect(x, y, width, height); err != nil {
+		return err
+	}
+	if d.buffer != nil {
+		for py := y; py < y+height; py++ {
+			for px := x; px < x+width; px++ {
+				p := d.point(px, py)
+				d.Image.SetRGBA(p.X, p.Y, d.buffer.RGBAAt(p.X, p.Y))
+			}
+		}
+	}
+	d.Frames++
+	return nil
+}
+
+func (d *Display) quantise(c color.RGBA) color.RGBA {
+	switch d.Mode {
+	case DisplayRGB565:
+		return color.RGBA{
+			R: c.R&0xF8 | c.R>>5,
+			G: c.G&0xFC | c.G>>6,
+			B: c.B&0xF8 | c.B>>5,
+			A: 0xFF,
+		}
+	case DisplayMonochrome:
+		if c.R != 0 || c.G != 0 || c.B != 0 {
+			return d.OnColor
+		}
+		return color.RGBA{A: 0xFF}
+	}
+	c.A = 0xFF
+	return c
+}
+
+// WritePNG writes what is shown on the display to w as a PNG image.
+func (d *Display) WritePNG(w io.Writer) error {
+	return png.Encode(w, d.Image)
+}
+
+// SavePNG writes what is shown on the display to the named PNG file.
+func (d *Display) SavePNG(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := d.WritePNG(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AssertImage fails the test unless what is shown on the display is equal to
+// the golden PNG image in the named file. If the file does not exist yet, it
+// is created from the display and the test fails so that it can be checked.
+func (d *Display) AssertImage(c Failer, name string) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		if err := d.SavePNG(name); err != nil {
+			c.Fatalf("cannot create golden image: %v", err)
+			return
+		}
+		c.Fatalf("created golden image %s, check it and run the test again", name)
+		return
+	}
+	if err != nil {
+		c.Fatalf("cannot open golden image: %v", err)
+		return
+	}
+	defer f.Close()
+	// The png package of this module only decodes to RGB565 callbacks.
+	golden, err := stdpng.Decode(f)
+	if err != nil {
+		c.Fatalf("cannot decode golden image %s: %v", name, err)
+		return
+	}
+	if golden.Bounds() != d.Image.Rect {
+		c.Fatalf("display size %v differs from golden image %s of size %v", d.Image.Rect.Size(), name, golden.Bounds().Size())
+		return
+	}
+	diffs := 0
+	var first image.Point
+	for y := d.Image.Rect.Min.Y; y < d.Image.Rect.Max.Y; y++ {
+		for x := d.Image.Rect.Min.X; x < d.Image.Rect.Max.X; x++ {
+			if color.RGBAModel.Convert(golden.At(x, y)) != d.Image.RGBAAt(x, y) {
+				if diffs == 0 {
+					first = image.Point{x, y}
+				}
+				diffs++
+			}
+		}
+	}
+	if diffs > 0 {
+		c.Fatalf("display differs from golden image %s in %d pixels, the first at %v: got %v, want %v",
+			name, diffs, first, d.Image.RGBAAt(first.X, first.Y), golden.At(first.X, first.Y))
+	}
+}
@@ -0,0 +1,134 @@
+package tester
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/tinyfont"
+)
+
+var _ interface {
+	drivers.RectFiller
+	drivers.BitmapDrawer
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Display)(nil)
+
+func TestDisplayModes(t *testing.T) {
+	c := qt.New(t)
+	d := NewDisplay(8, 4)
+	w, h := d.Size()
+	c.Assert(w, qt.Equals, int16(8))
+	c.Assert(h, qt.Equals, int16(4))
+
+	orange := color.RGBA{R: 0xFF, G: 0x87, B: 0x0F, A: 0xFF}
+	d.SetPixel(0, 0, orange)
+	c.Assert(d.GetPixel(0, 0), qt.Equals, orange)
+
+	d.Mode = DisplayRGB565
+	d.SetPixel(1, 0, orange)
+	c.Assert(d.GetPixel(1, 0), qt.Equals, color.RGBA{R: 0xFF, G: 0x86, B: 0x08, A: 0xFF})
+
+	d.Mode = DisplayMonochrome
+	d.OnColor = color.RGBA{B: 0xFF, A: 0xFF}
+	d.SetPixel(2, 0, color.RGBA{R: 1})
+	d.SetPixel(3, 0, color.RGBA{A: 0xFF})
+	c.Assert(d.GetPixel(2, 0), qt.Equals, d.OnColor)
+	c.Assert(d.GetPixel(3, 0), qt.Equals, color.RGBA{A: 0xFF})
+
+	// Pixels outside of the display are ignored.
+	d.SetPixel(-1, 0, orange)
+	d.SetPixel(8, 4, orange)
+}
+
+func TestBufferedDisplay(t *testing.T) {
+	c := qt.New(t)
+	d := NewBufferedDisplay(4, 4)
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	d.SetPixel(1, 1, white)
+	c.Assert(d.GetPixel(1, 1), qt.Equals, color.RGBA{A: 0xFF})
+	c.Assert(d.Display(), qt.IsNil)
+	c.Assert(d.GetPixel(1, 1), qt.Equals, white)
+	c.Assert(d.Frames, qt.Equals, 1)
+}
+
+func TestDisplayFillAndBitmap(t *testing.T) {
+	c := qt.New(t)
+	d := NewDisplay(4, 4)
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	c.Assert(d.FillRectangle(1, 1, 2, 3, red), qt.IsNil)
+	c.Assert(d.GetPixel(0, 1), qt.Equals, color.RGBA{A: 0xFF})
+	c.Assert(d.GetPixel(1, 1), qt.Equals, red)
+	c.Assert(d.GetPixel(2, 3), qt.Equals, red)
+	c.Assert(d.FillRectangle(3, 3, 2, 1, red), qt.Not(qt.IsNil))
+
+	c.Assert(d.DrawRGBBitmap(0, 0, []uint16{0xF800, 0x07E0, 0x001F}, 3, 1), qt.IsNil)
+	c.Assert(d.GetPixel(0, 0), qt.Equals, color.RGBA{R: 0xF8, A: 0xFF})
+	c.Assert(d.GetPixel(1, 0), qt.Equals, color.RGBA{G: 0xFC, A: 0xFF})
+	c.Assert(d.GetPixel(2, 0), qt.Equals, color.RGBA{B: 0xF8, A: 0xFF})
+	c.Assert(d.DrawRGBBitmap(0, 0, []uint16{0}, 2, 1), qt.Not(qt.IsNil))
+}
+
+func TestDisplayRotation(t *testing.T) {
+	c := qt.New(t)
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	for _, tc := range []struct {
+		rotation drivers.Rotation
+		x, y     int // position of logical pixel 1, 0 in Image
+	}{
+		{drivers.Rotation0, 1, 0},
+		{drivers.Rotation90, 3, 1},
+		{drivers.Rotation180, 2, 2},
+		{drivers.Rotation270, 0, 1},
+		{drivers.Rotation0Mirror, 2, 0},
+		{drivers.Rotation90Mirror, 3, 1},
+		{drivers.Rotation180Mirror, 1, 2},
+		{drivers.Rotation270Mirror, 0, 1},
+	} {
+		d := NewDisplay(4, 3)
+		c.Assert(d.SetRotation(tc.rotation), qt.IsNil)
+		c.Assert(d.Rotation(), qt.Equals, tc.rotation)
+		d.SetPixel(1, 0, white)
+		c.Assert(d.Image.RGBAAt(tc.x, tc.y), qt.Equals, white, qt.Commentf("rotation %d", tc.rotation))
+		c.Assert(d.GetPixel(1, 0), qt.Equals, white)
+		w, h := d.Size()
+		if tc.rotation%2 == 1 {
+			c.Assert([]int16{w, h}, qt.DeepEquals, []int16{3, 4})
+		} else {
+			c.Assert([]int16{w, h}, qt.DeepEquals, []int16{4, 3})
+		}
+	}
+	d := NewDisplay(4, 3)
+	c.Assert(d.SetRotation(8), qt.Equals, drivers.ErrInvalidRotation)
+}
+
+func TestDisplayRect(t *testing.T) {
+	c := qt.New(t)
+	d := NewBufferedDisplay(4, 4)
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	d.SetPixel(0, 0, white)
+	d.SetPixel(3, 3, white)
+	c.Assert(d.DisplayRect(2, 2, 2, 2), qt.IsNil)
+	c.Assert(d.GetPixel(0, 0), qt.Equals, color.RGBA{A: 0xFF})
+	c.Assert(d.GetPixel(3, 3), qt.Equals, white)
+	c.Assert(d.Frames, qt.Equals, 1)
+	c.Assert(d.DisplayRect(2, 2, 3, 2), qt.Not(qt.IsNil))
+}
+
+func TestDisplayPNG(t *testing.T) {
+	c := qt.New(t)
+	d := NewDisplay(64, 16)
+	d.Mode = DisplayMonochrome
+	tinyfont.WriteLine(d, &tinyfont.TomThumb, 2, 10, "TinyGo", color.RGBA{R: 0xFF})
+	d.AssertImage(c, "testdata/display.png")
+
+	var buf bytes.Buffer
+	c.Assert(d.WritePNG(&buf), qt.IsNil)
+	img, err := png.Decode(&buf)
+	c.Assert(err, qt.IsNil)
+	c.Assert(img.Bounds(), qt.Equals, d.Image.Rect)
+}
@@ -0,0 +1,108 @@
+package tester
+
+// EEPROM simulates an AT24Cxx style I2C EEPROM with 16-bit addresses. It
+// implements I2CDevice and can be added to an I2CBus.
+//
+// A write transaction starts with the address, followed by up to a page of
+// data. Like a real chip, data that crosses the end of a page wraps around
+// to the start of the same page. Reads continue across pages and wrap around
+// at the end of memory.
+type EEPROM struct {
+	c    Failer
+	addr uint8
+
+	// Data holds the contents of the EEPROM.
+	Data []byte
+
+	// PageSize is the size of a write page in bytes.
+	PageSize int
+
+	// WriteLimit is the number of bytes that can still be written before
+	// the power is lost, or -1 for no limit. After a power loss, all
+	// transactions return ErrPowerLoss until PowerCycle is called.
+	WriteLimit int
+
+	// Writes counts the write transactions to each page.
+	Writes []int
+
+	// If Err is non-nil, it will be returned as the error from the I2C
+	// methods.
+	Err error
+
+	current int // address counter
+}
+
+// NewEEPROM returns a new simulated EEPROM at the given I2C address with the
+// given size and page size. Its contents are all 0xFF.
+func NewEEPROM(c Failer, addr uint8, size, pageSize int) *EEPROM {
+	d := &EEPROM{
+		c:          c,
+		addr:       addr,
+		Data:       make([]byte, size),
+		PageSize:   pageSize,
+		WriteLimit: -1,
+		Writes:     make([]int, size/pageSize),
+	}
+	for i := range d.Data {
+		d.Data[i] = 0xFF
+	}
+	return d
+}
+
+// Addr returns the device address.
+func (d *EEPROM) Addr() uint8 {
+	return d.addr
+}
+
+// PowerCycle clears a previous power loss.
+func (d *EEPROM) PowerCycle() {
+	d.Err = nil
+	d.WriteLimit = -1
+	d.current = 0
+}
+
+// ReadRegister implements I2C.ReadRegister. EEPROMs don't have registers.
+func (d *EEPROM) ReadRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("register read from EEPROM")
+	return nil
+}
+
+// WriteRegister implements I2C.WriteRegister. EEPROMs don't have registers.
+func (d *EEPROM) WriteRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("register write to EEPROM")
+	return nil
+}
+
+// Tx implements I2C.Tx.
+func (d *EEPROM) Tx(w, r []byte) error {
+	if d.Err != nil {
+		return d.Err
+	}
+	if len(w) == 1 {
+		d.c.Fatalf("EEPROM address must be two bytes")
+	}
+	if len(w) >= 2 {
+		d.current = (int(w[0])<<8 | int(w[1])) % len(d.Data)
+	}
+	if len(w) > 2 {
+		data := w[2:]
+		page := d.current - d.current%d.PageSize
+		d.Writes[page/d.PageSize]++
+		for i, b := range data {
+			if d.WriteLimit == 0 {
+				d.Err = ErrPowerLoss
+				return d.Err
+			}
+			if d.WriteLimit > 0 {
+				d.WriteLimit--
+			}
+			d.Data[page+(d.current-page+i)%d.PageSize] = b
+		}
+		d.current = page + (d.current-page+len(data))%d.PageSize
+	}
+	for i := range r {
+		r[i] = d.Data[d.current]
+		d.current = (d.current + 1) % len(d.Data)
+	}
+	return nil
+}
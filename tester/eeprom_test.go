@@ -0,0 +1,41 @@
+package tester
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEEPROM(t *testing.T) {
+	c := qt.New(t)
+	bus := NewI2CBus(c)
+	d := NewEEPROM(c, 0x57, 4096, 32)
+	bus.AddDevice(d)
+
+	// A write crossing a page boundary wraps around in the page.
+	c.Assert(bus.Tx(0x57, []byte{0x00, 0x1E, 1, 2, 3, 4}, nil), qt.IsNil)
+	c.Assert(d.Data[0x1E:0x20], qt.DeepEquals, []byte{1, 2})
+	c.Assert(d.Data[0x00:0x02], qt.DeepEquals, []byte{3, 4})
+	c.Assert(d.Data[0x20], qt.Equals, byte(0xFF))
+	c.Assert(d.Writes[0], qt.Equals, 1)
+
+	// Reads continue across pages and wrap at the end of memory.
+	buf := make([]byte, 4)
+	c.Assert(bus.Tx(0x57, []byte{0x00, 0x1E}, buf), qt.IsNil)
+	c.Assert(buf, qt.DeepEquals, []byte{1, 2, 0xFF, 0xFF})
+	d.Data[4095] = 9
+	c.Assert(bus.Tx(0x57, []byte{0x0F, 0xFF}, buf[:2]), qt.IsNil)
+	c.Assert(buf[:2], qt.DeepEquals, []byte{9, 3})
+
+	// Current address read.
+	c.Assert(bus.Tx(0x57, nil, buf[:1]), qt.IsNil)
+	c.Assert(buf[0], qt.Equals, byte(4))
+
+	// Power loss in the middle of a write.
+	d.WriteLimit = 2
+	c.Assert(bus.Tx(0x57, []byte{0x01, 0x00, 5, 6, 7}, nil), qt.Equals, ErrPowerLoss)
+	c.Assert(d.Data[0x100:0x103], qt.DeepEquals, []byte{5, 6, 0xFF})
+	c.Assert(bus.Tx(0x57, []byte{0x01, 0x00}, buf), qt.Equals, ErrPowerLoss)
+	d.PowerCycle()
+	c.Assert(bus.Tx(0x57, []byte{0x01, 0x00}, buf[:2]), qt.IsNil)
+}
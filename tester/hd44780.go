@@ -0,0 +1,244 @@
+package tester
+
+// HD44780 simulates a HD44780 character LCD controller: its display data RAM
+// (DDRAM), its character generator RAM (CGRAM) and the instructions that
+// change them. It doesn't simulate the timing of the instructions.
+//
+// Drivers talk to it through an interface such as HD44780I2C.
+type HD44780 struct {
+	c Failer
+
+	// Width and Height are the size of the display, in characters.
+	Width, Height int
+
+	// DDRAM holds the character codes. The first line starts at 0x00 and
+	// the second one at 0x40; the third and fourth lines of 4-line displays
+	// continue the first and second ones.
+	DDRAM [0x80]byte
+
+	// CGRAM holds the patterns of the 8 custom characters, 8 rows each.
+	CGRAM [0x40]byte
+
+	DisplayOn, CursorOn, CursorBlink bool
+
+	// FourBit is true when the controller is in 4-bit mode, where each
+	// byte is transferred as two nibbles.
+	FourBit bool
+
+	address   int  // address counter
+	cgram     bool // whether the address counter points to CGRAM
+	decrement bool
+	autoShift bool
+	shift     int // of the display, in characters
+}
+
+// NewHD44780 returns a simulated controller for a display of the given size,
+// in the state it has after power on.
+func NewHD44780(c Failer, width, height int) *HD44780 {
+	d := &HD44780{c: c, Width: width, Height: height}
+	for i := range d.DDRAM {
+		d.DDRAM[i] = ' '
+	}
+	return d
+}
+
+// lineLength returns the number of characters of a line of DDRAM.
+func (d *HD44780) lineLength() int {
+	if d.Height > 1 {
+		return 40
+	}
+	return 80
+}
+
+// Instruction executes an instruction, sent with RS low.
+func (d *HD44780) Instruction(b byte) {
+	switch {
+	case b >= 0x80: // set DDRAM address
+		d.address = int(b & 0x7F)
+		d.cgram = false
+	case b >= 0x40: // set CGRAM address
+		d.address = int(b & 0x3F)
+		d.cgram = true
+	case b >= 0x20: // function set
+		d.FourBit = b&0x10 == 0
+	case b >= 0x10: // cursor or display shift
+		step := -1
+		if b&0x04 != 0 {
+			step = 1
+		}
+		if b&0x08 != 0 {
+			d.shift += step
+		} else {
+			d.moveAddress(step)
+		}
+	case b >= 0x08: // display on/off control
+		d.DisplayOn = b&0x04 != 0
+		d.CursorOn = b&0x02 != 0
+		d.CursorBlink = b&0x01 != 0
+	case b >= 0x04: // entry mode set
+		d.decrement = b&0x02 == 0
+		d.autoShift = b&0x01 != 0
+	case b >= 0x02: // return home
+		d.address = 0
+		d.cgram = false
+		d.shift = 0
+	case b == 0x01: // clear display
+		for i := range d.DDRAM {
+			d.DDRAM[i] = ' '
+		}
+		d.address = 0
+		d.cgram = false
+		d.shift = 0
+		d.decrement = false
+	default:
+		d.c.Fatalf("hd44780: invalid instruction %#x", b)
+	}
+}
+
+// Data writes a byte to DDRAM or CGRAM, sent with RS high.
+func (d *HD44780) Data(b byte) {
+	step := 1
+	if d.decrement {
+		step = -1
+	}
+	if d.cgram {
+		d.CGRAM[d.address&0x3F] = b
+		d.address = (d.address + step) & 0x3F
+		return
+	}
+	d.DDRAM[d.address] = b
+	d.moveAddress(step)
+	if d.autoShift {
+		d.shift += step
+	}
+}
+
+// moveAddress moves the DDRAM address counter, from the end of a line to the
+// start of the next one.
+func (d *HD44780) moveAddress(step int) {
+	if d.Height == 1 {
+		d.address = (d.address + step + 80) % 80
+		return
+	}
+	line, pos := d.address/0x40, d.address%0x40
+	pos += step
+	switch {
+	case pos >= 40:
+		pos = 0
+		line++
+	case pos < 0:
+		pos = 39
+		line--
+	}
+	d.address = (line&1)*0x40 + pos
+}
+
+// Cursor returns the DDRAM address counter.
+func (d *HD44780) Cursor() byte {
+	return byte(d.address)
+}
+
+// Lines returns the character codes shown on each line of the display.
+func (d *HD44780) Lines() []string {
+	n := d.lineLength()
+	lines := make([]string, d.Height)
+	for y := range lines {
+		start := []int{0x00, 0x40, d.Width, 0x40 + d.Width}[y%4]
+		line := make([]byte, d.Width)
+		for x := range line {
+			pos := ((start%0x40+x+d.shift)%n + n) % n
+			line[x] = d.DDRAM[start/0x40*0x40+pos]
+		}
+		lines[y] = string(line)
+	}
+	return lines
+}
+
+// Glyph returns the pattern of custom character code (0-7).
+func (d *HD44780) Glyph(code byte) [8]byte {
+	var g [8]byte
+	copy(g[:], d.CGRAM[(code&0x07)*8:])
+	return g
+}
+
+// HD44780I2C simulates a HD44780 controller behind a PCF8574 I2C backpack,
+// as driven by hd44780i2c. It implements I2CDevice.
+//
+// The port of the PCF8574 drives RS (bit 0), RW (bit 1), E (bit 2), the
+// backlight (bit 3) and D4 to D7 (bits 4 to 7). The controller reads a nibble
+// on each falling edge of E.
+type HD44780I2C struct {
+	*HD44780
+
+	// Backlight is true when the backlight is on.
+	Backlight bool
+
+	addr    uint8
+	port    byte
+	nibble  byte
+	pending bool // whether nibble holds the first half of a byte
+}
+
+// NewHD44780I2C returns a simulated backpack at the given address, with a
+// display of the given size.
+func NewHD44780I2C(c Failer, addr uint8, width, height int) *HD44780I2C {
+	return &HD44780I2C{HD44780: NewHD44780(c, width, height), addr: addr}
+}
+
+// Addr returns the device address.
+func (d *HD44780I2C) Addr() uint8 {
+	return d.addr
+}
+
+// ReadRegister implements I2C.ReadRegister. The PCF8574 doesn't have
+// registers.
+func (d *HD44780I2C) ReadRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("hd44780: unexpected ReadRegister")
+	return nil
+}
+
+// WriteRegister implements I2C.WriteRegister. The PCF8574 doesn't have
+// registers.
+func (d *HD44780I2C) WriteRegister(r uint8, buf []byte) error {
+	d.c.Fatalf("hd44780: unexpected WriteRegister")
+	return nil
+}
+
+// Tx implements I2C.Tx. Each byte written sets the port.
+func (d *HD44780I2C) Tx(w, r []byte) error {
+	if len(r) != 0 {
+		d.c.Fatalf("hd44780: unexpected read")
+	}
+	for _, b := range w {
+		if d.port&0x04 != 0 && b&0x04 == 0 {
+			d.latch(d.port>>4, d.port&0x01 != 0)
+		}
+		d.port = b
+		d.Backlight = b&0x08 != 0
+	}
+	return nil
+}
+
+// latch passes a nibble to the controller.
+func (d *HD44780I2C) latch(nibble byte, data bool) {
+	if !d.FourBit {
+		// D0 to D3 are not connected
+		d.execute(nibble<<4, data)
+		return
+	}
+	if !d.pending {
+		d.nibble = nibble
+		d.pending = true
+		return
+	}
+	d.pending = false
+	d.execute(d.nibble<<4|nibble, data)
+}
+
+func (d *HD44780I2C) execute(b byte, data bool) {
+	if data {
+		d.Data(b)
+	} else {
+		d.Instruction(b)
+	}
+}
@@ -0,0 +1,492 @@
+package tester
+
+import "errors"
+
+// ErrPowerLoss is returned by simulated storage devices once their write
+// limit has been reached, simulating a power loss in the middle of a write.
+var ErrPowerLoss = errors.New("tester: power loss")
+
+// SPI NOR flash commands understood by NORFlash.
+const (
+	norWriteStatus  = 0x01
+	norPageProgram  = 0x02
+	norRead         = 0x03
+	norWriteDisable = 0x04
+	norReadStatus   = 0x05
+	norWriteEnable  = 0x06
+	norFastRead     = 0x0B
+	norReadStatus3  = 0x15
+	norEraseSector  = 0x20
+	norWriteStatus2 = 0x31
+	norReadStatus2  = 0x35
+	norProgramSec   = 0x42
+	norEraseSec     = 0x44
+	norReadSec      = 0x48
+	norUniqueID     = 0x4B
+	norErase32K     = 0x52
+	norReadSFDP     = 0x5A
+	norEraseChip60  = 0x60
+	norEnableReset  = 0x66
+	norReset        = 0x99
+	norReadJEDEC    = 0x9F
+	norRelease      = 0xAB
+	norEnter4Byte   = 0xB7
+	norPowerDown    = 0xB9
+	norEraseChip    = 0xC7
+	norEraseBlock   = 0xD8
+	norExit4Byte    = 0xE9
+	norStatusBusy   = 0x01
+	norStatusWEL    = 0x02
+	norStatusBP     = 0x1C // block protect bits BP0-BP2
+	norStatusTB     = 0x20 // top/bottom protect
+	norStatusLB     = 0x38 // security register lock bits in status register 2
+	norPageSize     = 256
+	norSectorSize   = 4096
+)
+
+// NORFlash simulates a SPI NOR flash chip. It implements drivers.SPI, and the
+// chip select line is available through CS.
+//
+// Like a real chip, programming can only clear bits, erasing sets all bits of
+// a sector or block, and program or erase commands are ignored unless they
+// follow a write enable command. Commands are executed when the chip select
+// line goes high.
+//
+// The block protect bits BP0-BP2 in status register 1 protect the upper
+// 1/64 << (BP-1) of memory, or all of it when they are all set. The TB bit
+// protects the lower part instead. Program and erase commands that touch a
+// protected area are ignored. The three 256 byte security registers are at
+// addresses 0x1000, 0x2000 and 0x3000 and can be locked with the LB1-LB3 bits
+// of status register 2, which can't be cleared again.
+type NORFlash struct {
+	c Failer
+
+	// Data holds the contents of the chip.
+	Data []byte
+
+	// JEDECID is returned by the 0x9F command.
+	JEDECID [3]byte
+
+	// SFDP holds the SFDP tables returned by the 0x5A command, if any.
+	SFDP []byte
+
+	// UniqueID is returned by the 0x4B command.
+	UniqueID [8]byte
+
+	// Status holds status registers 1 to 3. The busy and write enable
+	// bits of status register 1 are maintained by the simulator.
+	Status [3]byte
+
+	// Security holds the contents of the security registers.
+	Security [3][256]byte
+
+	// ProgramPolls and ErasePolls are the number of status register reads
+	// for which the chip reports to be busy after a program or erase.
+	ProgramPolls int
+	ErasePolls   int
+
+	// WriteLimit is the number of bytes that can still be programmed
+	// before the power is lost, or -1 for no limit. An erase counts as a
+	// single byte; an erase that is cut off leaves its block partially
+	// erased. After a power loss, all SPI methods return ErrPowerLoss until
+	// PowerCycle is called.
+	WriteLimit int
+
+	// Commands counts the commands received, indexed by opcode.
+	Commands [256]int
+
+	// Erases counts the erase operations of each 4KiB sector.
+	Erases []int
+
+	// If StrictProgram is set, programming a 1 over a bit that is not erased
+	// fails the test. A real chip leaves the bit at 0, which usually means
+	// that the driver forgot an erase.
+	StrictProgram bool
+
+	// BaudRate is the last frequency set with SetBaudRate.
+	BaudRate uint32
+
+	// If Err is non-nil, it will be returned as the error from the SPI
+	// methods.
+	Err error
+
+	selected   bool
+	in         []byte // bytes received since the chip was selected
+	busy       int
+	addr4      bool
+	resetReady bool
+	powerDown  bool
+}
+
+// NewNORFlash returns a new simulated NOR flash chip of the given size in
+// bytes, which is erased.
+func NewNORFlash(c Failer, size int) *NORFlash {
+	s := &NORFlash{
+		c:            c,
+		Data:         make([]byte, size),
+		JEDECID:      [3]byte{0xEF, 0x40, byte(log2(size))},
+		ProgramPolls: 1,
+		ErasePolls:   3,
+		WriteLimit:   -1,
+		Erases:       make([]int, (size+norSectorSize-1)/norSectorSize),
+	}
+	for i := range s.Data {
+		s.Data[i] = 0xFF
+	}
+	for i := range s.Security {
+		for j := range s.Security[i] {
+			s.Security[i][j] = 0xFF
+		}
+	}
+	return s
+}
+
+func log2(n int) int {
+	i := 0
+	for ; n > 1; n >>= 1 {
+		i++
+	}
+	return i
+}
+
+// CS returns the chip select line of the chip.
+func (s *NORFlash) CS() *NORFlashCS {
+	return &NORFlashCS{s}
+}
+
+// SetBaudRate records the frequency of the bus, like machine.SPI on the chips
+// that can change it.
+func (s *NORFlash) SetBaudRate(br uint32) error {
+	s.BaudRate = br
+	return nil
+}
+
+// PowerCycle simulates switching the chip off and on again: volatile state
+// is reset and a previous power loss is cleared.
+func (s *NORFlash) PowerCycle() {
+	s.Err = nil
+	s.WriteLimit = -1
+	s.selected = false
+	s.in = s.in[:0]
+	s.busy = 0
+	s.addr4 = false
+	s.resetReady = false
+	s.powerDown = false
+	s.Status[0] &^= norStatusBusy | norStatusWEL
+}
+
+// PoweredDown returns whether the chip is in deep power-down mode.
+func (s *NORFlash) PoweredDown() bool {
+	return s.powerDown
+}
+
+// NORFlashCS is the chip select line of a simulated NOR flash chip.
+type NORFlashCS struct {
+	chip *NORFlash
+}
+
+// High deselects the chip, which executes the command that was sent.
+func (cs *NORFlashCS) High() {
+	s := cs.chip
+	if s.selected && len(s.in) > 0 {
+		s.execute()
+	}
+	s.selected = false
+	s.in = s.in[:0]
+}
+
+// Low selects the chip.
+func (cs *NORFlashCS) Low() {
+	s := cs.chip
+	s.selected = true
+	s.in = s.in[:0]
+}
+
+// Tx implements SPI.Tx.
+func (s *NORFlash) Tx(w, r []byte) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	n := len(w)
+	if w == nil {
+		n = len(r)
+	}
+	for i := 0; i < n; i++ {
+		b := byte(0)
+		if w != nil {
+			b = w[i]
+		}
+		resp := s.transfer(b)
+		if r != nil {
+			r[i] = resp
+		}
+	}
+	return nil
+}
+
+// Transfer implements SPI.Transfer.
+func (s *NORFlash) Transfer(b byte) (byte, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	return s.transfer(b), nil
+}
+
+func (s *NORFlash) addrLen() int {
+	if s.addr4 {
+		return 4
+	}
+	return 3
+}
+
+// address returns the address sent after the command byte.
+func (s *NORFlash) address(n int) int {
+	addr := 0
+	for i := 1; i <= n; i++ {
+		addr = addr<<8 | int(s.in[i])
+	}
+	return addr
+}
+
+// transfer exchanges a single byte with the chip.
+func (s *NORFlash) transfer(b byte) byte {
+	if !s.selected {
+		return 0xFF
+	}
+	pos := len(s.in) // position of this byte in the transaction
+	s.in = append(s.in, b)
+	if pos == 0 {
+		s.Commands[b]++
+		return 0xFF
+	}
+
+	cmd := s.in[0]
+	if s.powerDown || s.busy > 0 && cmd != norReadStatus {
+		return 0xFF
+	}
+	switch cmd {
+	case norReadStatus:
+		status := s.Status[0] &^ norStatusBusy
+		if s.busy > 0 {
+			status |= norStatusBusy
+			s.busy--
+		}
+		return status
+	case norReadStatus2:
+		return s.Status[1]
+	case norReadStatus3:
+		return s.Status[2]
+	case norReadJEDEC:
+		if pos <= len(s.JEDECID) {
+			return s.JEDECID[pos-1]
+		}
+	case norUniqueID:
+		if i := pos - 5; i >= 0 && i < len(s.UniqueID) {
+			return s.UniqueID[i]
+		}
+	case norRead, norFastRead:
+		start := 1 + s.addrLen()
+		if cmd == norFastRead {
+			start++ // dummy byte
+		}
+		if pos >= start {
+			addr := s.address(s.addrLen()) + pos - start
+			return s.Data[addr%len(s.Data)]
+		}
+	case norReadSec:
+		if i := pos - 2 - s.addrLen(); i >= 0 {
+			if reg, off, ok := s.securityAddress(); ok {
+				return s.Security[reg][(off+i)%256]
+			}
+		}
+	case norReadSFDP:
+		if i := pos - 5; i >= 0 {
+			addr := s.address(3) + i
+			if addr < len(s.SFDP) {
+				return s.SFDP[addr]
+			}
+		}
+	}
+	return 0xFF
+}
+
+// execute runs the command in s.in when the chip is deselected.
+func (s *NORFlash) execute() {
+	if s.Err != nil {
+		return
+	}
+	cmd := s.in[0]
+	if s.powerDown {
+		if cmd == norRelease {
+			s.powerDown = false
+		}
+		return
+	}
+	if cmd == norReset && s.resetReady {
+		s.busy = 0
+		s.Status[0] &^= norStatusWEL
+	}
+	s.resetReady = cmd == norEnableReset
+	if s.busy > 0 {
+		return
+	}
+
+	wel := s.Status[0]&norStatusWEL != 0
+	switch cmd {
+	case norWriteEnable:
+		s.Status[0] |= norStatusWEL
+	case norWriteDisable:
+		s.Status[0] &^= norStatusWEL
+	case norPowerDown:
+		s.powerDown = true
+	case norEnter4Byte:
+		s.addr4 = true
+	case norExit4Byte:
+		s.addr4 = false
+	case norWriteStatus, norWriteStatus2:
+		if !wel || len(s.in) < 2 {
+			return
+		}
+		if cmd == norWriteStatus {
+			s.Status[0] = s.in[1]&^(norStatusBusy|norStatusWEL) | s.Status[0]&(norStatusBusy|norStatusWEL)
+			if len(s.in) > 2 {
+				s.Status[1] = s.in[2] | s.Status[1]&norStatusLB
+			}
+		} else {
+			s.Status[1] = s.in[1] | s.Status[1]&norStatusLB
+		}
+		s.Status[0] &^= norStatusWEL
+	case norPageProgram:
+		if !wel || len(s.in) < 1+s.addrLen() {
+			return
+		}
+		s.Status[0] &^= norStatusWEL
+		addr := s.address(s.addrLen()) % len(s.Data)
+		if s.protected(addr&^(norPageSize-1), norPageSize) {
+			return
+		}
+		s.program(addr, s.in[1+s.addrLen():])
+		s.busy = s.ProgramPolls
+	case norEraseSector, norErase32K, norEraseBlock, norEraseChip, norEraseChip60:
+		if !wel {
+			return
+		}
+		start, size := 0, len(s.Data)
+		switch cmd {
+		case norEraseSector:
+			size = norSectorSize
+		case norErase32K:
+			size = 32 * 1024
+		case norEraseBlock:
+			size = 64 * 1024
+		}
+		if size < len(s.Data) {
+			if len(s.in) < 1+s.addrLen() {
+				return
+			}
+			start = s.address(s.addrLen()) % len(s.Data) &^ (size - 1)
+		}
+		s.Status[0] &^= norStatusWEL
+		if s.protected(start, size) {
+			return
+		}
+		s.erase(start, size)
+		s.busy = s.ErasePolls
+	case norProgramSec, norEraseSec:
+		if !wel || len(s.in) < 1+s.addrLen() {
+			return
+		}
+		s.Status[0] &^= norStatusWEL
+		reg, off, ok := s.securityAddress()
+		if !ok || s.Status[1]&(0x08<<uint(reg)) != 0 {
+			return
+		}
+		if cmd == norEraseSec {
+			if s.consumeWrite() {
+				for i := range s.Security[reg] {
+					s.Security[reg][i] = 0xFF
+				}
+			}
+			s.busy = s.ErasePolls
+			return
+		}
+		for i, b := range s.in[1+s.addrLen():] {
+			if !s.consumeWrite() {
+				break
+			}
+			s.Security[reg][(off+i)%256] &= b
+		}
+		s.busy = s.ProgramPolls
+	}
+}
+
+// securityAddress decodes the address of a security register command into
+// the register index (0 to 2) and the offset in the register.
+func (s *NORFlash) securityAddress() (reg, off int, ok bool) {
+	addr := s.address(s.addrLen())
+	reg = addr>>12 - 1
+	return reg, addr & 0xFF, reg >= 0 && reg < len(s.Security)
+}
+
+// protected returns whether any part of the given range is write protected
+// by the block protect bits.
+func (s *NORFlash) protected(start, size int) bool {
+	bp := int(s.Status[0]&norStatusBP) >> 2
+	if bp == 0 {
+		return false
+	}
+	n := len(s.Data)
+	if bp < 7 {
+		n = len(s.Data) / 64 << uint(bp-1)
+	}
+	if s.Status[0]&norStatusTB != 0 {
+		return start < n
+	}
+	return start+size > len(s.Data)-n
+}
+
+// program writes data to the page containing addr, wrapping around at the
+// end of the page.
+func (s *NORFlash) program(addr int, data []byte) {
+	addr %= len(s.Data)
+	page := addr &^ (norPageSize - 1)
+	for i, b := range data {
+		if !s.consumeWrite() {
+			return
+		}
+		p := page + (addr-page+i)%norPageSize
+		if s.StrictProgram && b&^s.Data[p] != 0 {
+			s.c.Fatalf("program of %#02x over %#02x at %#x without erase", b, s.Data[p], p)
+		}
+		s.Data[p] &= b
+	}
+}
+
+func (s *NORFlash) erase(start, size int) {
+	if !s.consumeWrite() {
+		// interrupted: only the first half is erased
+		size /= 2
+	}
+	for i := start; i < start+size; i++ {
+		s.Data[i] = 0xFF
+	}
+	for i := start / norSectorSize; i < (start+size+norSectorSize-1)/norSectorSize; i++ {
+		s.Erases[i]++
+	}
+}
+
+// consumeWrite uses up one byte of the write limit. It returns false, and
+// cuts the power, when the limit has been reached.
+func (s *NORFlash) consumeWrite() bool {
+	if s.Err == ErrPowerLoss {
+		return false
+	}
+	if s.WriteLimit == 0 {
+		s.Err = ErrPowerLoss
+		return false
+	}
+	if s.WriteLimit > 0 {
+		s.WriteLimit--
+	}
+	return true
+}
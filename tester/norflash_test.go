@@ -0,0 +1,195 @@
+package tester
+
+import (
+	"fmt"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func norCommand(s *NORFlash, w []byte, r []byte) {
+	cs := s.CS()
+	cs.Low()
+	s.Tx(w, nil)
+	if r != nil {
+		s.Tx(nil, r)
+	}
+	cs.High()
+}
+
+func TestNORFlashProgram(t *testing.T) {
+	c := qt.New(t)
+	s := NewNORFlash(c, 64*1024)
+
+	// Programming without write enable is ignored.
+	norCommand(s, []byte{norPageProgram, 0, 0, 0x10, 0x12}, nil)
+	c.Assert(s.Data[0x10], qt.Equals, byte(0xFF))
+
+	norCommand(s, []byte{norWriteEnable}, nil)
+	status := make([]byte, 1)
+	norCommand(s, []byte{norReadStatus}, status)
+	c.Assert(status[0], qt.Equals, byte(norStatusWEL))
+
+	// Data crossing the end of the page wraps around.
+	norCommand(s, []byte{norPageProgram, 0, 0, 0xFF, 0x0F, 0xF0}, nil)
+	c.Assert(s.Data[0xFF], qt.Equals, byte(0x0F))
+	c.Assert(s.Data[0x00], qt.Equals, byte(0xF0))
+	c.Assert(s.Data[0x100], qt.Equals, byte(0xFF))
+
+	// The chip is busy for a while, and write enable is reset.
+	norCommand(s, []byte{norReadStatus}, status)
+	c.Assert(status[0], qt.Equals, byte(norStatusBusy))
+	norCommand(s, []byte{norReadStatus}, status)
+	c.Assert(status[0], qt.Equals, byte(0))
+
+	// Programming can only clear bits.
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0, 0, 0xFF, 0xF0}, nil)
+	c.Assert(s.Data[0xFF], qt.Equals, byte(0x00))
+
+	// Reads are ignored while the chip is busy.
+	buf := make([]byte, 3)
+	norCommand(s, []byte{norRead, 0, 0, 0xFE}, buf)
+	c.Assert(buf, qt.DeepEquals, []byte{0xFF, 0xFF, 0xFF})
+	norCommand(s, []byte{norReadStatus}, status)
+	norCommand(s, []byte{norRead, 0, 0, 0xFE}, buf)
+	c.Assert(buf, qt.DeepEquals, []byte{0xFF, 0x00, 0xFF})
+}
+
+// failures records the failures of a test instead of stopping it.
+type failures []string
+
+func (f *failures) Fatalf(format string, a ...interface{}) {
+	*f = append(*f, fmt.Sprintf(format, a...))
+}
+
+func TestNORFlashStrictProgram(t *testing.T) {
+	c := qt.New(t)
+	var f failures
+	s := NewNORFlash(&f, 64*1024)
+	s.StrictProgram = true
+
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0, 0, 0, 0x0F}, nil)
+	s.PowerCycle()
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0, 0, 0, 0x03}, nil)
+	c.Assert(f, qt.HasLen, 0)
+	s.PowerCycle()
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0, 0, 0, 0x07}, nil)
+	c.Assert(f, qt.DeepEquals, failures{"program of 0x07 over 0x03 at 0x0 without erase"})
+}
+
+func TestNORFlashErase(t *testing.T) {
+	c := qt.New(t)
+	s := NewNORFlash(c, 128*1024)
+	for i := range s.Data {
+		s.Data[i] = 0
+	}
+	s.ErasePolls = 0
+
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseSector, 0x01, 0x23, 0x45}, nil)
+	c.Assert(s.Data[0x11FFF], qt.Equals, byte(0))
+	c.Assert(s.Data[0x12000:0x13000], qt.DeepEquals, fill(0x1000, 0xFF))
+	c.Assert(s.Data[0x13000], qt.Equals, byte(0))
+	c.Assert(s.Erases[0x12], qt.Equals, 1)
+
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseBlock, 0x00, 0x80, 0x00}, nil)
+	c.Assert(s.Data[0:0x10000], qt.DeepEquals, fill(0x10000, 0xFF))
+
+	// An erase interrupted by a power loss.
+	s.WriteLimit = 0
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norErase32K, 0x01, 0x80, 0x00}, nil)
+	c.Assert(s.Data[0x18000:0x1C000], qt.DeepEquals, fill(0x4000, 0xFF))
+	c.Assert(s.Data[0x1C000], qt.Equals, byte(0))
+	_, err := s.Transfer(norReadStatus)
+	c.Assert(err, qt.Equals, ErrPowerLoss)
+	s.PowerCycle()
+	_, err = s.Transfer(norReadStatus)
+	c.Assert(err, qt.IsNil)
+}
+
+func fill(n int, b byte) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = b
+	}
+	return buf
+}
+
+func TestNORFlashProtection(t *testing.T) {
+	c := qt.New(t)
+	s := NewNORFlash(c, 128*1024)
+	s.ProgramPolls, s.ErasePolls = 0, 0
+
+	// BP0 protects the upper 1/64 of memory.
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norWriteStatus, 0x04}, nil)
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0x01, 0xF8, 0x00, 0x00}, nil)
+	c.Assert(s.Data[0x1F800], qt.Equals, byte(0xFF))
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0x01, 0xF7, 0x00, 0x00}, nil)
+	c.Assert(s.Data[0x1F700], qt.Equals, byte(0x00))
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseChip}, nil)
+	c.Assert(s.Data[0x1F700], qt.Equals, byte(0x00))
+
+	// With TB the lower part is protected instead.
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norWriteStatus, 0x24}, nil)
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseSector, 0x01, 0xF0, 0x00}, nil)
+	c.Assert(s.Data[0x1F700], qt.Equals, byte(0xFF))
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norPageProgram, 0x00, 0x00, 0x00, 0x00}, nil)
+	c.Assert(s.Data[0], qt.Equals, byte(0xFF))
+}
+
+func TestNORFlashSecurityRegisters(t *testing.T) {
+	c := qt.New(t)
+	s := NewNORFlash(c, 64*1024)
+	s.ProgramPolls, s.ErasePolls = 0, 0
+
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norProgramSec, 0x00, 0x20, 0x10, 0x12, 0x34}, nil)
+	c.Assert(s.Security[1][0x10:0x12], qt.DeepEquals, []byte{0x12, 0x34})
+	buf := make([]byte, 3)
+	norCommand(s, []byte{norReadSec, 0x00, 0x20, 0x10, 0x00}, buf)
+	c.Assert(buf, qt.DeepEquals, []byte{0x12, 0x34, 0xFF})
+
+	// Lock security register 2; the lock bit can't be cleared.
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norWriteStatus2, 0x10}, nil)
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norWriteStatus2, 0x00}, nil)
+	c.Assert(s.Status[1], qt.Equals, byte(0x10))
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseSec, 0x00, 0x20, 0x00}, nil)
+	c.Assert(s.Security[1][0x10], qt.Equals, byte(0x12))
+	s.Security[2][0] = 0
+	norCommand(s, []byte{norWriteEnable}, nil)
+	norCommand(s, []byte{norEraseSec, 0x00, 0x30, 0x00}, nil)
+	c.Assert(s.Security[2][0], qt.Equals, byte(0xFF))
+}
+
+func TestNORFlashPowerDown(t *testing.T) {
+	c := qt.New(t)
+	s := NewNORFlash(c, 64*1024)
+	s.JEDECID = [3]byte{1, 2, 3}
+
+	norCommand(s, []byte{norPowerDown}, nil)
+	c.Assert(s.PoweredDown(), qt.IsTrue)
+	id := make([]byte, 3)
+	norCommand(s, []byte{norReadJEDEC}, id)
+	c.Assert(id, qt.DeepEquals, []byte{0xFF, 0xFF, 0xFF})
+
+	norCommand(s, []byte{norRelease}, nil)
+	c.Assert(s.PoweredDown(), qt.IsFalse)
+	norCommand(s, []byte{norReadJEDEC}, id)
+	c.Assert(id, qt.DeepEquals, []byte{1, 2, 3})
+}
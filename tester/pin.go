@@ -0,0 +1,71 @@
+package tester
+
+// Pin is a fake digital pin. It implements drivers.Pin and records the level
+// changes of the pin, so that tests can check the signals a driver generates
+// on its control lines.
+type Pin struct {
+	c Failer
+
+	// Level is the current level of the pin, true for high. Tests can set it
+	// to simulate an input.
+	Level bool
+
+	// Transitions holds the new level after each change of the level by
+	// High, Low or Set, in order.
+	Transitions []bool
+
+	// OnChange, if not nil, is called with the new level after each change
+	// of the level by High, Low or Set. It can be used to connect the pin to
+	// a simulated device.
+	OnChange func(level bool)
+}
+
+// NewPin returns a new fake pin, which is low.
+func NewPin(c Failer) *Pin {
+	return &Pin{c: c}
+}
+
+// High sets the pin high.
+func (p *Pin) High() {
+	p.Set(true)
+}
+
+// Low sets the pin low.
+func (p *Pin) Low() {
+	p.Set(false)
+}
+
+// Set sets the level of the pin.
+func (p *Pin) Set(level bool) {
+	if level == p.Level {
+		return
+	}
+	p.Level = level
+	p.Transitions = append(p.Transitions, level)
+	if p.OnChange != nil {
+		p.OnChange(level)
+	}
+}
+
+// Get returns the current level of the pin.
+func (p *Pin) Get() bool {
+	return p.Level
+}
+
+// AssertTransitions fails the test unless the pin changed to the given
+// levels, in order, since the previous call. It clears the recorded
+// transitions.
+func (p *Pin) AssertTransitions(levels ...bool) {
+	got := p.Transitions
+	p.Transitions = nil
+	if len(got) != len(levels) {
+		p.c.Fatalf("pin transitions: got %v, want %v", got, levels)
+		return
+	}
+	for i := range got {
+		if got[i] != levels[i] {
+			p.c.Fatalf("pin transitions: got %v, want %v", got, levels)
+			return
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package tester
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+)
+
+var _ drivers.Pin = (*Pin)(nil)
+
+func TestPin(t *testing.T) {
+	c := qt.New(t)
+	p := NewPin(c)
+	var changes []bool
+	p.OnChange = func(level bool) {
+		changes = append(changes, level)
+	}
+
+	p.High()
+	p.High()
+	p.Low()
+	p.Set(true)
+	c.Assert(p.Get(), qt.IsTrue)
+	c.Assert(changes, qt.DeepEquals, []bool{true, false, true})
+	p.AssertTransitions(true, false, true)
+	c.Assert(p.Transitions, qt.HasLen, 0)
+
+	// An input driven by the test.
+	p.Level = false
+	c.Assert(p.Get(), qt.IsFalse)
+	p.AssertTransitions()
+}
@@ -0,0 +1,465 @@
+package tester
+
+// SD card commands understood by SDCard.
+const (
+	sdCMD0   = 0
+	sdCMD8   = 8
+	sdCMD9   = 9
+	sdCMD10  = 10
+	sdCMD12  = 12
+	sdCMD16  = 16
+	sdCMD17  = 17
+	sdCMD18  = 18
+	sdCMD24  = 24
+	sdCMD25  = 25
+	sdCMD32  = 32
+	sdCMD33  = 33
+	sdCMD38  = 38
+	sdCMD55  = 55
+	sdCMD58  = 58
+	sdCMD59  = 59
+	sdACMD13 = 13
+	sdACMD41 = 41
+)
+
+// R1 response bits.
+const (
+	sdR1Idle       = 0x01
+	sdR1EraseReset = 0x02
+	sdR1IllegalCmd = 0x04
+	sdR1CRCError   = 0x08
+	sdR1EraseSeq   = 0x10
+	sdR1AddrError  = 0x20
+	sdR1ParamError = 0x40
+)
+
+const sdBlockSize = 512
+
+type sdState uint8
+
+const (
+	sdStateCommand    sdState = iota // waiting for a command
+	sdStateWriteToken                // waiting for a data token after CMD24/CMD25
+	sdStateWriteData                 // receiving a data block
+)
+
+// SDCard simulates an SD card in SPI mode. It implements drivers.SPI, and
+// the chip select line is available through CS.
+//
+// The contents of the card are held in Data, which can be inspected or
+// prepared by the test. The card starts in the power-on state and must be
+// initialized by the driver with CMD0, CMD8 and ACMD41 like a real card.
+type SDCard struct {
+	c Failer
+
+	// Data holds the contents of the card. Its length is a multiple of 512.
+	Data []byte
+
+	// CID and CSD are returned by CMD10 and CMD9.
+	CID [16]byte
+	CSD [16]byte
+
+	// HighCapacity selects block addressing (SDHC/SDXC). Standard capacity
+	// cards use byte addresses.
+	HighCapacity bool
+
+	// EraseValue is the value of erased bytes after CMD38, which is 0x00
+	// or 0xFF depending on the card.
+	EraseValue byte
+
+	// SpeedClass is the speed class code reported in the SD status
+	// (0 to 4 for class 0, 2, 4, 6 and 10).
+	SpeedClass byte
+
+	// ReadCRCErrors is the number of data blocks still to be sent with a
+	// corrupted CRC, to test CRC checking in the driver.
+	ReadCRCErrors int
+
+	// InitRetries is the number of ACMD41 commands answered with the idle
+	// bit set before the card reports that initialization is complete.
+	InitRetries int
+
+	// Commands counts the commands received, indexed by command number.
+	// Application commands are counted separately in AppCommands.
+	Commands    [64]int
+	AppCommands [64]int
+
+	// If Err is non-nil, it will be returned as the error from the SPI
+	// methods.
+	Err error
+
+	selected bool
+	idle     bool
+	spiMode  bool
+	appCmd   bool
+	initLeft int
+	crc      bool
+
+	state    sdState
+	cmd      [6]byte
+	cmdLen   int
+	out      []byte
+	multi    bool
+	reading  bool
+	addr     int64
+	erase    [2]int64
+	eraseSet int
+	block    []byte
+	blockLen int
+}
+
+// NewSDCard returns a new simulated high capacity SD card with the given
+// size in bytes.
+func NewSDCard(c Failer, size int64) *SDCard {
+	s := &SDCard{
+		c:            c,
+		Data:         make([]byte, size),
+		HighCapacity: true,
+		InitRetries:  2,
+		block:        make([]byte, sdBlockSize+2),
+	}
+	copy(s.CID[:], []byte{0x03, 'S', 'D', 'T', 'G', 'S', 'I', 'M', 0x10, 0x12, 0x34, 0x56, 0x78, 0x01, 0x5A, 0x01})
+	// CSD version 2.0: C_SIZE is the size in units of 512 KiB minus one.
+	cSize := uint32(size/(512*1024)) - 1
+	copy(s.CSD[:], []byte{0x40, 0x0E, 0x00, 0x32, 0x5B, 0x59, 0x00,
+		byte(cSize >> 16 & 0x3F), byte(cSize >> 8), byte(cSize),
+		0x7F, 0x80, 0x0A, 0x40, 0x00, 0x01})
+	return s
+}
+
+// CS returns the chip select line of the card.
+func (s *SDCard) CS() *SDCardCS {
+	return &SDCardCS{s}
+}
+
+// SDCardCS is the chip select line of a simulated SD card.
+type SDCardCS struct {
+	card *SDCard
+}
+
+// High deselects the card.
+func (cs *SDCardCS) High() {
+	s := cs.card
+	s.selected = false
+	s.cmdLen = 0
+}
+
+// Low selects the card.
+func (cs *SDCardCS) Low() {
+	cs.card.selected = true
+}
+
+// Tx implements SPI.Tx.
+func (s *SDCard) Tx(w, r []byte) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	n := len(w)
+	if w == nil {
+		n = len(r)
+	}
+	for i := 0; i < n; i++ {
+		b := byte(0xFF)
+		if w != nil {
+			b = w[i]
+		}
+		resp := s.transfer(b)
+		if r != nil {
+			r[i] = resp
+		}
+	}
+	return nil
+}
+
+// Transfer implements SPI.Transfer.
+func (s *SDCard) Transfer(b byte) (byte, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	return s.transfer(b), nil
+}
+
+// transfer exchanges a single byte with the card.
+func (s *SDCard) transfer(b byte) byte {
+	if !s.selected {
+		return 0xFF
+	}
+	if len(s.out) == 0 && s.reading && s.addr+sdBlockSize <= int64(len(s.Data)) {
+		// Multiple block read: keep sending blocks until CMD12 or the end
+		// of the card.
+		s.sendData(s.Data[s.addr : s.addr+sdBlockSize])
+		s.addr += sdBlockSize
+	}
+	resp := byte(0xFF)
+	if len(s.out) > 0 {
+		resp = s.out[0]
+		s.out = s.out[1:]
+	}
+
+	switch s.state {
+	case sdStateCommand:
+		if s.cmdLen == 0 && b&0xC0 != 0x40 {
+			break
+		}
+		s.cmd[s.cmdLen] = b
+		s.cmdLen++
+		if s.cmdLen == len(s.cmd) {
+			s.cmdLen = 0
+			s.command()
+		}
+	case sdStateWriteToken:
+		switch {
+		case b == 0xFE && !s.multi, b == 0xFC && s.multi:
+			s.state = sdStateWriteData
+			s.blockLen = 0
+		case b == 0xFD && s.multi:
+			// Stop transmission token: the card is busy for a moment.
+			s.state = sdStateCommand
+			s.out = append(s.out, 0xFF, 0x00, 0x00)
+		}
+	case sdStateWriteData:
+		s.block[s.blockLen] = b
+		s.blockLen++
+		if s.blockLen == len(s.block) {
+			s.writeBlock()
+		}
+	}
+	return resp
+}
+
+// command executes the command in s.cmd.
+func (s *SDCard) command() {
+	cmd := s.cmd[0] & 0x3F
+	arg := uint32(s.cmd[1])<<24 | uint32(s.cmd[2])<<16 | uint32(s.cmd[3])<<8 | uint32(s.cmd[4])
+	app := s.appCmd
+	s.appCmd = false
+	if app {
+		s.AppCommands[cmd]++
+	} else {
+		s.Commands[cmd]++
+	}
+
+	// The CRC is always checked for CMD0 and CMD8, and for all commands
+	// once enabled with CMD59.
+	checkCRC := s.crc || !app && (cmd == sdCMD0 || cmd == sdCMD8)
+	if checkCRC && SDCRC7(s.cmd[:5]) != s.cmd[5] {
+		s.respond(s.r1() | sdR1CRCError)
+		return
+	}
+	if !s.spiMode && cmd != sdCMD0 {
+		// Commands before CMD0 are not answered in SPI mode.
+		return
+	}
+
+	switch {
+	case !app && cmd == sdCMD0:
+		s.spiMode = true
+		s.crc = false
+		s.reading = false
+		s.idle = true
+		s.initLeft = s.InitRetries
+		s.respond(s.r1())
+	case !app && cmd == sdCMD8:
+		s.respond(s.r1(), 0x00, 0x00, byte(arg>>8&0x0F), byte(arg))
+	case !app && cmd == sdCMD55:
+		s.appCmd = true
+		s.respond(s.r1())
+	case app && cmd == sdACMD41:
+		if s.initLeft > 0 {
+			s.initLeft--
+		} else {
+			s.idle = false
+		}
+		s.respond(s.r1())
+	case !app && cmd == sdCMD59:
+		s.crc = arg&1 != 0
+		s.respond(s.r1())
+	case s.idle:
+		s.respond(s.r1() | sdR1IllegalCmd)
+	case !app && cmd == sdCMD12:
+		if !s.reading {
+			s.respond(sdR1IllegalCmd)
+			return
+		}
+		// The card skips a stuff byte, then answers R1b.
+		s.reading = false
+		s.out = append(s.out[:0], 0xFF, 0x00, 0x00, 0x00)
+	case app && cmd == sdACMD13:
+		var status [64]byte
+		status[8] = s.SpeedClass
+		s.respond(0, 0)
+		s.sendData(status[:])
+	case !app && cmd == sdCMD18:
+		addr, ok := s.address(arg)
+		if !ok {
+			s.respond(sdR1AddrError)
+			return
+		}
+		s.respond(0)
+		s.addr = addr
+		s.reading = true
+	case !app && (cmd == sdCMD32 || cmd == sdCMD33):
+		addr, ok := s.address(arg)
+		if !ok {
+			s.eraseSet = 0
+			s.respond(sdR1AddrError)
+			return
+		}
+		if cmd == sdCMD32 {
+			s.erase[0] = addr
+			s.eraseSet = 1
+		} else if s.eraseSet == 1 {
+			s.erase[1] = addr
+			s.eraseSet = 2
+		} else {
+			s.eraseSet = 0
+			s.respond(sdR1EraseSeq)
+			return
+		}
+		s.respond(0)
+	case !app && cmd == sdCMD38:
+		if s.eraseSet != 2 || s.erase[1] < s.erase[0] {
+			s.eraseSet = 0
+			s.respond(sdR1EraseSeq)
+			return
+		}
+		for i := s.erase[0]; i < s.erase[1]+sdBlockSize; i++ {
+			s.Data[i] = s.EraseValue
+		}
+		s.eraseSet = 0
+		// R1b: busy for a few bytes while erasing
+		s.respond(0, 0x00, 0x00, 0x00)
+	case !app && cmd == sdCMD58:
+		ocr := byte(0x80)
+		if s.HighCapacity {
+			ocr |= 0x40
+		}
+		s.respond(s.r1(), ocr, 0xFF, 0x80, 0x00)
+	case !app && cmd == sdCMD16:
+		if arg != sdBlockSize {
+			s.respond(sdR1ParamError)
+			return
+		}
+		s.respond(0)
+	case !app && (cmd == sdCMD9 || cmd == sdCMD10):
+		reg := s.CSD[:]
+		if cmd == sdCMD10 {
+			reg = s.CID[:]
+		}
+		s.respond(0)
+		s.sendData(reg)
+	case !app && cmd == sdCMD17:
+		addr, ok := s.address(arg)
+		if !ok {
+			s.respond(sdR1AddrError)
+			return
+		}
+		s.respond(0)
+		s.sendData(s.Data[addr : addr+sdBlockSize])
+	case !app && (cmd == sdCMD24 || cmd == sdCMD25):
+		addr, ok := s.address(arg)
+		if !ok {
+			s.respond(sdR1AddrError)
+			return
+		}
+		s.respond(0)
+		s.addr = addr
+		s.multi = cmd == sdCMD25
+		s.state = sdStateWriteToken
+	default:
+		s.respond(sdR1IllegalCmd)
+	}
+}
+
+// address converts a command argument to a byte offset in Data.
+func (s *SDCard) address(arg uint32) (int64, bool) {
+	addr := int64(arg)
+	if s.HighCapacity {
+		addr *= sdBlockSize
+	} else if addr%sdBlockSize != 0 {
+		return 0, false
+	}
+	return addr, addr+sdBlockSize <= int64(len(s.Data))
+}
+
+func (s *SDCard) r1() byte {
+	if s.idle {
+		return sdR1Idle
+	}
+	return 0
+}
+
+// respond queues a response. The card answers after one byte of command
+// response time (NCR).
+func (s *SDCard) respond(resp ...byte) {
+	s.out = append(s.out[:0], 0xFF)
+	s.out = append(s.out, resp...)
+}
+
+// sendData queues a data block with start token and CRC.
+func (s *SDCard) sendData(data []byte) {
+	crc := SDCRC16(data)
+	if s.ReadCRCErrors > 0 {
+		s.ReadCRCErrors--
+		crc = ^crc
+	}
+	s.out = append(s.out, 0xFF, 0xFE)
+	s.out = append(s.out, data...)
+	s.out = append(s.out, byte(crc>>8), byte(crc))
+}
+
+// writeBlock stores a received data block and queues the data response
+// followed by a busy period.
+func (s *SDCard) writeBlock() {
+	if s.addr+sdBlockSize > int64(len(s.Data)) {
+		s.c.Fatalf("sdcard: write past end of card at %#x", s.addr)
+	}
+	crc := uint16(s.block[sdBlockSize])<<8 | uint16(s.block[sdBlockSize+1])
+	if s.crc && crc != SDCRC16(s.block[:sdBlockSize]) {
+		// Data rejected due to a CRC error; a multiple block write is
+		// aborted.
+		s.out = append(s.out[:0], 0x0B)
+		s.state = sdStateCommand
+		return
+	}
+	copy(s.Data[s.addr:], s.block[:sdBlockSize])
+	s.out = append(s.out[:0], 0x05, 0x00, 0x00)
+	if s.multi {
+		s.addr += sdBlockSize
+		s.state = sdStateWriteToken
+	} else {
+		s.state = sdStateCommand
+	}
+}
+
+// SDCRC7 returns the CRC7 of an SD card command, shifted left by one with
+// the end bit set, as it is sent over the bus.
+func SDCRC7(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if (b^crc)&0x80 != 0 {
+				crc ^= 0x09
+			}
+			b <<= 1
+		}
+	}
+	return crc<<1 | 1
+}
+
+// SDCRC16 returns the CRC16-CCITT of an SD card data block.
+func SDCRC16(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
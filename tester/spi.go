@@ -0,0 +1,70 @@
+package tester
+
+// SPIBus is a fake SPI bus that records the bytes written to it. It
+// implements drivers.SPI.
+type SPIBus struct {
+	// DC, if not nil, is the data/command pin of the device: the bytes sent
+	// while it is low are recorded in Commands instead of Data.
+	DC *Pin
+
+	// Commands holds the bytes sent while DC was low.
+	Commands []byte
+
+	// Data holds the other bytes sent on the bus.
+	Data []byte
+}
+
+// NewSPIBus returns a new fake SPI bus. If dc is not nil, the commands are
+// recorded separately from the data, see SPIBus.DC.
+func NewSPIBus(dc *Pin) *SPIBus {
+	return &SPIBus{DC: dc}
+}
+
+// Tx records the bytes of w. Nothing is read into r.
+func (s *SPIBus) Tx(w, r []byte) error {
+	if s.DC != nil && !s.DC.Level {
+		s.Commands = append(s.Commands, w...)
+	} else {
+		s.Data = append(s.Data, w...)
+	}
+	return nil
+}
+
+// Transfer records b and returns 0.
+func (s *SPIBus) Transfer(b byte) (byte, error) {
+	return 0, s.Tx([]byte{b}, nil)
+}
+
+// AsyncSPIBus is a fake SPI bus that also implements drivers.AsyncSPI. The
+// bytes passed to StartTx are only recorded by the next call to Wait, as if
+// they were sent in the background.
+type AsyncSPIBus struct {
+	SPIBus
+
+	// Pending holds the bytes of the transfer started by StartTx, until
+	// Wait is called.
+	Pending []byte
+
+	// Waits is the number of calls to Wait.
+	Waits int
+}
+
+// NewAsyncSPIBus returns a new fake SPI bus with asynchronous transfers. See
+// NewSPIBus for dc.
+func NewAsyncSPIBus(dc *Pin) *AsyncSPIBus {
+	return &AsyncSPIBus{SPIBus: SPIBus{DC: dc}}
+}
+
+// StartTx starts sending w in the background.
+func (s *AsyncSPIBus) StartTx(w []byte) error {
+	s.Pending = w
+	return nil
+}
+
+// Wait records the pending bytes, with the current level of the DC pin.
+func (s *AsyncSPIBus) Wait() error {
+	s.Tx(s.Pending, nil)
+	s.Pending = nil
+	s.Waits++
+	return nil
+}
@@ -0,0 +1,44 @@
+package tester
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+)
+
+var _ drivers.SPI = (*SPIBus)(nil)
+var _ drivers.AsyncSPI = (*AsyncSPIBus)(nil)
+
+func TestSPIBus(t *testing.T) {
+	c := qt.New(t)
+	bus := NewSPIBus(nil)
+	bus.Tx([]byte{1, 2}, nil)
+	bus.Transfer(3)
+	c.Assert(bus.Data, qt.DeepEquals, []byte{1, 2, 3})
+	c.Assert(bus.Commands, qt.HasLen, 0)
+
+	dc := NewPin(c)
+	bus = NewSPIBus(dc)
+	bus.Transfer(0x2C)
+	dc.High()
+	bus.Tx([]byte{4, 5}, nil)
+	c.Assert(bus.Commands, qt.DeepEquals, []byte{0x2C})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{4, 5})
+}
+
+func TestAsyncSPIBus(t *testing.T) {
+	c := qt.New(t)
+	dc := NewPin(c)
+	bus := NewAsyncSPIBus(dc)
+	bus.StartTx([]byte{1, 2})
+	c.Assert(bus.Pending, qt.DeepEquals, []byte{1, 2})
+	c.Assert(bus.Commands, qt.HasLen, 0)
+
+	// The bytes are recorded with the level of DC when the transfer ends.
+	dc.High()
+	c.Assert(bus.Wait(), qt.IsNil)
+	c.Assert(bus.Pending, qt.IsNil)
+	c.Assert(bus.Data, qt.DeepEquals, []byte{1, 2})
+	c.Assert(bus.Waits, qt.Equals, 1)
+}
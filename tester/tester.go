@@ -1,4 +1,6 @@
-// Package tester contains mock structs to make it easier to test I2C devices.
+// Package tester contains mock structs to make it easier to test I2C devices,
+// simulated storage devices such as SD cards and displays, fake pins and SPI
+// buses.
 //
 // TODO: info on how to use this.
 //
@@ -1,4 +1,5 @@
-// Package tm1637 provides a driver for the TM1637 4-digit 7-segment LED display.
+// Package tm1637 provides a driver for the TM1637 7-segment LED display, with
+// up to 6 digits.
 //
 // Datasheet: https://www.mcielectronics.cl/website_MCI/static/documents/Datasheet_TM1637.pdf
 //
@@ -7,6 +8,8 @@ package tm1637
 import (
 	"machine"
 	"time"
+
+	"tinygo.org/x/drivers/segment"
 )
 
 // Device wraps the pins of the TM1637.
@@ -14,11 +17,12 @@ type Device struct {
 	clk        machine.Pin
 	dio        machine.Pin
 	brightness uint8
+	digits     uint8
 }
 
-// New creates a new TM1637 device.
+// New creates a new TM1637 device, for a display of 4 digits.
 func New(clk machine.Pin, dio machine.Pin, brightness uint8) Device {
-	return Device{clk: clk, dio: dio, brightness: brightness}
+	return Device{clk: clk, dio: dio, brightness: brightness, digits: 4}
 }
 
 // Configure sets up the pins.
@@ -29,6 +33,18 @@ func (d *Device) Configure() {
 	d.dio.Low() // required for future pull-down
 }
 
+// SetDigits sets the number of digits of the display (1-6), for the
+// functions that write to all digits.
+func (d *Device) SetDigits(digits uint8) {
+	if digits < 1 {
+		digits = 1
+	}
+	if digits > 6 {
+		digits = 6
+	}
+	d.digits = digits
+}
+
 // Brightness sets the brightness of the display (0-7).
 func (d *Device) Brightness(brightness uint8) {
 	if brightness > 7 {
@@ -41,70 +57,76 @@ func (d *Device) Brightness(brightness uint8) {
 
 // ClearDisplay clears the display.
 func (d *Device) ClearDisplay() {
-	d.writeData([]byte{0, 0, 0, 0}, 0)
+	var buf [6]byte
+	d.writeData(buf[:d.digits], 0)
 }
 
 // DisplayText shows a text on the display.
 //
-// Only the first 4 letters in the array text would be shown.
+// Only the first letters in the array text that fit on the display would be
+// shown.
 func (d *Device) DisplayText(text []byte) {
-	var sequences []byte
-	for i, t := range text {
-		if i > 3 {
-			break
-		}
-		sequences = append(sequences, encodeChr(t))
-	}
-	d.writeData(sequences, 0)
+	d.DisplayString(string(text), segment.AlignLeft)
+}
+
+// DisplayString shows a text on the display with the given alignment. A '.'
+// lights the decimal point of the previous digit.
+func (d *Device) DisplayString(text string, align segment.Align) {
+	var buf [6]byte
+	segment.Text(buf[:d.digits], text, align)
+	d.writeData(buf[:d.digits], 0)
+}
+
+// DisplaySegments shows digits encoded as in the segment package, such as
+// the frames of a segment.Marquee, from position pos.
+func (d *Device) DisplaySegments(segments []byte, pos uint8) {
+	d.writeData(segments, pos)
 }
 
 // DisplayChr shows a single character (A-Z, a-z)
 // on the display at position 0-3.
 func (d *Device) DisplayChr(chr byte, pos uint8) {
-	if pos > 3 {
-		pos = 3
+	if pos > d.digits-1 {
+		pos = d.digits - 1
 	}
-	d.writeData([]byte{encodeChr(chr)}, pos)
+	d.writeData([]byte{segment.Char(chr)}, pos)
 }
 
 // DisplayNumber shows a number on the display.
 //
-// Only 4 rightmost digits of the number would be shown.
-//
-// For negative numbers, only -999 to -1 would be
-// shown with a negaive sign.
+// Only the rightmost digits of the number that fit on the display would be
+// shown.
 func (d *Device) DisplayNumber(num int16) {
-	var sequences []byte
-	var start int16
-	if num < 0 {
-		sequences = append(sequences, segments[37])
-		num *= -1
-		start = 100
-		num %= 1000
-	} else {
-		start = 1000
-		num %= 10000
+	limit := 1
+	for i := uint8(0); i < d.digits; i++ {
+		limit *= 10
 	}
-	for i := start; i >= 1; i /= 10 {
-		if num >= i {
-			n := (num / int16(i)) % 10
-			sequences = append(sequences, segments[n])
-		} else {
-			if i == 1 && num == 0 {
-				sequences = append(sequences, segments[0])
-			} else {
-				sequences = append(sequences, 0)
-			}
-		}
+	n := int(num)
+	if n < 0 {
+		// keep a digit for the sign
+		n = -(-n % (limit / 10))
+	} else {
+		n %= limit
 	}
-	d.writeData(sequences, 0)
+	var buf [6]byte
+	segment.Int(buf[:d.digits], n)
+	d.writeData(buf[:d.digits], 0)
+}
+
+// DisplayFloat shows a number on the display, rounded to the given number of
+// decimals. It returns segment.ErrOverflow if the number doesn't fit.
+func (d *Device) DisplayFloat(num float32, decimals int) error {
+	var buf [6]byte
+	err := segment.Float(buf[:d.digits], num, decimals)
+	d.writeData(buf[:d.digits], 0)
+	return err
 }
 
 // DisplayDigit shows a single-digit number (0-9)
 // at position 0-3.
 func (d *Device) DisplayDigit(digit uint8, pos uint8) {
 	digit %= 10
-	d.writeData([]byte{segments[digit]}, pos)
+	d.writeData([]byte{segment.Char('0' + digit)}, pos)
 }
 
 // DisplayClock allows you to display hour and minute numbers
@@ -115,7 +137,7 @@ func (d *Device) DisplayClock(num1 uint8, num2 uint8, colon bool) {
 	for k := 0; k < 2; k++ {
 		for i := 10; i >= 1; i /= 10 {
 			n := (num[k] / uint8(i)) % 10
-			sequences = append(sequences, segments[n])
+			sequences = append(sequences, segment.Char('0'+n))
 		}
 	}
 	if colon {
@@ -124,26 +146,6 @@ func (d *Device) DisplayClock(num1 uint8, num2 uint8, colon bool) {
 	d.writeData(sequences, 0)
 }
 
-func encodeChr(c byte) byte {
-	r := rune(c)
-	switch {
-	case r == 32:
-		return segments[36] // space
-	case r == 42:
-		return segments[38] // star/degrees
-	case r == 45:
-		return segments[37] // dash
-	case r >= 65 && r <= 90:
-		return segments[r-55] // uppercase A-Z
-	case r >= 97 && r <= 122:
-		return segments[r-87] // lowercase a-z
-	case r >= 48 && r <= 57:
-		return segments[r-48] // 0-9
-	default:
-		return byte(0)
-	}
-}
-
 func delaytm() {
 	time.Sleep(time.Microsecond * time.Duration(TM1637_DELAY))
 }
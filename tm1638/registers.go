@@ -0,0 +1,13 @@
+package tm1638
+
+const (
+	TM1638_CMD_DATA    = 0x40
+	TM1638_CMD_ADDRESS = 0xC0
+	TM1638_CMD_DISPLAY = 0x80
+
+	// bits of the data command
+	TM1638_READ_KEYS     = 0x02
+	TM1638_FIXED_ADDRESS = 0x04
+
+	TM1638_DSP_ON = 0x08
+)
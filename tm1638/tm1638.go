@@ -0,0 +1,176 @@
+// Package tm1638 provides a driver for the TM1638 LED and key controller, as
+// used on the "LED&KEY" boards with 8 7-segment digits, 8 LEDs and 8 buttons.
+//
+// Datasheet: https://cdn.sparkfun.com/datasheets/Widgets/TM1638_V1.3_EN.pdf
+package tm1638
+
+import (
+	"time"
+
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
+	"tinygo.org/x/drivers/segment"
+)
+
+// Device wraps the pins of the TM1638.
+type Device struct {
+	stb        drivers.PinOutput
+	clk        drivers.PinOutput
+	dio        drivers.Pin
+	brightness uint8
+
+	// Digits holds the segments of the 8 digits, encoded as in the segment
+	// package, shown by Display.
+	Digits [8]byte
+
+	// LEDs holds the state of the 8 LEDs, bit i for LED i, shown by Display.
+	LEDs uint8
+}
+
+// New creates a new TM1638 device. The data pin is used both as an output and
+// as an input, to read the keys.
+func New(stb, clk drivers.PinOutput, dio drivers.Pin, brightness uint8) Device {
+	return Device{stb: stb, clk: clk, dio: dio, brightness: brightness}
+}
+
+// Configure sets up the pins, and clears and turns on the display.
+func (d *Device) Configure() {
+	legacy.ConfigurePinOut(d.stb)
+	legacy.ConfigurePinOut(d.clk)
+	legacy.ConfigurePinOut(d.dio)
+	d.stb.High()
+	d.clk.High()
+	d.ClearDisplay()
+	d.Brightness(d.brightness)
+}
+
+// Brightness sets the brightness of the display (0-7).
+func (d *Device) Brightness(brightness uint8) {
+	if brightness > 7 {
+		brightness = 7
+	}
+	d.brightness = brightness
+	d.command(TM1638_CMD_DISPLAY | TM1638_DSP_ON | brightness)
+}
+
+// ClearDisplay turns all digits and LEDs off.
+func (d *Device) ClearDisplay() {
+	d.Digits = [8]byte{}
+	d.LEDs = 0
+	d.Display()
+}
+
+// Display shows the digits and the LEDs.
+func (d *Device) Display() {
+	d.command(TM1638_CMD_DATA)
+	d.stb.Low()
+	d.writeByte(TM1638_CMD_ADDRESS)
+	for i, digit := range d.Digits {
+		// digits are at even addresses, LEDs at odd ones
+		d.writeByte(digit)
+		d.writeByte((d.LEDs >> uint(i)) & 1)
+	}
+	d.stb.High()
+}
+
+// DisplayString shows a text with the given alignment. A '.' lights the
+// decimal point of the previous digit.
+func (d *Device) DisplayString(text string, align segment.Align) {
+	segment.Text(d.Digits[:], text, align)
+	d.Display()
+}
+
+// DisplayNumber shows a number, aligned on the right. It returns
+// segment.ErrOverflow if the number doesn't fit.
+func (d *Device) DisplayNumber(num int) error {
+	err := segment.Int(d.Digits[:], num)
+	d.Display()
+	return err
+}
+
+// DisplayFloat shows a number, rounded to the given number of decimals. It
+// returns segment.ErrOverflow if the number doesn't fit.
+func (d *Device) DisplayFloat(num float32, decimals int) error {
+	err := segment.Float(d.Digits[:], num, decimals)
+	d.Display()
+	return err
+}
+
+// SetLED turns LED i (0-7) on or off.
+func (d *Device) SetLED(i uint8, on bool) {
+	if i > 7 {
+		return
+	}
+	if on {
+		d.LEDs |= 1 << i
+	} else {
+		d.LEDs &^= 1 << i
+	}
+	d.command(TM1638_CMD_DATA | TM1638_FIXED_ADDRESS)
+	d.stb.Low()
+	d.writeByte(TM1638_CMD_ADDRESS | (2*i + 1))
+	d.writeByte((d.LEDs >> i) & 1)
+	d.stb.High()
+}
+
+// ReadKeys returns the state of the keys of the 8x3 key matrix, as the 4
+// bytes read from the chip: byte i in bits 8*i to 8*i+7. Key K3/KS1 is bit 0,
+// K2/KS1 bit 1, K1/KS1 bit 2, K3/KS2 bit 4 and so on.
+func (d *Device) ReadKeys() uint32 {
+	d.stb.Low()
+	d.writeByte(TM1638_CMD_DATA | TM1638_READ_KEYS)
+	legacy.ConfigurePinInput(d.dio)
+	d.dio.High() // release the line, for open-drain pins
+	delay()
+	var keys uint32
+	for i := 0; i < 32; i++ {
+		d.clk.Low()
+		delay()
+		d.clk.High()
+		if d.dio.Get() {
+			keys |= 1 << uint(i)
+		}
+		delay()
+	}
+	d.stb.High()
+	legacy.ConfigurePinOut(d.dio)
+	return keys
+}
+
+// Buttons returns the state of the 8 buttons of the LED&KEY boards, bit i
+// for button S(i+1). They are connected to K3.
+func (d *Device) Buttons() uint8 {
+	keys := d.ReadKeys()
+	var buttons uint8
+	for i := uint(0); i < 4; i++ {
+		b := keys >> (8 * i)
+		buttons |= uint8(b&0x01) << i
+		buttons |= uint8(b>>4&0x01) << (i + 4)
+	}
+	return buttons
+}
+
+func delay() {
+	time.Sleep(time.Microsecond)
+}
+
+// command sends a command byte on its own.
+func (d *Device) command(cmd uint8) {
+	d.stb.Low()
+	d.writeByte(cmd)
+	d.stb.High()
+}
+
+func (d *Device) writeByte(data uint8) {
+	for i := 0; i < 8; i++ {
+		d.clk.Low()
+		if data&(1<<i) != 0 { // send bits from LSB to MSB
+			d.dio.High()
+		} else {
+			d.dio.Low()
+		}
+		delay()
+		d.clk.High()
+		delay()
+	}
+}
@@ -0,0 +1,101 @@
+package tm1638
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/segment"
+	"tinygo.org/x/drivers/tester"
+)
+
+// chip simulates a TM1638: it records the bytes of each transfer, and sends
+// keys when asked to.
+type chip struct {
+	stb, clk, dio *tester.Pin
+
+	transfers [][]byte
+	bits      int
+	keys      uint32
+	reading   bool
+}
+
+func newChip(c *qt.C) *chip {
+	s := &chip{stb: tester.NewPin(c), clk: tester.NewPin(c), dio: tester.NewPin(c)}
+	s.stb.OnChange = func(level bool) {
+		if !level {
+			s.transfers = append(s.transfers, nil)
+			s.bits = 0
+		}
+		s.reading = false
+	}
+	s.clk.OnChange = func(level bool) {
+		if s.stb.Level {
+			return
+		}
+		if s.reading {
+			if !level {
+				// the chip outputs the next bit on the falling edge
+				s.dio.Level = s.keys>>uint(s.bits)&1 != 0
+				s.bits++
+			}
+			return
+		}
+		if level {
+			t := &s.transfers[len(s.transfers)-1]
+			if s.bits%8 == 0 {
+				*t = append(*t, 0)
+			}
+			if s.dio.Level {
+				(*t)[len(*t)-1] |= 1 << uint(s.bits%8)
+			}
+			s.bits++
+			if s.bits == 8 && (*t)[0] == TM1638_CMD_DATA|TM1638_READ_KEYS {
+				s.reading = true
+				s.bits = 0
+			}
+		}
+	}
+	return s
+}
+
+func TestDisplay(t *testing.T) {
+	c := qt.New(t)
+	s := newChip(c)
+	d := New(s.stb, s.clk, s.dio, 9)
+	d.Configure()
+	c.Assert(s.transfers, qt.HasLen, 3)
+	c.Assert(s.transfers[2], qt.DeepEquals, []byte{TM1638_CMD_DISPLAY | TM1638_DSP_ON | 7})
+
+	s.transfers = nil
+	d.LEDs = 0x81
+	d.DisplayString("Hi", segment.AlignRight)
+	c.Assert(s.transfers, qt.DeepEquals, [][]byte{
+		{TM1638_CMD_DATA},
+		{TM1638_CMD_ADDRESS, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			segment.Char('H'), 0, segment.Char('i'), 1},
+	})
+
+	s.transfers = nil
+	d.SetLED(2, true)
+	c.Assert(d.LEDs, qt.Equals, uint8(0x85))
+	c.Assert(s.transfers, qt.DeepEquals, [][]byte{
+		{TM1638_CMD_DATA | TM1638_FIXED_ADDRESS},
+		{TM1638_CMD_ADDRESS | 5, 1},
+	})
+
+	c.Assert(d.DisplayNumber(123456789), qt.Equals, segment.ErrOverflow)
+	c.Assert(d.DisplayFloat(-1.5, 1), qt.IsNil)
+	c.Assert(d.Digits[5:], qt.DeepEquals, []byte{segment.Char('-'), segment.Char('1') | segment.DP, segment.Char('5')})
+}
+
+func TestKeys(t *testing.T) {
+	c := qt.New(t)
+	s := newChip(c)
+	d := New(s.stb, s.clk, s.dio, 0)
+	d.Configure()
+
+	s.keys = 0x00100401
+	c.Assert(d.ReadKeys(), qt.Equals, uint32(0x00100401))
+	// S1 is bit 0 of byte 0, S7 bit 4 of byte 2
+	c.Assert(d.Buttons(), qt.Equals, uint8(0x41))
+}
@@ -8,10 +8,12 @@ package uc8151 // import "tinygo.org/x/drivers/uc8151"
 import (
 	"errors"
 	"image/color"
-	"machine"
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/image/dither"
+	"tinygo.org/x/drivers/internal/dirty"
+	"tinygo.org/x/drivers/internal/legacy"
 )
 
 type Config struct {
@@ -20,32 +22,51 @@ type Config struct {
 	Rotation Rotation // Rotation is clock-wise
 	Speed    Speed    // Value from DEFAULT, MEDIUM, FAST, TURBO
 	Blocking bool
+
+	// FullRefreshInterval is the number of times Display may only refresh
+	// the area that changed before it refreshes the whole display again.
+	// Zero always refreshes the whole display. The partial refreshes use the
+	// waveform of Speed, which drives every pixel of the area like a full
+	// refresh does, so the controller needs no separate partial update LUT.
+	FullRefreshInterval int
+
+	// Grayscale enables 4 levels of gray: SetPixel shows colors as black,
+	// dark gray, light gray or white depending on their brightness, so that
+	// color.RGBA{0, 0, 0, 255} is black. Speed and partial refreshes are
+	// ignored in this mode.
+	Grayscale bool
 }
 
 type Device struct {
 	bus          drivers.SPI
-	cs           machine.Pin
-	dc           machine.Pin
-	rst          machine.Pin
-	busy         machine.Pin
+	cs           drivers.PinOutput
+	dc           drivers.PinOutput
+	rst          drivers.PinOutput
+	busy         drivers.PinInput
 	width        int16
 	height       int16
 	buffer       []uint8
+	buffer2      []uint8 // low bit of the gray levels, in grayscale mode
 	bufferLength uint32
 	rotation     Rotation
 	speed        Speed
 	blocking     bool
+	grayscale    bool
+
+	dirty dirty.Refresher // pixels changed since the last Display
 }
 
-type Rotation uint8
+type Rotation = drivers.Rotation
 type Speed uint8
 
 // New returns a new epd2in13x driver. Pass in a fully configured SPI bus.
-func New(bus drivers.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) Device {
-	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	rstPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	busyPin.Configure(machine.PinConfig{Mode: machine.PinInput})
+// The pins are configured if they are a machine.Pin, other pins must already
+// be configured.
+func New(bus drivers.SPI, csPin, dcPin, rstPin drivers.PinOutput, busyPin drivers.PinInput) Device {
+	legacy.ConfigurePinOut(csPin)
+	legacy.ConfigurePinOut(dcPin)
+	legacy.ConfigurePinOut(rstPin)
+	legacy.ConfigurePinInput(busyPin)
 	return Device{
 		bus:  bus,
 		cs:   csPin,
@@ -70,22 +91,34 @@ func (d *Device) Configure(cfg Config) {
 	d.rotation = cfg.Rotation
 	d.speed = cfg.Speed
 	d.blocking = cfg.Blocking
+	d.grayscale = cfg.Grayscale
+	d.dirty = dirty.Refresher{Interval: cfg.FullRefreshInterval}
+	d.dirty.AddRect(0, 0, d.width, d.height)
 	d.bufferLength = (uint32(d.width) * uint32(d.height)) / 8
 	d.buffer = make([]uint8, d.bufferLength)
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	d.buffer2 = nil
+	if d.grayscale {
+		d.buffer2 = make([]uint8, d.bufferLength)
+		d.ClearBuffer()
+	}
 
 	d.Reset()
 
 	d.SendCommand(PSR)
-	if d.speed == 0 {
+	if d.speed == 0 && !d.grayscale {
 		d.SendData(RES_128x296 | LUT_OTP | FORMAT_BW | SHIFT_RIGHT | BOOSTER_ON | RESET_NONE | SCAN_UP)
 	} else {
 		d.SendData(RES_128x296 | LUT_REG | FORMAT_BW | SHIFT_RIGHT | BOOSTER_ON | RESET_NONE | SCAN_UP)
 	}
 
-	d.SetLUT(d.speed)
+	if d.grayscale {
+		d.setGrayLUT()
+	} else {
+		d.SetLUT(d.speed)
+	}
 
 	d.SendCommand(PWR)
 	d.SendData(VDS_INTERNAL | VDG_INTERNAL)
@@ -162,22 +195,69 @@ func (d *Device) sendDataCommand(isCommand bool, data uint8) {
 // The display have 2 colors: black and white
 // We use RGBA(0,0,0, 255) as white (transparent)
 // Anything else as black
+// To dither images, use dither.BlackWhite with dither.EPaperBlackWhite as the
+// colors to draw.
+//
+// In grayscale mode, the pixel is set to the gray closest to c: unlike in
+// black and white mode, RGBA(0,0,0, 255) is black and RGBA(255,255,255, 255)
+// white.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
+	if d.grayscale {
+		d.SetGrayPixel(x, y, uint8(dither.Gray4.Index(c)))
+		return
+	}
 	x, y = d.xy(x, y)
 
 	if x < 0 || x >= d.width || y < 0 || y >= d.height {
 		return
 	}
 	byteIndex := x/8 + y*(d.width/8)
+	old := d.buffer[byteIndex]
 	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
 		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
 	} else { // WHITE / EMPTY
 		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
+}
+
+// SetGrayPixel sets a pixel to a level of gray, from 0 (black) to 3 (white),
+// in grayscale mode.
+func (d *Device) SetGrayPixel(x int16, y int16, level uint8) {
+	x, y = d.xy(x, y)
+	if !d.grayscale || x < 0 || x >= d.width || y < 0 || y >= d.height {
+		return
+	}
+	byteIndex := x/8 + y*(d.width/8)
+	mask := uint8(0x80) >> uint8(x%8)
+	// bit set: darker, the same as in black and white mode
+	if level&2 == 0 {
+		d.buffer[byteIndex] |= mask
+	} else {
+		d.buffer[byteIndex] &^= mask
+	}
+	if level&1 == 0 {
+		d.buffer2[byteIndex] |= mask
+	} else {
+		d.buffer2[byteIndex] &^= mask
+	}
 }
 
-// Display sends the buffer to the screen.
+// Display sends the buffer to the screen. If Config.FullRefreshInterval is
+// set, it may only refresh the area that changed since the previous call.
 func (d *Device) Display() error {
+	if d.grayscale {
+		return d.displayGray()
+	}
+	r, partial := d.dirty.Next(dirty.Rect{X1: d.width, Y1: d.height})
+	if r.Empty() {
+		return nil
+	}
+	if partial {
+		return d.displayWindow(r.AlignX(8))
+	}
 	if d.blocking {
 		d.WaitUntilIdle()
 	}
@@ -197,14 +277,37 @@ func (d *Device) Display() error {
 	return nil
 }
 
-// DisplayRect sends only an area of the buffer to the screen.
-// The rectangle points need to be a multiple of 8 in the screen.
-// They might not work as expected if the screen is rotated.
-func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error {
+// displayGray sends both planes of the gray levels to the screen.
+func (d *Device) displayGray() error {
+	d.dirty.Reset()
+	if d.blocking {
+		d.WaitUntilIdle()
+	}
+	d.SendCommand(PON)
+	d.SendCommand(PTOU)
+	// the LUTs expect the bits of the levels, with 3 being white
+	d.SendCommand(DTM1)
+	for i := uint32(0); i < d.bufferLength; i++ {
+		d.SendData(^d.buffer[i])
+	}
+	d.SendCommand(DTM2)
+	for i := uint32(0); i < d.bufferLength; i++ {
+		d.SendData(^d.buffer2[i])
+	}
+
+	d.SendCommand(DSP)
+	d.SendCommand(DRF)
 	if d.blocking {
 		d.WaitUntilIdle()
+		d.PowerOff()
 	}
+	return nil
+}
 
+// DisplayRect sends only an area of the buffer to the screen.
+// The rectangle points need to be a multiple of 8 in the screen.
+// They might not work as expected if the screen is rotated.
+func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error {
 	x, y = d.xy(x, y)
 	if x < 0 || y < 0 || x >= d.width || y >= d.height || width < 0 || height < 0 {
 		return errors.New("wrong rectangle")
@@ -229,24 +332,31 @@ func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error
 	if height > d.height {
 		height = d.height
 	}
+	return d.displayWindow(dirty.Rect{X0: x, Y0: y, X1: width, Y1: height})
+}
+
+// displayWindow refreshes a window of the display, in buffer coordinates.
+// X0 and X1 must be multiples of 8.
+func (d *Device) displayWindow(r dirty.Rect) error {
+	if d.blocking {
+		d.WaitUntilIdle()
+	}
 
 	d.SendCommand(PON)
 	d.SendCommand(PTIN)
 	d.SendCommand(PTL)
 
-	d.SendData(uint8(x))
-	d.SendData(uint8(x+width-1) | 0x07)
-	d.SendData(uint8(y >> 8))
-	d.SendData(uint8(y))
-	d.SendData(uint8((y + height - 1) >> 8))
-	d.SendData(uint8(y + height - 1))
+	d.SendData(uint8(r.X0))
+	d.SendData(uint8(r.X1-1) | 0x07)
+	d.SendData(uint8(r.Y0 >> 8))
+	d.SendData(uint8(r.Y0))
+	d.SendData(uint8((r.Y1 - 1) >> 8))
+	d.SendData(uint8(r.Y1 - 1))
 	d.SendData(0x01)
 
 	d.SendCommand(DTM2)
-	x = x / 8
-	width = width / 8
-	for ; y < height; y++ {
-		for i := x; i < width; i++ {
+	for y := r.Y0; y < r.Y1; y++ {
+		for i := r.X0 / 8; i < r.X1/8; i++ {
 			d.SendData(d.buffer[i+y*(d.width/8)])
 		}
 	}
@@ -284,6 +394,10 @@ func (d *Device) ClearBuffer() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0x00
 	}
+	for i := range d.buffer2 {
+		d.buffer2[i] = 0x00
+	}
+	d.dirty.AddRect(0, 0, d.width, d.height)
 }
 
 // Size returns the current size of the display.
@@ -294,9 +408,19 @@ func (d *Device) Size() (w, h int16) {
 	return d.width, d.height
 }
 
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation (clock-wise) of the device. Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
 	d.rotation = rotation
+	return nil
 }
 
 // SetBlocking changes the blocking flag of the device
@@ -327,6 +451,9 @@ func (d *Device) SetSpeed(speed Speed) {
 		Rotation: d.rotation,
 		Speed:    speed,
 		Blocking: d.blocking,
+
+		FullRefreshInterval: d.dirty.Interval,
+		Grayscale:           d.grayscale,
 	})
 }
 
@@ -339,6 +466,30 @@ func (d *Device) Invert(invert bool) {
 	}
 }
 
+// setGrayLUT sets the look up tables for 4 levels of gray.
+func (d *Device) setGrayLUT() {
+	d.SendCommand(LUT_VCOM)
+	for _, b := range lutGrayVCOM {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_WW)
+	for _, b := range lutGrayWW {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_BW)
+	for _, b := range lutGrayBW {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_WB)
+	for _, b := range lutGrayWB {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_BB)
+	for _, b := range lutGrayBB {
+		d.SendData(b)
+	}
+}
+
 // SetLUT sets the look up tables for full or partial updates
 func (d *Device) SetLUT(speed Speed) {
 	switch speed {
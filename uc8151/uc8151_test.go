@@ -0,0 +1,99 @@
+package uc8151
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/image/dither"
+	"tinygo.org/x/drivers/tester"
+)
+
+var _ interface {
+	drivers.Displayer
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
+func newTestDevice(c *qt.C, cfg Config) *Device {
+	dc, busy := tester.NewPin(c), tester.NewPin(c)
+	busy.Level = true // idle
+	d := New(tester.NewSPIBus(dc), tester.NewPin(c), dc, tester.NewPin(c), busy)
+	d.Configure(cfg)
+	return &d
+}
+
+func TestDither(t *testing.T) {
+	c := qt.New(t)
+	// A black square on a white background, from an image.
+	img := func(x, y int16) color.RGBA {
+		if x >= 4 && x < 12 && y >= 2 && y < 6 {
+			return color.RGBA{A: 0xFF}
+		}
+		return color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	}
+	want := make([]uint8, 16)
+	for y := 2; y < 6; y++ {
+		want[2*y], want[2*y+1] = 0x0F, 0xF0 // set bits are black
+	}
+
+	d := newTestDevice(c, Config{Width: 16, Height: 8})
+	d.ClearBuffer()
+	o := dither.Ordered{Displayer: d, Palette: dither.BlackWhite, Colors: dither.EPaperBlackWhite}
+	for y := int16(0); y < 8; y++ {
+		for x := int16(0); x < 16; x++ {
+			o.SetPixel(x, y, img(x, y))
+		}
+	}
+	c.Assert(d.buffer, qt.DeepEquals, want)
+
+	d = newTestDevice(c, Config{Width: 16, Height: 8})
+	d.ClearBuffer()
+	f := dither.NewFloydSteinberg(dither.BlackWhite, 16)
+	f.Colors = dither.EPaperBlackWhite
+	row := make([]color.RGBA, 16)
+	for y := int16(0); y < 8; y++ {
+		for x := range row {
+			row[x] = img(int16(x), y)
+		}
+		f.DrawRow(d, 0, y, row)
+	}
+	c.Assert(d.buffer, qt.DeepEquals, want)
+
+	// In grayscale mode, the colors are drawn as they are.
+	d = newTestDevice(c, Config{Width: 16, Height: 8, Grayscale: true})
+	o = dither.Ordered{Displayer: d, Palette: dither.Gray4}
+	for y := int16(0); y < 8; y++ {
+		for x := int16(0); x < 16; x++ {
+			o.SetPixel(x, y, img(x, y))
+		}
+	}
+	c.Assert(d.buffer, qt.DeepEquals, want)
+	c.Assert(d.buffer2, qt.DeepEquals, want)
+}
+
+func TestPartialRefresh(t *testing.T) {
+	c := qt.New(t)
+	d := newTestDevice(c, Config{Width: 16, Height: 16, FullRefreshInterval: 1})
+	bus := d.bus.(*tester.SPIBus)
+	display := func() []byte {
+		bus.Commands, bus.Data = nil, nil
+		c.Assert(d.Display(), qt.IsNil)
+		return bus.Commands
+	}
+	full := []byte{PON, PTOU, DTM2, DSP, DRF}
+	c.Assert(display(), qt.DeepEquals, full)
+	c.Assert(display(), qt.HasLen, 0) // nothing changed
+
+	// The window covers whole bytes of the changed rows.
+	d.SetPixel(9, 3, color.RGBA{A: 0xFF})
+	c.Assert(display(), qt.DeepEquals, []byte{PON, PTIN, PTL, DTM2, DSP, DRF})
+	c.Assert(bus.Data, qt.DeepEquals, []byte{8, 15, 0, 3, 0, 3, 1, 0xBF})
+
+	// Every other refresh is full.
+	d.SetPixel(9, 3, color.RGBA{R: 0xFF, A: 0xFF})
+	c.Assert(display(), qt.DeepEquals, full)
+	d.SetPixel(9, 3, color.RGBA{A: 0xFF})
+	c.Assert(display(), qt.DeepEquals, []byte{PON, PTIN, PTL, DTM2, DSP, DRF})
+}
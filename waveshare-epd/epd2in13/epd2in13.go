@@ -2,6 +2,12 @@
 //
 // Datasheet: https://www.waveshare.com/w/upload/e/e6/2.13inch_e-Paper_Datasheet.pdf
 //
+// Unlike uc8151 and epd4in2, this display has no grayscale mode: its
+// controller drives all the pixels with a single waveform LUT, with no
+// separate transitions for old and new data to make levels of gray from. Use
+// the image/dither package to dither images to black and white instead, with
+// dither.EPaperBlackWhite as the colors to draw.
+//
 package epd2in13 // import "tinygo.org/x/drivers/waveshare-epd/epd2in13"
 
 import (
@@ -11,6 +17,7 @@ import (
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/dirty"
 )
 
 type Config struct {
@@ -18,6 +25,15 @@ type Config struct {
 	Height       int16
 	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
 	Rotation     Rotation // Rotation is clock-wise
+
+	// FullRefreshInterval is the number of times Display may only send the
+	// area that changed and refresh it with the faster partial update LUT,
+	// before it refreshes the whole display with the full update LUT again
+	// to remove ghosting. Zero always sends the whole buffer, refreshed with
+	// the LUT set by SetLUT.
+	FullRefreshInterval int
+
+	// There is no Grayscale option, see the package documentation.
 }
 
 type Device struct {
@@ -32,9 +48,18 @@ type Device struct {
 	buffer       []uint8
 	bufferLength uint32
 	rotation     Rotation
+
+	dirty   dirty.Refresher // pixels changed since the last Display
+	fullLUT bool            // the LUT for full updates is loaded
 }
 
-type Rotation uint8
+var _ interface {
+	drivers.Displayer
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
+type Rotation = drivers.Rotation
 
 // Look up table for full updates
 var lutFullUpdate = [30]uint8{
@@ -85,11 +110,13 @@ func (d *Device) Configure(cfg Config) {
 		d.height = 250
 	}
 	d.rotation = cfg.Rotation
+	d.dirty = dirty.Refresher{Interval: cfg.FullRefreshInterval}
 	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
 	d.buffer = make([]uint8, d.bufferLength)
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 
 	d.cs.Low()
 	d.dc.Low()
@@ -155,6 +182,7 @@ func (d *Device) sendDataCommand(isCommand bool, data uint8) {
 
 // SetLUT sets the look up tables for full or partial updates
 func (d *Device) SetLUT(fullUpdate bool) {
+	d.fullLUT = fullUpdate
 	d.SendCommand(WRITE_LUT_REGISTER)
 	if fullUpdate {
 		for i := 0; i < 30; i++ {
@@ -171,35 +199,71 @@ func (d *Device) SetLUT(fullUpdate bool) {
 // The display have 2 colors: black and white
 // We use RGBA(0,0,0, 255) as white (transparent)
 // Anything else as black
+// To dither images, use dither.BlackWhite with dither.EPaperBlackWhite as the
+// colors to draw.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 	x, y = d.xy(x, y)
 	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
 		return
 	}
 	byteIndex := (x + y*d.logicalWidth) / 8
+	old := d.buffer[byteIndex]
 	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
 		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
 	} else { // WHITE / EMPTY
 		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
 }
 
-// Display sends the buffer to the screen.
+// Display sends the buffer to the screen. If Config.FullRefreshInterval is
+// set, it may only send and refresh the area that changed since the previous
+// call.
 func (d *Device) Display() error {
-	d.setMemoryArea(0, 0, d.logicalWidth-1, d.height-1)
-	for j := int16(0); j < d.height; j++ {
-		d.setMemoryPointer(0, j)
+	r, partial := d.dirty.Next(dirty.Rect{X1: d.logicalWidth, Y1: d.height})
+	if d.dirty.Interval == 0 {
+		d.writeWindow(r)
+		d.refresh()
+		return nil
+	}
+	if r.Empty() {
+		return nil
+	}
+	if partial {
+		r = r.AlignX(8)
+	}
+	if d.fullLUT == partial {
+		d.SetLUT(!partial)
+	}
+	d.writeWindow(r)
+	d.refresh()
+	// The controller switches to its other RAM bank after each refresh, so
+	// write the window again to keep both banks equal for partial refreshes.
+	d.writeWindow(r)
+	return nil
+}
+
+// writeWindow writes a window of the buffer to the RAM of the display. X0
+// and X1 must be multiples of 8.
+func (d *Device) writeWindow(r dirty.Rect) {
+	d.setMemoryArea(r.X0, r.Y0, r.X1-1, r.Y1-1)
+	for y := r.Y0; y < r.Y1; y++ {
+		d.setMemoryPointer(r.X0, y)
 		d.SendCommand(WRITE_RAM)
-		for i := int16(0); i < d.logicalWidth/8; i++ {
-			d.SendData(d.buffer[i+j*(d.logicalWidth/8)])
+		for i := r.X0 / 8; i < r.X1/8; i++ {
+			d.SendData(d.buffer[int32(i)+int32(y)*int32(d.logicalWidth/8)])
 		}
 	}
+}
 
+// refresh updates the display with the content of its RAM.
+func (d *Device) refresh() {
 	d.SendCommand(DISPLAY_UPDATE_CONTROL_2)
 	d.SendData(0xC4)
 	d.SendCommand(MASTER_ACTIVATION)
 	d.SendCommand(TERMINATE_FRAME_READ_WRITE)
-	return nil
 }
 
 // DisplayRect sends only an area of the buffer to the screen.
@@ -230,21 +294,8 @@ func (d *Device) DisplayRect(x int16, y int16, width int16, height int16) error
 	if height > d.height {
 		height = d.height
 	}
-	d.setMemoryArea(x, y, width, height)
-	x = x / 8
-	width = width / 8
-	for ; y < height; y++ {
-		d.setMemoryPointer(8*x, y)
-		d.SendCommand(WRITE_RAM)
-		for i := int16(x); i < width; i++ {
-			d.SendData(d.buffer[i+y*d.logicalWidth/8])
-		}
-	}
-
-	d.SendCommand(DISPLAY_UPDATE_CONTROL_2)
-	d.SendData(0xC4)
-	d.SendCommand(MASTER_ACTIVATION)
-	d.SendCommand(TERMINATE_FRAME_READ_WRITE)
+	d.writeWindow(dirty.Rect{X0: x, Y0: y, X1: width, Y1: height})
+	d.refresh()
 	return nil
 }
 
@@ -256,6 +307,7 @@ func (d *Device) ClearDisplay() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.SendData(0xFF)
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 	d.Display()
 }
 
@@ -298,6 +350,7 @@ func (d *Device) ClearBuffer() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 }
 
 // Size returns the current size of the display.
@@ -308,9 +361,19 @@ func (d *Device) Size() (w, h int16) {
 	return d.logicalWidth, d.height
 }
 
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation (clock-wise) of the device. Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
 	d.rotation = rotation
+	return nil
 }
 
 // xy chages the coordinates according to the rotation
@@ -31,6 +31,8 @@ type Device struct {
 	bufferLength uint32
 }
 
+var _ drivers.Displayer = (*Device)(nil)
+
 type Color uint8
 
 // New returns a new epd2in13x driver. Pass in a fully configured SPI bus.
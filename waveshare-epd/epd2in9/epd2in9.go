@@ -12,11 +12,13 @@
 package epd2in9 // import "tinygo.org/x/drivers/waveshare-epd/epd2in9"
 
 import (
+	"errors"
 	"image/color"
 	"machine"
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/dirty"
 )
 
 type Config struct {
@@ -24,6 +26,11 @@ type Config struct {
 	Height       int16
 	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
 	Rotation     Rotation // Rotation is clock-wise
+
+	// FullRefreshInterval is the number of times Display may only refresh
+	// the area that changed, with the partial update LUT, before a full
+	// refresh removes the ghosting. Zero disables partial refreshes.
+	FullRefreshInterval int
 }
 
 type Device struct {
@@ -38,9 +45,18 @@ type Device struct {
 	buffer       []uint8
 	bufferLength uint32
 	rotation     Rotation
+
+	dirty   dirty.Refresher // pixels changed since the last Display
+	fullLUT bool            // the LUT for full updates is loaded
 }
 
-type Rotation uint8
+var _ interface {
+	drivers.Displayer
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
+type Rotation = drivers.Rotation
 
 // Look up table for full updates
 var lutFullUpdate = [30]uint8{
@@ -93,11 +109,13 @@ func (d *Device) Configure(cfg Config) {
 		d.height = 296
 	}
 	d.rotation = cfg.Rotation
+	d.dirty = dirty.Refresher{Interval: cfg.FullRefreshInterval}
 	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
 	d.buffer = make([]uint8, d.bufferLength)
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 
 	d.cs.Low()
 	d.dc.Low()
@@ -163,6 +181,7 @@ func (d *Device) sendDataCommand(isCommand bool, data uint8) {
 
 // SetLUT sets the look up tables for full or partial updates
 func (d *Device) SetLUT(fullUpdate bool) {
+	d.fullLUT = fullUpdate
 	d.SendCommand(WRITE_LUT_REGISTER)
 	if fullUpdate {
 		for i := 0; i < 30; i++ {
@@ -179,35 +198,96 @@ func (d *Device) SetLUT(fullUpdate bool) {
 // The display have 2 colors: black and white
 // We use RGBA(0,0,0, 255) as white (transparent)
 // Anything else as black
+// To dither images, use dither.BlackWhite with dither.EPaperBlackWhite as the
+// colors to draw.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
 	x, y = d.xy(x, y)
 	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
 		return
 	}
 	byteIndex := (int32(x) + int32(y)*int32(d.logicalWidth)) / 8
+	old := d.buffer[byteIndex]
 	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
 		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
 	} else { // WHITE / EMPTY
 		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
 }
 
-// Display sends the buffer to the screen.
+// Display sends the buffer to the screen. If Config.FullRefreshInterval is
+// set, it may only send and refresh the area that changed since the previous
+// call.
 func (d *Device) Display() error {
-	d.setMemoryArea(0, 0, d.logicalWidth-1, d.height-1)
-	for j := int16(0); j < d.height; j++ {
-		d.setMemoryPointer(0, j)
+	r, partial := d.dirty.Next(dirty.Rect{X1: d.logicalWidth, Y1: d.height})
+	if d.dirty.Interval == 0 {
+		d.writeWindow(r)
+		d.refresh()
+		return nil
+	}
+	if r.Empty() {
+		return nil
+	}
+	if partial {
+		r = r.AlignX(8)
+	}
+	if d.fullLUT == partial {
+		d.SetLUT(!partial)
+	}
+	d.displayWindow(r)
+	return nil
+}
+
+// DisplayRect sends the given rectangle of the buffer to the screen and
+// refreshes it with the LUT that is loaded, see SetLUT.
+func (d *Device) DisplayRect(x, y, width, height int16) error {
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > w || y+height > h {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	var r dirty.Rect
+	r.Add(d.xy(x, y))
+	r.Add(d.xy(x+width-1, y+height-1))
+	r = r.AlignX(8)
+	if r.X0 < 0 {
+		r.X0 = 0 // outside of Width when rotated
+	}
+	d.displayWindow(r)
+	return nil
+}
+
+// displayWindow writes a window of the buffer to the display and refreshes
+// it. X0 and X1 must be multiples of 8.
+func (d *Device) displayWindow(r dirty.Rect) {
+	d.writeWindow(r)
+	d.refresh()
+	// The controller switches to its other RAM bank after each refresh, so
+	// write the window again to keep both banks equal for partial refreshes.
+	d.writeWindow(r)
+}
+
+// writeWindow writes a window of the buffer to the RAM of the display. X0
+// and X1 must be multiples of 8.
+func (d *Device) writeWindow(r dirty.Rect) {
+	d.setMemoryArea(r.X0, r.Y0, r.X1-1, r.Y1-1)
+	for y := r.Y0; y < r.Y1; y++ {
+		d.setMemoryPointer(r.X0, y)
 		d.SendCommand(WRITE_RAM)
-		for i := int16(0); i < d.logicalWidth/8; i++ {
-			d.SendData(d.buffer[i+j*(d.logicalWidth/8)])
+		for i := r.X0 / 8; i < r.X1/8; i++ {
+			d.SendData(d.buffer[int32(i)+int32(y)*int32(d.logicalWidth/8)])
 		}
 	}
+}
 
+// refresh updates the display with the content of its RAM.
+func (d *Device) refresh() {
 	d.SendCommand(DISPLAY_UPDATE_CONTROL_2)
 	d.SendData(0xC4)
 	d.SendCommand(MASTER_ACTIVATION)
 	d.SendCommand(TERMINATE_FRAME_READ_WRITE)
-	return nil
 }
 
 // ClearDisplay erases the device SRAM
@@ -218,6 +298,7 @@ func (d *Device) ClearDisplay() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.SendData(0xFF)
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 	d.Display()
 }
 
@@ -260,6 +341,7 @@ func (d *Device) ClearBuffer() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 }
 
 // Size returns the current size of the display.
@@ -270,9 +352,19 @@ func (d *Device) Size() (w, h int16) {
 	return d.logicalWidth, d.height
 }
 
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation (clock-wise) of the device. Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
 	d.rotation = rotation
+	return nil
 }
 
 // xy chages the coordinates according to the rotation
@@ -9,11 +9,14 @@
 package epd4in2
 
 import (
+	"errors"
 	"image/color"
 	"machine"
 	"time"
 
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/image/dither"
+	"tinygo.org/x/drivers/internal/dirty"
 )
 
 type Config struct {
@@ -21,6 +24,18 @@ type Config struct {
 	Height       int16
 	LogicalWidth int16    // LogicalWidth must be a multiple of 8 and same size or bigger than Width
 	Rotation     Rotation // Rotation is clock-wise
+
+	// FullRefreshInterval is the number of times Display may only send the
+	// area that changed before it sends the whole buffer again. The whole
+	// display is refreshed in both cases, as this display has no partial
+	// update LUT. Zero always sends the whole buffer.
+	FullRefreshInterval int
+
+	// Grayscale enables 4 levels of gray: SetPixel shows colors as black,
+	// dark gray, light gray or white depending on their brightness, so that
+	// color.RGBA{0, 0, 0, 255} is black. Partial transfers are not supported
+	// in this mode.
+	Grayscale bool
 }
 
 type Device struct {
@@ -33,11 +48,21 @@ type Device struct {
 	width        int16
 	height       int16
 	buffer       []uint8
+	buffer2      []uint8 // low bit of the gray levels, in grayscale mode
 	bufferLength uint32
 	rotation     Rotation
+	grayscale    bool
+
+	dirty dirty.Refresher // pixels changed since the last Display
 }
 
-type Rotation uint8
+var _ interface {
+	drivers.Displayer
+	drivers.Rotater
+	drivers.PartialDisplayer
+} = (*Device)(nil)
+
+type Rotation = drivers.Rotation
 
 // New returns a new epd4in2 driver. Pass in a fully configured SPI bus.
 func New(bus drivers.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) Device {
@@ -72,11 +97,19 @@ func (d *Device) Configure(cfg Config) {
 		d.height = EPD_HEIGHT
 	}
 	d.rotation = cfg.Rotation
+	d.grayscale = cfg.Grayscale
+	d.dirty = dirty.Refresher{}
+	if !d.grayscale {
+		d.dirty.Interval = cfg.FullRefreshInterval
+	}
 	d.bufferLength = (uint32(d.logicalWidth) * uint32(d.height)) / 8
 	d.buffer = make([]uint8, d.bufferLength)
-	for i := uint32(0); i < d.bufferLength; i++ {
-		d.buffer[i] = 0xFF
+	d.buffer2 = nil
+	if d.grayscale {
+		d.buffer2 = make([]uint8, d.bufferLength)
 	}
+	d.ClearBuffer()
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 
 	d.cs.Low()
 	d.dc.Low()
@@ -229,25 +262,99 @@ func (d *Device) SetLUT() {
 	}
 }
 
+// setGrayLUT sets the look up tables for 4 levels of gray.
+func (d *Device) setGrayLUT() {
+	d.SendCommand(LUT_FOR_VCOM)
+	for _, b := range lutGrayVCOM {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_WHITE_TO_WHITE)
+	for _, b := range lutGrayWW {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_BLACK_TO_WHITE)
+	for _, b := range lutGrayBW {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_WHITE_TO_BLACK)
+	for _, b := range lutGrayWB {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_BLACK_TO_BLACK)
+	for _, b := range lutGrayBB {
+		d.SendData(b)
+	}
+	d.SendCommand(LUT_VCOM2)
+	for _, b := range lutGrayWW {
+		d.SendData(b)
+	}
+}
+
 // SetPixel modifies the internal buffer in a single pixel.
 // The display have 2 colors: black and white
 // We use RGBA(0,0,0, 255) as white (transparent)
 // Anything else as black
+// To dither images, use dither.BlackWhite with dither.EPaperBlackWhite as the
+// colors to draw.
+//
+// In grayscale mode, the pixel is set to the gray closest to c: unlike in
+// black and white mode, RGBA(0,0,0, 255) is black and RGBA(255,255,255, 255)
+// white.
 func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
+	if d.grayscale {
+		d.SetGrayPixel(x, y, uint8(dither.Gray4.Index(c)))
+		return
+	}
 	x, y = d.xy(x, y)
 	if x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
 		return
 	}
 	byteIndex := (uint32(x) + uint32(y)*uint32(d.logicalWidth)) / 8
+	old := d.buffer[byteIndex]
 	if c.R == 0 && c.G == 0 && c.B == 0 { // TRANSPARENT / WHITE
 		d.buffer[byteIndex] |= 0x80 >> uint8(x%8)
 	} else { // WHITE / EMPTY
 		d.buffer[byteIndex] &^= 0x80 >> uint8(x%8)
 	}
+	if d.buffer[byteIndex] != old {
+		d.dirty.Add(x, y)
+	}
+}
+
+// SetGrayPixel sets a pixel to a level of gray, from 0 (black) to 3 (white),
+// in grayscale mode.
+func (d *Device) SetGrayPixel(x int16, y int16, level uint8) {
+	x, y = d.xy(x, y)
+	if !d.grayscale || x < 0 || x >= d.logicalWidth || y < 0 || y >= d.height {
+		return
+	}
+	byteIndex := (uint32(x) + uint32(y)*uint32(d.logicalWidth)) / 8
+	mask := uint8(0x80) >> uint8(x%8)
+	// bit set: lighter, the same as in black and white mode
+	if level&2 != 0 {
+		d.buffer[byteIndex] |= mask
+	} else {
+		d.buffer[byteIndex] &^= mask
+	}
+	if level&1 != 0 {
+		d.buffer2[byteIndex] |= mask
+	} else {
+		d.buffer2[byteIndex] &^= mask
+	}
 }
 
-// Display sends the buffer to the screen.
+// Display sends the buffer to the screen. If Config.FullRefreshInterval is
+// set, it may only send the area that changed since the previous call.
 func (d *Device) Display() error {
+	r, partial := d.dirty.Next(dirty.Rect{X1: d.logicalWidth, Y1: d.height})
+	if r.Empty() {
+		return nil
+	}
+	if partial {
+		d.displayWindow(r.AlignX(8))
+		return nil
+	}
+
 	d.SendCommand(RESOLUTION_SETTING)
 	d.SendData(uint8(d.height >> 8))
 	d.SendData(uint8(d.logicalWidth & 0xff))
@@ -263,16 +370,28 @@ func (d *Device) Display() error {
 	d.SendCommand(DATA_START_TRANSMISSION_1)
 	var i int16
 	for i = 0; i < d.logicalWidth/8*d.height; i++ {
-		d.SendData(0xFF) // bit set: white, bit reset: black
+		if d.grayscale {
+			d.SendData(d.buffer[i]) // high bit of the gray levels
+		} else {
+			d.SendData(0xFF) // bit set: white, bit reset: black
+		}
 	}
 	time.Sleep(2 * time.Millisecond)
 	d.SendCommand(DATA_START_TRANSMISSION_2)
 	for i = 0; i < d.logicalWidth/8*d.height; i++ {
-		d.SendData(d.buffer[i])
+		if d.grayscale {
+			d.SendData(d.buffer2[i]) // low bit of the gray levels
+		} else {
+			d.SendData(d.buffer[i])
+		}
 	}
 	time.Sleep(2 * time.Millisecond)
 
-	d.SetLUT()
+	if d.grayscale {
+		d.setGrayLUT()
+	} else {
+		d.SetLUT()
+	}
 
 	d.SendCommand(DISPLAY_REFRESH)
 	time.Sleep(100 * time.Millisecond)
@@ -281,8 +400,66 @@ func (d *Device) Display() error {
 	return nil
 }
 
+// DisplayRect sends the given rectangle of the buffer to the screen and
+// refreshes it. In grayscale mode, it sends the whole buffer.
+func (d *Device) DisplayRect(x, y, width, height int16) error {
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > w || y+height > h {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	var r dirty.Rect
+	r.Add(d.xy(x, y))
+	r.Add(d.xy(x+width-1, y+height-1))
+	r = r.AlignX(8)
+	if r.X0 < 0 {
+		r.X0 = 0 // outside of Width when rotated
+	}
+	if d.grayscale {
+		return d.Display()
+	}
+	d.displayWindow(r)
+	return nil
+}
+
+// displayWindow sends a window of the buffer to the display and refreshes
+// it. X0 and X1 must be multiples of 8.
+func (d *Device) displayWindow(r dirty.Rect) {
+	d.writeWindow(r)
+	d.SetLUT()
+	d.SendCommand(DISPLAY_REFRESH)
+	time.Sleep(100 * time.Millisecond)
+	d.WaitUntilIdle()
+}
+
+// writeWindow sends a window of the buffer to the display, which keeps the
+// rest of its RAM. X0 and X1 must be multiples of 8.
+func (d *Device) writeWindow(r dirty.Rect) {
+	d.SendCommand(PARTIAL_IN)
+	d.SendCommand(PARTIAL_WINDOW)
+	d.SendData(uint8(r.X0 >> 8))
+	d.SendData(uint8(r.X0))
+	d.SendData(uint8((r.X1 - 1) >> 8))
+	d.SendData(uint8(r.X1-1) | 0x07)
+	d.SendData(uint8(r.Y0 >> 8))
+	d.SendData(uint8(r.Y0))
+	d.SendData(uint8((r.Y1 - 1) >> 8))
+	d.SendData(uint8(r.Y1 - 1))
+	d.SendData(0x01) // gates scan both inside and outside of the window
+	time.Sleep(2 * time.Millisecond)
+	d.SendCommand(DATA_START_TRANSMISSION_2)
+	for y := r.Y0; y < r.Y1; y++ {
+		for i := r.X0 / 8; i < r.X1/8; i++ {
+			d.SendData(d.buffer[int32(i)+int32(y)*int32(d.logicalWidth/8)])
+		}
+	}
+	time.Sleep(2 * time.Millisecond)
+	d.SendCommand(PARTIAL_OUT)
+}
+
 // ClearDisplay erases the device SRAM
 func (d *Device) ClearDisplay() {
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height) // the RAM no longer matches the buffer
 	d.SendCommand(RESOLUTION_SETTING)
 	d.SendData(uint8(d.height >> 8))
 	d.SendData(uint8(d.logicalWidth & 0xff))
@@ -326,6 +503,10 @@ func (d *Device) ClearBuffer() {
 	for i := uint32(0); i < d.bufferLength; i++ {
 		d.buffer[i] = 0xFF
 	}
+	for i := range d.buffer2 {
+		d.buffer2[i] = 0xFF
+	}
+	d.dirty.AddRect(0, 0, d.logicalWidth, d.height)
 }
 
 // Size returns the current size of the display.
@@ -336,9 +517,19 @@ func (d *Device) Size() (w, h int16) {
 	return d.logicalWidth, d.height
 }
 
-// SetRotation changes the rotation (clock-wise) of the device
-func (d *Device) SetRotation(rotation Rotation) {
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation (clock-wise) of the device. Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
 	d.rotation = rotation
+	return nil
 }
 
 // xy chages the coordinates according to the rotation
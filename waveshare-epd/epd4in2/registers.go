@@ -26,6 +26,7 @@ const (
 	LUT_BLACK_TO_WHITE             = 0x22
 	LUT_WHITE_TO_BLACK             = 0x23
 	LUT_BLACK_TO_BLACK             = 0x24
+	LUT_VCOM2                      = 0x25
 	PLL_CONTROL                    = 0x30
 	TEMPERATURE_SENSOR_COMMAND     = 0x40
 	TEMPERATURE_SENSOR_SELECTION   = 0x41
@@ -53,3 +54,38 @@ const (
 	ROTATION_180 Rotation = 2
 	ROTATION_270 Rotation = 3
 )
+
+// Look up tables for 4 levels of gray, derived from
+// https://github.com/waveshare/e-Paper/blob/master/RaspberryPi_JetsonNano/c/lib/e-Paper/EPD_4in2.c
+var (
+	lutGrayVCOM = [44]uint8{
+		0x00, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x60, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x13, 0x0A, 0x01, 0x00, 0x01,
+	}
+	lutGrayWW = [42]uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x10, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0xA0, 0x13, 0x01, 0x00, 0x00, 0x01,
+	}
+	lutGrayBW = [42]uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x99, 0x0C, 0x01, 0x03, 0x04, 0x01,
+	}
+	lutGrayWB = [42]uint8{
+		0x40, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x00, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x99, 0x0B, 0x04, 0x04, 0x01, 0x01,
+	}
+	lutGrayBB = [42]uint8{
+		0x80, 0x0A, 0x00, 0x00, 0x00, 0x01,
+		0x90, 0x14, 0x14, 0x00, 0x00, 0x01,
+		0x20, 0x14, 0x0A, 0x00, 0x00, 0x01,
+		0x50, 0x13, 0x01, 0x00, 0x00, 0x01,
+	}
+)
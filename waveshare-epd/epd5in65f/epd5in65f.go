@@ -0,0 +1,299 @@
+// Package epd5in65f implements a driver for Waveshare 5.65in 7-color (ACeP) e-paper device.
+//
+// Derived from:
+//   https://github.com/waveshare/e-Paper/blob/master/RaspberryPi_JetsonNano/c/lib/e-Paper/EPD_5in65f.c
+//
+// Datasheet: https://www.waveshare.com/wiki/5.65inch_e-Paper_Module_(F)
+//
+package epd5in65f // import "tinygo.org/x/drivers/waveshare-epd/epd5in65f"
+
+import (
+	"errors"
+	"image/color"
+	"machine"
+	"time"
+
+	"tinygo.org/x/drivers"
+)
+
+type Config struct {
+	Width    int16 // Width is the display resolution
+	Height   int16
+	Rotation Rotation // Rotation is clock-wise
+}
+
+type Device struct {
+	bus          drivers.SPI
+	cs           machine.Pin
+	dc           machine.Pin
+	rst          machine.Pin
+	busy         machine.Pin
+	width        int16
+	height       int16
+	buffer       []uint8 // 4 bits per pixel, the left pixel in the high nibble
+	bufferLength uint32
+	rotation     Rotation
+}
+
+var _ interface {
+	drivers.Displayer
+	drivers.RectFiller
+	drivers.Rotater
+} = (*Device)(nil)
+
+type Color uint8
+
+type Rotation = drivers.Rotation
+
+// New returns a new epd5in65f driver. Pass in a fully configured SPI bus.
+func New(bus drivers.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) Device {
+	csPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	dcPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	rstPin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	busyPin.Configure(machine.PinConfig{Mode: machine.PinInput})
+	return Device{
+		bus:  bus,
+		cs:   csPin,
+		dc:   dcPin,
+		rst:  rstPin,
+		busy: busyPin,
+	}
+}
+
+// Configure sets up the device.
+func (d *Device) Configure(cfg Config) {
+	if cfg.Width != 0 {
+		d.width = cfg.Width
+	} else {
+		d.width = EPD_WIDTH
+	}
+	if cfg.Height != 0 {
+		d.height = cfg.Height
+	} else {
+		d.height = EPD_HEIGHT
+	}
+	d.rotation = cfg.Rotation
+	d.bufferLength = (uint32(d.width) * uint32(d.height)) / 2
+	d.buffer = make([]uint8, d.bufferLength)
+	d.ClearBuffer()
+
+	d.cs.Low()
+	d.dc.Low()
+	d.rst.Low()
+
+	d.Reset()
+	d.WaitUntilIdle()
+	d.SendCommand(PANEL_SETTING)
+	d.SendData(0xEF)
+	d.SendData(0x08)
+	d.SendCommand(POWER_SETTING)
+	d.SendData(0x37)
+	d.SendData(0x00)
+	d.SendData(0x23)
+	d.SendData(0x23)
+	d.SendCommand(POWER_OFF_SEQUENCE_SETTING)
+	d.SendData(0x00)
+	d.SendCommand(BOOSTER_SOFT_START)
+	d.SendData(0xC7)
+	d.SendData(0xC7)
+	d.SendData(0x1D)
+	d.SendCommand(PLL_CONTROL)
+	d.SendData(0x3C)
+	d.SendCommand(TEMPERATURE_SENSOR_SELECTION)
+	d.SendData(0x00)
+	d.SendCommand(VCOM_AND_DATA_INTERVAL_SETTING)
+	d.SendData(0x37)
+	d.SendCommand(TCON_SETTING)
+	d.SendData(0x22)
+	d.setResolution()
+	d.SendCommand(POWER_SAVING)
+	d.SendData(0xAA)
+	time.Sleep(100 * time.Millisecond)
+	d.SendCommand(VCOM_AND_DATA_INTERVAL_SETTING)
+	d.SendData(0x37)
+}
+
+// Reset resets the device
+func (d *Device) Reset() {
+	d.rst.High()
+	time.Sleep(200 * time.Millisecond)
+	d.rst.Low()
+	time.Sleep(1 * time.Millisecond)
+	d.rst.High()
+	time.Sleep(200 * time.Millisecond)
+}
+
+// DeepSleep puts the display into deepsleep
+func (d *Device) DeepSleep() {
+	time.Sleep(100 * time.Millisecond)
+	d.SendCommand(DEEP_SLEEP)
+	d.SendData(0xA5)
+}
+
+// SendCommand sends a command to the display
+func (d *Device) SendCommand(command uint8) {
+	d.sendDataCommand(true, command)
+}
+
+// SendData sends a data byte to the display
+func (d *Device) SendData(data uint8) {
+	d.sendDataCommand(false, data)
+}
+
+// sendDataCommand sends image data or a command to the screen
+func (d *Device) sendDataCommand(isCommand bool, data uint8) {
+	if isCommand {
+		d.dc.Low()
+	} else {
+		d.dc.High()
+	}
+	d.cs.Low()
+	d.bus.Transfer(data)
+	d.cs.High()
+}
+
+// SetPixel modifies the internal buffer in a single pixel.
+// The display has 7 colors, c is set to the closest one in Palette.
+// Use the dither package for smoother results.
+func (d *Device) SetPixel(x int16, y int16, c color.RGBA) {
+	d.SetEPDPixel(x, y, Color(Palette.Index(c)))
+}
+
+// SetEPDPixel modifies the internal buffer in a single pixel.
+func (d *Device) SetEPDPixel(x int16, y int16, c Color) {
+	if w, h := d.Size(); x < 0 || x >= w || y < 0 || y >= h {
+		return
+	}
+	x, y = d.xy(x, y)
+	byteIndex := (uint32(x) + uint32(y)*uint32(d.width)) / 2
+	if x%2 == 0 {
+		d.buffer[byteIndex] = d.buffer[byteIndex]&0x0F | uint8(c)<<4
+	} else {
+		d.buffer[byteIndex] = d.buffer[byteIndex]&0xF0 | uint8(c)&0x0F
+	}
+}
+
+// FillRectangle fills a rectangle of the buffer with the color of Palette
+// closest to c.
+func (d *Device) FillRectangle(x, y, width, height int16, c color.RGBA) error {
+	w, h := d.Size()
+	if x < 0 || y < 0 || width <= 0 || height <= 0 ||
+		x+width > w || y+height > h {
+		return errors.New("rectangle coordinates outside display area")
+	}
+	epdColor := Color(Palette.Index(c))
+	for j := y; j < y+height; j++ {
+		for i := x; i < x+width; i++ {
+			d.SetEPDPixel(i, j, epdColor)
+		}
+	}
+	return nil
+}
+
+// Display sends the buffer to the screen and refreshes it, which takes about
+// 15 seconds.
+func (d *Device) Display() error {
+	d.setResolution()
+	d.SendCommand(DATA_START_TRANSMISSION_1)
+	for i := uint32(0); i < d.bufferLength; i++ {
+		d.SendData(d.buffer[i])
+	}
+	d.refresh()
+	return nil
+}
+
+// ClearDisplay fills the display with the CLEAN color, which leaves it white
+// and removes the ghosting of previous images. The buffer is not modified.
+func (d *Device) ClearDisplay() {
+	d.setResolution()
+	d.SendCommand(DATA_START_TRANSMISSION_1)
+	for i := uint32(0); i < d.bufferLength; i++ {
+		d.SendData(uint8(CLEAN)<<4 | uint8(CLEAN))
+	}
+	d.refresh()
+}
+
+// setResolution sets the resolution of the display.
+func (d *Device) setResolution() {
+	d.SendCommand(RESOLUTION_SETTING)
+	d.SendData(uint8(d.width >> 8))
+	d.SendData(uint8(d.width))
+	d.SendData(uint8(d.height >> 8))
+	d.SendData(uint8(d.height))
+}
+
+// refresh shows the data sent to the display.
+func (d *Device) refresh() {
+	d.SendCommand(POWER_ON)
+	d.WaitUntilIdle()
+	d.SendCommand(DISPLAY_REFRESH)
+	d.WaitUntilIdle()
+	d.SendCommand(POWER_OFF)
+	d.waitPowerOff()
+	time.Sleep(200 * time.Millisecond)
+}
+
+// WaitUntilIdle waits until the display is ready. The busy pin is low while
+// the display is busy.
+func (d *Device) WaitUntilIdle() {
+	for !d.busy.Get() {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitPowerOff waits until the busy pin goes low after a power off.
+func (d *Device) waitPowerOff() {
+	for d.busy.Get() {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// IsBusy returns the busy status of the display
+func (d *Device) IsBusy() bool {
+	return !d.busy.Get()
+}
+
+// ClearBuffer sets the buffer to white
+func (d *Device) ClearBuffer() {
+	for i := uint32(0); i < d.bufferLength; i++ {
+		d.buffer[i] = uint8(WHITE)<<4 | uint8(WHITE)
+	}
+}
+
+// Size returns the current size of the display.
+func (d *Device) Size() (w, h int16) {
+	if d.rotation == ROTATION_90 || d.rotation == ROTATION_270 {
+		return d.height, d.width
+	}
+	return d.width, d.height
+}
+
+// Rotation returns the current rotation of the device.
+func (d *Device) Rotation() Rotation {
+	return d.rotation
+}
+
+// SetRotation changes the rotation (clock-wise) of the device. Mirrored
+// rotations are not supported.
+func (d *Device) SetRotation(rotation Rotation) error {
+	if rotation > ROTATION_270 {
+		return drivers.ErrInvalidRotation
+	}
+	d.rotation = rotation
+	return nil
+}
+
+// xy chages the coordinates according to the rotation
+func (d *Device) xy(x, y int16) (int16, int16) {
+	switch d.rotation {
+	case NO_ROTATION:
+		return x, y
+	case ROTATION_90:
+		return d.width - y - 1, x
+	case ROTATION_180:
+		return d.width - x - 1, d.height - y - 1
+	case ROTATION_270:
+		return y, d.height - x - 1
+	}
+	return x, y
+}
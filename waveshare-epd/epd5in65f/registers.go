@@ -0,0 +1,52 @@
+package epd5in65f
+
+import "tinygo.org/x/drivers/image/dither"
+
+// Registers
+const (
+	// Display resolution
+	EPD_WIDTH  = 600
+	EPD_HEIGHT = 448
+
+	BLACK  Color = 0
+	WHITE  Color = 1
+	GREEN  Color = 2
+	BLUE   Color = 3
+	RED    Color = 4
+	YELLOW Color = 5
+	ORANGE Color = 6
+	CLEAN  Color = 7 // Used to clear the display, not a real color
+
+	PANEL_SETTING                  = 0x00
+	POWER_SETTING                  = 0x01
+	POWER_OFF                      = 0x02
+	POWER_OFF_SEQUENCE_SETTING     = 0x03
+	POWER_ON                       = 0x04
+	BOOSTER_SOFT_START             = 0x06
+	DEEP_SLEEP                     = 0x07
+	DATA_START_TRANSMISSION_1      = 0x10
+	DISPLAY_REFRESH                = 0x12
+	PLL_CONTROL                    = 0x30
+	TEMPERATURE_SENSOR_SELECTION   = 0x41
+	VCOM_AND_DATA_INTERVAL_SETTING = 0x50
+	TCON_SETTING                   = 0x60
+	RESOLUTION_SETTING             = 0x61
+	POWER_SAVING                   = 0xE3
+
+	NO_ROTATION  Rotation = 0
+	ROTATION_90  Rotation = 1 // 90 degrees clock-wise rotation
+	ROTATION_180 Rotation = 2
+	ROTATION_270 Rotation = 3
+)
+
+// Palette holds the colors of the display, indexed by Color. It can be used
+// with the dither package.
+var Palette = dither.Palette{
+	BLACK:  {0x00, 0x00, 0x00, 0xFF},
+	WHITE:  {0xFF, 0xFF, 0xFF, 0xFF},
+	GREEN:  {0x00, 0xFF, 0x00, 0xFF},
+	BLUE:   {0x00, 0x00, 0xFF, 0xFF},
+	RED:    {0xFF, 0x00, 0x00, 0xFF},
+	YELLOW: {0xFF, 0xFF, 0x00, 0xFF},
+	ORANGE: {0xFF, 0x80, 0x00, 0xFF},
+}
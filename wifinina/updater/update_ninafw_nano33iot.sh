@@ -8,6 +8,6 @@ bossac -d -i -e -w -v -R --port=/dev/ttyACM0 --offset=0x2000 ./SerialNINAPassthr
 # download the nina-fw binary
 wget https://github.com/arduino/nina-fw/releases/download/1.4.8/NINA_W102-v1.4.8.bin 
 
-# flash the nina-fw binary to the ESP32 using esptool
-esptool --port /dev/ttyACM0 --before default_reset --baud 115200 write_flash 0 ./NINA_W102-v1.4.8.bin
+# flash the nina-fw binary to the ESP32 using tinygo.org/x/drivers/cmd/ninafw
+go run tinygo.org/x/drivers/cmd/ninafw -port /dev/ttyACM0 -baud 115200 ./NINA_W102-v1.4.8.bin
 
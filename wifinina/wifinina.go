@@ -15,9 +15,8 @@ import (
 	"sync"
 	"time"
 
-	"machine"
-
 	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/internal/legacy"
 	"tinygo.org/x/drivers/net"
 )
 
@@ -275,10 +274,10 @@ type command struct {
 
 type Device struct {
 	SPI   drivers.SPI
-	CS    machine.Pin
-	ACK   machine.Pin
-	GPIO0 machine.Pin
-	RESET machine.Pin
+	CS    drivers.PinOutput
+	ACK   drivers.PinInput
+	GPIO0 drivers.PinOutput
+	RESET drivers.PinOutput
 
 	buf   [64]byte
 	ssids [10]string
@@ -292,8 +291,9 @@ type Device struct {
 	mu    sync.Mutex
 }
 
-// New returns a new Wifinina device.
-func New(bus drivers.SPI, csPin, ackPin, gpio0Pin, resetPin machine.Pin) *Device {
+// New returns a new Wifinina device. The pins are configured by Configure if
+// they are a machine.Pin, other pins must already be configured.
+func New(bus drivers.SPI, csPin drivers.PinOutput, ackPin drivers.PinInput, gpio0Pin, resetPin drivers.PinOutput) *Device {
 	return &Device{
 		SPI:   bus,
 		CS:    csPin,
@@ -307,10 +307,10 @@ func (d *Device) Configure() {
 	net.UseDriver(d)
 	pinUseDevice(d)
 
-	d.CS.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	d.ACK.Configure(machine.PinConfig{Mode: machine.PinInput})
-	d.RESET.Configure(machine.PinConfig{Mode: machine.PinOutput})
-	d.GPIO0.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	legacy.ConfigurePinOut(d.CS)
+	legacy.ConfigurePinInput(d.ACK)
+	legacy.ConfigurePinOut(d.RESET)
+	legacy.ConfigurePinOut(d.GPIO0)
 
 	d.GPIO0.High()
 	d.CS.High()
@@ -320,7 +320,7 @@ func (d *Device) Configure() {
 	time.Sleep(1 * time.Millisecond)
 
 	d.GPIO0.Low()
-	d.GPIO0.Configure(machine.PinConfig{Mode: machine.PinInput})
+	legacy.ConfigurePinInput(d.GPIO0)
 
 }
 
@@ -8,6 +8,8 @@ import (
 	"errors"
 	"image/color"
 	"machine"
+
+	"tinygo.org/x/drivers/ledstrip"
 )
 
 var errUnknownClockSpeed = errors.New("ws2812: unknown CPU clock speed")
@@ -41,3 +43,16 @@ func (d Device) WriteColors(buf []color.RGBA) error {
 	}
 	return nil
 }
+
+// WriteBuffer writes the colors of the buffer out using the WS2812 protocol,
+// encoded with the color order, brightness and gamma of the buffer.
+func (d Device) WriteBuffer(b *ledstrip.Buffer) error {
+	var buf [4]byte
+	for i := 0; i < b.Len(); i++ {
+		n := b.Encode(buf[:], i)
+		for _, c := range buf[:n] {
+			d.WriteByte(c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+// Package ws2812spi implements a driver for WS2812 and SK6812 LED strips that
+// generates the signal with the data output of a SPI bus.
+//
+// Unlike the ws2812 package, it works at any CPU clock speed and doesn't
+// disable interrupts. Each bit sent to the LEDs is encoded as 3 SPI bits, with
+// the bus running at 2.4 MHz, or as 4 SPI bits at 3.2 MHz. Only the SDO (MOSI)
+// pin is connected to the LEDs.
+//
+// If the bus implements drivers.AsyncSPI, for example using DMA, the colors
+// are sent in the background.
+package ws2812spi // import "tinygo.org/x/drivers/ws2812spi"
+
+import (
+	"errors"
+	"image/color"
+
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/ledstrip"
+)
+
+// Frequencies of the SPI bus for 3 and 4 bits per LED bit.
+const (
+	Frequency3Bits = 2400000
+	Frequency4Bits = 3200000
+)
+
+// resetTime is the time in microseconds the signal must stay low for the LEDs
+// to show the colors they received. It is 50µs for the original WS2812, but
+// 280µs for newer ones.
+const resetTime = 300
+
+var errBitsPerBit = errors.New("ws2812spi: bits per bit must be 3 or 4")
+
+// Device wraps a SPI bus connected to WS2812 LEDs.
+type Device struct {
+	bus        drivers.SPI
+	async      drivers.AsyncSPI
+	bitsPerBit int
+	buf        []byte // encoded bits, followed by the reset
+	sending    bool
+}
+
+// New returns a new WS2812 driver that encodes each bit sent to the LEDs as
+// bitsPerBit SPI bits, 3 or 4. The bus must already be configured, at
+// Frequency3Bits or Frequency4Bits respectively, in mode 0.
+func New(bus drivers.SPI, bitsPerBit int) (*Device, error) {
+	if bitsPerBit != 3 && bitsPerBit != 4 {
+		return nil, errBitsPerBit
+	}
+	d := &Device{
+		bus:        bus,
+		bitsPerBit: bitsPerBit,
+	}
+	d.async, _ = bus.(drivers.AsyncSPI)
+	return d, nil
+}
+
+// Write the raw bitstring out using the WS2812 protocol.
+func (d *Device) Write(buf []byte) (n int, err error) {
+	if err := d.Wait(); err != nil {
+		return 0, err
+	}
+	d.buf = Encode(d.buf[:0], buf, d.bitsPerBit)
+	return len(buf), d.send()
+}
+
+// WriteColors writes the given color slice out using the WS2812 protocol.
+// Colors are sent out in the usual GRB format.
+func (d *Device) WriteColors(buf []color.RGBA) error {
+	if err := d.Wait(); err != nil {
+		return err
+	}
+	d.buf = d.buf[:0]
+	for _, c := range buf {
+		d.buf = Encode(d.buf, []byte{c.G, c.R, c.B}, d.bitsPerBit)
+	}
+	return d.send()
+}
+
+// WriteBuffer writes the colors of the buffer out using the WS2812 protocol,
+// encoded with the color order, brightness and gamma of the buffer.
+func (d *Device) WriteBuffer(b *ledstrip.Buffer) error {
+	if err := d.Wait(); err != nil {
+		return err
+	}
+	d.buf = d.buf[:0]
+	var px [4]byte
+	for i := 0; i < b.Len(); i++ {
+		n := b.Encode(px[:], i)
+		d.buf = Encode(d.buf, px[:n], d.bitsPerBit)
+	}
+	return d.send()
+}
+
+// Wait blocks until the previous write has been sent, when the bus
+// implements drivers.AsyncSPI. Writes call it before reusing the buffer.
+func (d *Device) Wait() error {
+	if !d.sending {
+		return nil
+	}
+	d.sending = false
+	return d.async.Wait()
+}
+
+// send appends the reset to the encoded bits and sends them.
+func (d *Device) send() error {
+	for i := ResetLen(d.bitsPerBit); i > 0; i-- {
+		d.buf = append(d.buf, 0)
+	}
+	if d.async == nil {
+		return d.bus.Tx(d.buf, nil)
+	}
+	if err := d.async.StartTx(d.buf); err != nil {
+		return err
+	}
+	d.sending = true
+	return nil
+}
+
+// Encode appends the SPI bytes that send data to the LEDs to dst, and returns
+// the extended slice. Each bit of data becomes bitsPerBit bits, 3 or 4:
+// 100 or 110 with 3 bits, 1000 or 1110 with 4 bits, for 0 and 1
+// respectively, most significant bits first.
+func Encode(dst, data []byte, bitsPerBit int) []byte {
+	for _, b := range data {
+		if bitsPerBit == 3 {
+			var v uint32
+			for i := 0; i < 8; i++ {
+				v <<= 3
+				if b&0x80 != 0 {
+					v |= 0b110
+				} else {
+					v |= 0b100
+				}
+				b <<= 1
+			}
+			dst = append(dst, byte(v>>16), byte(v>>8), byte(v))
+		} else {
+			for i := 0; i < 4; i++ {
+				v := byte(0x88) // 1000 1000
+				if b&0x80 != 0 {
+					v |= 0x60
+				}
+				if b&0x40 != 0 {
+					v |= 0x06
+				}
+				dst = append(dst, v)
+				b <<= 2
+			}
+		}
+	}
+	return dst
+}
+
+// EncodedLen returns the number of SPI bytes that encode n bytes for the
+// LEDs, without the reset.
+func EncodedLen(n, bitsPerBit int) int {
+	return n * bitsPerBit
+}
+
+// ResetLen returns the number of zero bytes sent after the data, which keep
+// the signal low long enough for the LEDs to show the new colors.
+func ResetLen(bitsPerBit int) int {
+	return resetTime * 800000 * bitsPerBit / 8 / 1000000
+}
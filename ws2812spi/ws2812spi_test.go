@@ -0,0 +1,75 @@
+package ws2812spi
+
+import (
+	"image/color"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"tinygo.org/x/drivers/ledstrip"
+	"tinygo.org/x/drivers/tester"
+)
+
+func TestEncode(t *testing.T) {
+	c := qt.New(t)
+	// 0xA5 = 1010 0101
+	// 110 100 110 100 100 110 100 110
+	c.Assert(Encode(nil, []byte{0xA5}, 3), qt.DeepEquals, []byte{0b11010011, 0b01001001, 0b10100110})
+	c.Assert(Encode(nil, []byte{0x00}, 3), qt.DeepEquals, []byte{0x92, 0x49, 0x24})
+	c.Assert(Encode(nil, []byte{0xFF}, 3), qt.DeepEquals, []byte{0xDB, 0x6D, 0xB6})
+	// 1110 1000 1110 1000 1000 1110 1000 1110
+	c.Assert(Encode(nil, []byte{0xA5}, 4), qt.DeepEquals, []byte{0xE8, 0xE8, 0x8E, 0x8E})
+	c.Assert(Encode([]byte{1}, []byte{0, 0}, 4), qt.DeepEquals, []byte{1, 0x88, 0x88, 0x88, 0x88, 0x88, 0x88, 0x88, 0x88})
+	c.Assert(EncodedLen(3, 3), qt.Equals, 9)
+	c.Assert(ResetLen(3), qt.Equals, 90) // 300µs at 2.4 MHz
+	c.Assert(ResetLen(4), qt.Equals, 120)
+}
+
+func TestWrite(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewSPIBus(nil)
+	d, err := New(bus, 4)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(d.WriteColors([]color.RGBA{{R: 0xFF}}), qt.IsNil)
+	want := Encode(nil, []byte{0x00, 0xFF, 0x00}, 4)
+	want = append(want, make([]byte, ResetLen(4))...)
+	c.Assert(bus.Data, qt.DeepEquals, want)
+
+	bus.Data = nil
+	b := ledstrip.NewBuffer(1, ledstrip.GRBW)
+	b.Pixels[0] = color.RGBA{R: 0x10, G: 0x10, B: 0x10}
+	c.Assert(d.WriteBuffer(b), qt.IsNil)
+	want = Encode(nil, []byte{0, 0, 0, 0x10}, 4)
+	want = append(want, make([]byte, ResetLen(4))...)
+	c.Assert(bus.Data, qt.DeepEquals, want)
+
+	bus.Data = nil
+	n, err := d.Write([]byte{1, 2, 3})
+	c.Assert(err, qt.IsNil)
+	c.Assert(n, qt.Equals, 3)
+	c.Assert(len(bus.Data), qt.Equals, EncodedLen(3, 4)+ResetLen(4))
+
+	_, err = New(bus, 5)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestWriteAsync(t *testing.T) {
+	c := qt.New(t)
+	bus := tester.NewAsyncSPIBus(nil)
+	d, err := New(bus, 3)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(d.WriteColors([]color.RGBA{{G: 0xFF}}), qt.IsNil)
+	c.Assert(bus.Data, qt.HasLen, 0)
+	c.Assert(bus.Pending, qt.HasLen, EncodedLen(3, 3)+ResetLen(3))
+
+	// The next write waits for the previous one before reusing the buffer.
+	c.Assert(d.WriteColors([]color.RGBA{{B: 0xFF}}), qt.IsNil)
+	c.Assert(bus.Waits, qt.Equals, 1)
+	c.Assert(bus.Data[:9], qt.DeepEquals, Encode(nil, []byte{0xFF, 0, 0}, 3))
+
+	c.Assert(d.Wait(), qt.IsNil)
+	c.Assert(d.Wait(), qt.IsNil)
+	c.Assert(bus.Waits, qt.Equals, 2)
+	c.Assert(bus.Data[90+9:90+18], qt.DeepEquals, Encode(nil, []byte{0, 0, 0xFF}, 3))
+}